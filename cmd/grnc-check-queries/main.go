@@ -0,0 +1,112 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+/*
+The grnc-check-queries tool - used to check that the query IDs and parameter names used in your application's code
+match the query templates loaded by the QueryManager facility.
+
+Typos in query IDs or parameter names are otherwise only discovered when the query is executed. This tool parses the Go
+source files in your application (excluding tests and vendored code) and finds calls to the methods of rdbms.Client and
+dsquery.QueryManager that accept a query ID. Where the query ID is a string literal or a constant declared in the same
+package, the tool checks that a template with that ID exists (or that the ID is registered with RegisterTempQuery). For
+methods that accept a single named parameter (e.g. SelectBindQIDParam), the tool also checks that the query uses a
+variable with that name.
+
+Calls are matched by method name only, so methods with the same names on other types will also be checked.
+
+In most cases, grnc-check-queries will be run, without arguments, in your application's root directory (the same folder
+that contains your resource directory). It exits with a non-zero status if any problems are found, so it can be run
+as part of a build.
+
+Usage of grnc-check-queries:
+
+	grnc-check-queries [-s source-dirs] [-t template-location] [-p query-id-prefix] [-v var-regex]
+
+	-s string
+		A comma separated list of directories containing Go source files to check (default ".")
+	-t string
+		The directory containing your query templates (default "resource/queries")
+	-p string
+		The prefix of lines in template files that start a new query (default "ID:")
+	-v string
+		The regular expression used to find variables in templates (default "\$\{([^\}]*)\}")
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/logging"
+	"os"
+	"strings"
+)
+
+const (
+	toolName = "grnc-check-queries"
+
+	sourceFlag    = "s"
+	sourceDefault = "."
+	sourceHelp    = "A comma separated list of directories containing Go source files to check"
+
+	templateFlag    = "t"
+	templateDefault = "resource/queries"
+	templateHelp    = "The directory containing your query templates"
+
+	prefixFlag    = "p"
+	prefixDefault = "ID:"
+	prefixHelp    = "The prefix of lines in template files that start a new query"
+
+	varFlag    = "v"
+	varDefault = "\\$\\{([^\\}]*)\\}"
+	varHelp    = "The regular expression used to find variables in templates"
+)
+
+func main() {
+
+	sources := flag.String(sourceFlag, sourceDefault, sourceHelp)
+	templates := flag.String(templateFlag, templateDefault, templateHelp)
+	prefix := flag.String(prefixFlag, prefixDefault, prefixHelp)
+	varRegex := flag.String(varFlag, varDefault, varHelp)
+
+	flag.Parse()
+
+	qm := dsquery.NewTemplatedQueryManager()
+	qm.TemplateLocation = *templates
+	qm.QueryIDPrefix = *prefix
+	qm.VarMatchRegEx = *varRegex
+	qm.TrimIDWhiteSpace = true
+	qm.ElementSeparator = ", "
+	qm.ValueProcessor = new(dsquery.SQLProcessor)
+	qm.FrameworkLogger = logging.NewStdoutLogger(logging.Error, toolName+": ")
+
+	if err := qm.StartComponent(); err != nil {
+		exitError(err.Error())
+	}
+
+	sc := newScanner()
+
+	for _, dir := range strings.Split(*sources, ",") {
+
+		if err := sc.scanDirectory(strings.TrimSpace(dir)); err != nil {
+			exitError(err.Error())
+		}
+	}
+
+	problems := sc.check(qm)
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) > 0 {
+		exitError(fmt.Sprintf("%d problems found", len(problems)))
+	}
+
+	fmt.Printf("%s: %d query references checked against %d queries\n", toolName, len(sc.references), len(qm.QueryIDs()))
+}
+
+func exitError(message string) {
+	fmt.Printf("%s: %s\n", toolName, message)
+	os.Exit(1)
+}
@@ -0,0 +1,254 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package main
+
+import (
+	"fmt"
+	"github.com/graniticio/granitic/v2/dsquery"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A reference to a query ID (and optionally a parameter name) found in source code
+type reference struct {
+	pos   token.Position
+	qid   string
+	param string
+}
+
+type scanner struct {
+	fset       *token.FileSet
+	references []reference
+
+	// Query IDs registered with RegisterTempQuery
+	temporary map[string]bool
+}
+
+func newScanner() *scanner {
+	s := new(scanner)
+	s.fset = token.NewFileSet()
+	s.temporary = make(map[string]bool)
+
+	return s
+}
+
+// scanDirectory finds query references in the Go source files in the supplied directory and its sub-directories.
+// Test files and vendor, testdata and hidden directories are skipped.
+func (s *scanner) scanDirectory(root string) error {
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		name := info.Name()
+
+		if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+			return filepath.SkipDir
+		}
+
+		return s.scanPackage(path)
+	})
+}
+
+// scanPackage parses the non-test Go files in a single directory
+func (s *scanner) scanPackage(dir string) error {
+
+	notTest := func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}
+
+	pkgs, err := parser.ParseDir(s.fset, dir, notTest, 0)
+
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range pkgs {
+
+		consts := stringConstants(pkg)
+
+		for _, f := range pkg.Files {
+
+			ast.Inspect(f, func(n ast.Node) bool {
+
+				if call, found := n.(*ast.CallExpr); found {
+					s.inspectCall(call, consts)
+				}
+
+				return true
+			})
+		}
+	}
+
+	return nil
+}
+
+// stringConstants finds the package-level string constants declared in a package
+func stringConstants(pkg *ast.Package) map[string]string {
+
+	consts := make(map[string]string)
+
+	for _, f := range pkg.Files {
+		for _, d := range f.Decls {
+
+			gd, found := d.(*ast.GenDecl)
+
+			if !found || gd.Tok != token.CONST {
+				continue
+			}
+
+			for _, spec := range gd.Specs {
+
+				vs := spec.(*ast.ValueSpec)
+
+				for i, name := range vs.Names {
+
+					if i >= len(vs.Values) {
+						continue
+					}
+
+					if lit, found := vs.Values[i].(*ast.BasicLit); found && lit.Kind == token.STRING {
+
+						if v, err := strconv.Unquote(lit.Value); err == nil {
+							consts[name.Name] = v
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return consts
+}
+
+// inspectCall records any query IDs and parameter names passed to a method of rdbms.Client or dsquery.QueryManager
+func (s *scanner) inspectCall(call *ast.CallExpr, consts map[string]string) {
+
+	sel, found := call.Fun.(*ast.SelectorExpr)
+
+	if !found {
+		return
+	}
+
+	method := sel.Sel.Name
+	args := call.Args
+
+	arg := func(i int) (string, bool) {
+
+		if i >= len(args) {
+			return "", false
+		}
+
+		return resolveString(args[i], consts)
+	}
+
+	add := func(qid string, param string) {
+		s.references = append(s.references, reference{pos: s.fset.Position(call.Pos()), qid: qid, param: param})
+	}
+
+	switch {
+	case method == "RegisterTempQuery":
+
+		if qid, found := arg(0); found {
+			s.temporary[qid] = true
+		}
+
+	case method == "ExistingIDOrInsertParams":
+
+		for i := 0; i < 2; i++ {
+			if qid, found := arg(i); found {
+				add(qid, "")
+			}
+		}
+
+	case strings.Contains(method, "QID") || method == "FindFragment" || method == "BuildQueryFromID" ||
+		method == "FragmentFromID" || method == "BuildParameterisedQueryFromID":
+
+		qid, found := arg(0)
+
+		if !found {
+			return
+		}
+
+		var param string
+
+		if strings.HasSuffix(method, "QIDParam") {
+			param, _ = arg(1)
+		}
+
+		add(qid, param)
+	}
+}
+
+// resolveString returns the value of a string literal or of a constant declared in the same package
+func resolveString(e ast.Expr, consts map[string]string) (string, bool) {
+
+	switch t := e.(type) {
+	case *ast.BasicLit:
+
+		if t.Kind != token.STRING {
+			return "", false
+		}
+
+		v, err := strconv.Unquote(t.Value)
+
+		return v, err == nil
+
+	case *ast.Ident:
+		v, found := consts[t.Name]
+
+		return v, found
+	}
+
+	return "", false
+}
+
+// check compares the references found in source code with the queries held by the supplied QueryInspector,
+// returning a description of each problem found.
+func (s *scanner) check(qi dsquery.QueryInspector) []string {
+
+	known := make(map[string]bool)
+
+	for _, id := range qi.QueryIDs() {
+		known[id] = true
+	}
+
+	problems := make([]string, 0)
+
+	for _, r := range s.references {
+
+		if s.temporary[r.qid] {
+			continue
+		}
+
+		if !known[r.qid] {
+			problems = append(problems, fmt.Sprintf("%s: unknown query ID %s", r.pos, r.qid))
+			continue
+		}
+
+		if r.param == "" {
+			continue
+		}
+
+		vars, _ := qi.QueryVariables(r.qid)
+
+		if i := sort.SearchStrings(vars, r.param); i >= len(vars) || vars[i] != r.param {
+			problems = append(problems, fmt.Sprintf("%s: query %s does not have a variable named %s", r.pos, r.qid, r.param))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"strings"
+	"testing"
+)
+
+func TestScanAndCheck(t *testing.T) {
+
+	qm := dsquery.NewTemplatedQueryManager()
+	qm.TemplateLocation = test.FilePath("queries")
+	qm.QueryIDPrefix = prefixDefault
+	qm.VarMatchRegEx = varDefault
+	qm.TrimIDWhiteSpace = true
+	qm.ValueProcessor = new(dsquery.SQLProcessor)
+	qm.FrameworkLogger = logging.CreateAnonymousLogger("qm", logging.Fatal)
+
+	test.ExpectNil(t, qm.StartComponent())
+
+	sc := newScanner()
+	test.ExpectNil(t, sc.scanDirectory(test.FilePath("src")))
+
+	test.ExpectInt(t, len(sc.references), 7)
+	test.ExpectBool(t, sc.temporary["TEMP"], true)
+
+	problems := sc.check(qm)
+
+	test.ExpectInt(t, len(problems), 3)
+	test.ExpectBool(t, strings.HasSuffix(problems[0], "app.go:11:2: query ARTIST_BY_ID does not have a variable named Id"), true)
+	test.ExpectBool(t, strings.HasSuffix(problems[1], "app.go:13:2: unknown query ID ARTIST_SERACH"), true)
+	test.ExpectBool(t, strings.HasSuffix(problems[2], "sub.go:4:2: unknown query ID ARTIST_INSERT"), true)
+}
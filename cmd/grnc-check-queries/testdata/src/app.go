@@ -0,0 +1,19 @@
+package app
+
+const artistSearch = "ARTIST_SEARCH"
+
+type finder struct {
+	db client
+}
+
+func (f *finder) find() {
+	f.db.SelectBindSingleQIDParam("ARTIST_BY_ID", "ID", 1, nil)
+	f.db.SelectBindSingleQIDParam("ARTIST_BY_ID", "Id", 1, nil)
+	f.db.SelectBindQIDParam(artistSearch, "Name", "Nico", nil)
+	f.db.SelectBindQIDParams("ARTIST_SERACH", nil)
+	f.db.RegisterTempQuery("TEMP", "SELECT 1")
+	f.db.SelectQID("TEMP")
+
+	qid := "DYNAMIC"
+	f.db.SelectQID(qid)
+}
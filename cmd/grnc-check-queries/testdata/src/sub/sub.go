@@ -0,0 +1,5 @@
+package sub
+
+func insert(db client) {
+	db.ExistingIDOrInsertParams("ARTIST_BY_ID", "ARTIST_INSERT", nil)
+}
@@ -2,4 +2,5 @@
 
 (cd cmd/grnc-bind && go install)
 (cd cmd/grnc-ctl && go install)
-(cd cmd/grnc-project && go install)
\ No newline at end of file
+(cd cmd/grnc-project && go install)
+(cd cmd/grnc-check-queries && go install)
@@ -50,6 +50,9 @@ type InitialSettings struct {
 
 	// Create a UUID and use it as the instance ID
 	GenerateInstanceUUID bool
+
+	// A schema migration command (status, apply, rollback or rollback:n) to run instead of starting the application
+	MigrationCommand string
 }
 
 // InitialSettingsFromEnvironment builds an InitialSettings and populates it with defaults or the values of command line
@@ -79,6 +82,7 @@ func processCommandLineArgs(is *InitialSettings) {
 	deferLogging := flag.Bool("d", false, "Defer logging messages from until application logging is configured")
 	mergeFile := flag.String("m", "", "Path to a file to write merged view of config then exit")
 	uuidInstanceID := flag.Bool("u", false, "Use a generated UUID as the instance ID for this application")
+	migrate := flag.String("migrate", "", "Run a schema migration command (status, apply, rollback or rollback:n) then exit")
 
 	flag.Parse()
 
@@ -112,6 +116,7 @@ func processCommandLineArgs(is *InitialSettings) {
 	is.DeferBootstrapLogging = *deferLogging
 	is.MergedConfigPath = *mergeFile
 	is.GenerateInstanceUUID = *uuidInstanceID
+	is.MigrationCommand = *migrate
 
 }
 
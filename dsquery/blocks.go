@@ -0,0 +1,218 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"bytes"
+	"fmt"
+	rt "github.com/graniticio/granitic/v2/reflecttools"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	ifBlock     = "if"
+	unlessBlock = "unless"
+	whereBlock  = "where"
+	endBlock    = "end"
+)
+
+// Matches the start and end of conditional blocks in a template: [[if name]], [[unless name]], [[where]] and [[end]]
+var blockMarker = regexp.MustCompile(`\[\[\s*(?:(if|unless)\s+([^\s\]]+)|(where)|(end))\s*\]\]`)
+
+// Conjunctions removed from the start of the content of a [[where]] block
+var leadingConjunction = regexp.MustCompile(`(?i)^(and|or)\s+`)
+
+// addFragment adds text from a template to the current template, converting any block markers it contains into tokens.
+func (qt *queryTemplate) addFragment(fragment string) {
+
+	markers := blockMarker.FindAllStringSubmatchIndex(fragment, -1)
+
+	if markers == nil {
+		qt.AddFragmentContent(fragment)
+		return
+	}
+
+	start := 0
+
+	for _, m := range markers {
+
+		if m[0] > start {
+			qt.AddFragmentContent(fragment[start:m[0]])
+		}
+
+		switch {
+		case m[2] >= 0:
+			qt.AddBlockStart(fragment[m[2]:m[3]], fragment[m[4]:m[5]])
+		case m[6] >= 0:
+			qt.AddBlockStart(whereBlock, "")
+		default:
+			qt.AddBlockEnd()
+		}
+
+		start = m[1]
+	}
+
+	if start < len(fragment) {
+		qt.AddFragmentContent(fragment[start:])
+	}
+}
+
+// renderTokens writes the tokens between from (inclusive) and to (exclusive) to the supplied buffer. Fragments are
+// written as-is and variables are passed to writeVar. The contents of [[if]] and [[unless]] blocks are only written
+// if the block's parameter is (or, for [[unless]], is not) set and the contents of [[where]] blocks are written
+// via writeWhere.
+func renderTokens(tokens []*queryTemplateToken, from, to int, b *bytes.Buffer, params map[string]interface{}, writeVar func(*queryTemplateToken, *bytes.Buffer) error) error {
+
+	for i := from; i < to; i++ {
+
+		token := tokens[i]
+
+		switch token.Type {
+
+		case fragmentToken:
+			b.WriteString(token.Content)
+
+		case ifBlockToken, unlessBlockToken:
+
+			if paramSet(params[token.Content]) == (token.Type == ifBlockToken) {
+
+				if err := renderTokens(tokens, i+1, token.Index, b, params, writeVar); err != nil {
+					return err
+				}
+			}
+
+			i = token.Index
+
+		case whereBlockToken:
+
+			var wb bytes.Buffer
+
+			if err := renderTokens(tokens, i+1, token.Index, &wb, params, writeVar); err != nil {
+				return err
+			}
+
+			writeWhere(b, wb.String())
+
+			i = token.Index
+
+		case endBlockToken:
+			continue
+
+		default:
+
+			if err := writeVar(token, b); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeWhere writes the rendered content of a [[where]] block prefixed with WHERE and with any leading AND or OR
+// removed. Nothing is written if the content is empty.
+func writeWhere(b *bytes.Buffer, content string) {
+
+	content = strings.TrimSpace(content)
+
+	if content == "" {
+		return
+	}
+
+	content = leadingConjunction.ReplaceAllString(content, "")
+
+	if l := b.Len(); l > 0 && !unicode.IsSpace(rune(b.Bytes()[l-1])) {
+		b.WriteString(" ")
+	}
+
+	b.WriteString("WHERE ")
+	b.WriteString(content)
+}
+
+// paramSet returns false if the supplied parameter value is nil, an unset Nilable type or an empty slice, array or map.
+func paramSet(v interface{}) bool {
+
+	if v == nil {
+		return false
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() == reflect.Ptr {
+
+		if rv.IsNil() {
+			return false
+		}
+
+	} else {
+		// Nilable types implement IsSet with a pointer receiver
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		v = p.Interface()
+	}
+
+	if n, found := v.(nilable); found {
+		return n.IsSet()
+	}
+
+	if rt.IsSliceOrArray(rv.Interface()) || rv.Kind() == reflect.Map {
+		return rv.Len() > 0
+	}
+
+	return true
+}
+
+// nilable is implemented by the Nilable types in the types package
+type nilable interface {
+	IsSet() bool
+}
+
+// hasBlocks returns true if the template contains any conditional blocks, meaning that the text of the rendered query
+// depends on the supplied parameters.
+func (qt *queryTemplate) hasBlocks() bool {
+
+	for _, token := range qt.Tokens {
+
+		switch token.Type {
+		case ifBlockToken, unlessBlockToken, whereBlockToken:
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchBlocks records the index of the token ending each block in the token starting the block. Returns an error if the
+// template's blocks are not correctly nested.
+func (qt *queryTemplate) matchBlocks() error {
+
+	open := make([]int, 0)
+
+	for i, token := range qt.Tokens {
+
+		switch token.Type {
+		case ifBlockToken, unlessBlockToken, whereBlockToken:
+			open = append(open, i)
+
+		case endBlockToken:
+
+			if len(open) == 0 {
+				return fmt.Errorf("query %s has an [[%s]] without a matching block start", qt.ID, endBlock)
+			}
+
+			last := len(open) - 1
+			qt.Tokens[open[last]].Index = i
+			open = open[:last]
+		}
+	}
+
+	if len(open) > 0 {
+		return fmt.Errorf("query %s has an unclosed [[%s]] block", qt.ID, qt.Tokens[open[len(open)-1]].blockName())
+	}
+
+	return nil
+}
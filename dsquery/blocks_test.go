@@ -0,0 +1,132 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/types"
+	"strings"
+	"testing"
+)
+
+func TestConditionalBlocks(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("blocks")
+
+	if err := qm.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	q, err := qm.BuildQueryFromID("ARTIST_SEARCH", map[string]interface{}{"IncludeInactive": true})
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	if strings.Contains(q, "WHERE") || !strings.Contains(q, "artist\n\nORDER BY name") {
+		t.Fatalf("Unexpected resulting query \n%s", q)
+	}
+
+	p := map[string]interface{}{
+		"Name":   "Nico",
+		"Genres": []string{"rock", "folk"},
+	}
+
+	q, err = qm.BuildQueryFromID("ARTIST_SEARCH", p)
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	if !strings.Contains(q, "WHERE name = 'Nico'\n    AND genre IN ('rock', 'folk')\n    AND active = true\nORDER BY name") {
+		t.Fatalf("Unexpected resulting query \n%s", q)
+	}
+
+	p = map[string]interface{}{
+		"Name":            types.NewNilableString("Nico"),
+		"Genres":          []string{},
+		"IncludeInactive": new(types.NilableBool),
+	}
+
+	q, err = qm.BuildQueryFromID("ARTIST_SEARCH", p)
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	if !strings.Contains(q, "WHERE name = 'Nico'\n    \n    AND active = true\n") || strings.Contains(q, "genre") {
+		t.Fatalf("Unexpected resulting query \n%s", q)
+	}
+
+	q, _ = qm.BuildQueryFromID("ARTIST_COUNT", map[string]interface{}{"Name": "Can"})
+	test.ExpectString(t, q, "SELECT COUNT(*) FROM artist WHERE name = 'Can'\n")
+
+	q, _ = qm.FragmentFromID("ARTIST_COUNT")
+	test.ExpectString(t, q, "SELECT COUNT(*) FROM artist\n")
+}
+
+func TestParameterisedBlocks(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("blocks")
+
+	if err := qm.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	p := map[string]interface{}{
+		"Genres":          []string{"rock", "folk"},
+		"IncludeInactive": true,
+	}
+
+	pq, err := qm.BuildParameterisedQueryFromID("ARTIST_SEARCH", p, NumberedPlaceholders)
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	if !strings.Contains(pq.Query, "WHERE genre IN ($1, $2)\n") {
+		t.Fatalf("Unexpected resulting query \n%s", pq.Query)
+	}
+
+	test.ExpectInt(t, len(pq.Values), 2)
+	test.ExpectString(t, pq.Values[1].(string), "folk")
+	test.ExpectBool(t, pq.Variable, true)
+
+	pq, err = qm.BuildParameterisedQueryFromID("ARTIST_SEARCH", map[string]interface{}{"Name": "Nico"}, NumberedPlaceholders)
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	test.ExpectBool(t, pq.Variable, true)
+}
+
+func TestUnclosedBlock(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("unclosedblock")
+
+	err := qm.StartComponent()
+
+	test.ExpectNotNil(t, err)
+	test.ExpectBool(t, strings.Contains(err.Error(), "unclosed [[where]] block"), true)
+}
+
+func TestParamSet(t *testing.T) {
+
+	test.ExpectBool(t, paramSet(nil), false)
+	test.ExpectBool(t, paramSet(""), true)
+	test.ExpectBool(t, paramSet(false), true)
+	test.ExpectBool(t, paramSet([]int{}), false)
+	test.ExpectBool(t, paramSet([]int{1}), true)
+	test.ExpectBool(t, paramSet(map[string]int{}), false)
+	test.ExpectBool(t, paramSet(types.NilableInt64{}), false)
+	test.ExpectBool(t, paramSet(types.NewNilableInt64(0)), true)
+
+	var ns *types.NilableString
+
+	test.ExpectBool(t, paramSet(ns), false)
+}
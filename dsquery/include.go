@@ -0,0 +1,151 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Variables starting with this prefix (e.g. ${>common_columns}) are replaced with the content of the query with that ID
+const includePrefix = ">"
+
+// AddInclude adds a token that will be replaced with the tokens of the query with the supplied ID once all template
+// files have been parsed.
+func (qt *queryTemplate) AddInclude(qid string) {
+
+	qt.closeFragmentToken()
+
+	t := newQueryTemplateToken(includeToken)
+	t.Content = qid
+	t.Line = qt.line
+
+	qt.Tokens = append(qt.Tokens, t)
+	qt.currentToken = t
+}
+
+// resolveIncludes replaces every include token in the supplied templates with the tokens of the included query.
+// Returns an error naming the file and line of the include if an included query does not exist or includes
+// itself (directly or indirectly).
+func resolveIncludes(templates map[string]*queryTemplate) error {
+
+	ids := make([]string, 0, len(templates))
+
+	for id := range templates {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	resolved := make(map[string]bool)
+
+	for _, id := range ids {
+		if err := templates[id].resolve(templates, resolved, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (qt *queryTemplate) resolve(templates map[string]*queryTemplate, resolved map[string]bool, chain []string) error {
+
+	if resolved[qt.ID] {
+		return nil
+	}
+
+	chain = append(chain, qt.ID)
+
+	tokens := make([]*queryTemplateToken, 0, len(qt.Tokens))
+	expanded := false
+
+	for _, token := range qt.Tokens {
+
+		if token.Type != includeToken {
+			tokens = append(tokens, token)
+			continue
+		}
+
+		included := templates[token.Content]
+
+		if included == nil {
+			return fmt.Errorf("%s line %d: query %s includes unknown query %s", qt.File, token.Line, qt.ID, token.Content)
+		}
+
+		for _, id := range chain {
+			if id == included.ID {
+				return fmt.Errorf("%s line %d: query %s cannot include %s as the queries include each other (%s -> %s)",
+					qt.File, token.Line, qt.ID, included.ID, strings.Join(chain, " -> "), included.ID)
+			}
+		}
+
+		if err := included.resolve(templates, resolved, chain); err != nil {
+			return err
+		}
+
+		tokens = append(tokens, includedTokens(included.Tokens)...)
+		expanded = true
+	}
+
+	qt.Tokens = tokens
+	resolved[qt.ID] = true
+
+	if !expanded {
+		return nil
+	}
+
+	if err := qt.matchBlocks(); err != nil {
+		return fmt.Errorf("%s: %s", qt.File, err.Error())
+	}
+
+	return nil
+}
+
+// includedTokens copies the tokens of an included query (so that block positions can be recalculated for the including
+// query) and removes the line ending from the end of the included query.
+func includedTokens(tokens []*queryTemplateToken) []*queryTemplateToken {
+
+	c := make([]*queryTemplateToken, len(tokens))
+
+	for i, token := range tokens {
+		t := *token
+		c[i] = &t
+	}
+
+	if l := len(c); l > 0 && c[l-1].Type == fragmentToken {
+		c[l-1].Content = strings.TrimSuffix(c[l-1].Content, "\n")
+	}
+
+	return c
+}
+
+// staticFragments returns the text of every query that has no variables or blocks, keyed by query ID.
+func staticFragments(templates map[string]*queryTemplate) map[string]string {
+
+	fragments := make(map[string]string)
+
+	for id, qt := range templates {
+
+		var b bytes.Buffer
+		static := true
+
+		for _, token := range qt.Tokens {
+
+			if token.Type != fragmentToken {
+				static = false
+				break
+			}
+
+			b.WriteString(token.Content)
+		}
+
+		if static {
+			fragments[id] = b.String()
+		}
+	}
+
+	return fragments
+}
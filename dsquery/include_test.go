@@ -0,0 +1,92 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"github.com/graniticio/granitic/v2/test"
+	"strings"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("includes")
+
+	if err := qm.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	q, err := qm.BuildQueryFromID("ARTIST_SEARCH", map[string]interface{}{"Name": "Can"})
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	test.ExpectString(t, q, "SELECT a.id, a.name, a.formed FROM artist a\nWHERE a.name = 'Can'\nORDER BY a.name\n")
+
+	q, _ = qm.BuildQueryFromID("ARTIST_SEARCH", nil)
+	test.ExpectString(t, q, "SELECT a.id, a.name, a.formed FROM artist a\n\nORDER BY a.name\n")
+
+	f, err := qm.FragmentFromID("ARTIST_COLUMNS_WITH_GENRE")
+	test.ExpectNil(t, err)
+	test.ExpectString(t, f, "a.id, a.name, a.formed, g.name\n")
+
+	// The included query is unchanged
+	f, _ = qm.FragmentFromID("ARTIST_COLUMNS")
+	test.ExpectString(t, f, "a.id, a.name, a.formed\n")
+
+	_, err = qm.FragmentFromID("MISSING")
+	test.ExpectNotNil(t, err)
+}
+
+func TestIncludeErrors(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("includecycle")
+
+	err := qm.StartComponent()
+	test.ExpectNotNil(t, err)
+
+	if !strings.Contains(err.Error(), "cycle line 11: query THIRD cannot include FIRST") || !strings.Contains(err.Error(), "(FIRST -> SECOND -> THIRD -> FIRST)") {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+
+	qm = buildQueryManager()
+	qm.TemplateLocation = test.FilePath("includeunknown")
+
+	err = qm.StartComponent()
+	test.ExpectNotNil(t, err)
+
+	if !strings.Contains(err.Error(), "unknown line 5: query ARTIST_SEARCH includes unknown query MISSING") {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+}
+
+func TestQueryInspection(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("includes")
+
+	if err := qm.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	var qi QueryInspector = qm
+
+	ids := qi.QueryIDs()
+	test.ExpectInt(t, len(ids), 4)
+	test.ExpectString(t, ids[0], "ARTIST_COLUMNS")
+
+	vars, err := qi.QueryVariables("ARTIST_SEARCH")
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(vars), 1)
+	test.ExpectString(t, vars[0], "Name")
+
+	vars, _ = qi.QueryVariables("ARTIST_COLUMNS")
+	test.ExpectInt(t, len(vars), 0)
+
+	_, err = qi.QueryVariables("MISSING")
+	test.ExpectNotNil(t, err)
+}
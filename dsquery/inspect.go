@@ -0,0 +1,69 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// QueryInspector is implemented by QueryManagers that can describe the queries they hold, allowing tools to verify
+// that queries referenced by code exist and that the queries themselves are valid.
+type QueryInspector interface {
+	// QueryIDs returns the ID of every query, sorted alphabetically.
+	QueryIDs() []string
+
+	// QueryVariables returns the names of the variables (including those that control conditional blocks) used by the
+	// query with the supplied ID, sorted alphabetically. Returns an error if there is no such query.
+	QueryVariables(qid string) ([]string, error)
+}
+
+// QueryIDs implements QueryInspector.QueryIDs
+func (qm *TemplatedQueryManager) QueryIDs() []string {
+
+	qm.mux.RLock()
+	defer qm.mux.RUnlock()
+
+	ids := make([]string, 0, len(qm.tokenisedTemplates))
+
+	for id := range qm.tokenisedTemplates {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// QueryVariables implements QueryInspector.QueryVariables. The names of required variables do not include the ! prefix.
+func (qm *TemplatedQueryManager) QueryVariables(qid string) ([]string, error) {
+
+	template := qm.template(qid)
+
+	if template == nil {
+		return nil, errors.New("Unknown query " + qid)
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+
+	for _, token := range template.Tokens {
+
+		switch token.Type {
+		case varNameToken, ifBlockToken, unlessBlockToken:
+
+			name := strings.TrimPrefix(token.Content, requiredPrefix)
+
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/logging"
+	rt "github.com/graniticio/granitic/v2/reflecttools"
+	"github.com/graniticio/granitic/v2/types"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PlaceholderStyle identifies the syntax a data source driver expects for placeholders in a parameterised query.
+type PlaceholderStyle int
+
+const (
+	// QuestionMarkPlaceholders renders each variable as ? (e.g. MySQL, SQLite)
+	QuestionMarkPlaceholders PlaceholderStyle = iota
+
+	// NumberedPlaceholders renders variables as $1, $2 etc (e.g. PostgreSQL)
+	NumberedPlaceholders
+
+	// NamedPlaceholders renders variables as :name (e.g. Oracle)
+	NamedPlaceholders
+)
+
+const (
+	questionMarkStyleName = "QUESTION"
+	numberedStyleName     = "NUMBERED"
+	namedStyleName        = "NAMED"
+)
+
+// PlaceholderStyleFromName converts one of the strings QUESTION, NUMBERED or NAMED (case insensitive) to a PlaceholderStyle.
+func PlaceholderStyleFromName(name string) (PlaceholderStyle, error) {
+
+	switch strings.ToUpper(name) {
+	case questionMarkStyleName:
+		return QuestionMarkPlaceholders, nil
+	case numberedStyleName:
+		return NumberedPlaceholders, nil
+	case namedStyleName:
+		return NamedPlaceholders, nil
+	}
+
+	return QuestionMarkPlaceholders, fmt.Errorf("%s is not a supported placeholder style. Must be one of %s, %s or %s", name,
+		questionMarkStyleName, numberedStyleName, namedStyleName)
+}
+
+// ParameterisedQueryManager is implemented by QueryManagers that are able to render the variables in a query template
+// as driver placeholders, rather than substituting values directly into the query.
+type ParameterisedQueryManager interface {
+	// BuildParameterisedQueryFromID finds a template with the supplied query ID and replaces its variables with placeholders
+	// in the supplied style. The values of the variables are returned in the order in which they should be passed to the driver.
+	BuildParameterisedQueryFromID(qid string, params map[string]interface{}, style PlaceholderStyle) (*ParameterisedQuery, error)
+}
+
+// ParameterisedQuery is a query containing driver placeholders along with the values that should be bound to them.
+type ParameterisedQuery struct {
+	// The query with variables replaced by placeholders.
+	Query string
+
+	// The values to bind to the placeholders, in order.
+	Values []interface{}
+
+	// The name of the placeholder each entry in Values should be bound to. Only meaningful for NamedPlaceholders.
+	Names []string
+
+	// True if the text of Query depends on the supplied parameters (because the template contains conditional blocks or
+	// a slice parameter has been expanded into one placeholder per element) and so is not suitable for caching as a
+	// prepared statement.
+	Variable bool
+}
+
+// BuildParameterisedQueryFromID implements ParameterisedQueryManager.BuildParameterisedQueryFromID. Values are not
+// escaped by the ValueProcessor, although it is still consulted when a parameter is missing to decide whether or not that is an error.
+// Missing, non-required parameters and unset Nilable values are bound as nil.
+func (qm *TemplatedQueryManager) BuildParameterisedQueryFromID(qid string, params map[string]interface{}, style PlaceholderStyle) (*ParameterisedQuery, error) {
+	template := qm.template(qid)
+
+	if template == nil {
+		return nil, errors.New("Unknown query " + qid)
+	}
+
+	return qm.buildParameterisedQuery(qid, template, params, style)
+}
+
+func (qm *TemplatedQueryManager) buildParameterisedQuery(qid string, template *queryTemplate, params map[string]interface{}, style PlaceholderStyle) (*ParameterisedQuery, error) {
+
+	var b bytes.Buffer
+
+	pq := new(ParameterisedQuery)
+	pq.Variable = template.hasBlocks()
+	named := make(map[string]bool)
+
+	vp := qm.ValueProcessor
+	log := qm.FrameworkLogger
+
+	writeVar := func(token *queryTemplateToken, b *bytes.Buffer) error {
+
+		key := token.Content
+		required := strings.HasPrefix(key, requiredPrefix)
+
+		if required {
+			key = strings.Replace(key, requiredPrefix, "", 1)
+		}
+
+		paramValue := params[key]
+
+		if paramValue == nil {
+
+			if required {
+				return fmt.Errorf("parameter %s is required for query %s but has not been set", key, qid)
+			}
+
+			vc := ParamValueContext{
+				Key:     key,
+				QueryID: qid,
+			}
+
+			if err := vp.SubstituteUnset(&vc); err != nil {
+				return err
+			}
+
+			pq.addPlaceholder(b, style, key, nil, named)
+			return nil
+		}
+
+		if _, raw := paramValue.([]byte); !raw && rt.IsSliceOrArray(paramValue) {
+
+			v := reflect.ValueOf(paramValue)
+			l := v.Len()
+
+			pq.Variable = true
+
+			for i := 0; i < l; i++ {
+
+				pq.addPlaceholder(b, style, key+"_"+strconv.Itoa(i), placeholderValue(v.Index(i).Interface()), named)
+
+				if i < (l - 1) {
+					b.WriteString(qm.ElementSeparator)
+				}
+			}
+
+			return nil
+		}
+
+		pq.addPlaceholder(b, style, key, placeholderValue(paramValue), named)
+
+		return nil
+	}
+
+	if err := renderTokens(template.Tokens, 0, len(template.Tokens), &b, params, writeVar); err != nil {
+		return nil, err
+	}
+
+	pq.Query = b.String()
+
+	if log.IsLevelEnabled(logging.Debug) {
+		log.LogDebugf("\n%s\n%v", pq.Query, pq.Values)
+	}
+
+	return pq, nil
+}
+
+// addPlaceholder writes a placeholder to the query and records the value to be bound to it. Named placeholders that
+// appear more than once in a query are only bound once.
+func (pq *ParameterisedQuery) addPlaceholder(b *bytes.Buffer, style PlaceholderStyle, name string, value interface{}, named map[string]bool) {
+
+	switch style {
+	case NamedPlaceholders:
+		b.WriteString(":" + name)
+
+		if named[name] {
+			return
+		}
+
+		named[name] = true
+
+	case NumberedPlaceholders:
+		b.WriteString("$" + strconv.Itoa(len(pq.Values)+1))
+	default:
+		b.WriteString("?")
+	}
+
+	pq.Values = append(pq.Values, value)
+	pq.Names = append(pq.Names, name)
+}
+
+// placeholderValue converts Nilable types to their underlying values (or nil if unset) so that they can be passed to a
+// driver. Other values are passed to the driver unaltered.
+func placeholderValue(v interface{}) interface{} {
+
+	switch t := v.(type) {
+	case *types.NilableString:
+		if t == nil || !t.IsSet() {
+			return nil
+		}
+		return t.String()
+	case types.NilableString:
+		return placeholderValue(&t)
+	case *types.NilableBool:
+		if t == nil || !t.IsSet() {
+			return nil
+		}
+		return t.Bool()
+	case types.NilableBool:
+		return placeholderValue(&t)
+	case *types.NilableInt64:
+		if t == nil || !t.IsSet() {
+			return nil
+		}
+		return t.Int64()
+	case types.NilableInt64:
+		return placeholderValue(&t)
+	case *types.NilableFloat64:
+		if t == nil || !t.IsSet() {
+			return nil
+		}
+		return t.Float64()
+	case types.NilableFloat64:
+		return placeholderValue(&t)
+	}
+
+	return v
+}
@@ -0,0 +1,93 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/types"
+	"strings"
+	"testing"
+)
+
+func TestParameterisedQueries(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("parameterised")
+
+	if err := qm.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	p := map[string]interface{}{
+		"Name":   "Bob's Band",
+		"Active": types.NewNilableBool(true),
+	}
+
+	pq, err := qm.BuildParameterisedQueryFromID("ARTIST_SEARCH", p, QuestionMarkPlaceholders)
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	if !strings.Contains(pq.Query, "name = ?\n    AND active = ?\n    AND genre IN (?)\n    AND name != ?") {
+		t.Fatalf("Unexpected resulting query \n%s", pq.Query)
+	}
+
+	test.ExpectInt(t, len(pq.Values), 4)
+	test.ExpectString(t, pq.Values[0].(string), "Bob's Band")
+	test.ExpectBool(t, pq.Values[1].(bool), true)
+	test.ExpectBool(t, pq.Values[2] == nil, true)
+	test.ExpectBool(t, pq.Variable, false)
+
+	p["Genres"] = []string{"rock", "jazz"}
+
+	pq, err = qm.BuildParameterisedQueryFromID("ARTIST_SEARCH", p, NumberedPlaceholders)
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	if !strings.Contains(pq.Query, "name = $1\n    AND active = $2\n    AND genre IN ($3, $4)\n    AND name != $5") {
+		t.Fatalf("Unexpected resulting query \n%s", pq.Query)
+	}
+
+	test.ExpectInt(t, len(pq.Values), 5)
+	test.ExpectString(t, pq.Values[3].(string), "jazz")
+	test.ExpectBool(t, pq.Variable, true)
+
+	pq, err = qm.BuildParameterisedQueryFromID("ARTIST_SEARCH", p, NamedPlaceholders)
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	if !strings.Contains(pq.Query, "name = :Name\n    AND active = :Active\n    AND genre IN (:Genres_0, :Genres_1)\n    AND name != :Name") {
+		t.Fatalf("Unexpected resulting query \n%s", pq.Query)
+	}
+
+	test.ExpectInt(t, len(pq.Values), 4)
+	test.ExpectString(t, strings.Join(pq.Names, ","), "Name,Active,Genres_0,Genres_1")
+
+	delete(p, "Name")
+
+	if _, err = qm.BuildParameterisedQueryFromID("ARTIST_SEARCH", p, QuestionMarkPlaceholders); err == nil {
+		t.Errorf("Expected an error for a missing required parameter")
+	}
+
+	if _, err = qm.BuildParameterisedQueryFromID("UNKNOWN", p, QuestionMarkPlaceholders); err == nil {
+		t.Errorf("Expected an error for an unknown query")
+	}
+}
+
+func TestPlaceholderStyleFromName(t *testing.T) {
+
+	s, err := PlaceholderStyleFromName("numbered")
+
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, s == NumberedPlaceholders, true)
+
+	if _, err = PlaceholderStyleFromName("AT"); err == nil {
+		t.Errorf("Expected an error for an unsupported style")
+	}
+}
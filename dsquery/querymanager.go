@@ -31,6 +31,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const requiredPrefix = "!"
@@ -90,35 +91,43 @@ type TemplatedQueryManager struct {
 	// The separator string to use been elements of a processed array parameter (e.g , )
 	ElementSeparator string
 
+	// If true, the files in TemplateLocation are checked for changes every WatchIntervalMilliseconds and all templates
+	// are reloaded if a file has been added, modified or removed. Intended for use during development.
+	WatchTemplates bool
+
+	// How often template files are checked for changes when WatchTemplates is true. Defaults to 1000.
+	WatchIntervalMilliseconds int
+
 	tokenisedTemplates map[string]*queryTemplate
 	fragments          map[string]string
 	state              ioc.ComponentState
+
+	// Guards the templates and fragments, which are replaced when templates are reloaded
+	mux          sync.RWMutex
+	reloadMux    sync.Mutex
+	stopWatching chan bool
 }
 
 // FragmentFromID implements QueryManager.FragmentFromID
 func (qm *TemplatedQueryManager) FragmentFromID(qid string) (string, error) {
 
-	f := qm.fragments[qid]
+	qm.mux.RLock()
+	f, found := qm.fragments[qid]
+	qm.mux.RUnlock()
 
-	if f != "" {
+	if found {
 		return f, nil
 	}
 
 	p := make(map[string]interface{})
 
-	f, err := qm.BuildQueryFromID(qid, p)
-
-	if err != nil {
-		qm.fragments[qid] = f
-	}
-
-	return f, err
+	return qm.BuildQueryFromID(qid, p)
 
 }
 
 // BuildQueryFromID implements QueryManager.BuildQueryFromID
 func (qm *TemplatedQueryManager) BuildQueryFromID(qid string, params map[string]interface{}) (string, error) {
-	template := qm.tokenisedTemplates[qid]
+	template := qm.template(qid)
 
 	if template == nil {
 		return "", errors.New("Unknown query " + qid)
@@ -127,6 +136,15 @@ func (qm *TemplatedQueryManager) BuildQueryFromID(qid string, params map[string]
 	return qm.buildQueryFromTemplate(qid, template, params)
 }
 
+// template returns the parsed template with the supplied ID, or nil if there is no such template
+func (qm *TemplatedQueryManager) template(qid string) *queryTemplate {
+
+	qm.mux.RLock()
+	defer qm.mux.RUnlock()
+
+	return qm.tokenisedTemplates[qid]
+}
+
 func (qm *TemplatedQueryManager) buildQueryFromTemplate(qid string, template *queryTemplate, params map[string]interface{}) (string, error) {
 
 	var b bytes.Buffer
@@ -135,81 +153,73 @@ func (qm *TemplatedQueryManager) buildQueryFromTemplate(qid string, template *qu
 	log := qm.FrameworkLogger
 	trace := log.IsLevelEnabled(logging.Trace)
 
-	for _, token := range template.Tokens {
-
-		if token.Type == fragmentToken {
-			b.WriteString(token.Content)
-		} else {
-
-			key := token.Content
-
-			if trace {
-				log.LogTracef("Processing parameter %s", key)
-			}
+	writeVar := func(token *queryTemplateToken, b *bytes.Buffer) error {
 
-			required := strings.HasPrefix(key, requiredPrefix)
+		key := token.Content
 
-			if required {
-				key = strings.Replace(key, requiredPrefix, "", 1)
-			}
+		if trace {
+			log.LogTracef("Processing parameter %s", key)
+		}
 
-			paramValue := params[key]
+		required := strings.HasPrefix(key, requiredPrefix)
 
-			vc := ParamValueContext{
-				Value:   paramValue,
-				Key:     key,
-				QueryID: qid,
-			}
-
-			if paramValue == nil {
+		if required {
+			key = strings.Replace(key, requiredPrefix, "", 1)
+		}
 
-				if trace {
-					log.LogTracef("Parameter %s is unset", key)
-				}
+		paramValue := params[key]
 
-				if required {
-					return "", fmt.Errorf("parameter %s is required for query %s but has not been set", key, qid)
-				}
+		vc := ParamValueContext{
+			Value:   paramValue,
+			Key:     key,
+			QueryID: qid,
+		}
 
-				if err := vp.SubstituteUnset(&vc); err != nil {
+		if paramValue == nil {
 
-					//ValueProcessor does not allow this parameter to be unset
-					return "", err
-				}
+			if trace {
+				log.LogTracef("Parameter %s is unset", key)
+			}
 
+			if required {
+				return fmt.Errorf("parameter %s is required for query %s but has not been set", key, qid)
 			}
 
-			var err error
+			if err := vp.SubstituteUnset(&vc); err != nil {
 
-			if rt.IsSliceOrArray(paramValue) {
+				//ValueProcessor does not allow this parameter to be unset
+				return err
+			}
 
-				v := reflect.ValueOf(paramValue)
-				l := v.Len()
+		}
 
-				for i := 0; i < l; i++ {
+		if rt.IsSliceOrArray(paramValue) {
 
-					vc.Value = v.Index(i).Interface()
+			v := reflect.ValueOf(paramValue)
+			l := v.Len()
 
-					if err = qm.writeValue(vp, vc, key, &b); err != nil {
-						return "", err
-					}
+			for i := 0; i < l; i++ {
 
-					if i < (l - 1) {
-						b.WriteString(qm.ElementSeparator)
-					}
+				vc.Value = v.Index(i).Interface()
 
+				if err := qm.writeValue(vp, vc, key, b); err != nil {
+					return err
 				}
 
-			} else {
-				err = qm.writeValue(vp, vc, key, &b)
-			}
+				if i < (l - 1) {
+					b.WriteString(qm.ElementSeparator)
+				}
 
-			if err != nil {
-				return "", err
 			}
 
+			return nil
 		}
 
+		return qm.writeValue(vp, vc, key, b)
+	}
+
+	if err := renderTokens(template.Tokens, 0, len(template.Tokens), &b, params, writeVar); err != nil {
+		return "", err
 	}
 
 	q := b.String()
@@ -270,9 +280,18 @@ func (qm *TemplatedQueryManager) StartComponent() error {
 
 	if err == nil {
 
-		qm.tokenisedTemplates = qm.parseQueryFiles(queryFiles)
+		if qm.tokenisedTemplates, err = qm.parseQueryFiles(queryFiles, false); err != nil {
+			return fmt.Errorf("Unable to start QueryManager due to problem parsing query files: %s", err.Error())
+		}
+
+		qm.fragments = staticFragments(qm.tokenisedTemplates)
+
 		fl.LogDebugf("Started QueryManager with %d queries", len(qm.tokenisedTemplates))
 
+		if qm.WatchTemplates {
+			qm.watch()
+		}
+
 		qm.state = ioc.RunningState
 
 		return nil
@@ -283,7 +302,9 @@ func (qm *TemplatedQueryManager) StartComponent() error {
 
 }
 
-func (qm *TemplatedQueryManager) parseQueryFiles(files []string) map[string]*queryTemplate {
+// parseQueryFiles parses the supplied template files. Files that cannot be opened are logged and skipped unless
+// requireAll is set, in which case an error is returned.
+func (qm *TemplatedQueryManager) parseQueryFiles(files []string, requireAll bool) (map[string]*queryTemplate, error) {
 	fl := qm.FrameworkLogger
 	tokenisedTemplates := map[string]*queryTemplate{}
 	re := regexp.MustCompile(qm.VarMatchRegEx)
@@ -295,6 +316,11 @@ func (qm *TemplatedQueryManager) parseQueryFiles(files []string) map[string]*que
 		file, err := os.Open(filePath)
 
 		if err != nil {
+
+			if requireAll {
+				return nil, fmt.Errorf("unable to open %s for parsing: %s", filePath, err.Error())
+			}
+
 			fl.LogErrorf("Unable to open %s for parsing: %s", filePath, err.Error())
 			continue
 		}
@@ -302,29 +328,44 @@ func (qm *TemplatedQueryManager) parseQueryFiles(files []string) map[string]*que
 		defer file.Close()
 
 		scanner := bufio.NewScanner(file)
-		qm.scanAndParse(scanner, tokenisedTemplates, re)
+
+		if err := qm.scanAndParse(filePath, scanner, tokenisedTemplates, re); err != nil {
+			return nil, fmt.Errorf("%s: %s", filePath, err.Error())
+		}
 	}
 
-	return tokenisedTemplates
+	// Includes can only be resolved once all files have been parsed, as queries may include queries from other files
+	if err := resolveIncludes(tokenisedTemplates); err != nil {
+		return nil, err
+	}
+
+	return tokenisedTemplates, nil
 }
 
-func (qm *TemplatedQueryManager) scanAndParse(scanner *bufio.Scanner, tokenisedTemplates map[string]*queryTemplate, re *regexp.Regexp) {
+func (qm *TemplatedQueryManager) scanAndParse(filePath string, scanner *bufio.Scanner, tokenisedTemplates map[string]*queryTemplate, re *regexp.Regexp) error {
 
 	var currentTemplate *queryTemplate
 	var fragmentBuffer bytes.Buffer
 
+	lineNumber := 0
+
 	for scanner.Scan() {
 		line := scanner.Text()
+		lineNumber++
 
 		idLine, id := qm.isIDLine(line)
 
 		if idLine {
 
 			if currentTemplate != nil {
-				currentTemplate.Finalise()
+
+				if err := currentTemplate.Finalise(); err != nil {
+					return err
+				}
 			}
 
 			currentTemplate = newQueryTemplate(id, &fragmentBuffer)
+			currentTemplate.File = filePath
 			tokenisedTemplates[id] = currentTemplate
 			continue
 		}
@@ -333,10 +374,12 @@ func (qm *TemplatedQueryManager) scanAndParse(scanner *bufio.Scanner, tokenisedT
 			continue
 		}
 
+		currentTemplate.line = lineNumber
+
 		varTokens := re.FindAllStringSubmatch(line, -1)
 
 		if varTokens == nil {
-			currentTemplate.AddFragmentContent(line)
+			currentTemplate.addFragment(line)
 		} else {
 
 			fragments := re.Split(line, -1)
@@ -361,9 +404,9 @@ func (qm *TemplatedQueryManager) scanAndParse(scanner *bufio.Scanner, tokenisedT
 
 					if startsWithVar {
 						qm.addVar(varToken, currentTemplate)
-						currentTemplate.AddFragmentContent(fragment)
+						currentTemplate.addFragment(fragment)
 					} else {
-						currentTemplate.AddFragmentContent(fragment)
+						currentTemplate.addFragment(fragment)
 						qm.addVar(varToken, currentTemplate)
 
 					}
@@ -372,7 +415,7 @@ func (qm *TemplatedQueryManager) scanAndParse(scanner *bufio.Scanner, tokenisedT
 					qm.addVar(varTokens[i][1], currentTemplate)
 
 				} else if fragAvailable {
-					currentTemplate.AddFragmentContent(fragments[i])
+					currentTemplate.addFragment(fragments[i])
 				}
 
 			}
@@ -383,9 +426,10 @@ func (qm *TemplatedQueryManager) scanAndParse(scanner *bufio.Scanner, tokenisedT
 	}
 
 	if currentTemplate != nil {
-		currentTemplate.Finalise()
+		return currentTemplate.Finalise()
 	}
 
+	return nil
 }
 
 func intMax(x, y int) int {
@@ -398,6 +442,11 @@ func intMax(x, y int) int {
 
 func (qm *TemplatedQueryManager) addVar(token string, currentTemplate *queryTemplate) {
 
+	if strings.HasPrefix(token, includePrefix) {
+		currentTemplate.AddInclude(strings.TrimSpace(strings.TrimPrefix(token, includePrefix)))
+		return
+	}
+
 	index, err := strconv.Atoi(token)
 
 	if err == nil {
@@ -435,18 +484,29 @@ const (
 	fragmentToken = iota
 	varNameToken
 	varIndexToken
+	ifBlockToken
+	unlessBlockToken
+	whereBlockToken
+	endBlockToken
+	includeToken
 )
 
 type queryTemplate struct {
 	Tokens         []*queryTemplateToken
 	ID             string
+	File           string
 	currentToken   *queryTemplateToken
 	fragmentBuffer *bytes.Buffer
+
+	// The line of the file currently being parsed
+	line int
 }
 
-func (qt *queryTemplate) Finalise() {
+func (qt *queryTemplate) Finalise() error {
 	qt.closeFragmentToken()
 	qt.fragmentBuffer = nil
+
+	return qt.matchBlocks()
 }
 
 func (qt *queryTemplate) AddFragmentContent(fragment string) {
@@ -493,6 +553,40 @@ func (qt *queryTemplate) AddLabelledVar(label string) {
 	qt.currentToken = t
 }
 
+// AddBlockStart adds a token starting an if, unless or where block. For if and unless blocks, param is the name of the
+// parameter that controls whether or not the block's content is included.
+func (qt *queryTemplate) AddBlockStart(kind string, param string) {
+
+	qt.closeFragmentToken()
+
+	var t *queryTemplateToken
+
+	switch kind {
+	case ifBlock:
+		t = newQueryTemplateToken(ifBlockToken)
+	case unlessBlock:
+		t = newQueryTemplateToken(unlessBlockToken)
+	default:
+		t = newQueryTemplateToken(whereBlockToken)
+	}
+
+	t.Content = param
+
+	qt.Tokens = append(qt.Tokens, t)
+	qt.currentToken = t
+}
+
+// AddBlockEnd adds a token ending the most recently started block.
+func (qt *queryTemplate) AddBlockEnd() {
+
+	qt.closeFragmentToken()
+
+	t := newQueryTemplateToken(endBlockToken)
+
+	qt.Tokens = append(qt.Tokens, t)
+	qt.currentToken = t
+}
+
 func (qt *queryTemplate) EndLine() {
 	qt.AddFragmentContent("\n")
 }
@@ -509,7 +603,12 @@ func newQueryTemplate(id string, buffer *bytes.Buffer) *queryTemplate {
 type queryTemplateToken struct {
 	Type    queryTokenType
 	Content string
-	Index   int
+
+	// The index of a variable or, for tokens starting a block, the position in the template of the token ending the block
+	Index int
+
+	// The line of the template file on which an include appears
+	Line int
 }
 
 func newQueryTemplateToken(tokenType queryTokenType) *queryTemplateToken {
@@ -533,8 +632,30 @@ func (qtt *queryTemplateToken) String() string {
 		return fmt.Sprintf("VN:%s", qtt.Content)
 	case varIndexToken:
 		return fmt.Sprintf("VI:%d", qtt.Index)
+	case ifBlockToken, unlessBlockToken:
+		return fmt.Sprintf("[[%s %s]]", qtt.blockName(), qtt.Content)
+	case whereBlockToken, endBlockToken:
+		return fmt.Sprintf("[[%s]]", qtt.blockName())
+	case includeToken:
+		return fmt.Sprintf("IN:%s", qtt.Content)
 	default:
 		return ""
 
 	}
 }
+
+func (qtt *queryTemplateToken) blockName() string {
+
+	switch qtt.Type {
+	case ifBlockToken:
+		return ifBlock
+	case unlessBlockToken:
+		return unlessBlock
+	case whereBlockToken:
+		return whereBlock
+	case endBlockToken:
+		return endBlock
+	default:
+		return ""
+	}
+}
@@ -70,7 +70,7 @@ func TestSingleSingleQueryNoVars(t *testing.T) {
 	queryFiles := []string{test.FilePath(f)}
 	qm := buildQueryManager()
 
-	tt := qm.parseQueryFiles(queryFiles)
+	tt, _ := qm.parseQueryFiles(queryFiles, false)
 
 	members := len(tt)
 
@@ -86,7 +86,7 @@ func TestSingleQueryIndexVars(t *testing.T) {
 	queryFiles := []string{test.FilePath(f)}
 	qm := buildQueryManager()
 
-	tt := qm.parseQueryFiles(queryFiles)
+	tt, _ := qm.parseQueryFiles(queryFiles, false)
 
 	members := len(tt)
 
@@ -117,7 +117,7 @@ func TestMultiQueryNameVars(t *testing.T) {
 	queryFiles := []string{test.FilePath(f)}
 	qm := buildQueryManager()
 
-	tt := qm.parseQueryFiles(queryFiles)
+	tt, _ := qm.parseQueryFiles(queryFiles, false)
 
 	members := len(tt)
 
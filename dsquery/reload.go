@@ -0,0 +1,221 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"bytes"
+	"github.com/graniticio/granitic/v2/config"
+	"os"
+	"sort"
+	"time"
+)
+
+const defaultWatchInterval = time.Second
+
+// ReloadSummary lists the IDs of the queries that were added, removed or changed when templates were reloaded.
+type ReloadSummary struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Unchanged returns true if no queries were added, removed or changed by the reload.
+func (rs *ReloadSummary) Unchanged() bool {
+	return len(rs.Added) == 0 && len(rs.Removed) == 0 && len(rs.Changed) == 0
+}
+
+// Reload re-reads and parses every template file in TemplateLocation. The new templates replace the current templates
+// only if all of the files can be opened and are parsed successfully - if there is a problem, the current templates are
+// kept and an error is returned.
+func (qm *TemplatedQueryManager) Reload() (*ReloadSummary, error) {
+
+	qm.reloadMux.Lock()
+	defer qm.reloadMux.Unlock()
+
+	fl := qm.FrameworkLogger
+
+	files, err := config.FileListFromPath(qm.TemplateLocation)
+
+	if err == nil {
+		var templates map[string]*queryTemplate
+
+		// A file that can't be opened (e.g. while an editor is replacing it) would otherwise look like its queries had been removed
+		if templates, err = qm.parseQueryFiles(files, true); err == nil {
+
+			qm.mux.Lock()
+			previous := qm.tokenisedTemplates
+			qm.tokenisedTemplates = templates
+			qm.fragments = staticFragments(templates)
+			qm.mux.Unlock()
+
+			rs := compareTemplates(previous, templates)
+
+			fl.LogInfof("Reloaded query templates from %s. Added: %v Removed: %v Changed: %v", qm.TemplateLocation, rs.Added, rs.Removed, rs.Changed)
+
+			return rs, nil
+		}
+	}
+
+	fl.LogErrorf("Unable to reload query templates (existing templates will continue to be used): %s", err.Error())
+
+	return nil, err
+}
+
+// compareTemplates finds the queries that differ between two sets of parsed templates
+func compareTemplates(previous, current map[string]*queryTemplate) *ReloadSummary {
+
+	rs := new(ReloadSummary)
+
+	for id, qt := range current {
+
+		if p := previous[id]; p == nil {
+			rs.Added = append(rs.Added, id)
+		} else if p.signature() != qt.signature() {
+			rs.Changed = append(rs.Changed, id)
+		}
+	}
+
+	for id := range previous {
+
+		if current[id] == nil {
+			rs.Removed = append(rs.Removed, id)
+		}
+	}
+
+	sort.Strings(rs.Added)
+	sort.Strings(rs.Removed)
+	sort.Strings(rs.Changed)
+
+	return rs
+}
+
+// signature is a representation of the template's tokens that can be compared with another template's
+func (qt *queryTemplate) signature() string {
+
+	var b bytes.Buffer
+
+	for _, token := range qt.Tokens {
+		b.WriteString(token.String())
+		b.WriteString("\x00")
+	}
+
+	return b.String()
+}
+
+type templateFileState struct {
+	modified time.Time
+	size     int64
+}
+
+// templateFiles records the modification time and size of every file in TemplateLocation
+func (qm *TemplatedQueryManager) templateFiles() (map[string]templateFileState, error) {
+
+	files, err := config.FileListFromPath(qm.TemplateLocation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(map[string]templateFileState)
+
+	for _, f := range files {
+
+		if fi, err := os.Stat(f); err == nil {
+			state[f] = templateFileState{modified: fi.ModTime(), size: fi.Size()}
+		}
+	}
+
+	return state, nil
+}
+
+// changedFiles returns the paths of files that have been added, modified or removed between two checks of TemplateLocation
+func changedFiles(previous, current map[string]templateFileState) []string {
+
+	changed := make([]string, 0)
+
+	for f, s := range current {
+
+		if p, found := previous[f]; !found || p != s {
+			changed = append(changed, f)
+		}
+	}
+
+	for f := range previous {
+
+		if _, found := current[f]; !found {
+			changed = append(changed, f)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+// watch polls TemplateLocation every WatchIntervalMilliseconds, reloading all templates when a file is added, modified
+// or removed, until Stop is called.
+func (qm *TemplatedQueryManager) watch() {
+
+	fl := qm.FrameworkLogger
+
+	interval := time.Duration(qm.WatchIntervalMilliseconds) * time.Millisecond
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	previous, _ := qm.templateFiles()
+
+	stop := make(chan bool)
+	qm.stopWatching = stop
+
+	fl.LogInfof("Watching %s for changes to query templates", qm.TemplateLocation)
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+
+				current, err := qm.templateFiles()
+
+				if err != nil {
+					fl.LogWarnf("Unable to check query templates for changes: %s", err.Error())
+					continue
+				}
+
+				if changed := changedFiles(previous, current); len(changed) > 0 {
+					fl.LogInfof("Query template files changed: %v", changed)
+					qm.Reload()
+				}
+
+				previous = current
+			}
+		}
+	}()
+}
+
+// PrepareToStop implements ioc.Stoppable.PrepareToStop
+func (qm *TemplatedQueryManager) PrepareToStop() {
+}
+
+// ReadyToStop implements ioc.Stoppable.ReadyToStop
+func (qm *TemplatedQueryManager) ReadyToStop() (bool, error) {
+	return true, nil
+}
+
+// Stop implements ioc.Stoppable.Stop. Stops watching for changes to template files.
+func (qm *TemplatedQueryManager) Stop() error {
+
+	if qm.stopWatching != nil {
+		close(qm.stopWatching)
+		qm.stopWatching = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package dsquery
+
+import (
+	"github.com/graniticio/granitic/v2/test"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "grnc-reload")
+	test.ExpectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "queries")
+	writeTemplates(t, f, "ID:FIRST\n\nSELECT 1\n\nID:SECOND\n\nSELECT 2\n")
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = dir
+
+	test.ExpectNil(t, qm.StartComponent())
+
+	writeTemplates(t, f, "ID:FIRST\n\nSELECT 1\n\nID:SECOND\n\nSELECT 22\n\nID:THIRD\n\nSELECT ${>SECOND}\n")
+
+	rs, err := qm.Reload()
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, rs.Unchanged(), false)
+	test.ExpectInt(t, len(rs.Added), 1)
+	test.ExpectString(t, rs.Added[0], "THIRD")
+	test.ExpectInt(t, len(rs.Changed), 1)
+	test.ExpectString(t, rs.Changed[0], "SECOND")
+
+	q, _ := qm.FragmentFromID("THIRD")
+	test.ExpectString(t, q, "SELECT SELECT 22\n")
+
+	// A template that cannot be parsed leaves the existing templates in place
+	writeTemplates(t, f, "ID:FIRST\n\nSELECT ${>MISSING}\n")
+
+	_, err = qm.Reload()
+	test.ExpectNotNil(t, err)
+
+	q, _ = qm.FragmentFromID("SECOND")
+	test.ExpectString(t, q, "SELECT 22\n")
+
+	// As does a template file that cannot be opened
+	writeTemplates(t, f, "ID:FIRST\n\nSELECT 1\n\nID:SECOND\n\nSELECT 22\n\nID:THIRD\n\nSELECT ${>SECOND}\n")
+
+	unreadable := filepath.Join(dir, "unreadable")
+	test.ExpectNil(t, os.Symlink(filepath.Join(dir, "missing"), unreadable))
+
+	_, err = qm.Reload()
+	test.ExpectNotNil(t, err)
+
+	q, _ = qm.FragmentFromID("SECOND")
+	test.ExpectString(t, q, "SELECT 22\n")
+
+	test.ExpectNil(t, os.Remove(unreadable))
+
+	writeTemplates(t, f, "ID:FIRST\n\nSELECT 1\n")
+
+	rs, _ = qm.Reload()
+	test.ExpectInt(t, len(rs.Removed), 2)
+
+	rs, _ = qm.Reload()
+	test.ExpectBool(t, rs.Unchanged(), true)
+}
+
+func TestWatchTemplates(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "grnc-watch")
+	test.ExpectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeTemplates(t, filepath.Join(dir, "queries"), "ID:FIRST\n\nSELECT 1\n")
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = dir
+	qm.WatchTemplates = true
+	qm.WatchIntervalMilliseconds = 10
+
+	test.ExpectNil(t, qm.StartComponent())
+	defer qm.Stop()
+
+	writeTemplates(t, filepath.Join(dir, "more-queries"), "ID:SECOND\n\nSELECT 2\n")
+
+	for i := 0; i < 100; i++ {
+
+		if _, err = qm.FragmentFromID("SECOND"); err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	test.ExpectNil(t, err)
+
+	test.ExpectNil(t, qm.Stop())
+	test.ExpectBool(t, qm.stopWatching == nil, true)
+}
+
+func TestChangedFiles(t *testing.T) {
+
+	now := time.Now()
+
+	previous := map[string]templateFileState{"a": {now, 10}, "b": {now, 10}}
+	current := map[string]templateFileState{"a": {now, 10}, "b": {now, 11}, "c": {now, 1}}
+
+	changed := changedFiles(previous, current)
+
+	test.ExpectInt(t, len(changed), 2)
+	test.ExpectString(t, changed[0], "b")
+	test.ExpectString(t, changed[1], "c")
+
+	test.ExpectInt(t, len(changedFiles(current, previous)), 2)
+}
+
+func writeTemplates(t *testing.T, path, content string) {
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Unable to write %s: %s", path, err.Error())
+	}
+}
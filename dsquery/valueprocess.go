@@ -1,9 +1,15 @@
 package dsquery
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/graniticio/granitic/v2/types"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 // ParamValueProcessor is implemented by components able to escape the value of a parameter to a query and handle unset parameters
@@ -130,3 +136,160 @@ func (sp *SQLProcessor) SubstituteUnset(v *ParamValueContext) error {
 
 	return nil
 }
+
+// JSONProcessor converts parameter values to JSON so that they can be injected into templates for JSON query documents
+// (for example the bodies of MongoDB or Elasticsearch queries). Strings are quoted and escaped, Nilable types are
+// converted to their underlying value (or null if unset) and maps and structs are converted to JSON objects. Values are
+// intended to be injected without surrounding quotes:
+//
+//	{"name": ${name}, "age": {"$gt": ${minAge}}}
+//
+// Elements of slice and array parameters are converted individually and separated with the query manager's
+// ElementSeparator, so the surrounding brackets must be included in the template: "tags": [${tags}]
+type JSONProcessor struct {
+	// Use the JSON value null instead of returning an error if a parameter required for a query is missing
+	NullForMissingParameter bool
+}
+
+// EscapeParamValue replaces the parameter's value with its JSON representation. The value is left unchanged if it
+// has already been escaped or cannot be converted to JSON.
+func (jp *JSONProcessor) EscapeParamValue(v *ParamValueContext) {
+
+	if v.Escaped {
+		return
+	}
+
+	m := marshalable(v.Value)
+
+	if n, found := m.(types.Nilable); found && !n.IsSet() {
+		// Nilable types do not produce valid JSON when unset
+		v.Value = "null"
+		return
+	}
+
+	var b bytes.Buffer
+
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+
+	if err := e.Encode(m); err == nil {
+		v.Value = strings.TrimSuffix(b.String(), "\n")
+	}
+}
+
+// SubstituteUnset changes the value of an unset parameter to null if NullForMissingParameter is true, otherwise
+// returns an error.
+func (jp *JSONProcessor) SubstituteUnset(v *ParamValueContext) error {
+
+	if !jp.NullForMissingParameter {
+		return fmt.Errorf("Parameter %s must be supplied for query %s", v.Key, v.QueryID)
+	}
+
+	v.Value = "null"
+	v.Escaped = true
+
+	return nil
+}
+
+// marshalable returns a pointer to a copy of Nilable values (which implement json.Marshaler with pointer receivers) so
+// that they are converted to JSON correctly.
+func marshalable(i interface{}) interface{} {
+
+	if i == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(i)
+
+	if rv.Kind() == reflect.Ptr {
+		return i
+	}
+
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+
+	if _, found := p.Interface().(types.Nilable); found {
+		return p.Interface()
+	}
+
+	return i
+}
+
+// URLProcessor escapes parameter values so that they can be injected into templates for the paths (and query strings)
+// of HTTP requests, for example:
+//
+//	/users/${userID}/orders?status=${status}
+//
+// Strings, numbers, bools and their Nilable equivalents are supported. Unset Nilable values are converted to an empty
+// string.
+type URLProcessor struct {
+	// Escape values for use in a URL's query string (spaces become +) rather than in its path (spaces become %20)
+	QueryEscape bool
+
+	// Use an empty string instead of returning an error if a parameter required for a query is missing
+	EmptyForMissingParameter bool
+}
+
+// EscapeParamValue replaces the parameter's value with a URL escaped string. The value is left unchanged if it has
+// already been escaped or is not a supported type.
+func (up *URLProcessor) EscapeParamValue(v *ParamValueContext) {
+
+	if v.Escaped {
+		return
+	}
+
+	var s string
+
+	switch t := v.Value.(type) {
+	default:
+		return
+	case string:
+		s = t
+	case int, int64, float64, bool:
+		s = fmt.Sprint(t)
+	case *types.NilableString, types.NilableString, *types.NilableInt64, types.NilableInt64,
+		*types.NilableFloat64, types.NilableFloat64, *types.NilableBool, types.NilableBool:
+		s = nilableString(marshalable(t).(types.Nilable))
+	}
+
+	if up.QueryEscape {
+		v.Value = url.QueryEscape(s)
+	} else {
+		v.Value = url.PathEscape(s)
+	}
+}
+
+// SubstituteUnset changes the value of an unset parameter to an empty string if EmptyForMissingParameter is true,
+// otherwise returns an error.
+func (up *URLProcessor) SubstituteUnset(v *ParamValueContext) error {
+
+	if !up.EmptyForMissingParameter {
+		return fmt.Errorf("Parameter %s must be supplied for query %s", v.Key, v.QueryID)
+	}
+
+	v.Value = ""
+	v.Escaped = true
+
+	return nil
+}
+
+// nilableString returns the value of a Nilable type as a string, or an empty string if no value is set.
+func nilableString(n types.Nilable) string {
+
+	if !n.IsSet() {
+		return ""
+	}
+
+	switch t := n.(type) {
+	case *types.NilableString:
+		return t.String()
+	case *types.NilableInt64:
+		return strconv.FormatInt(t.Int64(), 10)
+	case *types.NilableFloat64:
+		return strconv.FormatFloat(t.Float64(), 'f', -1, 64)
+	case *types.NilableBool:
+		return strconv.FormatBool(t.Bool())
+	}
+
+	return ""
+}
@@ -1,6 +1,8 @@
 package dsquery
 
 import (
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/types"
 	"testing"
 )
 
@@ -32,3 +34,92 @@ func TestSQLProcessor(t *testing.T) {
 	}
 
 }
+
+func TestJSONProcessor(t *testing.T) {
+
+	jp := new(JSONProcessor)
+
+	values := []struct {
+		in  interface{}
+		out string
+	}{
+		{"a \"quoted\" <value>", `"a \"quoted\" <value>"`},
+		{42, "42"},
+		{true, "true"},
+		{types.NewNilableString("abc"), `"abc"`},
+		{*types.NewNilableInt64(7), "7"},
+		{new(types.NilableBool), "null"},
+		{map[string]int{"$gt": 3}, `{"$gt":3}`},
+	}
+
+	for _, v := range values {
+
+		pvc := ParamValueContext{Value: v.in}
+		jp.EscapeParamValue(&pvc)
+
+		test.ExpectString(t, pvc.Value.(string), v.out)
+	}
+
+	pvc := ParamValueContext{Key: "name", QueryID: "Q"}
+	test.ExpectNotNil(t, jp.SubstituteUnset(&pvc))
+
+	jp.NullForMissingParameter = true
+	test.ExpectNil(t, jp.SubstituteUnset(&pvc))
+
+	jp.EscapeParamValue(&pvc)
+	test.ExpectString(t, pvc.Value.(string), "null")
+}
+
+func TestURLProcessor(t *testing.T) {
+
+	up := new(URLProcessor)
+
+	pvc := ParamValueContext{Value: "a b/c"}
+	up.EscapeParamValue(&pvc)
+	test.ExpectString(t, pvc.Value.(string), "a%20b%2Fc")
+
+	pvc = ParamValueContext{Value: types.NewNilableFloat64(1.5)}
+	up.EscapeParamValue(&pvc)
+	test.ExpectString(t, pvc.Value.(string), "1.5")
+
+	up.QueryEscape = true
+
+	pvc = ParamValueContext{Value: *types.NewNilableString("a b&c")}
+	up.EscapeParamValue(&pvc)
+	test.ExpectString(t, pvc.Value.(string), "a+b%26c")
+
+	pvc = ParamValueContext{Key: "id", QueryID: "Q"}
+	test.ExpectNotNil(t, up.SubstituteUnset(&pvc))
+
+	up.EmptyForMissingParameter = true
+	test.ExpectNil(t, up.SubstituteUnset(&pvc))
+	test.ExpectString(t, pvc.Value.(string), "")
+}
+
+func TestNonSQLTemplates(t *testing.T) {
+
+	qm := buildQueryManager()
+	qm.TemplateLocation = test.FilePath("nonsql")
+	qm.ValueProcessor = &JSONProcessor{NullForMissingParameter: true}
+
+	if err := qm.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	q, err := qm.BuildQueryFromID("ARTIST_SEARCH", map[string]interface{}{"name": "Nico", "ids": []int64{1, 2}})
+	test.ExpectNil(t, err)
+	test.ExpectString(t, q, "{\"query\": {\"bool\": {\"must\": [\n  {\"match\": {\"name\": \"Nico\"}},\n  {\"terms\": {\"id\": [1, 2]}}\n]}}}\n")
+
+	q, err = qm.BuildQueryFromID("ARTIST_SEARCH", map[string]interface{}{})
+	test.ExpectNil(t, err)
+	test.ExpectString(t, q, "{\"query\": {\"bool\": {\"must\": [\n  {\"match\": {\"name\": null}}\n]}}}\n")
+
+	qm.ValueProcessor = new(URLProcessor)
+
+	q, err = qm.BuildQueryFromID("ARTIST_ORDERS", map[string]interface{}{"artistID": "a/1", "status": "on hold"})
+	test.ExpectNil(t, err)
+	test.ExpectString(t, q, "/artists/a%2F1/orders?status=on%20hold\n")
+
+	_, err = qm.BuildQueryFromID("ARTIST_ORDERS", map[string]interface{}{})
+	test.ExpectNotNil(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/graniticio/granitic/v2/config"
 	"github.com/graniticio/granitic/v2/facility/httpserver"
 	"github.com/graniticio/granitic/v2/facility/logger"
+	"github.com/graniticio/granitic/v2/facility/migration"
 	"github.com/graniticio/granitic/v2/facility/querymanager"
 	"github.com/graniticio/granitic/v2/facility/rdbms"
 	"github.com/graniticio/granitic/v2/facility/runtimectl"
@@ -133,6 +134,7 @@ func (fi *FacilitiesInitialisor) Initialise(ca *config.Accessor) error {
 	fi.addFacility(new(ws.XMLFacilityBuilder))
 	fi.addFacility(new(serviceerror.FacilityBuilder))
 	fi.addFacility(new(rdbms.FacilityBuilder))
+	fi.addFacility(new(migration.FacilityBuilder))
 	fi.addFacility(new(runtimectl.FacilityBuilder))
 	fi.addFacility(new(taskscheduler.FacilityBuilder))
 
@@ -0,0 +1,141 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+/*
+Package migration provides the SchemaMigration facility which applies versioned SQL migrations to a relational database.
+
+The facility creates a migration.Migrator component (see the rdbms/migration package for details of how migration files are
+named and how applied migrations are recorded). It requires the RdbmsAccess facility to be enabled and uses the same
+rdbms.DatabaseProvider component. If your application defines more than one DatabaseProvider, set SchemaMigration.ProviderName
+to the name of the component that provides the database to be migrated.
+
+Configuration
+
+The default configuration for this facility is:
+
+	{
+	  "SchemaMigration":{
+		"Location": "resource/migrations",
+		"HistoryTable": "grnc_schema_history",
+		"LockStyle": "NONE",
+		"LockTimeoutSeconds": 60,
+		"ApplyOnStart": true,
+		"SplitStatements": true
+	  }
+	}
+
+If ApplyOnStart is true, pending migrations are applied while the container is starting components and before any component
+is made accessible (e.g. before HTTP servers start listening). A failure to apply migrations prevents the application from
+starting. Set LockStyle to POSTGRESQL or MYSQL to hold an advisory lock while migrations are applied, preventing several instances
+of your application applying migrations at the same time.
+
+Runtime control
+
+If the RuntimeCtl facility is enabled, the migrations command is available:
+
+	grnc-ctl migrations [status|apply|rollback] [-steps n]
+
+Command line
+
+Starting your application with the -migrate argument runs a single migration command and exits without making your application
+accessible:
+
+	-migrate status       Show the status of all migrations
+	-migrate apply        Apply pending migrations
+	-migrate rollback     Roll back the most recently applied migration
+	-migrate rollback:n   Roll back the n most recently applied migrations
+*/
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/config"
+	"github.com/graniticio/granitic/v2/facility/runtimectl"
+	"github.com/graniticio/granitic/v2/instance"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"github.com/graniticio/granitic/v2/rdbms/migration"
+	"strings"
+)
+
+const facilityName = "SchemaMigration"
+
+// MigratorComponentName is the name of the migration.Migrator component as stored in the IoC framework.
+const MigratorComponentName = instance.FrameworkPrefix + "SchemaMigrator"
+
+const migrationsCommandComp = instance.FrameworkPrefix + "CommandMigrations"
+
+const providerNamePath = facilityName + ".ProviderName"
+
+// FacilityBuilder creates the components that make up the SchemaMigration facility
+type FacilityBuilder struct {
+}
+
+// BuildAndRegister implements FacilityBuilder.BuildAndRegister
+func (fb *FacilityBuilder) BuildAndRegister(lm *logging.ComponentLoggerManager, ca *config.Accessor, cn *ioc.ComponentContainer) error {
+
+	providerName, err := fb.findProvider(ca, cn)
+
+	if err != nil {
+		return err
+	}
+
+	mg := new(migration.Migrator)
+	ca.Populate(facilityName, mg)
+
+	proto := ioc.CreateProtoComponent(mg, MigratorComponentName)
+	proto.AddDependency("Provider", providerName)
+	cn.AddProto(proto)
+
+	if runtimectl.Enabled(ca) {
+		c := new(migrationsCommand)
+		c.migrator = mg
+
+		cn.WrapAndAddProto(migrationsCommandComp, c)
+	}
+
+	return nil
+}
+
+func (fb *FacilityBuilder) findProvider(ca *config.Accessor, cn *ioc.ComponentContainer) (string, error) {
+
+	if ca.PathExists(providerNamePath) {
+		return ca.StringVal(providerNamePath)
+	}
+
+	matcher := func(i interface{}) (okay bool) {
+		_, okay = i.(rdbms.DatabaseProvider)
+		return
+	}
+
+	providers := cn.ProtoComponentsByType(matcher)
+
+	switch len(providers) {
+	case 0:
+		return "", errors.New("you must define a component that implements rdbms.DatabaseProvider if you want to use the SchemaMigration facility")
+	case 1:
+		return providers[0].Component.Name, nil
+	}
+
+	names := make([]string, len(providers))
+
+	for i, p := range providers {
+		names[i] = p.Component.Name
+	}
+
+	return "", fmt.Errorf("more than one rdbms.DatabaseProvider is defined (%s). Set %s to the name of the provider to be migrated",
+		strings.Join(names, ", "), providerNamePath)
+}
+
+// FacilityName implements FacilityBuilder.FacilityName
+func (fb *FacilityBuilder) FacilityName() string {
+	return facilityName
+}
+
+// DependsOnFacilities returns the other facilities that must be enabled in order to use the SchemaMigration facility. You must
+// enable the RdbmsAccess facility.
+func (fb *FacilityBuilder) DependsOnFacilities() []string {
+	return []string{"RdbmsAccess"}
+}
@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"bytes"
+	"database/sql"
+	"github.com/graniticio/granitic/v2/config"
+	"github.com/graniticio/granitic/v2/instance"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+)
+
+func TestFacilityNaming(t *testing.T) {
+
+	fb := new(FacilityBuilder)
+
+	test.ExpectString(t, fb.FacilityName(), "SchemaMigration")
+	test.ExpectString(t, fb.DependsOnFacilities()[0], "RdbmsAccess")
+}
+
+func TestProviderSelection(t *testing.T) {
+
+	lm := logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+	ca := &config.Accessor{JSONData: make(map[string]interface{}), FrameworkLogger: lm.CreateLogger("ca")}
+
+	cc := ioc.NewComponentContainer(lm, ca, new(instance.System))
+	fb := new(FacilityBuilder)
+
+	if err := fb.BuildAndRegister(lm, ca, cc); err == nil {
+		t.Errorf("Expected an error when no DatabaseProvider is defined")
+	}
+
+	cc.WrapAndAddProto("dbProvider", new(nilProvider))
+
+	test.ExpectNil(t, fb.BuildAndRegister(lm, ca, cc))
+
+	cc.WrapAndAddProto("otherProvider", new(nilProvider))
+
+	if _, err := fb.findProvider(ca, cc); err == nil {
+		t.Errorf("Expected an error when more than one DatabaseProvider is defined")
+	}
+
+	ca.JSONData["SchemaMigration"] = map[string]interface{}{"ProviderName": "otherProvider"}
+
+	name, err := fb.findProvider(ca, cc)
+
+	test.ExpectNil(t, err)
+	test.ExpectString(t, name, "otherProvider")
+}
+
+func TestCommandArguments(t *testing.T) {
+
+	c := new(migrationsCommand)
+
+	_, errs := c.ExecuteCommand([]string{"upgrade"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+
+	_, errs = c.ExecuteCommand([]string{"rollback"}, map[string]string{"steps": "0"})
+	test.ExpectInt(t, len(errs), 1)
+
+	lm := logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+	ca := &config.Accessor{JSONData: make(map[string]interface{}), FrameworkLogger: lm.CreateLogger("ca")}
+
+	err := RunCommand(ioc.NewComponentContainer(lm, ca, new(instance.System)), "status", new(bytes.Buffer))
+	test.ExpectNotNil(t, err)
+}
+
+type nilProvider struct{}
+
+func (np *nilProvider) Database() (*sql.DB, error) {
+	return nil, nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"github.com/graniticio/granitic/v2/ctl"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/rdbms/migration"
+	"github.com/graniticio/granitic/v2/ws"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	migrationsCommandName = "migrations"
+	migrationsSummary     = "Shows the status of schema migrations, applies pending migrations or rolls back applied migrations."
+	migrationsUsage       = "migrations [status|apply|rollback] [-steps n]"
+	migrationsHelp        = "With no qualifier or the status qualifier, lists every migration with its version, description and whether it is applied, pending, modified or missing."
+	migrationsHelpTwo     = "The apply qualifier applies all pending migrations. The rollback qualifier rolls back the most recently applied migration, or the number of migrations set with the '-steps n' argument."
+
+	statusAction   = "status"
+	applyAction    = "apply"
+	rollbackAction = "rollback"
+	stepsArg       = "steps"
+)
+
+type migrationsCommand struct {
+	migrator *migration.Migrator
+}
+
+func (c *migrationsCommand) ExecuteCommand(qualifiers []string, args map[string]string) (*ctl.CommandOutput, []*ws.CategorisedError) {
+
+	action := statusAction
+
+	if len(qualifiers) > 0 {
+		action = qualifiers[0]
+	}
+
+	if action != statusAction && action != applyAction && action != rollbackAction {
+		return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("unknown qualifier %s. Usage: %s", action, migrationsUsage))}
+	}
+
+	steps := 1
+
+	if s := args[stepsArg]; s != "" {
+
+		var err error
+
+		if steps, err = strconv.Atoi(s); err != nil || steps < 1 {
+			return nil, []*ws.CategorisedError{ctl.NewCommandClientError("steps must be a whole number greater than zero")}
+		}
+	}
+
+	co, err := execute(c.migrator, action, steps)
+
+	if err != nil {
+		return nil, []*ws.CategorisedError{ctl.NewCommandUnexpectedError(err.Error())}
+	}
+
+	return co, nil
+}
+
+func (c *migrationsCommand) Name() string {
+	return migrationsCommandName
+}
+
+func (c *migrationsCommand) Summmary() string {
+	return migrationsSummary
+}
+
+func (c *migrationsCommand) Usage() string {
+	return migrationsUsage
+}
+
+func (c *migrationsCommand) Help() []string {
+	return []string{migrationsHelp, migrationsHelpTwo}
+}
+
+/*
+RunCommand runs a single migration command (status, apply, rollback or rollback:n) using the Migrator in the supplied container
+and writes the outcome to the supplied writer. The container must have been populated, but should not have been started. Used
+to support the -migrate command line argument.
+*/
+func RunCommand(cc *ioc.ComponentContainer, command string, w io.Writer) error {
+
+	comp := cc.ComponentByName(MigratorComponentName)
+
+	if comp == nil {
+		return fmt.Errorf("the %s facility must be enabled to run migration commands", facilityName)
+	}
+
+	mg := comp.Instance.(*migration.Migrator)
+	mg.ApplyOnStart = false
+
+	if s, found := mg.Provider.(ioc.Startable); found {
+
+		if err := s.StartComponent(); err != nil {
+			return err
+		}
+	}
+
+	if err := mg.StartComponent(); err != nil {
+		return err
+	}
+
+	action := command
+	steps := 1
+
+	if i := strings.Index(command, ":"); i > 0 {
+
+		var err error
+
+		action = command[:i]
+
+		if steps, err = strconv.Atoi(command[i+1:]); err != nil || steps < 1 {
+			return fmt.Errorf("%s is not a valid migration command", command)
+		}
+	}
+
+	co, err := execute(mg, action, steps)
+
+	if err != nil {
+		return err
+	}
+
+	if co.OutputHeader != "" {
+		fmt.Fprintln(w, co.OutputHeader)
+	}
+
+	for _, row := range co.OutputBody {
+		fmt.Fprintln(w, strings.Join(row, "  "))
+	}
+
+	return nil
+}
+
+func execute(mg *migration.Migrator, action string, steps int) (*ctl.CommandOutput, error) {
+
+	ctx := context.Background()
+	co := new(ctl.CommandOutput)
+	co.RenderHint = ctl.Columns
+
+	switch action {
+	case statusAction:
+
+		status, err := mg.Status(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range status {
+
+			state := s.State
+
+			if !s.AppliedAt.IsZero() {
+				state = fmt.Sprintf("%s %s", state, s.AppliedAt.UTC().Format(time.RFC3339))
+			}
+
+			co.OutputBody = append(co.OutputBody, []string{describe(s.Version, s.Description), state})
+		}
+
+	case applyAction:
+
+		applied, err := mg.Apply(ctx)
+
+		co.OutputHeader = fmt.Sprintf("%d migration(s) applied", len(applied))
+		co.OutputBody = describeAll(applied, "applied")
+
+		if err != nil {
+			return nil, fmt.Errorf("%s before error: %s", co.OutputHeader, err.Error())
+		}
+
+	case rollbackAction:
+
+		rolledBack, err := mg.Rollback(ctx, steps)
+
+		co.OutputHeader = fmt.Sprintf("%d migration(s) rolled back", len(rolledBack))
+		co.OutputBody = describeAll(rolledBack, "rolled back")
+
+		if err != nil {
+			return nil, fmt.Errorf("%s before error: %s", co.OutputHeader, err.Error())
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown migration command %s. Must be one of %s, %s or %s", action, statusAction, applyAction, rollbackAction)
+	}
+
+	return co, nil
+}
+
+func describeAll(migrations []*migration.Migration, outcome string) [][]string {
+
+	rows := make([][]string, len(migrations))
+
+	for i, m := range migrations {
+		rows[i] = []string{describe(m.Version, m.Description), outcome}
+	}
+
+	return rows
+}
+
+func describe(version int64, description string) string {
+	return fmt.Sprintf("%d %s", version, description)
+}
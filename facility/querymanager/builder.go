@@ -71,15 +71,99 @@ Required parameters
 If you put a ! character before a parameter name in your template (e.g. ${!artistID}), an error will be returned if that parameter is
 not available when a query is built.
 
+Slice and array parameters
+
+If the value of a parameter is a slice or array, each element is processed separately and the results joined with
+QueryManager.ElementSeparator, so that a template can include, for example:
+
+	WHERE id IN (${ids})
+
+Conditional blocks
+
+Parts of a template can be included only when a parameter has been supplied, allowing a single template to serve
+searches with optional filters:
+
+	ID:ARTIST_SEARCH
+
+	SELECT id FROM artist
+	[[where]]
+		[[if name]]AND name = ${name}[[end]]
+		[[if ids]]AND id IN (${ids})[[end]]
+		[[unless includeInactive]]AND active = true[[end]]
+	[[end]]
+
+The content of an [[if param]] block is only included if param is set (not nil, not an unset Nilable type and not an
+empty slice, array or map). [[unless param]] blocks are included only if param is not set. A [[where]] block is
+replaced with WHERE followed by its content with any leading AND or OR removed, or with nothing if its content is
+empty. Blocks may be nested and are parsed when the query manager starts; a block without a matching [[end]] prevents startup.
+
+Including other queries
+
+A parameter name starting with > includes the query with that ID, so that column lists and joins can be shared between
+queries:
+
+	ID:ARTIST_COLUMNS
+
+	a.id, a.name
+
+	ID:ARTIST_SEARCH
+
+	SELECT ${>ARTIST_COLUMNS} FROM artist a
+
+Includes are resolved when the query manager starts (the included query may be in any file) and the final line ending of
+the included query is removed. Startup fails, with an error naming the file and line of the include, if the included
+query does not exist or if queries include each other. FragmentFromID returns the text of a query with its includes expanded.
+
+Reloading templates
+
+If QueryManager.WatchTemplates is set to true, TemplateLocation is checked for changed files every
+QueryManager.WatchIntervalMilliseconds and all templates are reloaded when a change is found. Templates are only replaced
+if all files can be parsed. If the RuntimeCtl facility is enabled, the reload-queries command reloads templates on demand.
+
 Parameter Values
 
-Parameter values are injected into the query using a component called a ParamValueProcessor. Granitic includes two
-built-in implementations - ConfigurableProcessor and SQLProcessor. These components a) decide how to handle missing parameter
-values and b) perform any escaping/substitution/conversion of values before they are injected into the query.
+Parameter values are injected into the query using a component called a ParamValueProcessor. Granitic includes four
+built-in implementations - ConfigurableProcessor, SQLProcessor, JSONProcessor and URLProcessor. These components a) decide how
+to handle missing parameter values and b) perform any escaping/substitution/conversion of values before they are injected into the query.
 
-To enable one of the default processors, set QueryManager.ProcessorName to Configurable or SQL (the default is Configurable). If
+To enable one of the default processors, set QueryManager.ProcessorName to Configurable, SQL, JSON or URL (the default is Configurable). If
 you want to implement your own processor, set QueryManager.CreateDefaultValueProcessor to false and define a component that
 implements ParamValueProcessor
+
+Data sources other than relational databases
+
+The JSON processor converts values to JSON (strings are quoted, Nilable types become their value or null) so templates
+can describe query documents for data stores like MongoDB or Elasticsearch. Values are injected without quotes:
+
+	ID:ARTIST_SEARCH
+
+	{"query": {"bool": {"must": [
+		{"match": {"name": ${name}}}
+		[[if ids]], {"terms": {"id": [${ids}]}}[[end]]
+	]}}}
+
+The URL processor escapes values for use in the paths and query strings of HTTP requests:
+
+	ID:ARTIST_ORDERS
+
+	/artists/${!artistID}/orders[[if status]]?status=${status}[[end]]
+
+Applications using more than one kind of data source can create additional query managers, each with its own templates
+and processor, in QueryManager.Managers:
+
+	{
+	  "QueryManager":{
+		"Managers": {
+		  "searchQueries": {
+			"TemplateLocation": "resource/search-queries",
+			"ProcessorName": "JSON"
+		  }
+		}
+	  }
+	}
+
+Each entry creates a component named after the entry (searchQueries in this example). Settings not defined in the
+entry (including ValueProcessors) are taken from QueryManager, but TemplateLocation must always be set.
 */
 package querymanager
 
@@ -88,9 +172,11 @@ import (
 	"fmt"
 	"github.com/graniticio/granitic/v2/config"
 	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/facility/runtimectl"
 	"github.com/graniticio/granitic/v2/instance"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
+	"sort"
 	"strings"
 )
 
@@ -102,10 +188,20 @@ const QueryManagerFacilityName = "QueryManager"
 
 const processorDecorator = instance.FrameworkPrefix + "ParamValueProcessorDecorator"
 
+const reloadCommandComp = instance.FrameworkPrefix + "CommandReloadQueries"
+
 const confValueProcess = "CONFIGURABLE"
 
 const sqlValueProcess = "SQL"
 
+const jsonValueProcess = "JSON"
+
+const urlValueProcess = "URL"
+
+const defaultPath = "QueryManager"
+
+const managersPath = "QueryManager.Managers"
+
 // FacilityBuilder creates an instance of dsquery.QueryManager and stores it in the IoC container.
 type FacilityBuilder struct {
 }
@@ -113,63 +209,160 @@ type FacilityBuilder struct {
 // BuildAndRegister implements FacilityBuilder.BuildAndRegister
 func (qmfb *FacilityBuilder) BuildAndRegister(lm *logging.ComponentLoggerManager, ca *config.Accessor, cn *ioc.ComponentContainer) error {
 
+	if _, err := AddQueryManager(ca, cn, QueryManagerComponentName, ""); err != nil {
+		return err
+	}
+
+	if err := addManagers(ca, cn); err != nil {
+		return err
+	}
+
+	if runtimectl.Enabled(ca) {
+		cn.WrapAndAddProto(reloadCommandComp, new(reloadQueriesCommand))
+	}
+
+	return nil
+}
+
+// AddQueryManager creates a dsquery.TemplatedQueryManager configured in the same way as the facility's query manager,
+// but loading its templates from the supplied location (or the configured TemplateLocation if the supplied location is empty),
+// and adds it to the IoC container with the supplied name. This allows other facilities to create query managers for
+// separate sets of templates.
+func AddQueryManager(ca *config.Accessor, cn *ioc.ComponentContainer, name string, templateLocation string) (*dsquery.TemplatedQueryManager, error) {
+	return addQueryManager(ca, cn, name, "", templateLocation)
+}
+
+// addManagers creates a query manager for each entry in QueryManager.Managers, using the entry's name as the name of
+// the component.
+func addManagers(ca *config.Accessor, cn *ioc.ComponentContainer) error {
+
+	if !ca.PathExists(managersPath) {
+		return nil
+	}
+
+	managers, err := ca.ObjectVal(managersPath)
+
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(managers))
+
+	for name := range managers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		path := managersPath + "." + name
+
+		if location, _ := ca.StringVal(path + ".TemplateLocation"); location == "" {
+			return fmt.Errorf("%s.TemplateLocation must be set", path)
+		}
+
+		if _, err := addQueryManager(ca, cn, name, path, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addQueryManager creates a query manager configured from the QueryManager configuration path. If overridePath is
+// set, any settings at that path replace the settings in QueryManager.
+func addQueryManager(ca *config.Accessor, cn *ioc.ComponentContainer, name string, overridePath string, templateLocation string) (*dsquery.TemplatedQueryManager, error) {
+
+	setting := func(key string) string {
+
+		if overridePath != "" && ca.PathExists(overridePath+"."+key) {
+			return overridePath + "." + key
+		}
+
+		return defaultPath + "." + key
+	}
+
 	queryManager := new(dsquery.TemplatedQueryManager)
-	ca.Populate("QueryManager", queryManager)
+	ca.Populate(defaultPath, queryManager)
 
-	cn.WrapAndAddProto(QueryManagerComponentName, queryManager)
+	if overridePath != "" {
+		ca.Populate(overridePath, queryManager)
+	}
 
-	if build, _ := ca.BoolVal("QueryManager.CreateDefaultValueProcessor"); build == false {
+	if templateLocation != "" {
+		queryManager.TemplateLocation = templateLocation
+	}
+
+	cn.WrapAndAddProto(name, queryManager)
+
+	if build, _ := ca.BoolVal(setting("CreateDefaultValueProcessor")); build == false {
 		//Construction of stock value processor has been disabled
 
+		decoratorName := processorDecorator
+
+		if name != QueryManagerComponentName {
+			decoratorName = name + "ParamValueProcessorDecorator"
+		}
+
 		vpd := new(valueProcessorDecorator)
 		vpd.QueryManager = queryManager
-		cn.WrapAndAddProto(processorDecorator, vpd)
+		cn.WrapAndAddProto(decoratorName, vpd)
 
-		return nil
+		return queryManager, nil
 	}
 
-	vpName, err := ca.StringVal("QueryManager.ProcessorName")
+	processorPath := setting("ProcessorName")
+	vpName, err := ca.StringVal(processorPath)
 
-	if err != nil || !qmfb.checkProcessor(vpName) {
-		return fmt.Errorf("QueryManager.ProcessorName must be set to '%s' or '%s' if you want to use a stock ValueProcessor", confValueProcess, sqlValueProcess)
+	if err != nil || !checkProcessor(vpName) {
+		return nil, fmt.Errorf("%s must be set to '%s', '%s', '%s' or '%s' if you want to use a stock ValueProcessor",
+			processorPath, confValueProcess, sqlValueProcess, jsonValueProcess, urlValueProcess)
 	}
 
 	vpName = strings.ToUpper(vpName)
 
+	var vp dsquery.ParamValueProcessor
 	var vpConfig string
 
-	if vpName == sqlValueProcess {
-		vpConfig = "QueryManager.ValueProcessors.SQL"
-	} else {
-		vpConfig = "QueryManager.ValueProcessors.Configurable"
+	switch vpName {
+	case sqlValueProcess:
+		vp = new(dsquery.SQLProcessor)
+		vpConfig = "ValueProcessors.SQL"
+	case jsonValueProcess:
+		vp = new(dsquery.JSONProcessor)
+		vpConfig = "ValueProcessors.JSON"
+	case urlValueProcess:
+		vp = new(dsquery.URLProcessor)
+		vpConfig = "ValueProcessors.URL"
+	default:
+		vp = new(dsquery.ConfigurableProcessor)
+		vpConfig = "ValueProcessors.Configurable"
 	}
 
-	if !ca.PathExists(vpConfig) {
-		return errors.New("Missing configuration path for ValueProcessor: " + vpConfig)
+	if !ca.PathExists(defaultPath + "." + vpConfig) {
+		return nil, errors.New("Missing configuration path for ValueProcessor: " + defaultPath + "." + vpConfig)
 	}
 
-	var vp dsquery.ParamValueProcessor
-
-	if vpName == confValueProcess {
-		vp = new(dsquery.ConfigurableProcessor)
-	} else if vpName == sqlValueProcess {
-
-		vp = new(dsquery.SQLProcessor)
+	ca.Populate(defaultPath+"."+vpConfig, vp)
 
+	if overridePath != "" && ca.PathExists(overridePath+"."+vpConfig) {
+		ca.Populate(overridePath+"."+vpConfig, vp)
 	}
 
-	ca.Populate(vpConfig, vp)
-
 	queryManager.ValueProcessor = vp
 
-	return nil
+	return queryManager, nil
 }
 
-func (qmfb *FacilityBuilder) checkProcessor(value string) bool {
+func checkProcessor(value string) bool {
 
-	value = strings.ToUpper(value)
+	switch strings.ToUpper(value) {
+	case confValueProcess, sqlValueProcess, jsonValueProcess, urlValueProcess:
+		return true
+	}
 
-	return value == confValueProcess || value == sqlValueProcess
+	return false
 }
 
 // FacilityName implements FacilityBuilder.FacilityName
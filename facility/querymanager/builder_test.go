@@ -169,6 +169,55 @@ func TestBuilderWithInjectedValueProcessor(t *testing.T) {
 	}
 }
 
+func TestBuilderWithAdditionalManagers(t *testing.T) {
+	lm := logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	ca, err := configAccessor(lm, test.FilePath("valid"), test.FilePath("managers.json"))
+
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	cc := ioc.NewComponentContainer(lm, ca, new(instance.System))
+
+	if err = new(FacilityBuilder).BuildAndRegister(lm, ca, cc); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if err = cc.Populate(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	tqm := cc.ComponentByName(QueryManagerComponentName).Instance.(*dsquery.TemplatedQueryManager)
+
+	if _, okay := tqm.ValueProcessor.(*dsquery.ConfigurableProcessor); !okay {
+		t.Fatalf("Unexpected type for ValueProcessor %T", tqm.ValueProcessor)
+	}
+
+	search := cc.ComponentByName("searchQueries").Instance.(*dsquery.TemplatedQueryManager)
+
+	jp, okay := search.ValueProcessor.(*dsquery.JSONProcessor)
+
+	if !okay {
+		t.Fatalf("Unexpected type for ValueProcessor %T", search.ValueProcessor)
+	}
+
+	test.ExpectBool(t, jp.NullForMissingParameter, false)
+	test.ExpectString(t, search.TemplateLocation, "testdata/valid")
+	test.ExpectString(t, search.QueryIDPrefix, "ID:")
+
+	paths := cc.ComponentByName("requestPaths").Instance.(*dsquery.TemplatedQueryManager)
+
+	if _, okay := paths.ValueProcessor.(*dsquery.URLProcessor); !okay {
+		t.Fatalf("Unexpected type for ValueProcessor %T", paths.ValueProcessor)
+	}
+
+	ca, _ = configAccessor(lm, test.FilePath("valid"), test.FilePath("managernolocation.json"))
+	cc = ioc.NewComponentContainer(lm, ca, new(instance.System))
+
+	test.ExpectNotNil(t, new(FacilityBuilder).BuildAndRegister(lm, ca, cc))
+}
+
 type nullValueProcessor struct {
 }
 
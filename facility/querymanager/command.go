@@ -0,0 +1,106 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package querymanager
+
+import (
+	"fmt"
+	"github.com/graniticio/granitic/v2/ctl"
+	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/ws"
+	"sort"
+	"strings"
+)
+
+const (
+	reloadCommandName = "reload-queries"
+	reloadSummary     = "Reloads query templates from the template files."
+	reloadUsage       = "reload-queries"
+	reloadHelp        = "Re-reads every file in the TemplateLocation of each query manager. If any file cannot be parsed, that query manager continues to use its existing templates and the error is reported."
+	reloadHelpTwo     = "The IDs of queries that were added, removed or changed are listed for each query manager."
+)
+
+// reloadQueriesCommand is a RuntimeCtl command that reloads the templates of every TemplatedQueryManager in the
+// container. It is also a decorator so that it can find query managers created by other facilities.
+type reloadQueriesCommand struct {
+	managers map[string]*dsquery.TemplatedQueryManager
+}
+
+func (c *reloadQueriesCommand) OfInterest(component *ioc.Component) bool {
+	_, found := component.Instance.(*dsquery.TemplatedQueryManager)
+
+	return found
+}
+
+func (c *reloadQueriesCommand) DecorateComponent(component *ioc.Component, container *ioc.ComponentContainer) {
+
+	if c.managers == nil {
+		c.managers = make(map[string]*dsquery.TemplatedQueryManager)
+	}
+
+	c.managers[component.Name] = component.Instance.(*dsquery.TemplatedQueryManager)
+}
+
+func (c *reloadQueriesCommand) ExecuteCommand(qualifiers []string, args map[string]string) (*ctl.CommandOutput, []*ws.CategorisedError) {
+
+	if len(qualifiers) > 0 {
+		return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("unknown qualifier %s. Usage: %s", qualifiers[0], reloadUsage))}
+	}
+
+	names := make([]string, 0, len(c.managers))
+
+	for name := range c.managers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	co := new(ctl.CommandOutput)
+	co.RenderHint = ctl.Columns
+
+	var errs []*ws.CategorisedError
+
+	for _, name := range names {
+
+		rs, err := c.managers[name].Reload()
+
+		if err != nil {
+			errs = append(errs, ctl.NewCommandUnexpectedError(fmt.Sprintf("%s: %s", name, err.Error())))
+			continue
+		}
+
+		co.OutputBody = append(co.OutputBody, []string{name, describeReload(rs)})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return co, nil
+}
+
+func (c *reloadQueriesCommand) Name() string {
+	return reloadCommandName
+}
+
+func (c *reloadQueriesCommand) Summmary() string {
+	return reloadSummary
+}
+
+func (c *reloadQueriesCommand) Usage() string {
+	return reloadUsage
+}
+
+func (c *reloadQueriesCommand) Help() []string {
+	return []string{reloadHelp, reloadHelpTwo}
+}
+
+func describeReload(rs *dsquery.ReloadSummary) string {
+
+	if rs.Unchanged() {
+		return "unchanged"
+	}
+
+	return fmt.Sprintf("added=[%s] removed=[%s] changed=[%s]", strings.Join(rs.Added, " "), strings.Join(rs.Removed, " "), strings.Join(rs.Changed, " "))
+}
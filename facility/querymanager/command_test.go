@@ -0,0 +1,44 @@
+package querymanager
+
+import (
+	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+)
+
+func TestReloadQueriesCommand(t *testing.T) {
+
+	qm := dsquery.NewTemplatedQueryManager()
+	qm.TemplateLocation = test.FilePath("valid")
+	qm.QueryIDPrefix = "ID:"
+	qm.VarMatchRegEx = "\\$\\{([^\\}]*)\\}"
+	qm.ValueProcessor = new(dsquery.SQLProcessor)
+	qm.FrameworkLogger = logging.CreateAnonymousLogger("qm", logging.Fatal)
+
+	test.ExpectNil(t, qm.StartComponent())
+
+	c := new(reloadQueriesCommand)
+
+	comp := ioc.NewComponent("testQueryManager", qm)
+
+	test.ExpectBool(t, c.OfInterest(comp), true)
+	test.ExpectBool(t, c.OfInterest(ioc.NewComponent("other", c)), false)
+
+	c.DecorateComponent(comp, nil)
+
+	co, errs := c.ExecuteCommand(nil, nil)
+	test.ExpectInt(t, len(errs), 0)
+	test.ExpectInt(t, len(co.OutputBody), 1)
+	test.ExpectString(t, co.OutputBody[0][0], "testQueryManager")
+	test.ExpectString(t, co.OutputBody[0][1], "unchanged")
+
+	_, errs = c.ExecuteCommand([]string{"all"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+
+	qm.TemplateLocation = test.FilePath("missing")
+
+	_, errs = c.ExecuteCommand(nil, nil)
+	test.ExpectInt(t, len(errs), 1)
+}
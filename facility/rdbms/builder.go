@@ -18,17 +18,27 @@ import (
 	"fmt"
 	"github.com/graniticio/granitic/v2/config"
 	"github.com/graniticio/granitic/v2/facility/querymanager"
+	"github.com/graniticio/granitic/v2/facility/runtimectl"
 	"github.com/graniticio/granitic/v2/instance"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
 	"github.com/graniticio/granitic/v2/rdbms"
 	"github.com/graniticio/granitic/v2/types"
+	"sort"
 )
 
 const rdbmsClientManagerConfigName = instance.FrameworkPrefix + "ClientManagerConfig"
 
+const defaultConfigPath = "RdbmsAccess.Default"
+
+const databasesConfigPath = "RdbmsAccess.Databases"
+
 const managerDecorator = instance.FrameworkPrefix + "DbClientManagerDecorator"
 
+const queryStatsCommandComp = instance.FrameworkPrefix + "CommandQueryStats"
+
+const dbStatsCommandComp = instance.FrameworkPrefix + "CommandDbStats"
+
 // FacilityBuilder creates an instance of rdbms.RDBMSClientManager that can be injected into your application components.
 type FacilityBuilder struct {
 	Log logging.Logger
@@ -54,31 +64,74 @@ func (rafb *FacilityBuilder) BuildAndRegister(lm *logging.ComponentLoggerManager
 	//See if client manager configs have been explicitly defined
 	rafb.findConfigurations(cn, managerConfigs)
 
-	if len(managerConfigs) == 0 {
+	explicit := len(managerConfigs) > 0
+
+	//Create configurations for any named databases
+	named, err := rafb.namedDatabases(ca, cn, pn, managerConfigs)
+
+	if err != nil {
+		return err
+	}
+
+	if !explicit {
 
 		log.LogTracef("Provider found but no explicit rdbms.ClientManagerConfig components. Creating default configuration")
 
-		//We have a provider
-		providerName := pn[0]
+		if err := rafb.defaultDatabase(ca, cn, pn, named, managerConfigs); err != nil {
+			return err
+		}
+	}
 
-		// Create config for a default ClientManager
-		mc := new(rdbms.ClientManagerConfig)
-		ca.Populate("RdbmsAccess.Default", mc)
+	return rafb.createManagers(ca, cn, managerConfigs, lm)
 
-		proto := ioc.CreateProtoComponent(mc, rdbmsClientManagerConfigName)
+}
 
-		proto.AddDependency("Provider", providerName)
-		cn.AddProto(proto)
+// defaultDatabase creates the configuration for the default ClientManager from RdbmsAccess.Default. If named databases
+// are also configured, the default ClientManager is only created if RdbmsAccess.Default.InjectFieldNames is not empty
+// and, if there is more than one provider it could use, RdbmsAccess.Default.ProviderName must be set.
+func (rafb *FacilityBuilder) defaultDatabase(ca *config.Accessor, cn *ioc.ComponentContainer, providers []string, named int, c map[string]*rdbms.ClientManagerConfig) error {
+
+	mc := new(rdbms.ClientManagerConfig)
+	ca.Populate(defaultConfigPath, mc)
+
+	if named > 0 && len(mc.InjectFieldNames) == 0 {
+		rafb.Log.LogTracef("%s.InjectFieldNames is empty - not creating a default ClientManager", defaultConfigPath)
+		return nil
+	}
+
+	providerName := mc.ProviderName
+
+	if providerName == "" {
+
+		//Use the first provider that is not providing connections to a replica
+		candidates := primaryProviders(providers, mc)
+
+		if len(candidates) == 0 {
+			return errors.New("all of the components implementing rdbms.DatabaseProvider are configured as replicas. One must provide connections to the primary database")
+		}
+
+		if named > 0 && len(candidates) > 1 {
+			return fmt.Errorf("%s.ProviderName must be set as named databases are configured and more than one component implements rdbms.DatabaseProvider "+
+				"(or set %s.InjectFieldNames to [] if you do not need a default ClientManager)", defaultConfigPath, defaultConfigPath)
+		}
 
-		managerConfigs[rdbmsClientManagerConfigName] = mc
+		providerName = candidates[0]
 
+	} else if cn.ProtoComponents()[providerName] == nil {
+		return fmt.Errorf("the default ClientManager requires a component named %s implementing rdbms.DatabaseProvider", providerName)
 	}
 
-	return rafb.createManagers(cn, managerConfigs, lm)
+	proto := ioc.CreateProtoComponent(mc, rdbmsClientManagerConfigName)
 
+	proto.AddDependency("Provider", providerName)
+	cn.AddProto(proto)
+
+	c[rdbmsClientManagerConfigName] = mc
+
+	return nil
 }
 
-func (rafb *FacilityBuilder) createManagers(cn *ioc.ComponentContainer, conf map[string]*rdbms.ClientManagerConfig, lm *logging.ComponentLoggerManager) error {
+func (rafb *FacilityBuilder) createManagers(ca *config.Accessor, cn *ioc.ComponentContainer, conf map[string]*rdbms.ClientManagerConfig, lm *logging.ComponentLoggerManager) error {
 
 	mn := types.NewEmptyUnorderedStringSet()
 
@@ -94,8 +147,14 @@ func (rafb *FacilityBuilder) createManagers(cn *ioc.ComponentContainer, conf map
 	}
 
 	fieldsToManager := make(map[string]rdbms.ClientManager)
+	managers := make(map[string]*rdbms.GraniticRdbmsClientManager)
 
 	for k, managerConf := range conf {
+
+		if err := resolveReplicas(cn, managerConf); err != nil {
+			return err
+		}
+
 		manager := new(rdbms.GraniticRdbmsClientManager)
 		manager.SharedLog = lm.CreateLogger(managerConf.ClientName)
 
@@ -105,10 +164,22 @@ func (rafb *FacilityBuilder) createManagers(cn *ioc.ComponentContainer, conf map
 
 		proto := ioc.CreateProtoComponent(manager, managerConf.ManagerName)
 
-		proto.AddDependency("QueryManager", querymanager.QueryManagerComponentName)
+		qm, err := queryManagerFor(ca, cn, managerConf)
+
+		if err != nil {
+			return err
+		}
+
+		proto.AddDependency("QueryManager", qm)
 		proto.AddDependency("Configuration", k)
 		cn.AddProto(proto)
 
+		if managerConf.DatabaseName != "" {
+			managers[managerConf.DatabaseName] = manager
+		} else {
+			managers[managerConf.ManagerName] = manager
+		}
+
 		for _, methodToInject := range managerConf.InjectFieldNames {
 			fieldsToManager[methodToInject] = manager
 		}
@@ -121,6 +192,18 @@ func (rafb *FacilityBuilder) createManagers(cn *ioc.ComponentContainer, conf map
 
 	cn.WrapAndAddProto(managerDecorator, md)
 
+	if runtimectl.Enabled(ca) {
+		c := new(queryStatsCommand)
+		c.managers = managers
+
+		cn.WrapAndAddProto(queryStatsCommandComp, c)
+
+		dc := new(dbStatsCommand)
+		dc.managers = managers
+
+		cn.WrapAndAddProto(dbStatsCommandComp, dc)
+	}
+
 	return nil
 
 }
@@ -157,6 +240,105 @@ func (rafb *FacilityBuilder) findConfigurations(cn *ioc.ComponentContainer, c ma
 
 }
 
+// namedDatabases creates a ClientManagerConfig for each database listed under RdbmsAccess.Databases. Each configuration
+// starts with the settings in RdbmsAccess.Default (apart from InjectFieldNames, ClientName, ProviderName and VerifyQueries) which are then
+// overridden by the database's own settings. Returns the number of named databases.
+func (rafb *FacilityBuilder) namedDatabases(ca *config.Accessor, cn *ioc.ComponentContainer, providers []string, c map[string]*rdbms.ClientManagerConfig) (int, error) {
+
+	if !ca.PathExists(databasesConfigPath) {
+		return 0, nil
+	}
+
+	databases, err := ca.ObjectVal(databasesConfigPath)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a JSON object: %s", databasesConfigPath, err.Error())
+	}
+
+	names := make([]string, 0, len(databases))
+
+	for name := range databases {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		mc := new(rdbms.ClientManagerConfig)
+
+		if err := ca.Populate(defaultConfigPath, mc); err != nil {
+			return 0, err
+		}
+
+		mc.InjectFieldNames = nil
+		mc.ClientName = ""
+		mc.ProviderName = ""
+
+		// Databases without their own templates share the default query manager, so would verify each other's queries
+		mc.VerifyQueries = false
+
+		if err := ca.Populate(databasesConfigPath+"."+name, mc); err != nil {
+			return 0, err
+		}
+
+		mc.DatabaseName = name
+
+		if mc.ClientName == "" {
+			mc.ClientName = name + "Client"
+		}
+
+		if mc.ManagerName == "" {
+			mc.ManagerName = mc.ClientName + "Manager"
+		}
+
+		if mc.ProviderName == "" {
+			mc.ProviderName = primaryProvider(providers, mc)
+		}
+
+		if mc.ProviderName == "" {
+			return 0, fmt.Errorf("database %s must set ProviderName as all of the components implementing rdbms.DatabaseProvider are configured as its replicas", name)
+		}
+
+		if cn.ProtoComponents()[mc.ProviderName] == nil {
+			return 0, fmt.Errorf("database %s requires a component named %s implementing rdbms.DatabaseProvider", name, mc.ProviderName)
+		}
+
+		rafb.Log.LogTracef("Database %s will use provider %s and ClientManager %s", name, mc.ProviderName, mc.ManagerName)
+
+		confName := rdbmsClientManagerConfigName + "_" + name
+
+		proto := ioc.CreateProtoComponent(mc, confName)
+		proto.AddDependency("Provider", mc.ProviderName)
+		cn.AddProto(proto)
+
+		c[confName] = mc
+	}
+
+	return len(names), nil
+}
+
+// queryManagerFor returns the name of the query manager component that should be used by the manager for the supplied
+// configuration, creating a query manager if the configuration has its own TemplateLocation.
+func queryManagerFor(ca *config.Accessor, cn *ioc.ComponentContainer, mc *rdbms.ClientManagerConfig) (string, error) {
+
+	if mc.QueryManagerName != "" {
+		return mc.QueryManagerName, nil
+	}
+
+	if mc.TemplateLocation == "" {
+		return querymanager.QueryManagerComponentName, nil
+	}
+
+	name := mc.ManagerName + "QueryManager"
+
+	if _, err := querymanager.AddQueryManager(ca, cn, name, mc.TemplateLocation); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
 func (rafb *FacilityBuilder) findProviders(cn *ioc.ComponentContainer) []string {
 
 	p := make([]string, 0)
@@ -175,6 +357,63 @@ func (rafb *FacilityBuilder) findProviders(cn *ioc.ComponentContainer) []string
 
 }
 
+// primaryProvider returns the first of the supplied provider names that is not used by one of the configuration's replicas
+func primaryProvider(providers []string, mc *rdbms.ClientManagerConfig) string {
+
+	if candidates := primaryProviders(providers, mc); len(candidates) > 0 {
+		return candidates[0]
+	}
+
+	return ""
+}
+
+// primaryProviders returns the supplied provider names that are not used by one of the configuration's replicas
+func primaryProviders(providers []string, mc *rdbms.ClientManagerConfig) []string {
+
+	replicas := types.NewEmptyUnorderedStringSet()
+
+	for _, r := range mc.Replicas {
+		replicas.Add(r.ProviderName)
+	}
+
+	candidates := make([]string, 0)
+
+	for _, p := range providers {
+		if !replicas.Contains(p) {
+			candidates = append(candidates, p)
+		}
+	}
+
+	return candidates
+}
+
+// resolveReplicas finds the DatabaseProvider component named by each of the configuration's replicas
+func resolveReplicas(cn *ioc.ComponentContainer, mc *rdbms.ClientManagerConfig) error {
+
+	for _, r := range mc.Replicas {
+
+		if r.Provider != nil {
+			continue
+		}
+
+		proto := cn.ProtoComponents()[r.ProviderName]
+
+		if proto == nil {
+			return fmt.Errorf("no component named %s is available to provide connections to a replica", r.ProviderName)
+		}
+
+		p, found := proto.Component.Instance.(rdbms.DatabaseProvider)
+
+		if !found {
+			return fmt.Errorf("component %s cannot be used as a replica as it does not implement rdbms.DatabaseProvider", r.ProviderName)
+		}
+
+		r.Provider = p
+	}
+
+	return nil
+}
+
 // FacilityName implements FacilityBuilder.FacilityName
 func (rafb *FacilityBuilder) FacilityName() string {
 	return "RdbmsAccess"
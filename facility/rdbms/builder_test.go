@@ -1,6 +1,16 @@
 package rdbms
 
-import "testing"
+import (
+	"database/sql"
+	"github.com/graniticio/granitic/v2/config"
+	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/instance"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+)
 
 func TestFacilityNaming(t *testing.T) {
 
@@ -11,3 +21,141 @@ func TestFacilityNaming(t *testing.T) {
 	}
 
 }
+
+func TestReplicaProviders(t *testing.T) {
+
+	lm := logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	replicas := []interface{}{map[string]interface{}{"ProviderName": "replicaProvider", "Weight": 2}}
+
+	ca := &config.Accessor{JSONData: map[string]interface{}{
+		"RdbmsAccess": map[string]interface{}{
+			"Default": map[string]interface{}{"ClientName": "testClient", "Replicas": replicas},
+		},
+	}, FrameworkLogger: lm.CreateLogger("ca")}
+
+	cc := ioc.NewComponentContainer(lm, ca, new(instance.System))
+
+	replica := new(nilProvider)
+
+	cc.WrapAndAddProto("replicaProvider", replica)
+	cc.WrapAndAddProto("primaryProvider", new(nilProvider))
+
+	fb := new(FacilityBuilder)
+
+	if err := fb.BuildAndRegister(lm, ca, cc); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	proto := cc.ProtoComponents()[rdbmsClientManagerConfigName]
+	mc := proto.Component.Instance.(*rdbms.ClientManagerConfig)
+
+	test.ExpectString(t, proto.Dependencies["Provider"], "primaryProvider")
+	test.ExpectInt(t, len(mc.Replicas), 1)
+	test.ExpectInt(t, mc.Replicas[0].Weight, 2)
+	test.ExpectBool(t, mc.Replicas[0].Provider == replica, true)
+
+	mc.Replicas[0].Provider = nil
+	mc.Replicas[0].ProviderName = "missingProvider"
+
+	test.ExpectNotNil(t, resolveReplicas(cc, mc))
+}
+
+func TestNamedDatabases(t *testing.T) {
+
+	lm := logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	databases := map[string]interface{}{
+		"orders":    map[string]interface{}{"ProviderName": "ordersProvider", "InjectFieldNames": []interface{}{"OrdersDb"}, "TemplateLocation": "queries/orders"},
+		"reporting": map[string]interface{}{"ProviderName": "reportingProvider", "QueryManagerName": "reportingQueries", "MaxOpenConnections": 2, "VerifyQueries": true},
+	}
+
+	ca := &config.Accessor{JSONData: map[string]interface{}{
+		"RdbmsAccess": map[string]interface{}{
+			"Default":   map[string]interface{}{"InjectFieldNames": []interface{}{"DbClientManager"}, "ClientName": "testClient", "MaxOpenConnections": 10, "ProviderName": "reportingProvider", "VerifyQueries": true},
+			"Databases": databases,
+		},
+	}, FrameworkLogger: lm.CreateLogger("ca")}
+
+	cc := ioc.NewComponentContainer(lm, ca, new(instance.System))
+
+	cc.WrapAndAddProto("ordersProvider", new(nilProvider))
+	cc.WrapAndAddProto("reportingProvider", new(nilProvider))
+
+	fb := new(FacilityBuilder)
+
+	if err := fb.BuildAndRegister(lm, ca, cc); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	protos := cc.ProtoComponents()
+
+	// The default ClientManager is still created
+	test.ExpectString(t, protos[rdbmsClientManagerConfigName].Dependencies["Provider"], "reportingProvider")
+	test.ExpectNotNil(t, protos["testClientManager"])
+
+	proto := protos[rdbmsClientManagerConfigName+"_orders"]
+	mc := proto.Component.Instance.(*rdbms.ClientManagerConfig)
+
+	test.ExpectString(t, proto.Dependencies["Provider"], "ordersProvider")
+	test.ExpectString(t, mc.DatabaseName, "orders")
+	test.ExpectString(t, mc.ManagerName, "ordersClientManager")
+	test.ExpectInt(t, mc.MaxOpenConnections, 10)
+	test.ExpectInt(t, len(mc.InjectFieldNames), 1)
+	test.ExpectString(t, mc.InjectFieldNames[0], "OrdersDb")
+	test.ExpectBool(t, mc.VerifyQueries, false)
+
+	qm := protos["ordersClientManagerQueryManager"]
+	test.ExpectNotNil(t, qm)
+	test.ExpectString(t, qm.Component.Instance.(*dsquery.TemplatedQueryManager).TemplateLocation, "queries/orders")
+	test.ExpectString(t, protos["ordersClientManager"].Dependencies["QueryManager"], "ordersClientManagerQueryManager")
+
+	proto = protos[rdbmsClientManagerConfigName+"_reporting"]
+	mc = proto.Component.Instance.(*rdbms.ClientManagerConfig)
+
+	test.ExpectString(t, proto.Dependencies["Provider"], "reportingProvider")
+	test.ExpectInt(t, mc.MaxOpenConnections, 2)
+	test.ExpectBool(t, mc.VerifyQueries, true)
+	test.ExpectInt(t, len(mc.InjectFieldNames), 0)
+	test.ExpectString(t, protos["reportingClientManager"].Dependencies["QueryManager"], "reportingQueries")
+
+	// The provider for the default ClientManager must be chosen if there is more than one
+	defaults := ca.JSONData["RdbmsAccess"].(map[string]interface{})["Default"].(map[string]interface{})
+	delete(defaults, "ProviderName")
+
+	cc = ioc.NewComponentContainer(lm, ca, new(instance.System))
+	cc.WrapAndAddProto("ordersProvider", new(nilProvider))
+	cc.WrapAndAddProto("reportingProvider", new(nilProvider))
+
+	test.ExpectNotNil(t, fb.BuildAndRegister(lm, ca, cc))
+
+	// Unless the default ClientManager is not wanted
+	defaults["InjectFieldNames"] = []interface{}{}
+
+	cc = ioc.NewComponentContainer(lm, ca, new(instance.System))
+	cc.WrapAndAddProto("ordersProvider", new(nilProvider))
+	cc.WrapAndAddProto("reportingProvider", new(nilProvider))
+
+	test.ExpectNil(t, fb.BuildAndRegister(lm, ca, cc))
+	test.ExpectBool(t, cc.ProtoComponents()[rdbmsClientManagerConfigName] == nil, true)
+
+	databases["reporting"] = map[string]interface{}{"ProviderName": "missingProvider"}
+
+	cc = ioc.NewComponentContainer(lm, ca, new(instance.System))
+	cc.WrapAndAddProto("ordersProvider", new(nilProvider))
+
+	test.ExpectNotNil(t, fb.BuildAndRegister(lm, ca, cc))
+
+	databases["reporting"] = map[string]interface{}{"ProviderName": "ordersProvider", "InjectFieldNames": []interface{}{"OrdersDb"}}
+
+	cc = ioc.NewComponentContainer(lm, ca, new(instance.System))
+	cc.WrapAndAddProto("ordersProvider", new(nilProvider))
+
+	test.ExpectNotNil(t, fb.BuildAndRegister(lm, ca, cc))
+}
+
+type nilProvider struct{}
+
+func (np *nilProvider) Database() (*sql.DB, error) {
+	return nil, nil
+}
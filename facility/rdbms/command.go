@@ -0,0 +1,191 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"fmt"
+	"github.com/graniticio/granitic/v2/ctl"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"github.com/graniticio/granitic/v2/ws"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	queryStatsCommandName = "querystats"
+	queryStatsSummary     = "Shows or resets statistics for the queries executed by each RDBMS client manager."
+	queryStatsUsage       = "querystats [reset]"
+	queryStatsHelp        = "With no qualifier, lists (for each client manager or named database) each query ID that has been executed with its execution count, error count, rows returned or affected, mean and maximum latency and a histogram of latencies."
+	queryStatsHelpTwo     = "The reset qualifier discards all statistics collected so far. Statistics are only collected by client managers with CollectQueryStatistics set to true."
+
+	resetAction = "reset"
+
+	dbStatsCommandName = "db-stats"
+	dbStatsSummary     = "Shows the health and connection pool statistics of each RDBMS client manager's database."
+	dbStatsUsage       = "db-stats"
+	dbStatsHelp        = "Lists each client manager (or named database) with the result of the most recent health check of its database, the number of open, in-use and idle connections, and the number and total duration of waits for a connection."
+	dbStatsHelpTwo     = "Health checks are made every HealthCheckIntervalSeconds. If health checks are disabled, the health of the database is shown as unknown."
+)
+
+type queryStatsCommand struct {
+	managers map[string]*rdbms.GraniticRdbmsClientManager
+}
+
+func (c *queryStatsCommand) ExecuteCommand(qualifiers []string, args map[string]string) (*ctl.CommandOutput, []*ws.CategorisedError) {
+
+	reset := false
+
+	if len(qualifiers) > 0 {
+
+		if qualifiers[0] != resetAction {
+			return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("unknown qualifier %s. Usage: %s", qualifiers[0], queryStatsUsage))}
+		}
+
+		reset = true
+	}
+
+	names := make([]string, 0, len(c.managers))
+
+	for name := range c.managers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	co := new(ctl.CommandOutput)
+	co.RenderHint = ctl.Columns
+
+	for _, name := range names {
+
+		stats := c.managers[name].QueryStatistics()
+
+		if stats == nil {
+			continue
+		}
+
+		if reset {
+			stats.Reset()
+			continue
+		}
+
+		for _, qs := range stats.Snapshot() {
+
+			id := qs.QueryID
+
+			if len(names) > 1 {
+				id = name + " " + id
+			}
+
+			co.OutputBody = append(co.OutputBody, []string{id, describeStats(qs)})
+		}
+	}
+
+	if reset {
+		co.OutputHeader = "Query statistics reset"
+	}
+
+	return co, nil
+}
+
+func (c *queryStatsCommand) Name() string {
+	return queryStatsCommandName
+}
+
+func (c *queryStatsCommand) Summmary() string {
+	return queryStatsSummary
+}
+
+func (c *queryStatsCommand) Usage() string {
+	return queryStatsUsage
+}
+
+func (c *queryStatsCommand) Help() []string {
+	return []string{queryStatsHelp, queryStatsHelpTwo}
+}
+
+func describeStats(qs rdbms.QueryStats) string {
+
+	d := fmt.Sprintf("count=%d errors=%d rows=%d mean=%v max=%v", qs.Count, qs.Errors, qs.Rows, qs.Mean().Round(time.Microsecond), qs.Max.Round(time.Microsecond))
+
+	buckets := make([]string, 0)
+
+	for i, n := range qs.Histogram {
+
+		if n == 0 {
+			continue
+		}
+
+		if i < len(rdbms.LatencyBuckets) {
+			buckets = append(buckets, fmt.Sprintf("<=%v:%d", rdbms.LatencyBuckets[i], n))
+		} else {
+			buckets = append(buckets, fmt.Sprintf(">%v:%d", rdbms.LatencyBuckets[i-1], n))
+		}
+	}
+
+	return d + " [" + strings.Join(buckets, " ") + "]"
+}
+
+type dbStatsCommand struct {
+	managers map[string]*rdbms.GraniticRdbmsClientManager
+}
+
+func (c *dbStatsCommand) ExecuteCommand(qualifiers []string, args map[string]string) (*ctl.CommandOutput, []*ws.CategorisedError) {
+
+	if len(qualifiers) > 0 {
+		return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("unknown qualifier %s. Usage: %s", qualifiers[0], dbStatsUsage))}
+	}
+
+	names := make([]string, 0, len(c.managers))
+
+	for name := range c.managers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	co := new(ctl.CommandOutput)
+	co.RenderHint = ctl.Columns
+
+	for _, name := range names {
+		co.OutputBody = append(co.OutputBody, []string{name, describePool(c.managers[name].PoolStatus())})
+	}
+
+	return co, nil
+}
+
+func (c *dbStatsCommand) Name() string {
+	return dbStatsCommandName
+}
+
+func (c *dbStatsCommand) Summmary() string {
+	return dbStatsSummary
+}
+
+func (c *dbStatsCommand) Usage() string {
+	return dbStatsUsage
+}
+
+func (c *dbStatsCommand) Help() []string {
+	return []string{dbStatsHelp, dbStatsHelpTwo}
+}
+
+func describePool(ps rdbms.PoolStatus) string {
+
+	var health string
+
+	switch {
+	case ps.Checked.IsZero():
+		health = "health=unknown"
+	case ps.Healthy:
+		health = "health=ok"
+	default:
+		health = fmt.Sprintf("health=failed (%s)", ps.Err.Error())
+	}
+
+	s := ps.Stats
+
+	return fmt.Sprintf("%s open=%d inUse=%d idle=%d maxOpen=%d waits=%d waited=%v closedIdle=%d closedLifetime=%d", health,
+		s.OpenConnections, s.InUse, s.Idle, s.MaxOpenConnections, s.WaitCount, s.WaitDuration.Round(time.Millisecond), s.MaxIdleClosed, s.MaxLifetimeClosed)
+}
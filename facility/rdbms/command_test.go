@@ -0,0 +1,63 @@
+package rdbms
+
+import (
+	"errors"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"github.com/graniticio/granitic/v2/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryStatsCommand(t *testing.T) {
+
+	m := new(rdbms.GraniticRdbmsClientManager)
+	m.Configuration = &rdbms.ClientManagerConfig{Provider: new(nilProvider), CollectQueryStatistics: true}
+
+	test.ExpectNil(t, m.StartComponent())
+
+	c := new(queryStatsCommand)
+	c.managers = map[string]*rdbms.GraniticRdbmsClientManager{"testManager": m, "unstarted": new(rdbms.GraniticRdbmsClientManager)}
+
+	co, errs := c.ExecuteCommand(nil, nil)
+
+	test.ExpectInt(t, len(errs), 0)
+	test.ExpectInt(t, len(co.OutputBody), 0)
+
+	_, errs = c.ExecuteCommand([]string{"clear"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+
+	stats := rdbms.QueryStats{QueryID: "Q", Count: 2, Histogram: []int64{1, 0, 0, 0, 0, 0, 0, 0, 1}}
+	d := describeStats(stats)
+
+	test.ExpectBool(t, strings.HasPrefix(d, "count=2 errors=0 rows=0"), true)
+	test.ExpectBool(t, strings.HasSuffix(d, "[<=1ms:1 >5s:1]"), true)
+
+	co, errs = c.ExecuteCommand([]string{"reset"}, nil)
+	test.ExpectInt(t, len(errs), 0)
+	test.ExpectString(t, co.OutputHeader, "Query statistics reset")
+}
+
+func TestDbStatsCommand(t *testing.T) {
+
+	m := new(rdbms.GraniticRdbmsClientManager)
+	m.Configuration = &rdbms.ClientManagerConfig{Provider: new(nilProvider)}
+
+	test.ExpectNil(t, m.StartComponent())
+
+	c := new(dbStatsCommand)
+	c.managers = map[string]*rdbms.GraniticRdbmsClientManager{"testManager": m}
+
+	co, errs := c.ExecuteCommand(nil, nil)
+
+	test.ExpectInt(t, len(errs), 0)
+	test.ExpectInt(t, len(co.OutputBody), 1)
+	test.ExpectString(t, co.OutputBody[0][0], "testManager")
+	test.ExpectBool(t, strings.HasPrefix(co.OutputBody[0][1], "health=unknown open=0"), true)
+
+	_, errs = c.ExecuteCommand([]string{"all"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+
+	d := describePool(rdbms.PoolStatus{Checked: time.Now(), Err: errors.New("refused")})
+	test.ExpectBool(t, strings.HasPrefix(d, "health=failed (refused)"), true)
+}
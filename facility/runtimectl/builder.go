@@ -76,6 +76,7 @@ const (
 	shutdownCommandComp        = instance.FrameworkPrefix + "CommandShutdown"
 	helpCommandComp            = instance.FrameworkPrefix + "CommandHelp"
 	componentsCommandComp      = instance.FrameworkPrefix + "CommandComponents"
+	policiesCommandComp        = instance.FrameworkPrefix + "CommandPolicies"
 	stopCommandComp            = instance.FrameworkPrefix + "CommandStop"
 	suspendCommandComp         = instance.FrameworkPrefix + "CommandSuspend"
 	resumeCommandComp          = instance.FrameworkPrefix + "CommandResume"
@@ -213,6 +214,9 @@ func (fb *FacilityBuilder) createBuiltinCommands(lm *logging.ComponentLoggerMana
 	cs := new(componentsCommand)
 	fb.addCommand(cc, componentsCommandComp, cs)
 
+	pc := new(policiesCommand)
+	fb.addCommand(cc, policiesCommandComp, pc)
+
 	stopc := newStopCommand()
 	fb.addCommand(cc, stopCommandComp, stopc)
 
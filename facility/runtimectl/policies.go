@@ -0,0 +1,110 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package runtimectl
+
+import (
+	"fmt"
+	"github.com/graniticio/granitic/v2/ctl"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/ws"
+	"github.com/graniticio/granitic/v2/ws/handler"
+	"sort"
+)
+
+const (
+	policiesCommandName = "policies"
+	policiesSummary     = "Show the access policy that applies to each web service endpoint."
+	policiesUsage       = "policies [-fw true]"
+	policiesHelp        = "Lists each web service handler with its HTTP method and path pattern, whether authentication is required and " +
+		"a description of the rules its access checker will apply."
+	policiesHelpTwo   = "Access checkers that do not implement ws.AccessPolicyDescriber (including custom access checkers) are shown by type only."
+	policiesHelpThree = "If the '-fw true' argument is supplied, the list will show built-in Granitic framework handlers instead of user-defined handlers."
+)
+
+type policiesCommand struct {
+	FrameworkLogger logging.Logger
+	container       *ioc.ComponentContainer
+}
+
+func (c *policiesCommand) Container(container *ioc.ComponentContainer) {
+	c.container = container
+}
+
+func (c *policiesCommand) ExecuteCommand(qualifiers []string, args map[string]string) (*ctl.CommandOutput, []*ws.CategorisedError) {
+
+	var frameworkOnly bool
+	var err error
+
+	if frameworkOnly, err = OperateOnFramework(args); err != nil {
+		return nil, []*ws.CategorisedError{ctl.NewCommandClientError(err.Error())}
+	}
+
+	comps := c.container.AllComponents()
+	sort.Sort(ioc.ByName{Components: comps})
+
+	rows := make([][]string, 0)
+
+	for _, comp := range comps {
+
+		h, found := comp.Instance.(*handler.WsHandler)
+
+		if !found || isFramework(comp) != frameworkOnly {
+			continue
+		}
+
+		endpoint := fmt.Sprintf("%s %s (%s)", h.HTTPMethod, h.PathPattern, comp.Name)
+
+		for i, line := range describeAccess(comp.Name, h) {
+
+			if i > 0 {
+				endpoint = ""
+			}
+
+			rows = append(rows, []string{endpoint, line})
+		}
+	}
+
+	co := new(ctl.CommandOutput)
+	co.OutputBody = rows
+	co.RenderHint = ctl.Columns
+
+	return co, nil
+}
+
+func describeAccess(name string, h *handler.WsHandler) []string {
+
+	lines := make([]string, 0)
+
+	if h.RequireAuthentication {
+		lines = append(lines, "authentication required")
+	}
+
+	switch ac := h.AccessChecker.(type) {
+	case nil:
+		lines = append(lines, "no access checker")
+	case ws.AccessPolicyDescriber:
+		lines = append(lines, ac.DescribePolicy(name, h.HTTPMethod)...)
+	default:
+		lines = append(lines, fmt.Sprintf("custom access checker (%T)", ac))
+	}
+
+	return lines
+}
+
+func (c *policiesCommand) Name() string {
+	return policiesCommandName
+}
+
+func (c *policiesCommand) Summmary() string {
+	return policiesSummary
+}
+
+func (c *policiesCommand) Usage() string {
+	return policiesUsage
+}
+
+func (c *policiesCommand) Help() []string {
+	return []string{policiesHelp, policiesHelpTwo, policiesHelpThree}
+}
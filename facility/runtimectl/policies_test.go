@@ -0,0 +1,56 @@
+package runtimectl
+
+import (
+	"github.com/graniticio/granitic/v2/config"
+	"github.com/graniticio/granitic/v2/instance"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/ws/access"
+	"github.com/graniticio/granitic/v2/ws/handler"
+	"testing"
+)
+
+func TestPoliciesCommand(t *testing.T) {
+
+	lm := logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+	ca := &config.Accessor{JSONData: make(map[string]interface{}), FrameworkLogger: lm.CreateLogger("ca")}
+
+	cc := ioc.NewComponentContainer(lm, ca, new(instance.System))
+
+	ac := new(access.Checker)
+	ac.Roles = []string{"admin"}
+	ac.StartComponent()
+
+	h := new(handler.WsHandler)
+	h.HTTPMethod = "DELETE"
+	h.PathPattern = "^/artist$"
+	h.RequireAuthentication = true
+	h.AccessChecker = ac
+
+	o := new(handler.WsHandler)
+	o.HTTPMethod = "GET"
+	o.PathPattern = "^/open$"
+
+	cc.WrapAndAddProto("deleteHandler", h)
+	cc.WrapAndAddProto("openHandler", o)
+	cc.WrapAndAddProto(instance.FrameworkPrefix+"Handler", new(handler.WsHandler))
+	cc.Populate()
+
+	pc := new(policiesCommand)
+	pc.Container(cc)
+
+	out, errs := pc.ExecuteCommand(nil, nil)
+
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+
+	test.ExpectInt(t, len(out.OutputBody), 3)
+
+	test.ExpectString(t, out.OutputBody[0][0], "DELETE ^/artist$ (deleteHandler)")
+	test.ExpectString(t, out.OutputBody[0][1], "authentication required")
+	test.ExpectString(t, out.OutputBody[1][0], "")
+	test.ExpectString(t, out.OutputBody[1][1], "role admin")
+	test.ExpectString(t, out.OutputBody[2][1], "no access checker")
+}
@@ -4,6 +4,7 @@
 package serviceerror
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/graniticio/granitic/v2/config"
@@ -11,6 +12,9 @@ import (
 	"github.com/graniticio/granitic/v2/instance"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 )
 
 const (
@@ -60,6 +64,82 @@ func (fb *FacilityBuilder) BuildAndRegister(lm *logging.ComponentLoggerManager,
 		return err
 	}
 
+	return fb.loadTranslations(manager, ca)
+}
+
+func (fb *FacilityBuilder) loadTranslations(manager *ge.ServiceErrorManager, ca *config.Accessor) error {
+
+	if ca.PathExists("ServiceErrorManager.LocaleFallbacks") {
+		if err := ca.Populate("ServiceErrorManager.LocaleFallbacks", &manager.LocaleFallbacks); err != nil {
+			return errors.New("Unable to load locale fallbacks from configuration: " + err.Error())
+		}
+	}
+
+	localisedPath, err := ca.StringVal("ServiceErrorManager.LocalisedDefinitions")
+
+	if err != nil {
+		return errors.New("Unable to load localised service error messages from configuration: " + err.Error())
+	}
+
+	if ca.PathExists(localisedPath) {
+
+		byLocale, err := ca.ObjectVal(localisedPath)
+
+		if err != nil {
+			return fmt.Errorf("couldn't load localised error messages from config path %s. Make sure %s is an object of locales to objects of codes and messages", localisedPath, localisedPath)
+		}
+
+		for locale, v := range byLocale {
+
+			messages, found := v.(map[string]interface{})
+
+			if !found {
+				return fmt.Errorf("localised error messages for locale %s at config path %s must be an object of codes and messages", locale, localisedPath)
+			}
+
+			manager.LoadLocalisedMessages(locale, messages)
+		}
+	}
+
+	dir, err := ca.StringVal("ServiceErrorManager.CatalogueDirectory")
+
+	if err != nil || dir == "" {
+		return nil
+	}
+
+	return fb.loadCatalogueFiles(manager, dir)
+}
+
+// loadCatalogueFiles loads each JSON file in the supplied directory as a set of translated messages, using the name
+// of the file (without its extension) as the locale. For example, a file called fr-CA.json will be loaded as translations
+// for the locale fr-CA
+func (fb *FacilityBuilder) loadCatalogueFiles(manager *ge.ServiceErrorManager, dir string) error {
+
+	files, err := config.FindJSONFilesInDir(dir)
+
+	if err != nil {
+		return fmt.Errorf("unable to load error message catalogues from %s: %s", dir, err.Error())
+	}
+
+	for _, f := range files {
+
+		data, err := ioutil.ReadFile(f)
+
+		if err != nil {
+			return fmt.Errorf("unable to read error message catalogue %s: %s", f, err.Error())
+		}
+
+		var messages map[string]interface{}
+
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("error message catalogue %s must be a JSON object of codes and messages: %s", f, err.Error())
+		}
+
+		locale := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+
+		manager.LoadLocalisedMessages(locale, messages)
+	}
+
 	return nil
 }
 
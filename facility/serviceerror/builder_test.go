@@ -2,6 +2,7 @@ package serviceerror
 
 import (
 	"github.com/graniticio/granitic/v2/config"
+	"github.com/graniticio/granitic/v2/grncerror"
 	"github.com/graniticio/granitic/v2/instance"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
@@ -43,6 +44,31 @@ func TestBuilderWithDefaultConfig(t *testing.T) {
 
 }
 
+func TestBuilderWithLocalisedMessages(t *testing.T) {
+	lm := logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	ca, err := configAccessor(lm, test.FilePath("localised.json"))
+
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	fb := new(FacilityBuilder)
+
+	cc := ioc.NewComponentContainer(lm, ca, new(instance.System))
+
+	if err = fb.BuildAndRegister(lm, ca, cc); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	sem := cc.ProtoComponents()[serviceErrorManagerComponentName].Component.Instance.(*grncerror.ServiceErrorManager)
+
+	test.ExpectString(t, sem.FindLocalised("INVALID_ARTIST", []string{"fr-FR"}).Message, "Impossible de créer un artiste avec les informations fournies.")
+	test.ExpectString(t, sem.FindLocalised("INVALID_ARTIST", []string{"de"}).Message, "Mit den angegebenen Informationen kann kein Künstler erstellt werden.")
+	test.ExpectString(t, sem.FindLocalised("INVALID_ARTIST", []string{"de-ch"}).Message, "Impossible de créer un artiste avec les informations fournies.")
+	test.ExpectString(t, sem.FindLocalised("INVALID_ARTIST", []string{"es"}).Message, "Cannot create an artist with the information provided.")
+}
+
 func configAccessor(lm *logging.ComponentLoggerManager, additionalFiles ...string) (*config.Accessor, error) {
 
 	jm := config.NewJSONMergerWithManagedLogging(lm, new(config.JSONContentParser))
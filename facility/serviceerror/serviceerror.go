@@ -53,6 +53,22 @@ application might reach production with error codes with missing definitions, yo
 
 In this case, ServiceErrorManager will return nil when asked for the definition of an unknown code.
 
+Localised messages
+
+Translations of messages can be defined per-locale at the config path localisedServiceErrors (overridden by setting
+ServiceErrorManager.LocalisedDefinitions) or loaded from one JSON file per locale in ServiceErrorManager.CatalogueDirectory:
+
+	{
+	  "localisedServiceErrors": {
+		"fr": {
+		  "CREATE_RECORD": "Impossible de créer un enregistrement avec les informations fournies."
+		}
+	  }
+	}
+
+The locale used for a request is taken from the caller's iam.ClientIdentity and the request's Accept-Language header. See
+the GoDoc for grncerror.ServiceErrorManager.FindLocalised and ws.LocaleFallbacks for details of how locales are matched.
+
 */
 package serviceerror
 
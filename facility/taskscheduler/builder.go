@@ -5,6 +5,7 @@ package taskscheduler
 
 import (
 	"github.com/graniticio/granitic/v2/config"
+	"github.com/graniticio/granitic/v2/facility/runtimectl"
 	"github.com/graniticio/granitic/v2/instance"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
@@ -32,6 +33,10 @@ func (fb *FacilityBuilder) BuildAndRegister(lm *logging.ComponentLoggerManager,
 
 	cn.WrapAndAddProto(TaskSchedulerComponentName, ts)
 
+	if runtimectl.Enabled(ca) {
+		cn.WrapAndAddProto(schedule.LLComponentName, schedule.NewTaskCommand(ts))
+	}
+
 	return nil
 }
 
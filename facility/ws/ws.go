@@ -98,6 +98,8 @@ func buildAndRegisterWsCommon(lm *logging.ComponentLoggerManager, ca *config.Acc
 	if err := ca.Populate("FrameworkServiceErrors", feg); err != nil {
 		return nil, err
 	}
+
+	normaliseFrameworkLocales(feg)
 	cn.WrapAndAddProto(wsFrameworkErrorGenerator, feg)
 
 	pb.FrameworkErrors = feg
@@ -106,6 +108,31 @@ func buildAndRegisterWsCommon(lm *logging.ComponentLoggerManager, ca *config.Acc
 
 }
 
+// normaliseFrameworkLocales rewrites the locale keys of any translated framework messages so that they
+// match the normalised locales generated from Accept-Language headers.
+func normaliseFrameworkLocales(feg *ws.FrameworkErrorGenerator) {
+
+	if feg.LocalisedMessages != nil {
+		m := make(map[string]map[ws.FrameworkErrorEvent]string)
+
+		for k, v := range feg.LocalisedMessages {
+			m[ws.NormaliseLocale(k)] = v
+		}
+
+		feg.LocalisedMessages = m
+	}
+
+	if feg.LocalisedHTTPMessages != nil {
+		m := make(map[string]map[string]string)
+
+		for k, v := range feg.LocalisedHTTPMessages {
+			m[ws.NormaliseLocale(k)] = v
+		}
+
+		feg.LocalisedHTTPMessages = m
+	}
+}
+
 func newWsCommon(pb *ws.ParamBinder, feg *ws.FrameworkErrorGenerator, sd *ws.GraniticHTTPStatusCodeDeterminer) *wsCommon {
 
 	wc := new(wsCommon)
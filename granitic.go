@@ -54,6 +54,7 @@ default your application will support the following arguments.
 	-d Defer any log messages emitted by the framework until your application's logging configuration has been applied
 	-m [path] Once Granitic has merged all of your configuration files together, write it to this path and exit
 	-u Generate a UUID and use it as the ID for this instance of your application (ignored if -i set)
+	-migrate [command] Run a schema migration command (status, apply, rollback or rollback:n) and exit (requires the SchemaMigration facility)
 
 If your application needs to perform command line processing and you want to prevent Granitic from attempting to parse command line arguments,
 you should start Granitic using the alternative:
@@ -77,6 +78,7 @@ import (
 
 	"github.com/cloudfactory/granitic/v2/config"
 	"github.com/cloudfactory/granitic/v2/facility"
+	"github.com/cloudfactory/granitic/v2/facility/migration"
 	"github.com/cloudfactory/granitic/v2/instance"
 	"github.com/cloudfactory/granitic/v2/ioc"
 	"github.com/cloudfactory/granitic/v2/logging"
@@ -210,6 +212,14 @@ func (i *initiator) buildContainer(ac *ioc.ProtoComponents, is *config.InitialSe
 	err = cc.Populate()
 	i.shutdownIfError(err, cc)
 
+	if is.MigrationCommand != "" {
+		//Run a schema migration command instead of starting the application
+		err = migration.RunCommand(cc, is.MigrationCommand, os.Stdout)
+		i.shutdownIfError(err, cc)
+
+		instance.ExitNormal()
+	}
+
 	//Proto components no longer needed
 	if ss.FlushMergedConfig {
 		ca.Flush()
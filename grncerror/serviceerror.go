@@ -20,6 +20,7 @@ import (
 	"github.com/graniticio/granitic/v2/logging"
 	"github.com/graniticio/granitic/v2/types"
 	"github.com/graniticio/granitic/v2/ws"
+	"sort"
 	"strings"
 )
 
@@ -42,9 +43,15 @@ type ServiceErrorManager struct {
 
 	// Determines whether or not a panic should be triggered if a method on this type is called with
 	// an error code that is not stored in the map of codes to errors.
-	PanicOnMissing   bool
+	PanicOnMissing bool
+
+	// Additional locales to try when no translation exists for a caller's preferred locale.
+	LocaleFallbacks ws.LocaleFallbacks
+
 	errorCodeSources []ErrorCodeUser
 	componentName    string
+	// Map of locale to a map of error code to translated message
+	localised map[string]map[string]string
 }
 
 // ComponentName implements ioc.ComponentNamer.ComponentName
@@ -79,6 +86,78 @@ func (sem *ServiceErrorManager) Find(code string) *ws.CategorisedError {
 
 }
 
+// FindLocalised implements ws.LocalisedServiceErrorFinder.FindLocalised. The returned CategorisedError is a copy of the
+// stored definition with its message replaced by the translation for the first matching locale (after fallbacks have been applied).
+// Behaviour for unknown codes is the same as Find.
+func (sem *ServiceErrorManager) FindLocalised(code string, locales []string) *ws.CategorisedError {
+	e := sem.Find(code)
+
+	if e == nil {
+		return nil
+	}
+
+	lc := *e
+
+	for _, l := range sem.LocaleFallbacks.Chain(locales) {
+		if m := sem.localised[l][code]; m != "" {
+			lc.Message = m
+			break
+		}
+	}
+
+	return &lc
+}
+
+// LoadLocalisedMessages stores translations of error messages for the supplied locale. The supplied definitions are
+// expected to be a map of error code to message. Translations for codes that have no corresponding error definition
+// are ignored with a warning.
+func (sem *ServiceErrorManager) LoadLocalisedMessages(locale string, definitions map[string]interface{}) {
+
+	l := sem.FrameworkLogger
+	locale = ws.NormaliseLocale(locale)
+
+	if sem.localised == nil {
+		sem.localised = make(map[string]map[string]string)
+	}
+
+	messages := sem.localised[locale]
+
+	if messages == nil {
+		messages = make(map[string]string)
+		sem.localised[locale] = messages
+	}
+
+	for code, v := range definitions {
+
+		if sem.errors[code] == nil {
+			l.LogWarnf("Locale %s: translation supplied for unknown code %s", locale, code)
+			continue
+		}
+
+		message, found := v.(string)
+
+		if !found || len(strings.TrimSpace(message)) == 0 {
+			l.LogWarnf("Locale %s: no message supplied for code %s", locale, code)
+			continue
+		}
+
+		messages[code] = message
+	}
+}
+
+// Locales returns the locales for which at least one translated message has been loaded.
+func (sem *ServiceErrorManager) Locales() []string {
+	locales := make([]string, 0, len(sem.localised))
+
+	for l := range sem.localised {
+		locales = append(locales, l)
+	}
+
+	sort.Strings(locales)
+
+	return locales
+}
+
 // LoadErrors parses error definitions from the supplied definitions which will be cast from []interface to [][]string
 // Each element of the sub-array is expected to be a []string with three elements.
 func (sem *ServiceErrorManager) LoadErrors(definitions []interface{}) {
@@ -3,6 +3,7 @@ package grncerror
 import (
 	"github.com/graniticio/granitic/v2/logging"
 	"github.com/graniticio/granitic/v2/types"
+	"github.com/graniticio/granitic/v2/ws"
 	"testing"
 )
 
@@ -102,6 +103,49 @@ func TestCodeSources(t *testing.T) {
 	}
 }
 
+func TestLocalisedLookup(t *testing.T) {
+
+	sem := createManager()
+	sem.LocaleFallbacks = map[string][]string{"fr-CA": {"de"}}
+
+	sem.LoadLocalisedMessages("fr-FR", map[string]interface{}{"INVALID_ARTIST": "Impossible de créer un artiste."})
+	sem.LoadLocalisedMessages("DE", map[string]interface{}{"INVALID_ARTIST": "Künstler kann nicht erstellt werden.", "UNKNOWN": "x"})
+
+	if ce := sem.FindLocalised("INVALID_ARTIST", []string{"es", "fr-fr"}); ce.Message != "Impossible de créer un artiste." {
+		t.Errorf("Unexpected message %s", ce.Message)
+	}
+
+	if ce := sem.FindLocalised("INVALID_ARTIST", []string{"fr-CA"}); ce.Message != "Künstler kann nicht erstellt werden." {
+		t.Errorf("Unexpected message %s", ce.Message)
+	}
+
+	if ce := sem.FindLocalised("INVALID_ARTIST", []string{"es"}); ce.Message != "Cannot create an artist with the information provided." {
+		t.Errorf("Unexpected message %s", ce.Message)
+	}
+
+	if sem.Find("INVALID_ARTIST").Message != "Cannot create an artist with the information provided." {
+		t.Errorf("Stored definition was modified")
+	}
+
+	if l := sem.Locales(); len(l) != 2 || l[0] != "de" || l[1] != "fr-fr" {
+		t.Errorf("Unexpected locales %v", l)
+	}
+
+	se := new(ws.ServiceErrors)
+	se.ErrorFinder = sem
+	se.Locales = []string{"de-at"}
+
+	se.AddPredefinedError("INVALID_ARTIST", "Name")
+
+	if e := se.Errors[0]; e.Message != "Künstler kann nicht erstellt werden." || e.Field != "Name" {
+		t.Errorf("Unexpected error %v", e)
+	}
+
+	if sem.Find("INVALID_ARTIST").Field != "" {
+		t.Errorf("Stored definition was modified")
+	}
+}
+
 func createManager() *ServiceErrorManager {
 	sem := new(ServiceErrorManager)
 
@@ -19,9 +19,14 @@ See also
 */
 package iam
 
+import "strings"
+
 const authenticated = "Authenticated"
 const anonymous = "Anonymous"
 const loggableUserID = "LoggableUserID"
+const locale = "Locale"
+const roles = "Roles"
+const scopes = "Scopes"
 
 // NewAuthenticatedIdentity creates a new ClientIdentity with the supplied log-friendly version of a user ID. The ClientIdentity will be marked
 // as Authenticated and not anonymous
@@ -92,3 +97,66 @@ func (ci ClientIdentity) LoggableUserID() string {
 
 	return a.(string)
 }
+
+// SetLocale records the locale (e.g. en-GB) that the user has chosen for messages displayed to them. This takes precedence
+// over any locales requested via the HTTP Accept-Language header.
+func (ci ClientIdentity) SetLocale(s string) {
+	ci[locale] = s
+}
+
+// Locale returns the locale that the user has chosen for messages displayed to them or an empty string if no locale
+// has been set.
+func (ci ClientIdentity) Locale() string {
+	a, found := ci[locale].(string)
+
+	if !found {
+		return ""
+	}
+
+	return a
+}
+
+// SetRoles records the roles that have been granted to the user.
+func (ci ClientIdentity) SetRoles(r []string) {
+	ci[roles] = r
+}
+
+// Roles returns the roles that have been granted to the user. Roles stored as a slice of strings, a slice of
+// interface{} (e.g. decoded from JSON) or a single space separated string are all supported.
+func (ci ClientIdentity) Roles() []string {
+	return stringList(ci[roles])
+}
+
+// SetScopes records the scopes (e.g. OAuth 2.0 scopes) that have been granted to the user.
+func (ci ClientIdentity) SetScopes(s []string) {
+	ci[scopes] = s
+}
+
+// Scopes returns the scopes that have been granted to the user. Scopes stored as a slice of strings, a slice of
+// interface{} (e.g. decoded from JSON) or a single space separated string (as in an OAuth 2.0 scope claim) are all supported.
+func (ci ClientIdentity) Scopes() []string {
+	return stringList(ci[scopes])
+}
+
+func stringList(v interface{}) []string {
+
+	switch l := v.(type) {
+	case []string:
+		return l
+	case string:
+		return strings.Fields(l)
+	case []interface{}:
+
+		s := make([]string, 0, len(l))
+
+		for _, e := range l {
+			if es, found := e.(string); found {
+				s = append(s, es)
+			}
+		}
+
+		return s
+	}
+
+	return []string{}
+}
@@ -11,3 +11,29 @@ func TestNewAuthenticatedIdentity(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestRolesAndScopes(t *testing.T) {
+
+	a := NewAuthenticatedIdentity("id")
+
+	if len(a.Roles()) != 0 || len(a.Scopes()) != 0 {
+		t.Fatalf("Expected no roles or scopes")
+	}
+
+	a.SetRoles([]string{"admin", "editor"})
+	a[scopes] = "read:artist write:artist"
+
+	if r := a.Roles(); len(r) != 2 || r[1] != "editor" {
+		t.Errorf("Unexpected roles %v", r)
+	}
+
+	if s := a.Scopes(); len(s) != 2 || s[0] != "read:artist" {
+		t.Errorf("Unexpected scopes %v", s)
+	}
+
+	a[roles] = []interface{}{"viewer", 1}
+
+	if r := a.Roles(); len(r) != 1 || r[0] != "viewer" {
+		t.Errorf("Unexpected roles %v", r)
+	}
+}
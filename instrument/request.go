@@ -16,6 +16,12 @@ allows your code to use the helper functions in this package to either gain acce
 Web service instrumentation
 
 Grantic's HTTPServer has support for instrumenting your web service requests. See the facility/httpserver package documentation for more details.
+
+Scheduled task instrumentation
+
+If your application contains a component implementing TaskInstrumentationManager, the TaskScheduler uses it to
+instrument each invocation of a scheduled task. The Instrumentor is available from the context.Context passed to tasks
+implementing schedule.ContextAwareTaskLogic.
 */
 package instrument
 
@@ -0,0 +1,17 @@
+// Copyright 2018-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package instrument
+
+import "context"
+
+// TaskInstrumentationManager is implemented by components that can instrument an invocation of a scheduled task.
+// Implementations are found automatically by the TaskScheduler facility.
+type TaskInstrumentationManager interface {
+
+	// Begin starts instrumentation of an invocation of the task with the supplied ID and returns an Instrumentor that is
+	// able to instrument sub/child events of the invocation. The returned function is called when the invocation ends.
+	// It is expected that most implementations will also store the Instrumentor in the context so it can be recovered
+	// using the function InstrumentorFromContext.
+	Begin(ctx context.Context, taskID string, invocation uint64) (context.Context, Instrumentor, func())
+}
@@ -0,0 +1,514 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/dsquery"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The maximum number of parameters in a single batch statement if the DatabaseProvider does not implement BatchDialect.
+const defaultMaxBatchParameters = 999
+
+const valuesKeyword = "VALUES"
+
+/*
+BatchDialect is an optional interface for DatabaseProvider implementations that describes how the multi-row INSERT statements
+built by ManagedClient.InsertBatchQIDOptions should be split and extended for their database. PostgreSQLBatchDialect
+and MySQLBatchDialect can be embedded in a DatabaseProvider to implement this interface.
+
+If a DatabaseProvider does not implement BatchDialect, statements are limited to 999 parameters and neither upserts nor
+the capture of generated IDs are supported.
+*/
+type BatchDialect interface {
+	// MaxBatchParameters returns the maximum number of parameters (values) the database allows in a single statement.
+	MaxBatchParameters() int
+
+	// UpsertClause returns the clause to append to an INSERT statement so that rows that conflict with an existing row on
+	// the conflict columns update the update columns of that row instead. If no update columns are supplied, conflicting rows should be ignored.
+	UpsertClause(conflict []string, update []string) (string, error)
+
+	// ReturningClause returns the clause to append to an INSERT statement so that it returns the value of the supplied
+	// generated ID column for each inserted row, or an empty string if the database does not support this.
+	ReturningClause(idColumn string) string
+}
+
+// PostgreSQLBatchDialect implements BatchDialect for PostgreSQL using ON CONFLICT and RETURNING.
+type PostgreSQLBatchDialect struct{}
+
+// MaxBatchParameters returns the maximum number of bind parameters supported by the PostgreSQL protocol.
+func (PostgreSQLBatchDialect) MaxBatchParameters() int {
+	return 65535
+}
+
+// UpsertClause renders ON CONFLICT (conflict...) DO UPDATE SET column = EXCLUDED.column... or ON CONFLICT (conflict...) DO NOTHING
+func (PostgreSQLBatchDialect) UpsertClause(conflict []string, update []string) (string, error) {
+
+	if len(conflict) == 0 {
+		return "", errors.New("PostgreSQL upserts require at least one conflict column")
+	}
+
+	c := "ON CONFLICT (" + strings.Join(conflict, ", ") + ")"
+
+	if len(update) == 0 {
+		return c + " DO NOTHING", nil
+	}
+
+	set := make([]string, len(update))
+
+	for i, col := range update {
+		set[i] = col + " = EXCLUDED." + col
+	}
+
+	return c + " DO UPDATE SET " + strings.Join(set, ", "), nil
+}
+
+// ReturningClause renders RETURNING idColumn
+func (PostgreSQLBatchDialect) ReturningClause(idColumn string) string {
+	return "RETURNING " + idColumn
+}
+
+// MySQLBatchDialect implements BatchDialect for MySQL and MariaDB using ON DUPLICATE KEY UPDATE. MySQL is unable to
+// return the IDs generated by a multi-row insert.
+type MySQLBatchDialect struct{}
+
+// MaxBatchParameters returns the maximum number of placeholders supported in a MySQL prepared statement.
+func (MySQLBatchDialect) MaxBatchParameters() int {
+	return 65535
+}
+
+// UpsertClause renders ON DUPLICATE KEY UPDATE column = VALUES(column)... MySQL applies the clause to conflicts on any
+// unique key, so the conflict columns are only used (to leave conflicting rows unchanged) when no update columns are supplied.
+func (MySQLBatchDialect) UpsertClause(conflict []string, update []string) (string, error) {
+
+	if len(update) == 0 {
+
+		if len(conflict) == 0 {
+			return "", errors.New("MySQL upserts require at least one conflict or update column")
+		}
+
+		return "ON DUPLICATE KEY UPDATE " + conflict[0] + " = " + conflict[0], nil
+	}
+
+	set := make([]string, len(update))
+
+	for i, col := range update {
+		set[i] = col + " = VALUES(" + col + ")"
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", "), nil
+}
+
+// ReturningClause returns an empty string as MySQL does not support RETURNING
+func (MySQLBatchDialect) ReturningClause(idColumn string) string {
+	return ""
+}
+
+// defaultBatchDialect is used when the DatabaseProvider does not implement BatchDialect.
+type defaultBatchDialect struct{}
+
+func (defaultBatchDialect) MaxBatchParameters() int {
+	return defaultMaxBatchParameters
+}
+
+func (defaultBatchDialect) UpsertClause(conflict []string, update []string) (string, error) {
+	return "", errors.New("upserts are not supported as the DatabaseProvider does not implement BatchDialect")
+}
+
+func (defaultBatchDialect) ReturningClause(idColumn string) string {
+	return ""
+}
+
+// BatchOptions modifies the statements built by ManagedClient.InsertBatchQIDOptions.
+type BatchOptions struct {
+	// If set, rows that conflict with an existing row on these columns update that row instead of causing an error (an 'upsert').
+	ConflictColumns []string
+
+	// The columns of an existing row that are updated when an upserted row conflicts with it. If empty, conflicting rows are ignored.
+	UpdateColumns []string
+
+	// If set, the values of this (database generated) column are returned for each inserted row in BatchResult.IDs.
+	// Requires a BatchDialect that supports RETURNING.
+	IDColumn string
+}
+
+// BatchResult summarises the outcome of a batch insert.
+type BatchResult struct {
+	// The total number of rows affected by the statements executed.
+	RowsAffected int64
+
+	// The generated IDs of the inserted (or upserted) rows, if BatchOptions.IDColumn was set.
+	IDs []int64
+}
+
+// batchStatement is a multi-row statement along with the arguments for its placeholders.
+type batchStatement struct {
+	text string
+	args []interface{}
+}
+
+// InsertBatchQID inserts each element of rows (a slice of structs, pointers to structs or map[string]interface{}) using
+// the supplied query. See InsertBatchQIDOptions.
+func (rc *ManagedClient) InsertBatchQID(qid string, rows interface{}) (*BatchResult, error) {
+	return rc.InsertBatchQIDOptions(qid, BatchOptions{}, rows)
+}
+
+/*
+InsertBatchQIDOptions inserts each element of rows (a slice of structs, pointers to structs or map[string]interface{})
+using the supplied query, which must be a single-row INSERT ... VALUES (...) statement. Parameters are taken from the fields
+of structs with the dbparam tag (see ParamsFromTags) or from the keys of maps.
+
+The query is built once for each row and the VALUES tuples combined into multi-row INSERT statements, each with no more
+parameters than the DatabaseProvider's BatchDialect allows. If more than one statement is needed and no transaction is
+open, the statements are executed in a transaction. The options can be used to turn the insert into an upsert and to
+capture the IDs generated for the new rows. Batch inserts are not supported with NAMED placeholders.
+*/
+func (rc *ManagedClient) InsertBatchQIDOptions(qid string, opts BatchOptions, rows interface{}) (br *BatchResult, err error) {
+
+	v := reflect.ValueOf(rows)
+
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("rows must be a slice or array (is %T)", rows)
+	}
+
+	br = new(BatchResult)
+
+	if v.Len() == 0 {
+		return br, nil
+	}
+
+	var observed []interface{}
+
+	if m, err := batchRowParams(v.Index(0).Interface()); err == nil {
+		observed = append(observed, m)
+	}
+
+	done := rc.observe(qid, observed)
+	defer func() { done(br.RowsAffected, err) }()
+
+	statements, err := rc.batchStatements(qid, opts, v)
+
+	if err != nil {
+		return br, err
+	}
+
+	rc.wrote()
+
+	run := func(Client) error {
+
+		*br = BatchResult{}
+
+		for _, bs := range statements {
+			if err := rc.execBatchStatement(bs, opts.IDColumn != "", br); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if len(statements) > 1 && rc.tx == nil {
+		err = rc.WithTransaction(run)
+	} else {
+		err = run(rc)
+	}
+
+	return br, err
+}
+
+func (rc *ManagedClient) execBatchStatement(bs *batchStatement, capture bool, br *BatchResult) error {
+
+	if !capture {
+
+		r, err := rc.exec(bs.text, bs.args...)
+
+		br.RowsAffected += rowsAffected(r)
+
+		return err
+	}
+
+	r, err := rc.query(bs.text, bs.args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	for r.Next() {
+
+		var id int64
+
+		if err := r.Scan(&id); err != nil {
+			return err
+		}
+
+		br.IDs = append(br.IDs, id)
+		br.RowsAffected++
+	}
+
+	return r.Err()
+}
+
+// batchStatements builds the query for each row, then combines the VALUES tuples of those queries into as few statements as the dialect allows.
+func (rc *ManagedClient) batchStatements(qid string, opts BatchOptions, rows reflect.Value) ([]*batchStatement, error) {
+
+	if rc.parameterised != nil && rc.placeholders == dsquery.NamedPlaceholders {
+		return nil, errors.New("batch inserts are not supported with NAMED placeholders")
+	}
+
+	dialect := rc.batchDialect()
+
+	suffix, err := batchSuffix(dialect, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix string
+	var statements []*batchStatement
+	var current *batchStatement
+	var tuples []string
+	var count int
+
+	flush := func() {
+		current.text = prefix + " " + strings.Join(tuples, ", ") + suffix
+		statements = append(statements, current)
+		current = nil
+		tuples = nil
+		count = 0
+	}
+
+	max := dialect.MaxBatchParameters()
+
+	for i := 0; i < rows.Len(); i++ {
+
+		pm, err := batchRowParams(rows.Index(i).Interface())
+
+		if err != nil {
+			return nil, err
+		}
+
+		eq, err := rc.buildQuery(qid, pm)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p, tuple, err := splitValues(eq.text)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to batch query %s: %s", qid, err.Error())
+		}
+
+		if i == 0 {
+			prefix = p
+		} else if p != prefix {
+			return nil, fmt.Errorf("unable to batch query %s: row %d produced a different INSERT statement to the first row", qid, i)
+		}
+
+		// Values are substituted into the text of non-parameterised queries, but are still counted towards the limit
+		size := len(eq.args)
+
+		if rc.parameterised == nil {
+			size = len(pm)
+		}
+
+		if current != nil && max > 0 && count+size > max {
+			flush()
+		}
+
+		if current == nil {
+			current = new(batchStatement)
+		}
+
+		if rc.parameterised != nil && rc.placeholders == dsquery.NumberedPlaceholders {
+			tuple = renumberPlaceholders(tuple, len(current.args))
+		}
+
+		tuples = append(tuples, tuple)
+		count += size
+		current.args = append(current.args, eq.args...)
+	}
+
+	flush()
+
+	return statements, nil
+}
+
+func (rc *ManagedClient) batchDialect() BatchDialect {
+
+	if rc.batch != nil {
+		return rc.batch
+	}
+
+	return defaultBatchDialect{}
+}
+
+// batchSuffix renders the upsert and RETURNING clauses (if required) to be appended to each statement.
+func batchSuffix(dialect BatchDialect, opts BatchOptions) (string, error) {
+
+	var suffix string
+
+	if len(opts.ConflictColumns) > 0 || len(opts.UpdateColumns) > 0 {
+
+		c, err := dialect.UpsertClause(opts.ConflictColumns, opts.UpdateColumns)
+
+		if err != nil {
+			return "", err
+		}
+
+		suffix += " " + c
+	}
+
+	if opts.IDColumn != "" {
+
+		c := dialect.ReturningClause(opts.IDColumn)
+
+		if c == "" {
+			return "", errors.New("the BatchDialect does not support returning generated IDs from a batch insert")
+		}
+
+		suffix += " " + c
+	}
+
+	return suffix, nil
+}
+
+// batchRowParams converts an element of a batch to a parameter map.
+func batchRowParams(row interface{}) (map[string]interface{}, error) {
+
+	if m, found := row.(map[string]interface{}); found {
+		return m, nil
+	}
+
+	return ParamsFromTags(row)
+}
+
+// splitValues divides a single-row INSERT statement into the text up to and including the VALUES keyword and the
+// parenthesised tuple of values that follows it.
+func splitValues(query string) (string, string, error) {
+
+	i := indexOfValues(query)
+
+	if i < 0 {
+		return "", "", errors.New("no VALUES clause found")
+	}
+
+	prefix := strings.TrimSpace(query[:i+len(valuesKeyword)])
+	tuple := strings.TrimSpace(query[i+len(valuesKeyword):])
+	tuple = strings.TrimSpace(strings.TrimSuffix(tuple, ";"))
+
+	if !strings.HasPrefix(tuple, "(") || closingParen(tuple) != len(tuple)-1 {
+		return "", "", errors.New("the VALUES clause must be a single parenthesised tuple at the end of the statement")
+	}
+
+	return prefix, tuple, nil
+}
+
+// indexOfValues returns the position of the first VALUES keyword outside of a quoted string, or -1.
+func indexOfValues(query string) int {
+
+	upper := strings.ToUpper(query)
+	quoted := false
+
+	for i := 0; i < len(upper); i++ {
+
+		switch {
+		case upper[i] == '\'':
+			quoted = !quoted
+		case quoted:
+			continue
+		case strings.HasPrefix(upper[i:], valuesKeyword) && !identChar(upper, i-1) && !identChar(upper, i+len(valuesKeyword)):
+			return i
+		}
+	}
+
+	return -1
+}
+
+// closingParen returns the position of the parenthesis that closes the one at the start of s, or -1.
+func closingParen(s string) int {
+
+	depth := 0
+	quoted := false
+
+	for i := 0; i < len(s); i++ {
+
+		switch c := s[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// renumberPlaceholders adds offset to each $n placeholder outside of a quoted string.
+func renumberPlaceholders(tuple string, offset int) string {
+
+	if offset == 0 {
+		return tuple
+	}
+
+	var b strings.Builder
+	quoted := false
+
+	for i := 0; i < len(tuple); i++ {
+
+		c := tuple[i]
+
+		if c == '\'' {
+			quoted = !quoted
+		}
+
+		if quoted || c != '$' {
+			b.WriteByte(c)
+			continue
+		}
+
+		j := i + 1
+
+		for j < len(tuple) && tuple[j] >= '0' && tuple[j] <= '9' {
+			j++
+		}
+
+		if j == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+
+		n, _ := strconv.Atoi(tuple[i+1 : j])
+		b.WriteString("$" + strconv.Itoa(n+offset))
+
+		i = j - 1
+	}
+
+	return b.String()
+}
+
+func identChar(s string, i int) bool {
+
+	if i < 0 || i >= len(s) {
+		return false
+	}
+
+	c := s[i]
+
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,182 @@
+package rdbms
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+)
+
+type batchArtist struct {
+	Name  string `dbparam:"Name"`
+	Year  int64  `dbparam:"Year"`
+	Notes string
+}
+
+func TestBatchInsert(t *testing.T) {
+
+	c := newRdbmsClient(db, new(batchQueryManager), DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+	c.stats = NewQueryStatistics()
+	drv.prepared = nil
+
+	rows := []*batchArtist{{Name: "A", Year: 1970}, {Name: "B", Year: 1980}}
+
+	r, err := c.InsertBatchQID("INSERT_ARTIST", rows)
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, int(r.RowsAffected), 1)
+
+	test.ExpectInt(t, len(drv.prepared), 1)
+	test.ExpectString(t, drv.prepared[0], "INSERT INTO artist(name, year) VALUES ('A', 1970), ('B', 1980)")
+
+	s := c.stats.Snapshot()
+	test.ExpectInt(t, len(s), 1)
+	test.ExpectString(t, s[0].QueryID, "INSERT_ARTIST")
+
+	// Maps and chunking
+	drv.prepared = nil
+	c.batch = limitedDialect{max: 4}
+
+	maps := []map[string]interface{}{
+		{"Name": "A", "Year": 1}, {"Name": "B", "Year": 2}, {"Name": "C", "Year": 3},
+	}
+
+	_, err = c.InsertBatchQID("INSERT_ARTIST", maps)
+	test.ExpectNil(t, err)
+
+	test.ExpectInt(t, len(drv.prepared), 2)
+	test.ExpectString(t, drv.prepared[0], "INSERT INTO artist(name, year) VALUES ('A', 1), ('B', 2)")
+	test.ExpectString(t, drv.prepared[1], "INSERT INTO artist(name, year) VALUES ('C', 3)")
+	test.ExpectBool(t, c.tx == nil, true)
+
+	r, err = c.InsertBatchQID("INSERT_ARTIST", []batchArtist{})
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, int(r.RowsAffected), 0)
+
+	_, err = c.InsertBatchQID("INSERT_ARTIST", batchArtist{})
+	test.ExpectNotNil(t, err)
+
+	_, err = c.InsertBatchQID("NO_VALUES", rows)
+	test.ExpectNotNil(t, err)
+
+	_, err = c.InsertBatchQID("ERROR", rows)
+	test.ExpectNotNil(t, err)
+}
+
+func TestBatchUpsert(t *testing.T) {
+
+	c := newRdbmsClient(db, new(batchQueryManager), DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+
+	rows := []batchArtist{{Name: "A", Year: 1970}}
+	upsert := BatchOptions{ConflictColumns: []string{"name"}, UpdateColumns: []string{"year"}}
+
+	_, err := c.InsertBatchQIDOptions("INSERT_ARTIST", upsert, rows)
+	test.ExpectNotNil(t, err)
+
+	c.batch = PostgreSQLBatchDialect{}
+	drv.prepared = nil
+
+	_, err = c.InsertBatchQIDOptions("INSERT_ARTIST", upsert, rows)
+	test.ExpectNil(t, err)
+	test.ExpectString(t, drv.prepared[0], "INSERT INTO artist(name, year) VALUES ('A', 1970) ON CONFLICT (name) DO UPDATE SET year = EXCLUDED.year")
+
+	drv.prepared = nil
+	drv.colNames = []string{"id"}
+	drv.rowData = [][]driver.Value{{int64(7)}}
+
+	r, err := c.InsertBatchQIDOptions("INSERT_ARTIST", BatchOptions{ConflictColumns: []string{"name"}, IDColumn: "id"}, rows)
+	test.ExpectNil(t, err)
+	test.ExpectString(t, drv.prepared[0], "INSERT INTO artist(name, year) VALUES ('A', 1970) ON CONFLICT (name) DO NOTHING RETURNING id")
+	test.ExpectInt(t, len(r.IDs), 1)
+	test.ExpectInt(t, int(r.IDs[0]), 7)
+
+	c.batch = MySQLBatchDialect{}
+	drv.prepared = nil
+
+	_, err = c.InsertBatchQIDOptions("INSERT_ARTIST", upsert, rows)
+	test.ExpectNil(t, err)
+	test.ExpectString(t, drv.prepared[0], "INSERT INTO artist(name, year) VALUES ('A', 1970) ON DUPLICATE KEY UPDATE year = VALUES(year)")
+
+	_, err = c.InsertBatchQIDOptions("INSERT_ARTIST", BatchOptions{IDColumn: "id"}, rows)
+	test.ExpectNotNil(t, err)
+}
+
+func TestParameterisedBatchInsert(t *testing.T) {
+
+	c := newRdbmsClient(db, new(batchQueryManager), DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+	c.parameterised = new(batchQueryManager)
+	c.placeholders = dsquery.NumberedPlaceholders
+	c.batch = limitedDialect{max: 4}
+	drv.prepared = nil
+
+	rows := []batchArtist{{Name: "A", Year: 1}, {Name: "B", Year: 2}, {Name: "C", Year: 3}}
+
+	_, err := c.InsertBatchQID("INSERT_ARTIST", rows)
+	test.ExpectNil(t, err)
+
+	test.ExpectInt(t, len(drv.prepared), 2)
+	test.ExpectString(t, drv.prepared[0], "INSERT INTO artist(name, year) VALUES ($1, $2), ($3, $4)")
+	test.ExpectString(t, drv.prepared[1], "INSERT INTO artist(name, year) VALUES ($1, $2)")
+	test.ExpectInt(t, len(drv.lastArgs), 2)
+	test.ExpectBool(t, drv.lastArgs[0] == "C", true)
+
+	c.placeholders = dsquery.NamedPlaceholders
+
+	_, err = c.InsertBatchQID("INSERT_ARTIST", rows)
+	test.ExpectNotNil(t, err)
+}
+
+func TestSplitValues(t *testing.T) {
+
+	p, tuple, err := splitValues("insert into t(a, values_b) values ('VALUES (', f(1));")
+	test.ExpectNil(t, err)
+	test.ExpectString(t, p, "insert into t(a, values_b) values")
+	test.ExpectString(t, tuple, "('VALUES (', f(1))")
+
+	_, _, err = splitValues("INSERT INTO t(a) VALUES (1) ON CONFLICT DO NOTHING")
+	test.ExpectNotNil(t, err)
+
+	_, _, err = splitValues("INSERT INTO t(a) SELECT a FROM u")
+	test.ExpectNotNil(t, err)
+
+	test.ExpectString(t, renumberPlaceholders("($1, '$2', $2::int)", 4), "($5, '$2', $6::int)")
+}
+
+// batchQueryManager renders a single-row insert for the Name and Year parameters.
+type batchQueryManager struct{}
+
+func (bqm *batchQueryManager) BuildQueryFromID(qid string, params map[string]interface{}) (string, error) {
+
+	switch qid {
+	case "INSERT_ARTIST":
+		return fmt.Sprintf("INSERT INTO artist(name, year) VALUES ('%v', %v)", params["Name"], params["Year"]), nil
+	case "NO_VALUES":
+		return "INSERT INTO artist(name) SELECT name FROM other", nil
+	}
+
+	return "", fmt.Errorf("unknown query %s", qid)
+}
+
+func (bqm *batchQueryManager) BuildParameterisedQueryFromID(qid string, params map[string]interface{}, style dsquery.PlaceholderStyle) (*dsquery.ParameterisedQuery, error) {
+
+	pq := new(dsquery.ParameterisedQuery)
+	pq.Query = "INSERT INTO artist(name, year) VALUES ($1, $2)"
+	pq.Values = []interface{}{params["Name"], params["Year"]}
+	pq.Names = []string{"Name", "Year"}
+
+	return pq, nil
+}
+
+func (bqm *batchQueryManager) FragmentFromID(qid string) (string, error) {
+	return qid, nil
+}
+
+type limitedDialect struct {
+	defaultBatchDialect
+	max int
+}
+
+func (ld limitedDialect) MaxBatchParameters() int {
+	return ld.max
+}
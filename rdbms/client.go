@@ -8,7 +8,9 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/instrument"
 	"github.com/graniticio/granitic/v2/logging"
+	"time"
 )
 
 // Client provides access to methods for executing SQL queries and managing transactions
@@ -21,12 +23,20 @@ type Client interface {
 	ExistingIDOrInsertParams(checkQueryID, insertQueryID string, idTarget *int64, p ...interface{}) error
 	InsertQIDParams(qid string, params ...interface{}) (sql.Result, error)
 	InsertCaptureQIDParams(qid string, target *int64, params ...interface{}) error
+	InsertBatchQID(qid string, rows interface{}) (*BatchResult, error)
+	InsertBatchQIDOptions(qid string, opts BatchOptions, rows interface{}) (*BatchResult, error)
 	SelectBindSingleQID(qid string, target interface{}) (bool, error)
 	SelectBindSingleQIDParam(qid string, name string, value interface{}, target interface{}) (bool, error)
 	SelectBindSingleQIDParams(qid string, target interface{}, params ...interface{}) (bool, error)
 	SelectBindQID(qid string, template interface{}) ([]interface{}, error)
 	SelectBindQIDParam(qid string, name string, value interface{}, template interface{}) ([]interface{}, error)
 	SelectBindQIDParams(qid string, template interface{}, params ...interface{}) ([]interface{}, error)
+	SelectBindSliceQID(qid string, target interface{}) error
+	SelectBindSliceQIDParam(qid string, name string, value interface{}, target interface{}) error
+	SelectBindSliceQIDParams(qid string, target interface{}, params ...interface{}) error
+	SelectCursorQID(qid string, target interface{}) (*RowCursor, error)
+	SelectCursorQIDParam(qid string, name string, value interface{}, target interface{}) (*RowCursor, error)
+	SelectCursorQIDParams(qid string, target interface{}, params ...interface{}) (*RowCursor, error)
 	SelectQID(qid string) (*sql.Rows, error)
 	SelectQIDParam(qid string, name string, value interface{}) (*sql.Rows, error)
 	SelectQIDParams(qid string, params ...interface{}) (*sql.Rows, error)
@@ -34,6 +44,8 @@ type Client interface {
 	UpdateQIDParam(qid string, name string, value interface{}) (sql.Result, error)
 	StartTransaction() error
 	StartTransactionWithOptions(opts *sql.TxOptions) error
+	BeginTransaction() (Transaction, error)
+	WithTransaction(fn func(Client) error) error
 	Rollback()
 	CommitTransaction() error
 	Exec(query string, args ...interface{}) (sql.Result, error)
@@ -69,6 +81,34 @@ type ManagedClient struct {
 	binder          *RowBinder
 	ctx             context.Context
 	FrameworkLogger logging.Logger
+
+	// Set when the ClientManager is configured to use parameterised queries
+	parameterised dsquery.ParameterisedQueryManager
+	placeholders  dsquery.PlaceholderStyle
+	statements    *statementCache
+
+	// Set when the ClientManager has a healthy read replica available
+	replica       *sql.DB
+	replicaSource *replica
+	replicas      *replicaSet
+
+	// Nested transaction state
+	levels       []*txLevel
+	savepointSeq int
+	retry        transactionRetry
+
+	// Set when the DatabaseProvider implements BatchDialect
+	batch BatchDialect
+
+	// Set when the ClientManager is collecting query statistics
+	stats         *QueryStatistics
+	slowThreshold time.Duration
+
+	// Set on the copy of the client passed to an InsertWithReturnedID function
+	unobserved bool
+
+	// The ClientManagerConfig.DatabaseName of the ClientManager that created this client
+	database string
 }
 
 // FindFragment returns a partial query from the underlying QueryManager. Fragments are no
@@ -140,16 +180,29 @@ func (rc *ManagedClient) InsertQIDParams(qid string, params ...interface{}) (sql
 
 // InsertCaptureQIDParams executes the supplied query with the expectation that it is an 'INSERT' query and captures
 // the new row's server generated ID in the target int64
-func (rc *ManagedClient) InsertCaptureQIDParams(qid string, target *int64, params ...interface{}) error {
+func (rc *ManagedClient) InsertCaptureQIDParams(qid string, target *int64, params ...interface{}) (err error) {
 
-	var query string
-	var err error
+	done := rc.observe(qid, params)
+	defer func() { done(1, err) }()
+
+	eq, err := rc.buildQuery(qid, params...)
 
-	if query, err = rc.buildQuery(qid, params...); err != nil {
+	if err != nil {
 		return err
 	}
 
-	return rc.lastID(query, rc, target)
+	rc.wrote()
+
+	if len(eq.args) > 0 {
+		return rc.lastID(eq.text, &boundArgsClient{ManagedClient: rc, args: eq.args}, target)
+	}
+
+	// Statements executed by the insert function are part of this call, so are run on a copy of this client that does
+	// not observe them separately
+	unobserved := *rc
+	unobserved.unobserved = true
+
+	return rc.lastID(eq.text, &unobserved, target)
 }
 
 // SelectBindSingleQID executes the supplied query with the expectation that it is a 'SELECT' query that returns 0 or 1 rows.
@@ -169,12 +222,14 @@ func (rc *ManagedClient) SelectBindSingleQIDParam(qid string, name string, value
 
 // SelectBindSingleQIDParams executes the supplied query with the expectation that it is a 'SELECT' query that returns 0 or 1 rows.
 // Results of the query are bound into the target struct. Returns false if no rows were found.
-func (rc *ManagedClient) SelectBindSingleQIDParams(qid string, target interface{}, params ...interface{}) (bool, error) {
+func (rc *ManagedClient) SelectBindSingleQIDParams(qid string, target interface{}, params ...interface{}) (found bool, err error) {
+
+	done := rc.observe(qid, params)
+	defer func() { done(rowCount(found), err) }()
 
 	var r *sql.Rows
-	var err error
 
-	if r, err = rc.SelectQIDParams(qid, params...); err != nil {
+	if r, err = rc.selectQIDParams(qid, params...); err != nil {
 		return false, err
 	}
 
@@ -201,11 +256,14 @@ func (rc *ManagedClient) SelectBindQIDParam(qid string, name string, value inter
 
 // SelectBindQIDParams executes the supplied query with the expectation that it is a 'SELECT' query. Results of the query
 // are returned in a slice of the same type as the supplied template struct.
-func (rc *ManagedClient) SelectBindQIDParams(qid string, template interface{}, params ...interface{}) ([]interface{}, error) {
+func (rc *ManagedClient) SelectBindQIDParams(qid string, template interface{}, params ...interface{}) (results []interface{}, err error) {
+
+	done := rc.observe(qid, params)
+	defer func() { done(int64(len(results)), err) }()
+
 	var r *sql.Rows
-	var err error
 
-	if r, err = rc.SelectQIDParams(qid, params...); err != nil {
+	if r, err = rc.selectQIDParams(qid, params...); err != nil {
 		return nil, err
 	}
 
@@ -214,6 +272,73 @@ func (rc *ManagedClient) SelectBindQIDParams(qid string, template interface{}, p
 	return rc.binder.BindRows(r, template)
 }
 
+// SelectBindSliceQID executes the supplied query with the expectation that it is a 'SELECT' query. Results of the query
+// are appended to the slice pointed to by target, which must be a pointer to a slice of structs or of pointers to structs.
+func (rc *ManagedClient) SelectBindSliceQID(qid string, target interface{}) error {
+	return rc.SelectBindSliceQIDParams(qid, target, rc.emptyParams)
+}
+
+// SelectBindSliceQIDParam executes the supplied query with the expectation that it is a 'SELECT' query. Results of the query
+// are appended to the slice pointed to by target, which must be a pointer to a slice of structs or of pointers to structs.
+func (rc *ManagedClient) SelectBindSliceQIDParam(qid string, name string, value interface{}, target interface{}) error {
+	p := make(map[string]interface{})
+	p[name] = value
+
+	return rc.SelectBindSliceQIDParams(qid, target, p)
+}
+
+// SelectBindSliceQIDParams executes the supplied query with the expectation that it is a 'SELECT' query. Results of the query
+// are appended to the slice pointed to by target, which must be a pointer to a slice of structs or of pointers to structs.
+func (rc *ManagedClient) SelectBindSliceQIDParams(qid string, target interface{}, params ...interface{}) (err error) {
+
+	existing := sliceLen(target)
+
+	done := rc.observe(qid, params)
+	defer func() { done(int64(sliceLen(target)-existing), err) }()
+
+	r, err := rc.selectQIDParams(qid, params...)
+
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	return rc.binder.BindSlice(r, target)
+}
+
+// SelectCursorQID executes the supplied query with the expectation that it is a 'SELECT' query and returns a RowCursor
+// that copies each row of the results in turn into target, which must be a pointer to a struct.
+func (rc *ManagedClient) SelectCursorQID(qid string, target interface{}) (*RowCursor, error) {
+	return rc.SelectCursorQIDParams(qid, target, rc.emptyParams)
+}
+
+// SelectCursorQIDParam executes the supplied query with the expectation that it is a 'SELECT' query and returns a RowCursor
+// that copies each row of the results in turn into target, which must be a pointer to a struct.
+func (rc *ManagedClient) SelectCursorQIDParam(qid string, name string, value interface{}, target interface{}) (*RowCursor, error) {
+	p := make(map[string]interface{})
+	p[name] = value
+
+	return rc.SelectCursorQIDParams(qid, target, p)
+}
+
+// SelectCursorQIDParams executes the supplied query with the expectation that it is a 'SELECT' query and returns a RowCursor
+// that copies each row of the results in turn into target, which must be a pointer to a struct. The RowCursor must be closed
+// when it is no longer needed.
+func (rc *ManagedClient) SelectCursorQIDParams(qid string, target interface{}, params ...interface{}) (c *RowCursor, err error) {
+
+	done := rc.observe(qid, params)
+	defer func() { done(0, err) }()
+
+	r, err := rc.selectQIDParams(qid, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newRowCursor(rc.context(), r, target, rc.binder)
+}
+
 // SelectQID executes the supplied query with the expectation that it is a 'SELECT' query.
 func (rc *ManagedClient) SelectQID(qid string) (*sql.Rows, error) {
 	return rc.SelectQIDParams(qid, rc.emptyParams)
@@ -227,17 +352,76 @@ func (rc *ManagedClient) SelectQIDParam(qid string, name string, value interface
 	return rc.SelectQIDParams(qid, p)
 }
 
-// SelectQIDParams executes the supplied query with the expectation that it is a 'SELECT' query.
-func (rc *ManagedClient) SelectQIDParams(qid string, params ...interface{}) (*sql.Rows, error) {
+// SelectQIDParams executes the supplied query with the expectation that it is a 'SELECT' query. If the ClientManager
+// has read replicas, no transaction is open and this client has not yet modified any data, the query is executed against a replica.
+func (rc *ManagedClient) SelectQIDParams(qid string, params ...interface{}) (r *sql.Rows, err error) {
+
+	done := rc.observe(qid, params)
+	defer func() { done(0, err) }()
+
+	return rc.selectQIDParams(qid, params...)
+}
+
+func (rc *ManagedClient) selectQIDParams(qid string, params ...interface{}) (*sql.Rows, error) {
+
+	eq, err := rc.buildQuery(qid, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rc.useReplica() {
+		return rc.selectFromReplica(eq)
+	}
+
+	stmt, release, err := rc.preparedStatement(eq, rc.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if stmt != nil {
+		return stmt.QueryContext(rc.context(), eq.args...)
+	}
+
+	return rc.query(eq.text, eq.args...)
+
+}
+
+// useReplica returns true if SELECT queries should be sent to a replica rather than the primary database.
+func (rc *ManagedClient) useReplica() bool {
+	return rc.replica != nil && rc.tx == nil
+}
+
+func (rc *ManagedClient) selectFromReplica(eq *executableQuery) (*sql.Rows, error) {
+
+	ctx := rc.context()
+
+	if rc.contextAware() {
+		defer instrument.Event(ctx, replicaEventPrefix+rc.replicaSource.name, rc.replicas.replicaStatus(rc.replicaSource))()
+	}
 
-	query, err := rc.buildQuery(qid, params...)
+	stmt, release, err := rc.preparedStatement(eq, rc.replica)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return rc.Query(query)
+	defer release()
 
+	if stmt != nil {
+		return stmt.QueryContext(ctx, eq.args...)
+	}
+
+	return rc.replica.QueryContext(ctx, eq.text, eq.args...)
+}
+
+// wrote is called before a statement that modifies data is executed. Subsequent SELECT queries are sent to the primary
+// database so that the client can read its own writes.
+func (rc *ManagedClient) wrote() {
+	rc.replica = nil
 }
 
 // UpdateQIDParams executes the supplied query with the expectation that it is an 'UPDATE' query.
@@ -257,31 +441,47 @@ func (rc *ManagedClient) UpdateQIDParam(qid string, name string, value interface
 
 }
 
-func (rc *ManagedClient) execQIDParams(qid string, params ...interface{}) (sql.Result, error) {
+func (rc *ManagedClient) execQIDParams(qid string, params ...interface{}) (r sql.Result, err error) {
 
-	var query string
-	var err error
+	done := rc.observe(qid, params)
+	defer func() { done(rowsAffected(r), err) }()
+
+	eq, err := rc.buildQuery(qid, params...)
 
-	if query, err = rc.buildQuery(qid, params...); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
-	return rc.Exec(query)
+	rc.wrote()
+
+	stmt, release, err := rc.preparedStatement(eq, rc.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	if stmt != nil {
+		return stmt.ExecContext(rc.context(), eq.args...)
+	}
+
+	return rc.exec(eq.text, eq.args...)
 }
 
-func (rc *ManagedClient) buildQuery(qid string, p ...interface{}) (string, error) {
+func (rc *ManagedClient) buildQuery(qid string, p ...interface{}) (*executableQuery, error) {
 
 	tq := rc.tempQueries[qid]
 
 	if tq != "" {
-		return tq, nil
+		return &executableQuery{qid: qid, text: tq}, nil
 	}
 
 	var pm map[string]interface{}
 	var err error
 
 	if pm, err = ParamsFromFieldsOrTags(p...); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if rc.FrameworkLogger.IsLevelEnabled(logging.Trace) {
@@ -289,79 +489,152 @@ func (rc *ManagedClient) buildQuery(qid string, p ...interface{}) (string, error
 		rc.FrameworkLogger.LogTracef("Parameters: %v", pm)
 	}
 
-	return rc.queryManager.BuildQueryFromID(qid, pm)
+	if rc.parameterised != nil {
+		return rc.buildParameterisedQuery(qid, pm)
+	}
+
+	q, err := rc.queryManager.BuildQueryFromID(qid, pm)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &executableQuery{qid: qid, text: q}, nil
 
 }
 
-// StartTransaction opens a transaction on the underlying sql.DB object and re-maps all calls to non-transactional
-// methods to their transactional equivalents.
-func (rc *ManagedClient) StartTransaction() error {
+func (rc *ManagedClient) buildParameterisedQuery(qid string, pm map[string]interface{}) (*executableQuery, error) {
 
-	if rc.tx != nil {
-		return errors.New("Transaction already open")
+	pq, err := rc.parameterised.BuildParameterisedQueryFromID(qid, pm, rc.placeholders)
+
+	if err != nil {
+		return nil, err
 	}
 
-	tx, err := rc.db.Begin()
+	eq := &executableQuery{qid: qid, text: pq.Query, args: pq.Values, cacheable: !pq.Variable}
 
-	if err != nil {
-		return err
+	if rc.placeholders == dsquery.NamedPlaceholders {
+
+		eq.args = make([]interface{}, len(pq.Values))
+
+		for i, v := range pq.Values {
+			eq.args[i] = sql.Named(pq.Names[i], v)
+		}
 	}
 
-	rc.tx = tx
-	return nil
+	return eq, nil
 }
 
-// StartTransactionWithOptions opens a transaction on the underlying sql.DB object and re-maps all calls to non-transactional
-// methods to their transactional equivalents.
-func (rc *ManagedClient) StartTransactionWithOptions(opts *sql.TxOptions) error {
+// preparedStatement returns a cached prepared statement for the query on the supplied database (bound to the open transaction, if there is one)
+// or nil if the query should not be executed as a prepared statement. The returned function must be called once the statement
+// has been executed.
+func (rc *ManagedClient) preparedStatement(eq *executableQuery, db *sql.DB) (*sql.Stmt, func(), error) {
 
-	if rc.tx != nil {
-		return errors.New("Transaction already open")
+	if rc.statements == nil || !eq.cacheable {
+		return nil, func() {}, nil
 	}
 
-	var ctx context.Context
+	ctx := rc.context()
 
-	if rc.contextAware() {
-		ctx = rc.ctx
-	} else {
-		ctx = context.Background()
+	stmt, release, err := rc.statements.find(ctx, db, eq.qid, eq.text)
+
+	if err != nil {
+		return nil, nil, err
 	}
 
-	tx, err := rc.db.BeginTx(ctx, opts)
+	if rc.tx != nil {
+		// Statements bound to a transaction are closed when the transaction is committed or rolled back
+		return rc.tx.StmtContext(ctx, stmt), release, nil
+	}
+
+	return stmt, release, nil
+}
+
+// StartTransaction opens a transaction on the underlying sql.DB object and re-maps all calls to non-transactional
+// methods to their transactional equivalents. If a transaction is already open, a nested transaction is started by
+// creating a savepoint.
+func (rc *ManagedClient) StartTransaction() error {
+	_, err := rc.begin(nil, false)
+
+	return err
+}
+
+// StartTransactionWithOptions opens a transaction on the underlying sql.DB object and re-maps all calls to non-transactional
+// methods to their transactional equivalents. If a transaction is already open, a nested transaction is started by creating
+// a savepoint and the supplied options are ignored.
+func (rc *ManagedClient) StartTransactionWithOptions(opts *sql.TxOptions) error {
+	_, err := rc.begin(opts, true)
+
+	return err
+}
+
+// BeginTransaction starts a transaction (or a nested transaction if a transaction is already open) in the same way as
+// StartTransaction, returning a handle whose Commit and Rollback methods only apply to the new transaction.
+func (rc *ManagedClient) BeginTransaction() (Transaction, error) {
+
+	level, err := rc.begin(nil, false)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	rc.tx = tx
-	return nil
-
+	return &managedTransaction{rc: rc, level: level}, nil
 }
 
-// Rollback rolls the open transaction back - does nothing if no transaction is open.
+// Rollback rolls back the innermost open transaction (for a nested transaction, to the savepoint created when it
+// was started). Does nothing if no transaction is open. Code that might be called while another transaction is open
+// should use WithTransaction or BeginTransaction rather than deferring a call to this method, as a deferred Rollback that
+// follows the commit of a nested transaction rolls back the enclosing transaction.
 func (rc *ManagedClient) Rollback() {
 
-	if rc.tx != nil {
-		rc.tx.Rollback()
+	if rc.tx == nil {
+		return
 	}
+
+	rc.rollbackLevel(rc.levels[len(rc.levels)-1])
 }
 
-// CommitTransaction commits the open transaction - does nothing if no transaction is open.
+// CommitTransaction commits the innermost open transaction (for a nested transaction, by releasing the savepoint created
+// when it was started). Returns an error if no transaction is open.
 func (rc *ManagedClient) CommitTransaction() error {
 
 	if rc.tx == nil {
 		return errors.New("No open transaction to commit")
 	}
 
-	err := rc.tx.Commit()
-	rc.tx = nil
+	return rc.commitLevel()
+}
 
-	return err
+// Exec is a pass-through to its sql.DB equivalent (or sql.Tx equivalent is a transaction is open)
+func (rc *ManagedClient) Exec(query string, args ...interface{}) (r sql.Result, err error) {
+
+	done := rc.observe(DirectQueryID, args)
+	defer func() { done(rowsAffected(r), err) }()
 
+	return rc.exec(query, args...)
 }
 
-// Exec is a pass-through to its sql.DB equivalent (or sql.Tx equivalent is a transaction is open)
-func (rc *ManagedClient) Exec(query string, args ...interface{}) (sql.Result, error) {
+// Query is a pass-through to its sql.DB equivalent (or sql.Tx equivalent is a transaction is open)
+func (rc *ManagedClient) Query(query string, args ...interface{}) (r *sql.Rows, err error) {
+
+	done := rc.observe(DirectQueryID, args)
+	defer func() { done(0, err) }()
+
+	return rc.query(query, args...)
+}
+
+// QueryRow is a pass-through to its sql.DB equivalent (or sql.Tx equivalent is a transaction is open)
+func (rc *ManagedClient) QueryRow(query string, args ...interface{}) *sql.Row {
+
+	done := rc.observe(DirectQueryID, args)
+	defer done(0, nil)
+
+	return rc.queryRow(query, args...)
+}
+
+func (rc *ManagedClient) exec(query string, args ...interface{}) (sql.Result, error) {
+
+	rc.wrote()
 
 	tx := rc.tx
 
@@ -381,8 +654,7 @@ func (rc *ManagedClient) Exec(query string, args ...interface{}) (sql.Result, er
 	return rc.db.Exec(query, args...)
 }
 
-// Query is a pass-through to its sql.DB equivalent (or sql.Tx equivalent is a transaction is open)
-func (rc *ManagedClient) Query(query string, args ...interface{}) (*sql.Rows, error) {
+func (rc *ManagedClient) query(query string, args ...interface{}) (*sql.Rows, error) {
 	tx := rc.tx
 
 	if rc.contextAware() {
@@ -401,8 +673,7 @@ func (rc *ManagedClient) Query(query string, args ...interface{}) (*sql.Rows, er
 	return rc.db.Query(query, args...)
 }
 
-// QueryRow is a pass-through to its sql.DB equivalent (or sql.Tx equivalent is a transaction is open)
-func (rc *ManagedClient) QueryRow(query string, args ...interface{}) *sql.Row {
+func (rc *ManagedClient) queryRow(query string, args ...interface{}) *sql.Row {
 	tx := rc.tx
 
 	if rc.contextAware() {
@@ -424,3 +695,12 @@ func (rc *ManagedClient) QueryRow(query string, args ...interface{}) *sql.Row {
 func (rc *ManagedClient) contextAware() bool {
 	return rc.ctx != nil
 }
+
+func (rc *ManagedClient) context() context.Context {
+
+	if rc.contextAware() {
+		return rc.ctx
+	}
+
+	return context.Background()
+}
@@ -6,12 +6,14 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"github.com/graniticio/granitic/v2/dsquery"
 	"github.com/graniticio/granitic/v2/logging"
 	"github.com/graniticio/granitic/v2/reflecttools"
 	"github.com/graniticio/granitic/v2/test"
 	"github.com/graniticio/granitic/v2/types"
 	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -305,7 +307,8 @@ func TestTransactionBehaviour(t *testing.T) {
 	test.ExpectNil(t, err)
 
 	err = c.StartTransaction()
-	test.ExpectNotNil(t, err)
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(c.levels), 2)
 
 	c = newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
 
@@ -319,10 +322,11 @@ func TestTransactionBehaviour(t *testing.T) {
 	test.ExpectNil(t, err)
 
 	err = c.StartTransaction()
-	test.ExpectNotNil(t, err)
+	test.ExpectNil(t, err)
 
 	err = c.StartTransactionWithOptions(new(sql.TxOptions))
-	test.ExpectNotNil(t, err)
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(c.levels), 3)
 
 	c = newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
 	c.ctx = context.Background()
@@ -341,7 +345,7 @@ func TestTransactionBehaviour(t *testing.T) {
 	test.ExpectNil(t, err)
 
 	err = c.StartTransaction()
-	test.ExpectNotNil(t, err)
+	test.ExpectNil(t, err)
 
 }
 
@@ -377,6 +381,113 @@ func TestBuildQuery(t *testing.T) {
 	test.ExpectString(t, q, "")
 }
 
+func TestParameterisedQueries(t *testing.T) {
+
+	m := new(GraniticRdbmsClientManager)
+	m.QueryManager = qm
+	m.SharedLog = logging.CreateAnonymousLogger("testLog", logging.Fatal)
+	m.Configuration = &ClientManagerConfig{Provider: new(testDBProvider), ParameterisedQueries: true, PlaceholderStyle: "NUMBERED"}
+
+	if err := m.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	cl, _ := m.Client()
+	c := cl.(*ManagedClient)
+
+	r, err := c.SelectQIDParam("PQ", "ID", int64(5))
+	test.ExpectNil(t, err)
+	r.Close()
+
+	test.ExpectInt(t, len(drv.lastArgs), 1)
+	test.ExpectBool(t, drv.lastArgs[0] == int64(5), true)
+
+	r, err = c.SelectQIDParam("PQ", "ID", int64(6))
+	test.ExpectNil(t, err)
+	r.Close()
+
+	test.ExpectBool(t, drv.lastArgs[0] == int64(6), true)
+	test.ExpectInt(t, m.statements.size(), 1)
+
+	_, err = c.UpdateQIDParam("VARIABLE", "ID", "x")
+	test.ExpectNil(t, err)
+
+	test.ExpectBool(t, drv.lastArgs[0] == "x", true)
+	test.ExpectInt(t, m.statements.size(), 1)
+
+	c.StartTransaction()
+
+	_, err = c.DeleteQIDParam("PQ2", "ID", int64(7))
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, drv.lastArgs[0] == int64(7), true)
+
+	test.ExpectNil(t, c.CommitTransaction())
+	test.ExpectInt(t, m.statements.size(), 2)
+
+	var id int64
+
+	err = c.InsertCaptureQIDParams("IQ", &id, map[string]interface{}{"ID": int64(8)})
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, drv.lastArgs[0] == int64(8), true)
+	test.ExpectInt(t, int(id), 1)
+
+	_, err = c.UpdateQIDParam("ERROR", "ID", "x")
+	test.ExpectNotNil(t, err)
+
+	c.placeholders = dsquery.NamedPlaceholders
+	eq, _ := c.buildQuery("PQ", map[string]interface{}{"ID": "y"})
+
+	test.ExpectString(t, eq.args[0].(sql.NamedArg).Name, "ID")
+
+	m.Stop()
+	test.ExpectInt(t, m.statements.size(), 0)
+
+	m = new(GraniticRdbmsClientManager)
+	m.QueryManager = plainQueryManager{qm}
+	m.Configuration = &ClientManagerConfig{ParameterisedQueries: true}
+
+	test.ExpectNotNil(t, m.StartComponent())
+
+	m = new(GraniticRdbmsClientManager)
+	m.QueryManager = qm
+	m.Configuration = &ClientManagerConfig{ParameterisedQueries: true, PlaceholderStyle: "AT"}
+
+	test.ExpectNotNil(t, m.StartComponent())
+}
+
+func TestParameterisedBlockQueriesNotCached(t *testing.T) {
+
+	m := new(GraniticRdbmsClientManager)
+	m.QueryManager = templatedQueryManager(t)
+	m.SharedLog = logging.CreateAnonymousLogger("testLog", logging.Fatal)
+	m.Configuration = &ClientManagerConfig{Provider: new(testDBProvider), ParameterisedQueries: true, PlaceholderStyle: "NUMBERED"}
+
+	if err := m.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	defer m.Stop()
+
+	cl, _ := m.Client()
+	c := cl.(*ManagedClient)
+
+	// Alternate between two combinations of [[if]] blocks in the same query
+	for i := 0; i < 4; i++ {
+
+		p := map[string]interface{}{}
+
+		if i%2 == 0 {
+			p["Name"] = "Can"
+		}
+
+		r, err := c.SelectQIDParams("ARTIST_SEARCH", p)
+		test.ExpectNil(t, err)
+		r.Close()
+
+		test.ExpectInt(t, m.statements.size(), 0)
+	}
+}
+
 func TestIllegalResultContents(t *testing.T) {
 
 	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
@@ -711,6 +822,17 @@ type testParam struct {
 	IParam  int `dbparam:"IOV"`
 }
 
+type testDBProvider struct{}
+
+func (tp *testDBProvider) Database() (*sql.DB, error) {
+	return db, nil
+}
+
+// plainQueryManager hides the parameterised query support of the wrapped QueryManager
+type plainQueryManager struct {
+	dsquery.QueryManager
+}
+
 type testQueryManagerProxy struct {
 	lastParams        map[string]interface{}
 	lastQueryReturned string
@@ -735,6 +857,22 @@ func (tqm *testQueryManagerProxy) BuildQueryFromID(qid string, params map[string
 
 }
 
+func (tqm *testQueryManagerProxy) BuildParameterisedQueryFromID(qid string, params map[string]interface{}, style dsquery.PlaceholderStyle) (*dsquery.ParameterisedQuery, error) {
+	tqm.lastParams = params
+
+	if qid == "ERROR" {
+		return nil, errors.New("Forced error")
+	}
+
+	pq := new(dsquery.ParameterisedQuery)
+	pq.Query = qid
+	pq.Values = []interface{}{params["ID"]}
+	pq.Names = []string{"ID"}
+	pq.Variable = qid == "VARIABLE"
+
+	return pq, nil
+}
+
 func (tqm *testQueryManagerProxy) FragmentFromID(qid string) (string, error) {
 
 	tqm.lastQueryReturned = qid
@@ -759,8 +897,19 @@ type mockDriver struct {
 	colNames   []string
 	rowData    [][]driver.Value
 	forceError bool
+	lastArgs   []driver.Value
+	prepared   []string
+
+	// Queries containing this text cannot be prepared
+	rejectPrepare string
+
+	// If set, preparing a query containing slowPrepareText signals slowPrepareStarted then waits until slowPrepare is closed
+	slowPrepare        chan struct{}
+	slowPrepareStarted chan struct{}
 }
 
+const slowPrepareText = "SLOW"
+
 func (d *mockDriver) consumed() {
 	d.colNames = nil
 	d.rowData = nil
@@ -783,6 +932,18 @@ type mockConn struct {
 }
 
 func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+
+	if r := c.d.rejectPrepare; r != "" && strings.Contains(query, r) {
+		return nil, fmt.Errorf("unknown column %s", r)
+	}
+
+	if c.d.slowPrepare != nil && strings.Contains(query, slowPrepareText) {
+		c.d.slowPrepareStarted <- struct{}{}
+		<-c.d.slowPrepare
+	}
+
+	c.d.prepared = append(c.d.prepared, query)
+
 	return newMockStmt(c.d), nil
 }
 
@@ -810,11 +971,13 @@ func (s *mockStmt) Close() error {
 }
 
 func (s *mockStmt) NumInput() int {
-	return 0
+	return -1
 }
 
 func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
 
+	s.d.lastArgs = args
+
 	if s.d.forceError {
 		drv.consumed()
 		return nil, errors.New("Forced error")
@@ -831,6 +994,8 @@ func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
 
 func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
 
+	s.d.lastArgs = args
+
 	if s.d.forceError {
 		drv.consumed()
 		return nil, errors.New("Forced error")
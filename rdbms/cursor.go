@@ -0,0 +1,116 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	rt "github.com/graniticio/granitic/v2/reflecttools"
+)
+
+/*
+RowCursor iterates over the results of a query one row at a time, copying each row into the same target struct. This
+allows large result sets to be processed without holding every row in memory. Columns are mapped to the fields of the
+target using the same rules as RowBinder.BindRows.
+
+A RowCursor is used like:
+
+	artist := new(Artist)
+
+	cursor, err := rc.SelectCursorQIDParams("ALL_ARTISTS", artist, params)
+
+	if err != nil {
+	  return err
+	}
+
+	defer cursor.Close()
+
+	for cursor.Next() {
+	  // artist now contains the data from the current row
+	}
+
+	return cursor.Err()
+
+If the context of the client that created the RowCursor is cancelled, Next returns false and Err returns the context's error.
+*/
+type RowCursor struct {
+	rows     *sql.Rows
+	target   interface{}
+	scanners []interface{}
+	binder   *RowBinder
+	ctx      context.Context
+	err      error
+}
+
+// NewRowCursor creates a RowCursor that copies each of the supplied rows into target, which must be a pointer to a struct.
+// Iteration stops if the supplied context is cancelled. This is intended for alternative implementations of Client,
+// such as test doubles; application code should obtain a RowCursor from a Client's SelectCursor methods.
+func NewRowCursor(ctx context.Context, r *sql.Rows, target interface{}) (*RowCursor, error) {
+	return newRowCursor(ctx, r, target, new(RowBinder))
+}
+
+func newRowCursor(ctx context.Context, r *sql.Rows, target interface{}, binder *RowBinder) (*RowCursor, error) {
+
+	if !rt.IsPointerToStruct(target) {
+		r.Close()
+		return nil, errors.New("target must be a pointer to a struct")
+	}
+
+	scanners, err := binder.columnScanners(r, target)
+
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	c := new(RowCursor)
+	c.rows = r
+	c.target = target
+	c.scanners = scanners
+	c.binder = binder
+	c.ctx = ctx
+
+	return c, nil
+}
+
+// Next copies the next row of results into the target, returning false if there are no more rows or if an error
+// occurred (check Err to distinguish between the two cases). The underlying sql.Rows is closed when Next returns false.
+func (c *RowCursor) Next() bool {
+
+	if c.err != nil {
+		return false
+	}
+
+	if c.err = c.ctx.Err(); c.err != nil {
+		c.rows.Close()
+		return false
+	}
+
+	if !c.rows.Next() {
+		c.err = c.rows.Err()
+		return false
+	}
+
+	if c.err = c.rows.Scan(c.scanners...); c.err == nil {
+		c.err = c.binder.populate(c.target, c.scanners)
+	}
+
+	if c.err != nil {
+		c.rows.Close()
+		return false
+	}
+
+	return true
+}
+
+// Err returns the error, if any, that was encountered while iterating over the results.
+func (c *RowCursor) Err() error {
+	return c.err
+}
+
+// Close releases the underlying sql.Rows. It is safe to call Close more than once and after Next has returned false.
+func (c *RowCursor) Close() error {
+	return c.rows.Close()
+}
@@ -0,0 +1,99 @@
+package rdbms
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+)
+
+func TestRowCursor(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+
+	drv.colNames = []string{"StrResult", "Int64Result"}
+	drv.rowData = [][]driver.Value{{"a", int64(1)}, {nil, int64(2)}}
+
+	tt := new(testTarget)
+
+	cur, err := c.SelectCursorQIDParam("CURSOR", "p1", "v1", tt)
+	test.ExpectNil(t, err)
+
+	test.ExpectBool(t, cur.Next(), true)
+	test.ExpectString(t, tt.StrResult, "a")
+	test.ExpectInt(t, int(tt.Int64Result), 1)
+
+	test.ExpectBool(t, cur.Next(), true)
+	test.ExpectString(t, tt.StrResult, "")
+	test.ExpectInt(t, int(tt.Int64Result), 2)
+
+	test.ExpectBool(t, cur.Next(), false)
+	test.ExpectNil(t, cur.Err())
+	test.ExpectNil(t, cur.Close())
+
+	drv.colNames = []string{"Unknown"}
+	drv.rowData = [][]driver.Value{{"a"}}
+
+	_, err = c.SelectCursorQID("CURSOR", tt)
+	test.ExpectNotNil(t, err)
+
+	_, err = c.SelectCursorQID("CURSOR", "notAStruct")
+	test.ExpectNotNil(t, err)
+
+	drv.forceError = true
+	_, err = c.SelectCursorQID("CURSOR", tt)
+	test.ExpectNotNil(t, err)
+
+	// Cancelling the client's context stops iteration
+	ctx, cancel := context.WithCancel(context.Background())
+	c.ctx = ctx
+
+	drv.colNames = []string{"Int64Result"}
+	drv.rowData = [][]driver.Value{{int64(1)}, {int64(2)}}
+
+	cur, err = c.SelectCursorQID("CURSOR", tt)
+	test.ExpectNil(t, err)
+
+	test.ExpectBool(t, cur.Next(), true)
+
+	cancel()
+
+	test.ExpectBool(t, cur.Next(), false)
+	test.ExpectBool(t, cur.Err() == context.Canceled, true)
+	cur.Close()
+}
+
+func TestSelectBindSlice(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+
+	drv.colNames = []string{"Int64Result", "ColumnAlias"}
+	drv.rowData = [][]driver.Value{{int64(45), "x"}, {int64(32), "y"}}
+
+	var values []testTarget
+
+	test.ExpectNil(t, c.SelectBindSliceQID("SLICE", &values))
+	test.ExpectInt(t, len(values), 2)
+	test.ExpectInt(t, int(values[1].Int64Result), 32)
+	test.ExpectString(t, values[1].Aliased, "y")
+
+	drv.colNames = []string{"Int64Result"}
+	drv.rowData = [][]driver.Value{{int64(45)}, {int64(32)}}
+
+	var pointers []*testTarget
+
+	test.ExpectNil(t, c.SelectBindSliceQIDParams("SLICE", &pointers, map[string]interface{}{"p1": "v1"}))
+	test.ExpectInt(t, len(pointers), 2)
+	test.ExpectInt(t, int(pointers[0].Int64Result), 45)
+	test.ExpectInt(t, int(pointers[1].Int64Result), 32)
+
+	drv.colNames = []string{"Int64Result"}
+	drv.rowData = [][]driver.Value{{int64(1)}}
+
+	test.ExpectNotNil(t, c.SelectBindSliceQIDParam("SLICE", "p1", "v1", new(testTarget)))
+
+	var ints []int64
+
+	test.ExpectNotNil(t, c.SelectBindSliceQID("SLICE", &ints))
+}
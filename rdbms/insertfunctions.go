@@ -6,7 +6,9 @@ package rdbms
 import "database/sql"
 
 // InsertWithReturnedID is a function able execute an insert statement and return an RDBMS generated ID as an int64.
-// If your implementation requires access to the context, it is available on the *ManagedClient
+// If your implementation requires access to the context, it is available on the *ManagedClient. When the ClientManager
+// is using parameterised queries and the statement has placeholders, the Client is instead a wrapper that supplies the
+// placeholder values to Exec, Query and QueryRow.
 type InsertWithReturnedID func(string, Client, *int64) error
 
 // DefaultInsertWithReturnedID is an implementation of InsertWithReturnedID that will work with any Go database driver that implements LastInsertId
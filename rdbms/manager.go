@@ -109,7 +109,7 @@ for the methods available on ManagedClient is:
 Where
 
 	SQLVerb           Is Select, Delete, Update or Insert
-	BindingType       Is optional and can be Bind or BindSingle
+	BindingType       Is optional and can be Bind, BindSingle, BindSlice or Cursor
 	ParameterSource   Is optional and can be either Param or Params
 
 QID
@@ -144,6 +144,23 @@ If the method contains the word Bind (not BindSingle), you will supply an exampl
 	  return id.artistResults(r), nil
 	}
 
+Methods containing BindSlice instead append each row to a slice you supply (a pointer to a slice of structs or of pointers to structs),
+and methods containing Cursor return a RowCursor that copies one row at a time into a single struct, which is more
+efficient for large result sets. See the GoDoc for RowCursor for usage.
+
+Granitic supports versions of Go without type parameters, so there are no generic helpers returning []T or *T
+directly. BindSlice methods fill a []T (or []*T) you declare and a single row is bound into a *T you supply with
+the BindSingle methods.
+
+
+Batch inserts
+
+InsertBatchQID and InsertBatchQIDOptions accept a QID for a single-row INSERT ... VALUES (...) template and a slice of
+structs (whose fields are mapped with the dbparam tag) or maps. The VALUES tuples built for each element are combined
+into multi-row INSERT statements, split so that no statement exceeds the parameter limit of the DatabaseProvider's BatchDialect.
+BatchOptions can be used to perform an upsert (ON CONFLICT or ON DUPLICATE KEY UPDATE) and, where the dialect supports
+RETURNING, to capture the IDs generated for each row.
+
 
 Transactions
 
@@ -159,6 +176,68 @@ and end your method with:
 
 The deferred Rollback call will do nothing if the transaction has previously been committed.
 
+If StartTransaction is called while a transaction is already open, a nested transaction is started using a savepoint.
+CommitTransaction and Rollback always apply to the innermost open transaction, so once a nested transaction has been
+committed a deferred Rollback rolls back the enclosing transaction. Code that might be called from code that has already
+started a transaction should use BeginTransaction, which returns a handle whose methods only apply to the new transaction:
+
+	tx, err := db.BeginTransaction()
+	defer tx.Rollback()
+
+	...
+
+	return tx.Commit()
+
+Alternatively, pass a function to WithTransaction:
+
+	err := db.WithTransaction(func(tc rdbms.Client) error {
+	  return tc.UpdateQIDParam("ARTIST_RENAME", "Name", name)
+	})
+
+The transaction is committed if the function returns nil and rolled back if it returns an error or panics. If your
+DatabaseProvider implements TransactionDialect, transactions that fail because of a deadlock or serialisation failure
+are retried (see ClientManagerConfig.TransactionRetries).
+
+
+Parameterised queries
+
+If ClientManagerConfig.ParameterisedQueries is set to true, the variables in query templates are rendered as driver
+placeholders (in the syntax set by ClientManagerConfig.PlaceholderStyle) and their values passed to the driver as arguments, rather
+than being substituted into the text of the query. The statement for each QID is prepared when it is first used and cached by the
+ClientManager. The QID methods on ManagedClient are used in exactly the same way in either mode.
+
+
+Read replicas
+
+ClientManagerConfig.Replicas may list DatabaseProviders for read-only replicas of the primary database. SELECT queries executed
+via QID methods are then sent to a healthy replica (chosen at random according to each replica's weight) unless a transaction
+is open, the client has already modified data or the client was created with a context returned by ForcePrimary. All other
+queries are sent to the primary database. Replicas that fail a health check, or whose lag (see ReplicaLagReporter) exceeds
+ClientManagerConfig.ReplicaMaxLagSeconds, receive no queries until they recover.
+
+
+Connection pools
+
+The pool settings in ClientManagerConfig (MaxOpenConnections, MaxIdleConnections, ConnectionMaxLifetimeSeconds and
+ConnectionMaxIdleTimeSeconds) are applied to the sql.DB returned by the DatabaseProvider. The database is pinged every
+ClientManagerConfig.HealthCheckIntervalSeconds and the result, along with the pool's sql.DBStats, is available from
+GraniticRdbmsClientManager.PoolStatus and passed to any instrument.Instrumentor as an event with the ID PoolEventID.
+
+
+Verifying queries
+
+If ClientManagerConfig.VerifyQueries is set, every statement held by the QueryManager is prepared against the database
+by BlockAccess, preventing the application from becoming accessible if the database rejects any of them. As every query
+held by the QueryManager is verified, VerifyQueries should only be set for a database whose QueryManager does not hold
+queries for other databases. Named databases do not inherit VerifyQueries from RdbmsAccess.Default.
+
+
+Query statistics
+
+Each query executed by a ManagedClient is timed and reported to any instrument.Instrumentor in the client's context as an
+event with the ID QueryEventID. If ClientManagerConfig.CollectQueryStatistics is set, per-query ID statistics are available
+from GraniticRdbmsClientManager.QueryStatistics and queries slower than ClientManagerConfig.SlowQueryThresholdMilliseconds are logged.
+
 
 Direct access to Go DB methods
 
@@ -168,17 +247,13 @@ with Granitic's transaction pattern as described above.
 
 Multiple databases
 
-This iteration of Granitic is optimised for the most common use-case for RDBMS access, where a particular Granitic
-application will access a single logical database. It is fully acknowledged that there are many situations where an application
-needs to access mutiple logical databases.
-
-Facility support for that use-case will be added in later versions of Granitic, but for now you have two options:
+Applications that access more than one logical database can list them under RdbmsAccess.Databases in configuration. A
+GraniticRdbmsClientManager is created for each database, with its own DatabaseProvider, QueryManager (or directory of
+templates) and InjectFieldNames. The database's name is set in ClientManagerConfig.DatabaseName and is used to distinguish
+the database in log messages, instrumentation events and RuntimeCtl output. The default ClientManager (configured by
+RdbmsAccess.Default) is still created alongside the named databases unless RdbmsAccess.Default.InjectFieldNames is empty.
 
-Option 1: use this facility to provide support for your application's 'main' database and manually add components of type rdbms.DefaultRDBMSClientManager to
-your component definition file to support your other database.
-
-Option 2: disable this facility and manually add components of type rdbms.GraniticRdbmsClientManager to
-your component definition file to support all of your databases.
+Alternatively, add components of type rdbms.ClientManagerConfig to your component definition file, one per database.
 */
 package rdbms
 
@@ -186,9 +261,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/instrument"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
+	"time"
 )
 
 /*
@@ -234,6 +312,22 @@ type ProviderComponentReceiver interface {
 type ClientManagerConfig struct {
 	Provider DatabaseProvider
 
+	// The name of the database (from RdbmsAccess.Databases) this configuration was created for. Empty for the default
+	// database and for explicitly declared ClientManagerConfig components unless set. Used to distinguish databases in
+	// log messages, instrumentation and RuntimeCtl output.
+	DatabaseName string
+
+	// The name of the component implementing DatabaseProvider that should be injected into Provider. Only used by the
+	// RdbmsAccess facility when creating configurations for named databases and the default database.
+	ProviderName string
+
+	// The name of the dsquery.QueryManager component used by the ClientManager. If not set, the QueryManager facility's
+	// query manager is used (or one is created if TemplateLocation is set).
+	QueryManagerName string
+
+	// A directory of query templates to load into a query manager dedicated to this database. Ignored if QueryManagerName is set.
+	TemplateLocation string
+
 	// The names of fields on a component that should have a reference to this component's associated ClientManager
 	// automatically injected into them.
 	InjectFieldNames []string
@@ -245,6 +339,70 @@ type ClientManagerConfig struct {
 
 	// Name that will be given to the ClientManager component that will be created. If not set, it will be set the value of ClientName + "Manager"
 	ManagerName string
+
+	// If true, variables in query templates are passed to the database driver as arguments via placeholders (and the
+	// resulting statements prepared and cached per query ID) rather than being substituted into the text of the query.
+	// Requires a QueryManager that implements dsquery.ParameterisedQueryManager.
+	ParameterisedQueries bool
+
+	// The placeholder syntax expected by the database driver when ParameterisedQueries is true: QUESTION (?), NUMBERED ($1, $2...)
+	// or NAMED (:name). Defaults to QUESTION.
+	PlaceholderStyle string
+
+	// Read-only replicas of the database provided by Provider. If set, SELECT queries executed via QID methods outside of
+	// a transaction are routed to a healthy replica.
+	Replicas []*ReplicaConfig
+
+	// How often the health (and lag, if supported by the replica's provider) of each replica is checked. Defaults to 10.
+	ReplicaCheckIntervalSeconds int
+
+	// Replicas lagging the primary by more than this number of seconds stop receiving reads until they catch up. Zero
+	// means lag is not considered when deciding if a replica is healthy.
+	ReplicaMaxLagSeconds int
+
+	// The number of times ManagedClient.WithTransaction will retry a transaction that fails with an error that the
+	// Provider (if it implements TransactionDialect) identifies as a serialisation failure or deadlock.
+	TransactionRetries int
+
+	// The delay before a failed transaction is first retried. The delay doubles (with some random jitter) for each subsequent retry.
+	TransactionRetryBackoffMilliseconds int
+
+	// If true, the ClientManager records the number of executions, errors, rows and a latency histogram for each query ID
+	// (see QueryStatistics).
+	CollectQueryStatistics bool
+
+	// Queries taking at least this many milliseconds are logged (with the types, but not the values, of their parameters)
+	// at WARN level. Zero disables slow query logging.
+	SlowQueryThresholdMilliseconds int
+
+	// The maximum number of open connections to the database. Zero means the number of connections is not limited.
+	MaxOpenConnections int
+
+	// The maximum number of idle connections retained in the pool. Zero means the database/sql default is used.
+	MaxIdleConnections int
+
+	// Connections are closed once they have been open for this number of seconds. Zero means connections are not closed due to their age.
+	ConnectionMaxLifetimeSeconds int
+
+	// Connections are closed once they have been idle for this number of seconds (requires Go 1.15 or later). Zero means
+	// connections are not closed due to idleness.
+	ConnectionMaxIdleTimeSeconds int
+
+	// How often the database is pinged to check that it can still be reached (see GraniticRdbmsClientManager.PoolStatus).
+	// Zero disables periodic health checks.
+	HealthCheckIntervalSeconds int
+
+	// When BlockUntilConnected is set, the delay after a failed connection attempt before another attempt is made. The
+	// delay doubles after each failure up to ConnectRetryMaxBackoffMilliseconds. Defaults to 100.
+	ConnectRetryBackoffMilliseconds int
+
+	// The maximum delay between connection attempts when BlockUntilConnected is set. Defaults to 5000.
+	ConnectRetryMaxBackoffMilliseconds int
+
+	// If true, every query held by the QueryManager is prepared (but not executed) against the database before the
+	// application is made accessible. Startup fails if the database rejects any query. Queries are built with
+	// placeholders in the style set by PlaceholderStyle.
+	VerifyQueries bool
 }
 
 /*
@@ -267,30 +425,43 @@ type GraniticRdbmsClientManager struct {
 
 	SharedLog logging.Logger
 
-	state ioc.ComponentState
+	parameterised dsquery.ParameterisedQueryManager
+	placeholders  dsquery.PlaceholderStyle
+	statements    *statementCache
+	replicas      *replicaSet
+	stats         *QueryStatistics
+	pool          *connectionPool
+	verification  queryVerification
+	state         ioc.ComponentState
 }
 
 // BlockAccess returns true if BlockUntilConnected is set to true and a connection to the underlying RDBMS
-// has not yet been established.
+// has not yet been established. After a failed attempt to connect, further attempts are delayed according to
+// ClientManagerConfig.ConnectRetryBackoffMilliseconds. If VerifyQueries is set to true, also returns true if any of
+// the QueryManager's queries have been rejected by the database.
 func (cm *GraniticRdbmsClientManager) BlockAccess() (bool, error) {
 
-	if !cm.Configuration.BlockUntilConnected {
-		return false, nil
-	}
+	conf := cm.Configuration
 
-	provider := cm.Configuration.Provider
+	if conf.BlockUntilConnected {
 
-	db, err := provider.Database()
+		if cm.pool == nil {
+			cm.pool = newConnectionPool(conf, cm.FrameworkLogger)
+		}
 
-	if err != nil {
-		return true, errors.New("Unable to connect to database: " + err.Error())
+		if err := cm.pool.connected(); err != nil {
+			return true, errors.New("Unable to connect to database: " + err.Error())
+		}
 	}
 
-	if err = db.Ping(); err == nil {
-		return false, nil
+	if conf.VerifyQueries {
+
+		if err := cm.verifyQueries(); err != nil {
+			return true, err
+		}
 	}
 
-	return true, errors.New("Unable to connect to database: " + err.Error())
+	return false, nil
 
 }
 
@@ -310,7 +481,12 @@ func (cm *GraniticRdbmsClientManager) Client() (Client, error) {
 		return nil, err
 	}
 
-	return newRdbmsClient(db, cm.QueryManager, cm.chooseInsertFunction(), cm.SharedLog), nil
+	cm.preparePool(db)
+
+	rc := cm.newClient(db)
+	cm.assignReplica(context.Background(), rc)
+
+	return rc, nil
 }
 
 // ClientFromContext implements ClientManager.ClientFromContext
@@ -337,12 +513,104 @@ func (cm *GraniticRdbmsClientManager) ClientFromContext(ctx context.Context) (Cl
 		}
 	}
 
-	rc := newRdbmsClient(db, cm.QueryManager, cm.chooseInsertFunction(), cm.SharedLog)
+	cm.preparePool(db)
+	instrument.Event(ctx, PoolEventID, cm.PoolStatus())()
+
+	rc := cm.newClient(db)
 	rc.ctx = ctx
 
+	if !primaryForced(ctx) {
+		cm.assignReplica(ctx, rc)
+	}
+
 	return rc, nil
 }
 
+// QueryStatistics returns the statistics collected for queries executed by clients created by this manager, or nil
+// if ClientManagerConfig.CollectQueryStatistics is not set.
+func (cm *GraniticRdbmsClientManager) QueryStatistics() *QueryStatistics {
+	return cm.stats
+}
+
+// PoolStatus returns the result of the most recent health check of the primary database along with the current
+// statistics for its connection pool.
+func (cm *GraniticRdbmsClientManager) PoolStatus() PoolStatus {
+
+	if cm.pool == nil {
+
+		s := PoolStatus{Err: errNotStarted}
+
+		if cm.Configuration != nil {
+			s.Database = cm.Configuration.DatabaseName
+		}
+
+		return s
+	}
+
+	return cm.pool.current()
+}
+
+// preparePool applies the configured connection pool settings to the database.
+func (cm *GraniticRdbmsClientManager) preparePool(db *sql.DB) {
+
+	if cm.pool != nil {
+		cm.pool.prepare(db)
+	}
+}
+
+// ReplicaStatus returns the current health and lag of each of the replicas configured for this manager (or an empty
+// slice if no replicas are configured).
+func (cm *GraniticRdbmsClientManager) ReplicaStatus() []ReplicaStatus {
+
+	if cm.replicas == nil {
+		return []ReplicaStatus{}
+	}
+
+	return cm.replicas.statuses()
+}
+
+// assignReplica gives the client a connection to a healthy replica to use for SELECT queries. If no replica is
+// available, the client will use the primary database for all queries.
+func (cm *GraniticRdbmsClientManager) assignReplica(ctx context.Context, rc *ManagedClient) {
+
+	if cm.replicas == nil {
+		return
+	}
+
+	rc.replica, rc.replicaSource = cm.replicas.database(ctx)
+	rc.replicas = cm.replicas
+}
+
+func (cm *GraniticRdbmsClientManager) newClient(db *sql.DB) *ManagedClient {
+
+	rc := newRdbmsClient(db, cm.QueryManager, cm.chooseInsertFunction(), cm.SharedLog)
+
+	rc.parameterised = cm.parameterised
+	rc.placeholders = cm.placeholders
+	rc.statements = cm.statements
+
+	if conf := cm.Configuration; conf != nil {
+
+		if td, found := conf.Provider.(TransactionDialect); found {
+			rc.retry.retryable = td.RetryableTransactionError
+		}
+
+		if bd, found := conf.Provider.(BatchDialect); found {
+			rc.batch = bd
+		}
+
+		rc.retry.max = conf.TransactionRetries
+		rc.retry.backoff = time.Duration(conf.TransactionRetryBackoffMilliseconds) * time.Millisecond
+
+		rc.slowThreshold = time.Duration(conf.SlowQueryThresholdMilliseconds) * time.Millisecond
+		rc.database = conf.DatabaseName
+	}
+
+	rc.stats = cm.stats
+
+	return rc
+}
+
 func (cm *GraniticRdbmsClientManager) chooseInsertFunction() InsertWithReturnedID {
 
 	if iwi, found := cm.Configuration.Provider.(NonStandardInsertProvider); found {
@@ -361,6 +629,55 @@ func (cm *GraniticRdbmsClientManager) StartComponent() error {
 
 	cm.state = ioc.StartingState
 
+	if conf := cm.Configuration; conf != nil && conf.ParameterisedQueries {
+
+		pqm, found := cm.QueryManager.(dsquery.ParameterisedQueryManager)
+
+		if !found {
+			return fmt.Errorf("ParameterisedQueries is set but the QueryManager (%T) does not support parameterised queries", cm.QueryManager)
+		}
+
+		cm.parameterised = pqm
+
+		if conf.PlaceholderStyle != "" {
+
+			style, err := dsquery.PlaceholderStyleFromName(conf.PlaceholderStyle)
+
+			if err != nil {
+				return err
+			}
+
+			cm.placeholders = style
+		}
+
+		cm.statements = newStatementCache()
+	}
+
+	if conf := cm.Configuration; conf != nil && conf.CollectQueryStatistics {
+		cm.stats = NewQueryStatistics()
+	}
+
+	if conf := cm.Configuration; conf != nil {
+
+		if cm.pool == nil {
+			cm.pool = newConnectionPool(conf, cm.FrameworkLogger)
+		}
+
+		cm.pool.monitor()
+	}
+
+	if conf := cm.Configuration; conf != nil && len(conf.Replicas) > 0 {
+
+		rs, err := newReplicaSet(conf, cm.FrameworkLogger)
+
+		if err != nil {
+			return err
+		}
+
+		cm.replicas = rs
+		rs.monitor()
+	}
+
 	cm.state = ioc.RunningState
 
 	return nil
@@ -376,7 +693,20 @@ func (cm *GraniticRdbmsClientManager) ReadyToStop() (bool, error) {
 	return true, nil
 }
 
-// Stop always returns nil
+// Stop closes any cached prepared statements and stops checking the health of the database and its replicas. Always returns nil
 func (cm *GraniticRdbmsClientManager) Stop() error {
+
+	if cm.pool != nil {
+		cm.pool.halt()
+	}
+
+	if cm.replicas != nil {
+		cm.replicas.halt()
+	}
+
+	if cm.statements != nil {
+		cm.statements.close()
+	}
+
 	return nil
 }
@@ -0,0 +1,190 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+/*
+Package migration provides versioned schema migrations for relational databases accessed via the RdbmsAccess facility.
+
+Most applications will use the SchemaMigration facility (see the facility/migration package) which creates a Migrator
+component, applies any pending migrations as the application starts and adds runtime control commands to show the status
+of migrations and to apply or roll them back.
+
+Migration files
+
+Migrations are SQL files stored in a directory (resource/migrations by default). Each file name starts with a version number
+followed by an underscore and a description:
+
+	1_create_artist.sql
+	2_add_artist_genre.up.sql
+	2_add_artist_genre.down.sql
+
+Files ending .sql or .up.sql contain the statements to apply a migration. An optional file with the same version ending .down.sql
+contains the statements needed to roll the migration back. Migrations are applied in ascending order of version number. Version
+numbers do not need to be contiguous (timestamps such as 20200601153000 are a common choice).
+
+Statements in a file are separated by a semicolon at the end of a line. If your migrations contain statements that must
+include such a semicolon (for example stored procedure definitions), set SplitStatements to false on the Migrator and write
+one statement per file.
+
+History
+
+Each applied migration is recorded, along with a checksum of its contents, in a history table (grnc_schema_history by
+default) which is created if it does not exist. If the contents of a migration file change after it has been applied, the
+Migrator will refuse to apply further migrations until the difference has been resolved.
+*/
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+	sqlSuffix  = ".sql"
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)$`)
+
+var statementSeparator = regexp.MustCompile(`;[ \t]*(\r?\n|$)`)
+
+// Migration is a single, versioned change to a database schema.
+type Migration struct {
+	// The version number of the migration, taken from the start of its file name.
+	Version int64
+
+	// A description of the migration, taken from its file name.
+	Description string
+
+	// The SQL to apply the migration.
+	Up string
+
+	// The SQL to roll back the migration (empty if the migration cannot be rolled back).
+	Down string
+
+	// A checksum of the Up SQL.
+	Checksum string
+}
+
+// CanRollback returns true if the migration has SQL to roll it back.
+func (m *Migration) CanRollback() bool {
+	return strings.TrimSpace(m.Down) != ""
+}
+
+// LoadMigrations reads all of the migration files in the supplied directory (see the package documentation for naming
+// conventions) and returns them in ascending order of version. Returns an error if a file name is not in the expected
+// format, if more than one migration shares a version number or if a roll back file has no corresponding migration.
+func LoadMigrations(dir string) ([]*Migration, error) {
+
+	files, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Migration{}, nil
+		}
+
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	downs := make(map[int64]string)
+
+	for _, f := range files {
+
+		name := f.Name()
+
+		if f.IsDir() || !strings.HasSuffix(name, sqlSuffix) {
+			continue
+		}
+
+		down := strings.HasSuffix(name, downSuffix)
+
+		base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, downSuffix), upSuffix), sqlSuffix)
+
+		parts := fileNamePattern.FindStringSubmatch(base)
+
+		if parts == nil {
+			return nil, fmt.Errorf("migration file %s is not named in the form <version>_<description>.sql", name)
+		}
+
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s has an invalid version number: %s", name, err.Error())
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if down {
+			downs[version] = string(b)
+			continue
+		}
+
+		if _, found := byVersion[version]; found {
+			return nil, fmt.Errorf("more than one migration has the version %d", version)
+		}
+
+		m := new(Migration)
+		m.Version = version
+		m.Description = strings.Replace(parts[2], "_", " ", -1)
+		m.Up = string(b)
+		m.Checksum = checksum(m.Up)
+
+		byVersion[version] = m
+	}
+
+	for v, d := range downs {
+
+		m := byVersion[v]
+
+		if m == nil {
+			return nil, fmt.Errorf("roll back file found for version %d but there is no corresponding migration", v)
+		}
+
+		m.Down = d
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// splitStatements breaks a script into individual statements separated by a semicolon at the end of a line, discarding
+// empty statements.
+func splitStatements(script string) []string {
+
+	statements := make([]string, 0)
+
+	for _, s := range statementSeparator.Split(script, -1) {
+
+		if s = strings.TrimSpace(s); s != "" {
+			statements = append(statements, s)
+		}
+	}
+
+	return statements
+}
+
+func checksum(s string) string {
+	h := sha256.Sum256([]byte(s))
+
+	return hex.EncodeToString(h[:])
+}
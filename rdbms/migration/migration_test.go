@@ -0,0 +1,372 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+
+	dir := writeMigrations(t)
+	defer os.RemoveAll(dir)
+
+	m, err := LoadMigrations(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test.ExpectInt(t, len(m), 3)
+	test.ExpectInt(t, int(m[0].Version), 1)
+	test.ExpectString(t, m[0].Description, "create artist")
+	test.ExpectBool(t, m[0].CanRollback(), false)
+	test.ExpectInt(t, int(m[1].Version), 2)
+	test.ExpectBool(t, m[1].CanRollback(), true)
+	test.ExpectInt(t, int(m[2].Version), 10)
+
+	test.ExpectInt(t, len(splitStatements(m[0].Up)), 2)
+
+	ioutil.WriteFile(filepath.Join(dir, "11_orphan.down.sql"), []byte("DROP TABLE x;"), 0644)
+
+	if _, err = LoadMigrations(dir); err == nil {
+		t.Errorf("Expected an error for a roll back file without a migration")
+	}
+
+	os.Remove(filepath.Join(dir, "11_orphan.down.sql"))
+	ioutil.WriteFile(filepath.Join(dir, "badname.sql"), []byte("DROP TABLE x;"), 0644)
+
+	if _, err = LoadMigrations(dir); err == nil {
+		t.Errorf("Expected an error for a badly named file")
+	}
+
+	m, err = LoadMigrations(filepath.Join(dir, "missing"))
+
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(m), 0)
+}
+
+func TestApplyAndRollback(t *testing.T) {
+
+	dir := writeMigrations(t)
+	defer os.RemoveAll(dir)
+
+	mg, db := newTestMigrator(dir)
+	mg.LockStyle = "mysql"
+	mg.ApplyOnStart = true
+
+	if err := mg.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	test.ExpectInt(t, len(db.history), 3)
+	test.ExpectBool(t, db.executed("CREATE TABLE artist"), true)
+	test.ExpectBool(t, db.executed("CREATE INDEX artist_name"), true)
+	test.ExpectBool(t, db.executed("GET_LOCK"), true)
+	test.ExpectBool(t, db.executed("RELEASE_LOCK"), true)
+
+	applied, err := mg.Apply(context.Background())
+
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(applied), 0)
+
+	status, err := mg.Status(context.Background())
+
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(status), 3)
+	test.ExpectString(t, status[2].State, Applied)
+
+	rolledBack, err := mg.Rollback(context.Background(), 2)
+
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(rolledBack), 2)
+	test.ExpectInt(t, int(rolledBack[0].Version), 10)
+	test.ExpectBool(t, db.executed("DROP TABLE genre"), true)
+	test.ExpectInt(t, len(db.history), 1)
+
+	if _, err = mg.Rollback(context.Background(), 1); err == nil {
+		t.Errorf("Expected an error rolling back a migration without a roll back file")
+	}
+
+	status, _ = mg.Status(context.Background())
+	test.ExpectString(t, status[1].State, Pending)
+
+	applied, err = mg.Apply(context.Background())
+
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(applied), 2)
+}
+
+func TestModifiedAndOutOfOrder(t *testing.T) {
+
+	dir := writeMigrations(t)
+	defer os.RemoveAll(dir)
+
+	mg, db := newTestMigrator(dir)
+
+	if err := mg.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	db.history[1] = &historyRow{version: 1, description: "create artist", checksum: "abc"}
+	db.history[99] = &historyRow{version: 99, description: "gone", checksum: "abc"}
+
+	status, err := mg.Status(context.Background())
+
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(status), 4)
+	test.ExpectString(t, status[0].State, Modified)
+	test.ExpectString(t, status[3].State, Missing)
+
+	if _, err = mg.Apply(context.Background()); err == nil || !strings.Contains(err.Error(), "modified") {
+		t.Errorf("Expected an error applying migrations when one has been modified, got %v", err)
+	}
+
+	delete(db.history, 1)
+
+	if _, err = mg.Apply(context.Background()); err == nil || !strings.Contains(err.Error(), "order") {
+		t.Errorf("Expected an error applying migrations out of order, got %v", err)
+	}
+
+	delete(db.history, 99)
+	ioutil.WriteFile(filepath.Join(dir, "20_broken.sql"), []byte("FAIL;"), 0644)
+
+	applied, err := mg.Apply(context.Background())
+
+	test.ExpectNotNil(t, err)
+	test.ExpectInt(t, len(applied), 3)
+	test.ExpectInt(t, len(db.history), 3)
+}
+
+func TestConfiguration(t *testing.T) {
+
+	mg := new(Migrator)
+
+	test.ExpectNotNil(t, mg.StartComponent())
+
+	mg, _ = newTestMigrator("")
+	mg.LockStyle = "ORACLE"
+
+	test.ExpectNotNil(t, mg.StartComponent())
+
+	mg, _ = newTestMigrator("")
+
+	test.ExpectNil(t, mg.StartComponent())
+	test.ExpectString(t, mg.Location, defaultLocation)
+	test.ExpectString(t, mg.HistoryTable, defaultHistoryTable)
+	test.ExpectString(t, mg.LockStyle, NoLock)
+	test.ExpectBool(t, mg.state == ioc.RunningState, true)
+}
+
+func writeMigrations(t *testing.T) string {
+
+	dir, err := ioutil.TempDir("", "migrations")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"1_create_artist.sql":  "CREATE TABLE artist (id INT);\nCREATE INDEX artist_name ON artist (name);\n",
+		"2_add_genre.up.sql":   "CREATE TABLE genre (id INT);",
+		"2_add_genre.down.sql": "DROP TABLE genre;",
+		"10_seed.sql":          "INSERT INTO genre (id) VALUES (1);",
+		"10_seed.down.sql":     "DELETE FROM genre;",
+		"README.txt":           "Not a migration",
+	}
+
+	for n, c := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, n), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+var registerDriver sync.Once
+var testDB = new(mockDatabase)
+
+func newTestMigrator(dir string) (*Migrator, *mockDatabase) {
+
+	registerDriver.Do(func() {
+		sql.Register("grnc-migration-mock", testDB)
+	})
+
+	testDB.reset()
+
+	mg := new(Migrator)
+	mg.Location = dir
+	mg.SplitStatements = true
+	mg.Provider = new(mockProvider)
+	mg.FrameworkLogger = logging.NewStdoutLogger(logging.Fatal)
+
+	return mg, testDB
+}
+
+type mockProvider struct{}
+
+func (mp *mockProvider) Database() (*sql.DB, error) {
+	return sql.Open("grnc-migration-mock", "")
+}
+
+var insertPattern = regexp.MustCompile(`VALUES \((\d+), '([^']*)', '([^']*)', (\d+)\)`)
+var deletePattern = regexp.MustCompile(`version = (\d+)`)
+
+// mockDatabase is a minimal database driver that understands the statements used to manage the history table and
+// records all other statements.
+type mockDatabase struct {
+	history    map[int64]*historyRow
+	statements []string
+}
+
+func (md *mockDatabase) reset() {
+	md.history = make(map[int64]*historyRow)
+	md.statements = nil
+}
+
+func (md *mockDatabase) executed(fragment string) bool {
+
+	for _, s := range md.statements {
+		if strings.Contains(s, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (md *mockDatabase) Open(name string) (driver.Conn, error) {
+	return &mockConn{db: md}, nil
+}
+
+type mockConn struct {
+	db *mockDatabase
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockStmt{db: c.db, query: query}, nil
+}
+
+func (c *mockConn) Close() error {
+	return nil
+}
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	return new(mockTx), nil
+}
+
+type mockTx struct{}
+
+func (t *mockTx) Commit() error {
+	return nil
+}
+
+func (t *mockTx) Rollback() error {
+	return nil
+}
+
+type mockStmt struct {
+	db    *mockDatabase
+	query string
+}
+
+func (s *mockStmt) Close() error {
+	return nil
+}
+
+func (s *mockStmt) NumInput() int {
+	return -1
+}
+
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+
+	q := s.query
+	db := s.db
+
+	switch {
+	case strings.HasPrefix(q, "CREATE TABLE IF NOT EXISTS"):
+	case strings.HasPrefix(q, "INSERT INTO "+defaultHistoryTable):
+
+		m := insertPattern.FindStringSubmatch(q)
+		v, _ := strconv.ParseInt(m[1], 10, 64)
+		at, _ := strconv.ParseInt(m[4], 10, 64)
+
+		db.history[v] = &historyRow{version: v, description: m[2], checksum: m[3], appliedAt: at}
+
+	case strings.HasPrefix(q, "DELETE FROM "+defaultHistoryTable):
+
+		v, _ := strconv.ParseInt(deletePattern.FindStringSubmatch(q)[1], 10, 64)
+		delete(db.history, v)
+
+	case strings.Contains(q, "FAIL"):
+		return nil, errors.New("forced failure")
+
+	default:
+		db.statements = append(db.statements, q)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
+
+	r := new(mockRows)
+
+	if strings.HasPrefix(s.query, "SELECT version") {
+
+		r.columns = []string{"version", "description", "checksum", "applied_at"}
+
+		for _, h := range s.db.history {
+			r.data = append(r.data, []driver.Value{h.version, h.description, h.checksum, h.appliedAt})
+		}
+
+		return r, nil
+	}
+
+	s.db.statements = append(s.db.statements, s.query)
+
+	r.columns = []string{"result"}
+	r.data = [][]driver.Value{{int64(1)}}
+
+	return r, nil
+}
+
+type mockRows struct {
+	columns []string
+	data    [][]driver.Value
+	served  int
+}
+
+func (r *mockRows) Columns() []string {
+	return r.columns
+}
+
+func (r *mockRows) Close() error {
+	return nil
+}
+
+func (r *mockRows) Next(dest []driver.Value) error {
+
+	if r.served >= len(r.data) {
+		return io.EOF
+	}
+
+	copy(dest, r.data[r.served])
+	r.served++
+
+	return nil
+}
@@ -0,0 +1,530 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLocation     = "resource/migrations"
+	defaultHistoryTable = "grnc_schema_history"
+	defaultLockTimeout  = 60
+)
+
+// Supported values for Migrator.LockStyle
+const (
+	// NoLock means no lock is taken while migrations are applied.
+	NoLock = "NONE"
+
+	// PostgreSQLLock uses pg_advisory_lock
+	PostgreSQLLock = "POSTGRESQL"
+
+	// MySQLLock uses GET_LOCK
+	MySQLLock = "MYSQL"
+)
+
+// The states reported for a migration by Migrator.Status
+const (
+	// Applied means the migration has been applied and its file is unchanged.
+	Applied = "applied"
+
+	// Pending means the migration has not been applied.
+	Pending = "pending"
+
+	// Modified means the migration has been applied, but its file has since changed.
+	Modified = "modified"
+
+	// Missing means the migration has been applied, but there is no longer a file for it.
+	Missing = "missing"
+)
+
+// Status describes the state of a single migration.
+type Status struct {
+	Version     int64
+	Description string
+
+	// One of Applied, Pending, Modified or Missing
+	State string
+
+	// When the migration was applied (zero if pending).
+	AppliedAt time.Time
+}
+
+type historyRow struct {
+	version     int64
+	description string
+	checksum    string
+	appliedAt   int64
+}
+
+/*
+Migrator applies versioned SQL migrations (see the package documentation) to the database provided by an rdbms.DatabaseProvider.
+
+All of the work performed by a call to Apply or Rollback uses a single connection to the database. If LockStyle is POSTGRESQL or MYSQL,
+an advisory lock is held on that connection while migrations are applied, so that several instances of an application starting
+at the same time do not attempt to apply the same migrations. Each migration (and the update to the history table recording it)
+is executed in its own transaction, although note that some databases (e.g. MySQL) implicitly commit schema changes.
+*/
+type Migrator struct {
+	// The source of connections to the database to be migrated.
+	Provider rdbms.DatabaseProvider
+
+	// The directory containing migration files. Defaults to resource/migrations.
+	Location string
+
+	// The table used to record applied migrations. Defaults to grnc_schema_history.
+	HistoryTable string
+
+	// The type of advisory lock to take while applying migrations: NONE, POSTGRESQL or MYSQL
+	LockStyle string
+
+	// How long to wait for the lock before giving up. Defaults to 60 seconds.
+	LockTimeoutSeconds int
+
+	// Whether pending migrations should be applied when this component is started.
+	ApplyOnStart bool
+
+	// Whether the SQL in each file should be split into separate statements (see the package documentation).
+	SplitStatements bool
+
+	// Injected by Granitic.
+	FrameworkLogger logging.Logger
+
+	mux   sync.Mutex
+	state ioc.ComponentState
+}
+
+// Migrations loads the migrations from the directory in Location.
+func (mg *Migrator) Migrations() ([]*Migration, error) {
+	return LoadMigrations(mg.Location)
+}
+
+// Status returns the state of every migration that has either been applied or is found in Location, in ascending order of version.
+func (mg *Migrator) Status(ctx context.Context) ([]*Status, error) {
+
+	var result []*Status
+
+	err := mg.withConnection(ctx, false, func(conn *sql.Conn) error {
+
+		migrations, history, err := mg.load(ctx, conn)
+
+		if err != nil {
+			return err
+		}
+
+		result = mg.status(migrations, history)
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (mg *Migrator) status(migrations []*Migration, history map[int64]*historyRow) []*Status {
+
+	result := make([]*Status, 0)
+	seen := make(map[int64]bool)
+
+	for _, m := range migrations {
+
+		s := &Status{Version: m.Version, Description: m.Description, State: Pending}
+
+		if h := history[m.Version]; h != nil {
+			s.AppliedAt = time.Unix(h.appliedAt, 0)
+
+			if h.checksum == m.Checksum {
+				s.State = Applied
+			} else {
+				s.State = Modified
+			}
+		}
+
+		seen[m.Version] = true
+		result = append(result, s)
+	}
+
+	for v, h := range history {
+
+		if seen[v] {
+			continue
+		}
+
+		result = append(result, &Status{Version: v, Description: h.description, State: Missing, AppliedAt: time.Unix(h.appliedAt, 0)})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result
+}
+
+// Apply applies all pending migrations in ascending order of version and returns the migrations that were applied. No
+// migrations are applied if any applied migration has been modified or if a pending migration has a lower version than
+// a migration that has already been applied.
+func (mg *Migrator) Apply(ctx context.Context) ([]*Migration, error) {
+
+	applied := make([]*Migration, 0)
+
+	err := mg.withConnection(ctx, true, func(conn *sql.Conn) error {
+
+		migrations, history, err := mg.load(ctx, conn)
+
+		if err != nil {
+			return err
+		}
+
+		var highest int64
+
+		for v := range history {
+			if v > highest {
+				highest = v
+			}
+		}
+
+		pending := make([]*Migration, 0)
+
+		for _, m := range migrations {
+
+			h := history[m.Version]
+
+			if h == nil {
+
+				if m.Version < highest {
+					return fmt.Errorf("migration %d has not been applied but migration %d has. Migrations must be applied in order", m.Version, highest)
+				}
+
+				pending = append(pending, m)
+
+			} else if h.checksum != m.Checksum {
+				return fmt.Errorf("migration %d (%s) has been modified since it was applied", m.Version, m.Description)
+			}
+		}
+
+		for _, m := range pending {
+
+			mg.FrameworkLogger.LogInfof("Applying migration %d (%s)", m.Version, m.Description)
+
+			insert := fmt.Sprintf("INSERT INTO %s (version, description, checksum, applied_at) VALUES (%d, %s, %s, %d)",
+				mg.HistoryTable, m.Version, quote(m.Description), quote(m.Checksum), time.Now().Unix())
+
+			if err := mg.execute(ctx, conn, m.Up, insert); err != nil {
+				return fmt.Errorf("unable to apply migration %d (%s): %s", m.Version, m.Description, err.Error())
+			}
+
+			applied = append(applied, m)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Rollback rolls back the most recently applied migrations (up to the number specified by steps) in descending order of
+// version and returns the migrations that were rolled back. No migrations are rolled back if any of them has been modified,
+// is missing or does not have roll back SQL.
+func (mg *Migrator) Rollback(ctx context.Context, steps int) ([]*Migration, error) {
+
+	if steps < 1 {
+		return nil, errors.New("the number of migrations to roll back must be at least 1")
+	}
+
+	rolledBack := make([]*Migration, 0)
+
+	err := mg.withConnection(ctx, true, func(conn *sql.Conn) error {
+
+		migrations, history, err := mg.load(ctx, conn)
+
+		if err != nil {
+			return err
+		}
+
+		byVersion := make(map[int64]*Migration)
+
+		for _, m := range migrations {
+			byVersion[m.Version] = m
+		}
+
+		statuses := mg.status(migrations, history)
+		targets := make([]*Migration, 0)
+
+		for i := len(statuses) - 1; i >= 0 && len(targets) < steps; i-- {
+
+			s := statuses[i]
+
+			switch s.State {
+			case Pending:
+				continue
+			case Missing, Modified:
+				return fmt.Errorf("migration %d (%s) cannot be rolled back because it is %s", s.Version, s.Description, s.State)
+			}
+
+			m := byVersion[s.Version]
+
+			if !m.CanRollback() {
+				return fmt.Errorf("migration %d (%s) does not have a roll back file", m.Version, m.Description)
+			}
+
+			targets = append(targets, m)
+		}
+
+		for _, m := range targets {
+
+			mg.FrameworkLogger.LogInfof("Rolling back migration %d (%s)", m.Version, m.Description)
+
+			remove := fmt.Sprintf("DELETE FROM %s WHERE version = %d", mg.HistoryTable, m.Version)
+
+			if err := mg.execute(ctx, conn, m.Down, remove); err != nil {
+				return fmt.Errorf("unable to roll back migration %d (%s): %s", m.Version, m.Description, err.Error())
+			}
+
+			rolledBack = append(rolledBack, m)
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// execute runs the statements in the script followed by the statement updating the history table in a single transaction.
+func (mg *Migrator) execute(ctx context.Context, conn *sql.Conn, script, history string) error {
+
+	var statements []string
+
+	if mg.SplitStatements {
+		statements = splitStatements(script)
+	} else {
+		statements = []string{script}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	for _, s := range append(statements, history) {
+
+		if _, err := tx.ExecContext(ctx, s); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// load reads the migration files and the contents of the history table (creating it if necessary).
+func (mg *Migrator) load(ctx context.Context, conn *sql.Conn) ([]*Migration, map[int64]*historyRow, error) {
+
+	migrations, err := mg.Migrations()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, description VARCHAR(255) NOT NULL, "+
+		"checksum VARCHAR(64) NOT NULL, applied_at BIGINT NOT NULL)", mg.HistoryTable)
+
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return nil, nil, fmt.Errorf("unable to create migration history table %s: %s", mg.HistoryTable, err.Error())
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, description, checksum, applied_at FROM %s", mg.HistoryTable))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer rows.Close()
+
+	history := make(map[int64]*historyRow)
+
+	for rows.Next() {
+
+		h := new(historyRow)
+
+		if err := rows.Scan(&h.version, &h.description, &h.checksum, &h.appliedAt); err != nil {
+			return nil, nil, err
+		}
+
+		history[h.version] = h
+	}
+
+	return migrations, history, rows.Err()
+}
+
+// withConnection obtains a dedicated connection to the database and, if requested, holds the configured lock while the
+// supplied function is run. Calls are serialised within this instance of the application.
+func (mg *Migrator) withConnection(ctx context.Context, lock bool, f func(conn *sql.Conn) error) error {
+
+	mg.mux.Lock()
+	defer mg.mux.Unlock()
+
+	db, err := mg.Provider.Database()
+
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if lock {
+
+		if err := mg.lock(ctx, conn); err != nil {
+			return err
+		}
+
+		defer mg.unlock(conn)
+	}
+
+	return f(conn)
+}
+
+func (mg *Migrator) lock(ctx context.Context, conn *sql.Conn) error {
+
+	timeout := time.Duration(mg.LockTimeoutSeconds) * time.Second
+
+	switch mg.LockStyle {
+	case PostgreSQLLock:
+
+		lctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		if _, err := conn.ExecContext(lctx, fmt.Sprintf("SELECT pg_advisory_lock(%d)", mg.lockKey())); err != nil {
+			return fmt.Errorf("unable to obtain migration lock: %s", err.Error())
+		}
+
+	case MySQLLock:
+
+		var obtained sql.NullInt64
+
+		q := fmt.Sprintf("SELECT GET_LOCK(%s, %d)", quote(mg.HistoryTable), mg.LockTimeoutSeconds)
+
+		if err := conn.QueryRowContext(ctx, q).Scan(&obtained); err != nil {
+			return fmt.Errorf("unable to obtain migration lock: %s", err.Error())
+		}
+
+		if obtained.Int64 != 1 {
+			return fmt.Errorf("unable to obtain migration lock within %d seconds", mg.LockTimeoutSeconds)
+		}
+	}
+
+	return nil
+}
+
+func (mg *Migrator) unlock(conn *sql.Conn) {
+
+	var q string
+
+	switch mg.LockStyle {
+	case PostgreSQLLock:
+		q = fmt.Sprintf("SELECT pg_advisory_unlock(%d)", mg.lockKey())
+	case MySQLLock:
+		q = fmt.Sprintf("SELECT RELEASE_LOCK(%s)", quote(mg.HistoryTable))
+	default:
+		return
+	}
+
+	if _, err := conn.ExecContext(context.Background(), q); err != nil {
+		mg.FrameworkLogger.LogErrorf("Unable to release migration lock: %s", err.Error())
+	}
+}
+
+// lockKey derives the numeric key used for PostgreSQL advisory locks from the name of the history table.
+func (mg *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte(mg.HistoryTable))
+
+	return int64(h.Sum64())
+}
+
+// StartComponent is called by the IoC container. Applies defaults, checks that the migration files can be loaded and,
+// if ApplyOnStart is true, applies any pending migrations.
+func (mg *Migrator) StartComponent() error {
+
+	if mg.state != ioc.StoppedState {
+		return nil
+	}
+
+	mg.state = ioc.StartingState
+
+	if err := mg.configure(); err != nil {
+		return err
+	}
+
+	if mg.ApplyOnStart {
+
+		applied, err := mg.Apply(context.Background())
+
+		if err != nil {
+			return err
+		}
+
+		mg.FrameworkLogger.LogInfof("%d schema migration(s) applied", len(applied))
+	}
+
+	mg.state = ioc.RunningState
+
+	return nil
+}
+
+// configure applies defaults and validates settings.
+func (mg *Migrator) configure() error {
+
+	if mg.Provider == nil {
+		return errors.New("no rdbms.DatabaseProvider set on Migrator")
+	}
+
+	if mg.Location == "" {
+		mg.Location = defaultLocation
+	}
+
+	if mg.HistoryTable == "" {
+		mg.HistoryTable = defaultHistoryTable
+	}
+
+	if mg.LockTimeoutSeconds <= 0 {
+		mg.LockTimeoutSeconds = defaultLockTimeout
+	}
+
+	mg.LockStyle = strings.ToUpper(mg.LockStyle)
+
+	switch mg.LockStyle {
+	case "":
+		mg.LockStyle = NoLock
+	case NoLock, PostgreSQLLock, MySQLLock:
+	default:
+		return fmt.Errorf("unsupported LockStyle %s. Must be one of %s, %s or %s", mg.LockStyle, NoLock, PostgreSQLLock, MySQLLock)
+	}
+
+	if _, err := mg.Migrations(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// quote wraps a string in single quotes, escaping any single quotes it contains.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,274 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/graniticio/granitic/v2/logging"
+	"sync"
+	"time"
+)
+
+// PoolEventID is the ID of the instrument.Event started when a client is created by ClientFromContext. The
+// PoolStatus of the primary database's connection pool is passed as the event's metadata.
+const PoolEventID = "rdbms.pool"
+
+const (
+	defaultHealthCheckTimeout     = 5 * time.Second
+	defaultConnectRetryBackoff    = 100 * time.Millisecond
+	defaultConnectRetryMaxBackoff = 5 * time.Second
+)
+
+// errNotStarted is reported by GraniticRdbmsClientManager.PoolStatus for managers that have not been started
+var errNotStarted = errors.New("client manager has not been started")
+
+// PoolStatus is a snapshot of the health and usage of the connection pool to a database.
+type PoolStatus struct {
+	// The ClientManagerConfig.DatabaseName of the database (empty for an unnamed database)
+	Database string
+
+	// True if the most recent health check was able to ping the database
+	Healthy bool
+
+	// When the database was last checked
+	Checked time.Time
+
+	// The reason the most recent health check failed
+	Err error
+
+	// Usage statistics for the connection pool at the time the PoolStatus was created
+	Stats sql.DBStats
+}
+
+// idleTimeSetter is implemented by sql.DB from Go 1.15
+type idleTimeSetter interface {
+	SetConnMaxIdleTime(d time.Duration)
+}
+
+// connectionPool applies the pool settings from a ClientManagerConfig to the sql.DB returned by the primary
+// DatabaseProvider and periodically checks that the database can be reached.
+type connectionPool struct {
+	provider DatabaseProvider
+	conf     *ClientManagerConfig
+	log      logging.Logger
+
+	configured *sql.DB
+	status     PoolStatus
+
+	// Backoff state while waiting for the first successful connection
+	backoff     time.Duration
+	nextAttempt time.Time
+
+	stop chan bool
+	mux  sync.RWMutex
+}
+
+func newConnectionPool(conf *ClientManagerConfig, log logging.Logger) *connectionPool {
+	p := new(connectionPool)
+	p.conf = conf
+	p.provider = conf.Provider
+	p.log = log
+
+	return p
+}
+
+// prepare applies the configured pool settings to the supplied database if they have not already been applied to it.
+func (p *connectionPool) prepare(db *sql.DB) {
+
+	if db == nil {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.configured == db {
+		return
+	}
+
+	p.configured = db
+
+	conf := p.conf
+
+	if conf.MaxOpenConnections > 0 {
+		db.SetMaxOpenConns(conf.MaxOpenConnections)
+	}
+
+	if conf.MaxIdleConnections > 0 {
+		db.SetMaxIdleConns(conf.MaxIdleConnections)
+	}
+
+	if conf.ConnectionMaxLifetimeSeconds > 0 {
+		db.SetConnMaxLifetime(time.Duration(conf.ConnectionMaxLifetimeSeconds) * time.Second)
+	}
+
+	if conf.ConnectionMaxIdleTimeSeconds > 0 {
+
+		if its, found := interface{}(db).(idleTimeSetter); found {
+			its.SetConnMaxIdleTime(time.Duration(conf.ConnectionMaxIdleTimeSeconds) * time.Second)
+		} else if p.log != nil {
+			p.log.LogWarnf("ConnectionMaxIdleTimeSeconds is ignored as it requires Go 1.15 or later")
+		}
+	}
+}
+
+// check pings the database, recording whether or not it is healthy.
+func (p *connectionPool) check(ctx context.Context) error {
+
+	timeout := p.interval()
+
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	db, err := providerDatabase(ctx, p.provider)
+
+	if err == nil {
+		p.prepare(db)
+		err = db.PingContext(ctx)
+	}
+
+	p.update(err)
+
+	return err
+}
+
+func (p *connectionPool) update(err error) {
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	healthy := err == nil
+
+	if p.log != nil {
+		if p.status.Healthy && !healthy {
+			p.log.LogErrorf("%s health check failed: %s", p.describe(), err.Error())
+		} else if !p.status.Healthy && healthy && !p.status.Checked.IsZero() {
+			p.log.LogInfof("%s health check succeeded", p.describe())
+		}
+	}
+
+	p.status.Healthy = healthy
+	p.status.Err = err
+	p.status.Checked = time.Now()
+}
+
+// current returns the most recent health check result along with the current pool statistics.
+func (p *connectionPool) current() PoolStatus {
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	s := p.status
+	s.Database = p.conf.DatabaseName
+
+	if p.configured != nil {
+		s.Stats = p.configured.Stats()
+	}
+
+	return s
+}
+
+// connected returns nil if the database can be reached. After a failed attempt, further attempts are not made (and the
+// previous error is returned) until a backoff period has passed. The backoff doubles after each failure.
+func (p *connectionPool) connected() error {
+
+	p.mux.RLock()
+	wait := time.Now().Before(p.nextAttempt)
+	last := p.status.Err
+	p.mux.RUnlock()
+
+	if wait && last != nil {
+		return last
+	}
+
+	err := p.check(context.Background())
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if err == nil {
+		p.backoff = 0
+		p.nextAttempt = time.Time{}
+
+		return nil
+	}
+
+	if p.backoff == 0 {
+		p.backoff = p.millis(p.conf.ConnectRetryBackoffMilliseconds, defaultConnectRetryBackoff)
+	} else {
+		p.backoff *= 2
+	}
+
+	if max := p.millis(p.conf.ConnectRetryMaxBackoffMilliseconds, defaultConnectRetryMaxBackoff); p.backoff > max {
+		p.backoff = max
+	}
+
+	p.nextAttempt = time.Now().Add(p.backoff)
+
+	return err
+}
+
+// describe names the database in log messages
+func (p *connectionPool) describe() string {
+
+	if name := p.conf.DatabaseName; name != "" {
+		return "Database " + name
+	}
+
+	return "Database"
+}
+
+func (p *connectionPool) millis(ms int, def time.Duration) time.Duration {
+
+	if ms <= 0 {
+		return def
+	}
+
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (p *connectionPool) interval() time.Duration {
+	return time.Duration(p.conf.HealthCheckIntervalSeconds) * time.Second
+}
+
+// monitor checks the health of the database at the configured interval until halt is called. Does nothing if
+// HealthCheckIntervalSeconds is not set.
+func (p *connectionPool) monitor() {
+
+	interval := p.interval()
+
+	if interval <= 0 {
+		return
+	}
+
+	stop := make(chan bool)
+	p.stop = stop
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				p.check(context.Background())
+			}
+		}
+	}()
+}
+
+func (p *connectionPool) halt() {
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
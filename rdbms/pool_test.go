@@ -0,0 +1,125 @@
+package rdbms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/graniticio/granitic/v2/instrument"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+	"time"
+)
+
+func TestPoolConfiguration(t *testing.T) {
+
+	p := &namedProvider{dsn: "pooled"}
+
+	m := newReplicaManager(p)
+	m.Configuration.MaxOpenConnections = 3
+	m.Configuration.MaxIdleConnections = 1
+	m.Configuration.ConnectionMaxLifetimeSeconds = 60
+
+	s := m.PoolStatus()
+	test.ExpectBool(t, s.Err == errNotStarted, true)
+
+	test.ExpectNil(t, m.StartComponent())
+	defer m.Stop()
+
+	in := new(poolRecorder)
+	ctx := instrument.AddInstrumentorToContext(context.Background(), in)
+
+	_, err := m.ClientFromContext(ctx)
+	test.ExpectNil(t, err)
+
+	test.ExpectInt(t, in.status.Stats.MaxOpenConnections, 3)
+
+	s = m.PoolStatus()
+	test.ExpectInt(t, s.Stats.MaxOpenConnections, 3)
+	test.ExpectBool(t, s.Checked.IsZero(), true)
+
+	test.ExpectNil(t, m.pool.check(context.Background()))
+
+	s = m.PoolStatus()
+	test.ExpectBool(t, s.Healthy, true)
+	test.ExpectBool(t, s.Checked.IsZero(), false)
+
+	p.err = errors.New("unreachable")
+
+	test.ExpectNotNil(t, m.pool.check(context.Background()))
+
+	s = m.PoolStatus()
+	test.ExpectBool(t, s.Healthy, false)
+	test.ExpectString(t, s.Err.Error(), "unreachable")
+}
+
+func TestBlockUntilConnected(t *testing.T) {
+
+	p := new(countingProvider)
+	p.err = errors.New("unreachable")
+
+	conf := &ClientManagerConfig{Provider: p, BlockUntilConnected: true, ConnectRetryBackoffMilliseconds: 20, ConnectRetryMaxBackoffMilliseconds: 30}
+
+	m := new(GraniticRdbmsClientManager)
+	m.FrameworkLogger = logging.CreateAnonymousLogger("testLog", logging.Fatal)
+	m.Configuration = conf
+
+	block, err := m.BlockAccess()
+	test.ExpectBool(t, block, true)
+	test.ExpectNotNil(t, err)
+	test.ExpectInt(t, p.calls, 1)
+
+	// Further attempts are not made until the backoff has passed
+	block, _ = m.BlockAccess()
+	test.ExpectBool(t, block, true)
+	test.ExpectInt(t, p.calls, 1)
+
+	time.Sleep(25 * time.Millisecond)
+
+	m.BlockAccess()
+	test.ExpectInt(t, p.calls, 2)
+	test.ExpectBool(t, m.pool.backoff == 30*time.Millisecond, true)
+
+	time.Sleep(35 * time.Millisecond)
+
+	p.err = nil
+
+	block, err = m.BlockAccess()
+	test.ExpectBool(t, block, false)
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, p.calls, 3)
+
+	conf.BlockUntilConnected = false
+	block, _ = m.BlockAccess()
+	test.ExpectBool(t, block, false)
+}
+
+type countingProvider struct {
+	calls int
+	err   error
+}
+
+func (cp *countingProvider) Database() (*sql.DB, error) {
+
+	cp.calls++
+
+	if cp.err != nil {
+		return nil, cp.err
+	}
+
+	return sql.Open("grnc-routing-mock", "counted")
+}
+
+type poolRecorder struct {
+	instrument.Instrumentor
+	status PoolStatus
+}
+
+func (pr *poolRecorder) StartEvent(id string, metadata ...interface{}) instrument.EndEvent {
+
+	if s, found := metadata[0].(PoolStatus); found && id == PoolEventID {
+		pr.status = s
+	}
+
+	return func() {}
+}
@@ -0,0 +1,246 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/graniticio/granitic/v2/instrument"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DirectQueryID is the ID under which statistics are recorded for statements executed with the Exec, Query and QueryRow
+// pass-through methods on ManagedClient.
+const DirectQueryID = "(direct)"
+
+// QueryEventID is the ID of the instrument.Event started for every query executed by a ManagedClient. The ID of the
+// query (or DirectQueryID) is passed as the event's metadata, followed by the name of the database if
+// ClientManagerConfig.DatabaseName is set.
+const QueryEventID = "rdbms.query"
+
+// LatencyBuckets are the upper bounds of the latency histogram recorded for each query ID.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// QueryStats summarises the executions of a single query ID.
+type QueryStats struct {
+	// The ID of the query
+	QueryID string
+
+	// The number of times the query has been executed
+	Count int64
+
+	// The number of executions that returned an error
+	Errors int64
+
+	// The total number of rows returned by (or affected by) the query, where known. Rows are not counted for
+	// queries executed with methods that return a *sql.Rows or RowCursor.
+	Rows int64
+
+	// The total time spent executing the query
+	Total time.Duration
+
+	// The longest single execution of the query
+	Max time.Duration
+
+	// Histogram[i] is the number of executions that took no longer than LatencyBuckets[i] (and longer than LatencyBuckets[i-1]).
+	// The final element counts executions slower than the last bucket.
+	Histogram []int64
+}
+
+// Mean returns the average time taken to execute the query.
+func (qs *QueryStats) Mean() time.Duration {
+
+	if qs.Count == 0 {
+		return 0
+	}
+
+	return qs.Total / time.Duration(qs.Count)
+}
+
+// QueryStatistics collects QueryStats for each of the query IDs executed by the ManagedClients created by a ClientManager.
+// It is safe for concurrent use.
+type QueryStatistics struct {
+	byID map[string]*QueryStats
+	mux  sync.Mutex
+}
+
+// NewQueryStatistics creates an empty QueryStatistics.
+func NewQueryStatistics() *QueryStatistics {
+	s := new(QueryStatistics)
+	s.byID = make(map[string]*QueryStats)
+
+	return s
+}
+
+// Snapshot returns a copy of the statistics for every query ID executed since the statistics were last reset, ordered by query ID.
+func (s *QueryStatistics) Snapshot() []QueryStats {
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	snap := make([]QueryStats, 0, len(s.byID))
+
+	for _, qs := range s.byID {
+
+		c := *qs
+		c.Histogram = append([]int64(nil), qs.Histogram...)
+
+		snap = append(snap, c)
+	}
+
+	sort.Slice(snap, func(i, j int) bool { return snap[i].QueryID < snap[j].QueryID })
+
+	return snap
+}
+
+// Reset discards all statistics.
+func (s *QueryStatistics) Reset() {
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.byID = make(map[string]*QueryStats)
+}
+
+func (s *QueryStatistics) record(qid string, elapsed time.Duration, rows int64, err error) {
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	qs := s.byID[qid]
+
+	if qs == nil {
+		qs = &QueryStats{QueryID: qid, Histogram: make([]int64, len(LatencyBuckets)+1)}
+		s.byID[qid] = qs
+	}
+
+	qs.Count++
+	qs.Rows += rows
+	qs.Total += elapsed
+
+	if err != nil {
+		qs.Errors++
+	}
+
+	if elapsed > qs.Max {
+		qs.Max = elapsed
+	}
+
+	b := sort.Search(len(LatencyBuckets), func(i int) bool { return elapsed <= LatencyBuckets[i] })
+	qs.Histogram[b]++
+}
+
+// observe starts timing a query, returning a function that must be called when the query has completed. That function
+// ends the query's instrumentation event, records statistics (if enabled) and logs the query if it was slow.
+func (rc *ManagedClient) observe(qid string, params []interface{}) func(rows int64, err error) {
+
+	if rc.unobserved {
+		return func(int64, error) {}
+	}
+
+	start := time.Now()
+
+	metadata := []interface{}{qid}
+
+	if rc.database != "" {
+		metadata = append(metadata, rc.database)
+	}
+
+	end := instrument.Event(rc.context(), QueryEventID, metadata...)
+
+	return func(rows int64, err error) {
+
+		end()
+
+		elapsed := time.Since(start)
+
+		if rc.stats != nil {
+			rc.stats.record(qid, elapsed, rows, err)
+		}
+
+		if rc.slowThreshold > 0 && elapsed >= rc.slowThreshold {
+			if rc.database != "" {
+				rc.FrameworkLogger.LogWarnf("Slow query %s on database %s took %v. Parameters: %s", qid, rc.database, elapsed, redactParams(qid, params))
+			} else {
+				rc.FrameworkLogger.LogWarnf("Slow query %s took %v. Parameters: %s", qid, elapsed, redactParams(qid, params))
+			}
+		}
+	}
+}
+
+// redactParams describes the parameters supplied to a query without revealing their values. Parameters for templated
+// queries are shown as name=<type>, arguments to direct queries as a list of types.
+func redactParams(qid string, params []interface{}) string {
+
+	if qid == DirectQueryID {
+
+		types := make([]string, len(params))
+
+		for i, p := range params {
+			types[i] = fmt.Sprintf("<%T>", p)
+		}
+
+		return "[" + strings.Join(types, " ") + "]"
+	}
+
+	pm, err := ParamsFromFieldsOrTags(params...)
+
+	if err != nil {
+		return "[unavailable]"
+	}
+
+	names := make([]string, 0, len(pm))
+
+	for k, v := range pm {
+		names = append(names, fmt.Sprintf("%s=<%T>", k, v))
+	}
+
+	sort.Strings(names)
+
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+func rowsAffected(r sql.Result) int64 {
+
+	if r == nil {
+		return 0
+	}
+
+	n, _ := r.RowsAffected()
+
+	return n
+}
+
+func rowCount(found bool) int64 {
+
+	if found {
+		return 1
+	}
+
+	return 0
+}
+
+func sliceLen(target interface{}) int {
+
+	v := reflect.ValueOf(target)
+
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Slice {
+		return v.Elem().Len()
+	}
+
+	return 0
+}
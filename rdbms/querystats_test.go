@@ -0,0 +1,121 @@
+package rdbms
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/graniticio/granitic/v2/instrument"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+	"time"
+)
+
+func TestQueryStatistics(t *testing.T) {
+
+	s := NewQueryStatistics()
+
+	s.record("B", 2*time.Millisecond, 3, nil)
+	s.record("B", 10*time.Second, 0, errors.New("failed"))
+	s.record("A", 0, 1, nil)
+
+	snap := s.Snapshot()
+
+	test.ExpectInt(t, len(snap), 2)
+	test.ExpectString(t, snap[0].QueryID, "A")
+
+	b := snap[1]
+
+	test.ExpectInt(t, int(b.Count), 2)
+	test.ExpectInt(t, int(b.Errors), 1)
+	test.ExpectInt(t, int(b.Rows), 3)
+	test.ExpectBool(t, b.Max == 10*time.Second, true)
+	test.ExpectBool(t, b.Mean() == 5001*time.Millisecond, true)
+	test.ExpectInt(t, int(b.Histogram[1]), 1)
+	test.ExpectInt(t, int(b.Histogram[len(LatencyBuckets)]), 1)
+
+	// Snapshots are copies
+	b.Histogram[1] = 5
+	test.ExpectInt(t, int(s.Snapshot()[1].Histogram[1]), 1)
+
+	s.Reset()
+	test.ExpectInt(t, len(s.Snapshot()), 0)
+}
+
+func TestClientStatistics(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+	c.stats = NewQueryStatistics()
+	c.slowThreshold = time.Nanosecond
+
+	in := new(queryRecorder)
+	c.ctx = instrument.AddInstrumentorToContext(context.Background(), in)
+
+	drv.colNames = []string{"Int64Result"}
+	drv.rowData = [][]driver.Value{{int64(1)}, {int64(2)}}
+
+	_, err := c.SelectBindQIDParam("SELECT", "ID", 1, new(testTarget))
+	test.ExpectNil(t, err)
+
+	_, err = c.UpdateQIDParam("UPDATE", "ID", 1)
+	test.ExpectNil(t, err)
+
+	drv.forceError = true
+	_, err = c.UpdateQIDParam("UPDATE", "ID", 1)
+	test.ExpectNotNil(t, err)
+
+	// Insert functions are passed a ManagedClient whose statements are not observed separately
+	c.lastID = func(query string, client Client, target *int64) error {
+		_, managed := client.(*ManagedClient)
+		test.ExpectBool(t, managed, true)
+
+		return DefaultInsertWithReturnedID(query, client, target)
+	}
+
+	var id int64
+	test.ExpectNil(t, c.InsertCaptureQIDParams("INSERT", &id))
+
+	_, err = c.Exec("DIRECT")
+	test.ExpectNil(t, err)
+
+	snap := c.stats.Snapshot()
+
+	test.ExpectInt(t, len(snap), 4)
+
+	test.ExpectString(t, snap[0].QueryID, DirectQueryID)
+	test.ExpectInt(t, int(snap[0].Count), 1)
+
+	test.ExpectString(t, snap[1].QueryID, "INSERT")
+	test.ExpectInt(t, int(snap[1].Count), 1)
+
+	test.ExpectString(t, snap[2].QueryID, "SELECT")
+	test.ExpectInt(t, int(snap[2].Rows), 2)
+
+	test.ExpectString(t, snap[3].QueryID, "UPDATE")
+	test.ExpectInt(t, int(snap[3].Count), 2)
+	test.ExpectInt(t, int(snap[3].Errors), 1)
+	test.ExpectInt(t, int(snap[3].Rows), 1)
+
+	test.ExpectInt(t, len(in.ids), 5)
+	test.ExpectString(t, in.ids[0], "SELECT")
+}
+
+func TestRedactParams(t *testing.T) {
+
+	test.ExpectString(t, redactParams("Q", []interface{}{map[string]interface{}{"B": "secret", "A": 1}}), "[A=<int> B=<string>]")
+	test.ExpectString(t, redactParams(DirectQueryID, []interface{}{"secret", int64(1)}), "[<string> <int64>]")
+}
+
+type queryRecorder struct {
+	instrument.Instrumentor
+	ids []string
+}
+
+func (qr *queryRecorder) StartEvent(id string, metadata ...interface{}) instrument.EndEvent {
+
+	if id == QueryEventID {
+		qr.ids = append(qr.ids, metadata[0].(string))
+	}
+
+	return func() {}
+}
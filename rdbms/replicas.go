@@ -0,0 +1,327 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/logging"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReplicaCheckInterval = 10 * time.Second
+	replicaEventPrefix          = "rdbms.replica."
+)
+
+// ReplicaConfig describes a read-only replica of the primary database that SELECT queries may be routed to.
+type ReplicaConfig struct {
+	// The name of the component implementing DatabaseProvider that provides connections to the replica. Used by the
+	// RdbmsAccess facility to set Provider.
+	ProviderName string
+
+	// The provider of connections to the replica.
+	Provider DatabaseProvider `json:"-"`
+
+	// The relative share of reads that should be sent to this replica. Defaults to 1.
+	Weight int
+}
+
+// ReplicaLagReporter is an optional interface for DatabaseProvider implementations that provide connections to a replica and
+// are able to determine how far the replica is behind the primary database.
+type ReplicaLagReporter interface {
+	// ReplicaLag returns the time by which the replica's data trails the primary database.
+	ReplicaLag(ctx context.Context) (time.Duration, error)
+}
+
+// ReplicaStatus is a snapshot of the health of a replica. A ReplicaStatus is passed as metadata to an instrument.Event
+// whenever a query is routed to a replica.
+type ReplicaStatus struct {
+	// The ProviderName of the replica (or its position in ClientManagerConfig.Replicas if no name was set)
+	Name string
+
+	// The replica's share of reads
+	Weight int
+
+	// False if the replica has been ejected and is not currently receiving reads
+	Healthy bool
+
+	// The lag reported by the replica's provider at the last check (always zero if the provider does not implement ReplicaLagReporter)
+	Lag time.Duration
+
+	// When the replica was last checked
+	Checked time.Time
+
+	// The reason the replica was ejected (nil if Healthy is true)
+	Err error
+}
+
+// ForcePrimary returns a context that, when passed to ClientManager.ClientFromContext, will result in a client that
+// sends all queries to the primary database, even if read replicas are configured. Use this when a read must reflect
+// writes made elsewhere (e.g. in a previous request).
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey, true)
+}
+
+type primaryCtxKey int
+
+const forcePrimaryKey primaryCtxKey = 0
+
+func primaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(forcePrimaryKey).(bool)
+
+	return forced
+}
+
+// replica tracks the health of a single read replica.
+type replica struct {
+	name     string
+	weight   int
+	provider DatabaseProvider
+	status   ReplicaStatus
+}
+
+// replicaSet chooses between the healthy replicas configured for a ClientManager and periodically checks their health.
+type replicaSet struct {
+	replicas []*replica
+	maxLag   time.Duration
+	interval time.Duration
+	log      logging.Logger
+	mux      sync.RWMutex
+	stop     chan bool
+}
+
+func newReplicaSet(conf *ClientManagerConfig, log logging.Logger) (*replicaSet, error) {
+
+	rs := new(replicaSet)
+	rs.log = log
+	rs.maxLag = time.Duration(conf.ReplicaMaxLagSeconds) * time.Second
+	rs.interval = time.Duration(conf.ReplicaCheckIntervalSeconds) * time.Second
+
+	if rs.interval <= 0 {
+		rs.interval = defaultReplicaCheckInterval
+	}
+
+	for i, rc := range conf.Replicas {
+
+		name := rc.ProviderName
+
+		if name == "" {
+			name = fmt.Sprintf("replica%d", i)
+		}
+
+		if rc.Provider == nil {
+			return nil, fmt.Errorf("no DatabaseProvider has been set for replica %s", name)
+		}
+
+		if rc.Weight < 0 {
+			return nil, fmt.Errorf("replica %s has a negative weight", name)
+		}
+
+		r := new(replica)
+		r.name = name
+		r.provider = rc.Provider
+		r.weight = rc.Weight
+
+		if r.weight == 0 {
+			r.weight = 1
+		}
+
+		r.status = ReplicaStatus{Name: name, Weight: r.weight, Healthy: true}
+
+		rs.replicas = append(rs.replicas, r)
+	}
+
+	return rs, nil
+}
+
+// choose returns a healthy replica at random (in proportion to the weights of the healthy replicas) or nil if no
+// replica is healthy.
+func (rs *replicaSet) choose() *replica {
+
+	rs.mux.RLock()
+	defer rs.mux.RUnlock()
+
+	total := 0
+
+	for _, r := range rs.replicas {
+		if r.status.Healthy {
+			total += r.weight
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+
+	for _, r := range rs.replicas {
+
+		if !r.status.Healthy {
+			continue
+		}
+
+		if n < r.weight {
+			return r
+		}
+
+		n -= r.weight
+	}
+
+	return nil
+}
+
+// database returns a connection to a healthy replica or nil if no healthy replicas are available. A replica whose
+// provider cannot supply a connection is ejected and another replica tried.
+func (rs *replicaSet) database(ctx context.Context) (*sql.DB, *replica) {
+
+	for r := rs.choose(); r != nil; r = rs.choose() {
+
+		db, err := providerDatabase(ctx, r.provider)
+
+		if err == nil {
+			return db, r
+		}
+
+		rs.update(r, false, 0, err)
+	}
+
+	return nil, nil
+}
+
+// check pings every replica (healthy or not), ejecting replicas that cannot be reached or whose lag exceeds the
+// configured maximum and restoring replicas that have recovered.
+func (rs *replicaSet) check(ctx context.Context) {
+
+	for _, r := range rs.replicas {
+
+		lag, err := rs.checkReplica(ctx, r)
+
+		rs.update(r, err == nil, lag, err)
+	}
+}
+
+func (rs *replicaSet) checkReplica(ctx context.Context, r *replica) (time.Duration, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, rs.interval)
+	defer cancel()
+
+	db, err := providerDatabase(ctx, r.provider)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		return 0, err
+	}
+
+	lr, found := r.provider.(ReplicaLagReporter)
+
+	if !found {
+		return 0, nil
+	}
+
+	lag, err := lr.ReplicaLag(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if rs.maxLag > 0 && lag > rs.maxLag {
+		return lag, fmt.Errorf("replica lag of %v exceeds the maximum of %v", lag, rs.maxLag)
+	}
+
+	return lag, nil
+}
+
+func (rs *replicaSet) update(r *replica, healthy bool, lag time.Duration, err error) {
+
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+
+	if r.status.Healthy && !healthy {
+		rs.log.LogWarnf("Ejecting replica %s: %s", r.name, err.Error())
+	} else if !r.status.Healthy && healthy {
+		rs.log.LogInfof("Replica %s is healthy and will receive reads", r.name)
+	}
+
+	r.status.Healthy = healthy
+	r.status.Lag = lag
+	r.status.Err = err
+	r.status.Checked = time.Now()
+}
+
+func (rs *replicaSet) replicaStatus(r *replica) ReplicaStatus {
+
+	rs.mux.RLock()
+	defer rs.mux.RUnlock()
+
+	return r.status
+}
+
+func (rs *replicaSet) statuses() []ReplicaStatus {
+
+	rs.mux.RLock()
+	defer rs.mux.RUnlock()
+
+	s := make([]ReplicaStatus, len(rs.replicas))
+
+	for i, r := range rs.replicas {
+		s[i] = r.status
+	}
+
+	return s
+}
+
+// monitor checks the health of the replicas immediately and then at the configured interval until halt is called.
+func (rs *replicaSet) monitor() {
+
+	rs.check(context.Background())
+
+	stop := make(chan bool)
+	rs.stop = stop
+
+	go func() {
+		t := time.NewTicker(rs.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				rs.check(context.Background())
+			}
+		}
+	}()
+}
+
+func (rs *replicaSet) halt() {
+	if rs.stop != nil {
+		close(rs.stop)
+		rs.stop = nil
+	}
+}
+
+// providerDatabase obtains a connection to a replica from the supplied provider, passing the context if the provider supports it.
+func providerDatabase(ctx context.Context, p DatabaseProvider) (db *sql.DB, err error) {
+
+	if cdp, found := p.(ContextAwareDatabaseProvider); found {
+		db, err = cdp.DatabaseFromContext(ctx)
+	} else {
+		db, err = p.Database()
+	}
+
+	if err == nil && db == nil {
+		err = errors.New("provider returned a nil database")
+	}
+
+	return db, err
+}
@@ -0,0 +1,261 @@
+package rdbms
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/graniticio/granitic/v2/instrument"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"io"
+	"testing"
+	"time"
+)
+
+var routing = &routingDriver{queries: make(map[string]int)}
+
+func init() {
+	sql.Register("grnc-routing-mock", routing)
+}
+
+func TestReplicaRouting(t *testing.T) {
+
+	primary := &namedProvider{dsn: "primary"}
+	healthy := &namedProvider{dsn: "healthy"}
+	lagging := &namedProvider{dsn: "lagging", lag: time.Minute}
+
+	m := newReplicaManager(primary, &ReplicaConfig{ProviderName: "healthy", Provider: healthy},
+		&ReplicaConfig{ProviderName: "lagging", Provider: lagging, Weight: 5})
+
+	if err := m.StartComponent(); err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	defer m.Stop()
+
+	s := m.ReplicaStatus()
+
+	test.ExpectInt(t, len(s), 2)
+	test.ExpectBool(t, s[0].Healthy, true)
+	test.ExpectBool(t, s[1].Healthy, false)
+	test.ExpectBool(t, s[1].Lag == time.Minute, true)
+	test.ExpectInt(t, s[1].Weight, 5)
+
+	in := new(eventRecorder)
+	ctx := instrument.AddInstrumentorToContext(context.Background(), in)
+
+	c, _ := m.ClientFromContext(ctx)
+
+	routing.reset()
+	selectQuery(t, c)
+	test.ExpectInt(t, routing.queries["healthy"], 1)
+	test.ExpectString(t, in.id, "rdbms.replica.healthy")
+	test.ExpectBool(t, in.status.Healthy, true)
+
+	// Reads in a transaction go to the primary
+	c.StartTransaction()
+	selectQuery(t, c)
+	c.CommitTransaction()
+	test.ExpectInt(t, routing.queries["primary"], 1)
+
+	selectQuery(t, c)
+	test.ExpectInt(t, routing.queries["healthy"], 2)
+
+	// Reads after a write go to the primary
+	_, err := c.UpdateQIDParam("UPDATE", "ID", 1)
+	test.ExpectNil(t, err)
+
+	selectQuery(t, c)
+	test.ExpectInt(t, routing.queries["healthy"], 2)
+	test.ExpectInt(t, routing.queries["primary"], 2)
+
+	c, _ = m.ClientFromContext(ForcePrimary(context.Background()))
+	selectQuery(t, c)
+	test.ExpectInt(t, routing.queries["primary"], 3)
+
+	// Replicas that fail health checks are ejected and restored when they recover
+	healthy.err = errors.New("unavailable")
+	m.replicas.check(context.Background())
+
+	c, _ = m.Client()
+	selectQuery(t, c)
+	test.ExpectInt(t, routing.queries["primary"], 4)
+
+	healthy.err = nil
+	lagging.lag = time.Second
+	m.replicas.check(context.Background())
+
+	s = m.ReplicaStatus()
+	test.ExpectBool(t, s[0].Healthy && s[1].Healthy, true)
+
+	// Replicas whose providers fail when a client is created are ejected
+	healthy.err = errors.New("unavailable")
+	lagging.err = errors.New("unavailable")
+
+	c, _ = m.Client()
+	selectQuery(t, c)
+	test.ExpectInt(t, routing.queries["primary"], 5)
+
+	s = m.ReplicaStatus()
+	test.ExpectBool(t, s[0].Healthy || s[1].Healthy, false)
+}
+
+func TestReplicaWeighting(t *testing.T) {
+
+	conf := &ClientManagerConfig{Replicas: []*ReplicaConfig{
+		{ProviderName: "heavy", Provider: new(namedProvider), Weight: 3},
+		{ProviderName: "light", Provider: new(namedProvider)},
+	}}
+
+	rs, err := newReplicaSet(conf, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+	test.ExpectNil(t, err)
+
+	chosen := make(map[string]int)
+
+	for i := 0; i < 1000; i++ {
+		chosen[rs.choose().name]++
+	}
+
+	test.ExpectBool(t, chosen["heavy"] > 600 && chosen["light"] > 100, true)
+
+	rs.update(rs.replicas[0], false, 0, errors.New("ejected"))
+
+	for i := 0; i < 100; i++ {
+		test.ExpectString(t, rs.choose().name, "light")
+	}
+
+	conf.Replicas = append(conf.Replicas, &ReplicaConfig{ProviderName: "unset"})
+
+	_, err = newReplicaSet(conf, nil)
+	test.ExpectNotNil(t, err)
+}
+
+func newReplicaManager(primary DatabaseProvider, replicas ...*ReplicaConfig) *GraniticRdbmsClientManager {
+
+	m := new(GraniticRdbmsClientManager)
+	m.QueryManager = qm
+	m.SharedLog = logging.CreateAnonymousLogger("testLog", logging.Fatal)
+	m.FrameworkLogger = m.SharedLog
+	m.Configuration = &ClientManagerConfig{Provider: primary, Replicas: replicas, ReplicaMaxLagSeconds: 30}
+
+	return m
+}
+
+func selectQuery(t *testing.T, c Client) {
+
+	r, err := c.SelectQID("SELECT")
+
+	if err != nil {
+		t.Fatalf("Unexpected %s", err.Error())
+	}
+
+	r.Close()
+}
+
+// namedProvider opens a connection to the routing driver, so that the number of queries executed against each provider
+// can be counted.
+type namedProvider struct {
+	dsn string
+	lag time.Duration
+	err error
+	db  *sql.DB
+}
+
+func (np *namedProvider) Database() (*sql.DB, error) {
+
+	if np.err != nil {
+		return nil, np.err
+	}
+
+	if np.db == nil {
+		np.db, _ = sql.Open("grnc-routing-mock", np.dsn)
+	}
+
+	return np.db, nil
+}
+
+func (np *namedProvider) ReplicaLag(ctx context.Context) (time.Duration, error) {
+	return np.lag, nil
+}
+
+type eventRecorder struct {
+	instrument.Instrumentor
+	id     string
+	status ReplicaStatus
+}
+
+func (er *eventRecorder) StartEvent(id string, metadata ...interface{}) instrument.EndEvent {
+
+	if s, found := metadata[0].(ReplicaStatus); found {
+		er.id = id
+		er.status = s
+	}
+
+	return func() {}
+}
+
+type routingDriver struct {
+	queries map[string]int
+}
+
+func (d *routingDriver) reset() {
+	d.queries = make(map[string]int)
+}
+
+func (d *routingDriver) Open(name string) (driver.Conn, error) {
+	return &routingConn{d: d, name: name}, nil
+}
+
+type routingConn struct {
+	d    *routingDriver
+	name string
+}
+
+func (c *routingConn) Prepare(query string) (driver.Stmt, error) {
+	return &routingStmt{c: c}, nil
+}
+
+func (c *routingConn) Close() error {
+	return nil
+}
+
+func (c *routingConn) Begin() (driver.Tx, error) {
+	return new(mockTx), nil
+}
+
+type routingStmt struct {
+	c *routingConn
+}
+
+func (s *routingStmt) Close() error {
+	return nil
+}
+
+func (s *routingStmt) NumInput() int {
+	return -1
+}
+
+func (s *routingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return mockResult{ra: 1}, nil
+}
+
+func (s *routingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.c.d.queries[s.c.name]++
+
+	return new(emptyRows), nil
+}
+
+type emptyRows struct{}
+
+func (r *emptyRows) Columns() []string {
+	return []string{}
+}
+
+func (r *emptyRows) Close() error {
+	return nil
+}
+
+func (r *emptyRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
@@ -92,10 +92,6 @@ A target field may be a bool, any native int/uint type, any native float type, a
 */
 func (rb *RowBinder) BindRows(r *sql.Rows, t interface{}) ([]interface{}, error) {
 
-	var err error
-	var columnNames []string
-	var targetScanners map[string]*scanner
-
 	if r == nil {
 		return nil, errors.New("nil *sql.Rows supplied")
 	}
@@ -104,59 +100,135 @@ func (rb *RowBinder) BindRows(r *sql.Rows, t interface{}) ([]interface{}, error)
 		return nil, errors.New("template must be a pointer to a struct")
 	}
 
-	if columnNames, err = r.Columns(); err != nil {
+	scanners, err := rb.columnScanners(r, t)
+
+	if err != nil {
 		return nil, err
 	}
 
-	colCount := len(columnNames)
+	results := make([]interface{}, 0)
 
-	targetScanners = rb.generateTargets(t)
+	for r.Next() {
 
-	scanners := make([]interface{}, colCount)
+		if err := r.Scan(scanners...); err != nil {
+			return nil, err
+		}
 
-	results := make([]interface{}, 0)
+		if built, err := rb.buildAndPopulate(t, scanners); err == nil {
+			results = append(results, built)
+		} else {
+			return nil, err
+		}
 
-	matchedTargets := 0
+	}
 
-	for i, cn := range columnNames {
+	return results, r.Err()
+}
 
-		scanner := targetScanners[cn]
+/*
+BindSlice takes results from a SQL query and appends an element to the slice pointed to by target for each row. The
+target must be a pointer to a slice of structs or a pointer to a slice of pointers to structs. Columns are mapped to fields
+as described in the GoDoc for BindRows.
+*/
+func (rb *RowBinder) BindSlice(r *sql.Rows, target interface{}) error {
 
-		if scanner == nil {
-			return nil, fmt.Errorf("no field available to receive column %s (no matching field name or 'column:' tag)", cn)
-		}
+	if r == nil {
+		return errors.New("nil *sql.Rows supplied")
+	}
 
-		scanners[i] = scanner
-		matchedTargets++
+	tv := reflect.ValueOf(target)
+
+	if tv.Kind() != reflect.Ptr || tv.IsNil() || tv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("target must be a pointer to a slice. Is %T", target)
+	}
+
+	sv := tv.Elem()
+	et := sv.Type().Elem()
 
+	byPointer := et.Kind() == reflect.Ptr
+
+	if byPointer {
+		et = et.Elem()
 	}
 
-	if matchedTargets != colCount {
-		return nil, fmt.Errorf("not all of the columns in the results could be matched to fields on the template")
+	if et.Kind() != reflect.Struct {
+		return fmt.Errorf("target must be a pointer to a slice of structs or pointers to structs. Is %T", target)
+	}
+
+	row := reflect.New(et)
+
+	scanners, err := rb.columnScanners(r, row.Interface())
+
+	if err != nil {
+		return err
 	}
 
 	for r.Next() {
 
 		if err := r.Scan(scanners...); err != nil {
-			return nil, err
+			return err
 		}
 
-		if built, err := rb.buildAndPopulate(t, scanners); err == nil {
-			results = append(results, built)
+		if byPointer {
+			row = reflect.New(et)
+		}
+
+		if err := rb.populate(row.Interface(), scanners); err != nil {
+			return err
+		}
+
+		if byPointer {
+			sv.Set(reflect.Append(sv, row))
 		} else {
-			return nil, err
+			sv.Set(reflect.Append(sv, row.Elem()))
+		}
+	}
+
+	return r.Err()
+}
+
+// columnScanners matches each of the columns in the results to a field on the supplied pointer to a struct and returns
+// a scanner for each column that will receive that column's value for the current row.
+func (rb *RowBinder) columnScanners(r *sql.Rows, t interface{}) ([]interface{}, error) {
+
+	columnNames, err := r.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
+	targetScanners := rb.generateTargets(t)
+
+	scanners := make([]interface{}, len(columnNames))
+
+	for i, cn := range columnNames {
+
+		scanner := targetScanners[cn]
+
+		if scanner == nil {
+			return nil, fmt.Errorf("no field available to receive column %s (no matching field name or 'column:' tag)", cn)
 		}
 
+		scanners[i] = scanner
+
 	}
 
-	return results, nil
+	return scanners, nil
 }
 
-func (rb *RowBinder) buildAndPopulate(t interface{}, scanners []interface{}) (r interface{}, err error) {
+func (rb *RowBinder) buildAndPopulate(t interface{}, scanners []interface{}) (interface{}, error) {
 
-	r = reflect.New(reflect.TypeOf(t).Elem()).Interface()
+	r := reflect.New(reflect.TypeOf(t).Elem()).Interface()
 
-	rv := reflect.ValueOf(r).Elem()
+	return r, rb.populate(r, scanners)
+
+}
+
+// populate copies the values most recently scanned into the supplied scanners into the fields of t (which must be a pointer
+// to a struct). Fields whose column value was NULL are set to their zero value so that t can be re-used for each row.
+func (rb *RowBinder) populate(t interface{}, scanners []interface{}) (err error) {
+
+	rv := reflect.ValueOf(t).Elem()
 
 	for _, s := range scanners {
 
@@ -164,22 +236,24 @@ func (rb *RowBinder) buildAndPopulate(t interface{}, scanners []interface{}) (r
 
 		f := rv.FieldByName(v.field)
 
-		if v.val != nil {
+		if v.val == nil {
+			f.Set(reflect.Zero(f.Type()))
+			continue
+		}
 
-			pv := reflect.ValueOf(v.val)
+		pv := reflect.ValueOf(v.val)
 
-			defer func() {
-				if r := recover(); r != nil {
-					err = fmt.Errorf("unable to set field %s with value of type %T", v.field, pv.Interface())
-				}
-			}()
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("unable to set field %s with value of type %T", v.field, pv.Interface())
+			}
+		}()
 
-			f.Set(pv)
-		}
+		f.Set(pv)
 
 	}
 
-	return r, err
+	return err
 
 }
 
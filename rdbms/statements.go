@@ -0,0 +1,185 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// executableQuery is a query built from a template along with any arguments that need to be passed to the driver.
+type executableQuery struct {
+	qid       string
+	text      string
+	args      []interface{}
+	cacheable bool
+}
+
+// statementCache holds prepared statements for parameterised queries, keyed by the database they were prepared
+// against and query ID. It is shared by all of the ManagedClients created by a ClientManager.
+type statementCache struct {
+	statements map[statementKey]*cachedStatement
+	mux        sync.Mutex
+}
+
+type statementKey struct {
+	db  *sql.DB
+	qid string
+}
+
+type cachedStatement struct {
+	text string
+	stmt *sql.Stmt
+
+	// The number of queries currently executing the statement
+	users int
+	// Set when the statement has been removed from the cache, so it is closed when its last user has finished with it
+	replaced bool
+}
+
+func newStatementCache() *statementCache {
+	sc := new(statementCache)
+	sc.statements = make(map[statementKey]*cachedStatement)
+
+	return sc
+}
+
+// find returns a prepared statement for the supplied query, preparing and caching it if it has not been seen before. If the text of the
+// query associated with the query ID has changed, the old statement is replaced and closed once any queries still executing it
+// have finished. The returned function must be called when the caller has finished executing the statement.
+//
+// Statements are prepared without holding the cache's lock, so a slow prepare does not hold up other queries. If two
+// callers prepare the same query at the same time, the statement prepared by the second to finish is closed and the first is used.
+func (sc *statementCache) find(ctx context.Context, db *sql.DB, qid, text string) (*sql.Stmt, func(), error) {
+
+	k := statementKey{db: db, qid: qid}
+
+	if cs := sc.use(k, text); cs != nil {
+		return cs.stmt, func() { sc.release(cs) }, nil
+	}
+
+	stmt, err := db.PrepareContext(ctx, text)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	cs := sc.statements[k]
+
+	if cs != nil && cs.text == text {
+		// Prepared by another caller while this statement was being prepared
+		stmt.Close()
+	} else {
+		sc.replace(k)
+
+		cs = &cachedStatement{text: text, stmt: stmt}
+		sc.statements[k] = cs
+	}
+
+	cs.users++
+
+	return cs.stmt, func() { sc.release(cs) }, nil
+}
+
+// use returns the cached statement for the supplied key (recording a new user of it) if its text matches the supplied
+// text. Otherwise any cached statement for the key is replaced and nil is returned.
+func (sc *statementCache) use(k statementKey, text string) *cachedStatement {
+
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	cs := sc.statements[k]
+
+	if cs != nil && cs.text == text {
+		cs.users++
+		return cs
+	}
+
+	sc.replace(k)
+
+	return nil
+}
+
+// replace removes the statement cached for the supplied key (if any), closing it once no queries are executing it.
+// Must be called while holding the cache's lock.
+func (sc *statementCache) replace(k statementKey) {
+
+	if cs := sc.statements[k]; cs != nil {
+		delete(sc.statements, k)
+		cs.replaced = true
+		sc.closeUnused(cs)
+	}
+}
+
+// release records that a query has finished executing a statement.
+func (sc *statementCache) release(cs *cachedStatement) {
+
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	cs.users--
+	sc.closeUnused(cs)
+}
+
+// closeUnused closes a statement that has been removed from the cache once no queries are executing it.
+func (sc *statementCache) closeUnused(cs *cachedStatement) {
+
+	if cs.replaced && cs.users == 0 {
+		cs.stmt.Close()
+	}
+}
+
+// size returns the number of statements currently cached.
+func (sc *statementCache) size() int {
+
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	return len(sc.statements)
+}
+
+// close closes and discards all cached statements.
+func (sc *statementCache) close() {
+
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	for k, cs := range sc.statements {
+		cs.stmt.Close()
+		delete(sc.statements, k)
+	}
+}
+
+// boundArgsClient is passed to InsertWithReturnedID functions so that, when a parameterised query is being executed, the
+// arguments for the query's placeholders are supplied to the driver even though the function is only given the query text.
+// Statements executed via a boundArgsClient are not observed separately, as they are part of the InsertCaptureQIDParams call.
+type boundArgsClient struct {
+	*ManagedClient
+	args []interface{}
+}
+
+// Exec passes the bound arguments (followed by any supplied arguments) to ManagedClient.Exec
+func (bc *boundArgsClient) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return bc.ManagedClient.exec(query, bc.withBound(args)...)
+}
+
+// Query passes the bound arguments (followed by any supplied arguments) to ManagedClient.Query
+func (bc *boundArgsClient) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return bc.ManagedClient.query(query, bc.withBound(args)...)
+}
+
+// QueryRow passes the bound arguments (followed by any supplied arguments) to ManagedClient.QueryRow
+func (bc *boundArgsClient) QueryRow(query string, args ...interface{}) *sql.Row {
+	return bc.ManagedClient.queryRow(query, bc.withBound(args)...)
+}
+
+func (bc *boundArgsClient) withBound(args []interface{}) []interface{} {
+	a := make([]interface{}, 0, len(bc.args)+len(args))
+
+	return append(append(a, bc.args...), args...)
+}
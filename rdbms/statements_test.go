@@ -0,0 +1,88 @@
+package rdbms
+
+import (
+	"context"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+)
+
+func TestStatementReplacedWhileInUse(t *testing.T) {
+
+	sc := newStatementCache()
+	ctx := context.Background()
+
+	old, releaseOld, err := sc.find(ctx, db, "Q", "SELECT 1")
+	test.ExpectNil(t, err)
+
+	// The text of the query changes (e.g. a template is reloaded) while the old statement is still being executed
+	replacement, releaseReplacement, err := sc.find(ctx, db, "Q", "SELECT 2")
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, old != replacement, true)
+	test.ExpectInt(t, sc.size(), 1)
+
+	_, err = old.ExecContext(ctx)
+	test.ExpectNil(t, err)
+
+	releaseOld()
+
+	_, err = old.ExecContext(ctx)
+	test.ExpectNotNil(t, err)
+
+	releaseReplacement()
+
+	// Statements still in the cache stay open when released
+	_, err = replacement.ExecContext(ctx)
+	test.ExpectNil(t, err)
+
+	same, release, _ := sc.find(ctx, db, "Q", "SELECT 2")
+	test.ExpectBool(t, same == replacement, true)
+	release()
+
+	sc.close()
+	test.ExpectInt(t, sc.size(), 0)
+}
+
+func TestSlowPrepareDoesNotBlockCache(t *testing.T) {
+
+	sc := newStatementCache()
+	ctx := context.Background()
+
+	drv.slowPrepare = make(chan struct{})
+	drv.slowPrepareStarted = make(chan struct{})
+
+	defer func() {
+		drv.slowPrepare = nil
+		drv.slowPrepareStarted = nil
+	}()
+
+	cached, releaseCached, err := sc.find(ctx, db, "FAST", "SELECT 1")
+	test.ExpectNil(t, err)
+
+	done := make(chan error)
+
+	go func() {
+		_, release, err := sc.find(ctx, db, "SLOW", "SELECT "+slowPrepareText)
+
+		if err == nil {
+			release()
+		}
+
+		done <- err
+	}()
+
+	<-drv.slowPrepareStarted
+
+	// Cached statements can be found and released while another statement is being prepared
+	same, release, err := sc.find(ctx, db, "FAST", "SELECT 1")
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, same == cached, true)
+
+	release()
+	releaseCached()
+
+	close(drv.slowPrepare)
+	test.ExpectNil(t, <-done)
+	test.ExpectInt(t, sc.size(), 2)
+
+	sc.close()
+}
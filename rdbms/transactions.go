@@ -0,0 +1,257 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const savepointPrefix = "grnc_sp_"
+
+// TransactionDialect is an optional interface for DatabaseProvider implementations that are able to recognise the
+// errors their database returns when a transaction fails because of contention with other transactions.
+type TransactionDialect interface {
+	// RetryableTransactionError returns true if the supplied error indicates that a transaction failed because of a
+	// serialisation failure or a deadlock and might succeed if it is retried.
+	RetryableTransactionError(err error) bool
+}
+
+// transactionRetry controls how WithTransaction retries transactions that fail with a retryable error.
+type transactionRetry struct {
+	// Returns true if the error should cause the transaction to be retried. If nil, transactions are never retried.
+	retryable func(error) bool
+
+	// The number of times a transaction will be retried after its first attempt.
+	max int
+
+	// The delay before the first retry. The delay doubles for each subsequent retry.
+	backoff time.Duration
+}
+
+// WithTransaction calls the supplied function inside a transaction. The transaction is committed if the function returns
+// nil and rolled back if the function returns an error or panics (in which case the panic is propagated once the
+// transaction has been rolled back).
+//
+// If a transaction is already open, the function is executed in a nested transaction (see StartTransaction).
+// Otherwise, if the DatabaseProvider implements TransactionDialect and the transaction fails with an error the provider
+// considers retryable (e.g. a deadlock), the whole transaction is retried after a delay, up to the number of times set
+// in ClientManagerConfig.TransactionRetries. The function must therefore be safe to call more than once.
+func (rc *ManagedClient) WithTransaction(fn func(Client) error) error {
+
+	if rc.tx != nil {
+		return rc.inTransaction(fn)
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		err := rc.inTransaction(fn)
+
+		if err == nil || attempt >= rc.retry.max || rc.retry.retryable == nil || !rc.retry.retryable(err) {
+			return err
+		}
+
+		delay := rc.retry.backoff << uint(attempt)
+
+		if delay > 0 {
+			delay += time.Duration(rand.Int63n(int64(delay)))
+		}
+
+		rc.FrameworkLogger.LogDebugf("Retrying transaction in %v after %s", delay, err.Error())
+
+		if err := rc.pause(delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (rc *ManagedClient) inTransaction(fn func(Client) error) (err error) {
+
+	tx, err := rc.BeginTransaction()
+
+	if err != nil {
+		return err
+	}
+
+	// Does nothing if the transaction is committed. Panics are propagated after the rollback.
+	defer tx.Rollback()
+
+	if err = fn(rc); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// pause waits for the supplied duration, returning early with an error if the client's context is cancelled.
+func (rc *ManagedClient) pause(d time.Duration) error {
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-rc.context().Done():
+		return rc.context().Err()
+	}
+}
+
+// Transaction is a handle on a transaction (or nested transaction) started with Client.BeginTransaction. Unlike
+// Client.Rollback and Client.CommitTransaction, which apply to the innermost open transaction, its methods only ever
+// apply to the transaction the handle was returned for, so:
+//
+//	tx, err := db.BeginTransaction()
+//	defer tx.Rollback()
+//
+// is safe in code that might be called while another transaction is open.
+type Transaction interface {
+	// Commit commits the transaction. Returns an error if the transaction has already been committed or rolled back or
+	// if a transaction nested in it is still open.
+	Commit() error
+
+	// Rollback rolls back the transaction, along with any transactions nested in it that are still open. Does nothing if
+	// the transaction has already been committed or rolled back.
+	Rollback()
+}
+
+// managedTransaction is the Transaction returned by ManagedClient.BeginTransaction
+type managedTransaction struct {
+	rc    *ManagedClient
+	level *txLevel
+}
+
+// Commit implements Transaction.Commit
+func (mt *managedTransaction) Commit() error {
+
+	rc := mt.rc
+
+	switch rc.levelIndex(mt.level) {
+	case -1:
+		return errors.New("Transaction has already been committed or rolled back")
+	case len(rc.levels) - 1:
+		return rc.commitLevel()
+	default:
+		return errors.New("Cannot commit a transaction while a transaction nested in it is open")
+	}
+}
+
+// Rollback implements Transaction.Rollback
+func (mt *managedTransaction) Rollback() {
+
+	if mt.rc.levelIndex(mt.level) >= 0 {
+		mt.rc.rollbackLevel(mt.level)
+	}
+}
+
+// txLevel is one level of a (possibly nested) transaction. The outermost level has no savepoint.
+type txLevel struct {
+	savepoint string
+}
+
+// begin starts a transaction, or a nested transaction if a transaction is already open, and returns its level. The
+// supplied options are only used (if useOpts is set) for a transaction that is not nested.
+func (rc *ManagedClient) begin(opts *sql.TxOptions, useOpts bool) (*txLevel, error) {
+
+	if rc.tx != nil {
+		return rc.savepoint()
+	}
+
+	var tx *sql.Tx
+	var err error
+
+	if useOpts {
+		tx, err = rc.db.BeginTx(rc.context(), opts)
+	} else {
+		tx, err = rc.db.Begin()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	level := new(txLevel)
+
+	rc.tx = tx
+	rc.levels = []*txLevel{level}
+
+	return level, nil
+}
+
+// endOutermost records that the outermost transaction has been committed or rolled back.
+func (rc *ManagedClient) endOutermost() {
+	rc.tx = nil
+	rc.levels = nil
+}
+
+// levelIndex returns the position of the supplied level in the open transaction, or -1 if it is no longer open.
+func (rc *ManagedClient) levelIndex(level *txLevel) int {
+
+	for i, l := range rc.levels {
+		if l == level {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// commitLevel commits the innermost level of the open transaction.
+func (rc *ManagedClient) commitLevel() error {
+
+	n := len(rc.levels) - 1
+	level := rc.levels[n]
+
+	if level.savepoint == "" {
+		err := rc.tx.Commit()
+		rc.endOutermost()
+
+		return err
+	}
+
+	if _, err := rc.tx.ExecContext(rc.context(), "RELEASE SAVEPOINT "+level.savepoint); err != nil {
+		// The savepoint still exists, so a following Rollback rolls back to it
+		return err
+	}
+
+	rc.levels = rc.levels[:n]
+
+	return nil
+}
+
+// rollbackLevel rolls back the supplied level of the open transaction, discarding any levels nested in it.
+func (rc *ManagedClient) rollbackLevel(level *txLevel) {
+
+	if level.savepoint == "" {
+		rc.tx.Rollback()
+		rc.endOutermost()
+
+		return
+	}
+
+	rc.levels = rc.levels[:rc.levelIndex(level)]
+
+	if _, err := rc.tx.ExecContext(rc.context(), "ROLLBACK TO SAVEPOINT "+level.savepoint); err != nil {
+		rc.FrameworkLogger.LogErrorf("Unable to roll back to savepoint %s: %s", level.savepoint, err.Error())
+	}
+}
+
+// savepoint starts a nested transaction by creating a savepoint in the open transaction.
+func (rc *ManagedClient) savepoint() (*txLevel, error) {
+
+	rc.savepointSeq++
+	sp := fmt.Sprintf("%s%d", savepointPrefix, rc.savepointSeq)
+
+	if _, err := rc.tx.ExecContext(rc.context(), "SAVEPOINT "+sp); err != nil {
+		return nil, err
+	}
+
+	level := &txLevel{savepoint: sp}
+	rc.levels = append(rc.levels, level)
+
+	return level, nil
+}
@@ -0,0 +1,252 @@
+package rdbms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+	"time"
+)
+
+var errDeadlock = errors.New("deadlock detected")
+
+func TestNestedTransactions(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+	drv.prepared = nil
+
+	test.ExpectNil(t, c.StartTransaction())
+
+	test.ExpectNil(t, c.StartTransaction())
+	test.ExpectNil(t, c.CommitTransaction())
+
+	test.ExpectNil(t, c.StartTransaction())
+	c.Rollback()
+
+	test.ExpectBool(t, c.tx != nil, true)
+	test.ExpectNil(t, c.CommitTransaction())
+	test.ExpectBool(t, c.tx == nil, true)
+
+	expected := []string{"SAVEPOINT grnc_sp_1", "RELEASE SAVEPOINT grnc_sp_1", "SAVEPOINT grnc_sp_2", "ROLLBACK TO SAVEPOINT grnc_sp_2"}
+
+	test.ExpectInt(t, len(drv.prepared), len(expected))
+
+	for i, q := range expected {
+		test.ExpectString(t, drv.prepared[i], q)
+	}
+
+	c.Rollback()
+	test.ExpectNotNil(t, c.CommitTransaction())
+}
+
+func TestWithTransaction(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+
+	err := c.WithTransaction(func(tc Client) error {
+
+		test.ExpectBool(t, tc.(*ManagedClient).tx != nil, true)
+
+		return tc.WithTransaction(func(Client) error {
+			return errors.New("nested")
+		})
+	})
+
+	test.ExpectString(t, err.Error(), "nested")
+	test.ExpectBool(t, c.tx == nil, true)
+
+	err = c.WithTransaction(func(tc Client) error {
+
+		tc.WithTransaction(func(Client) error {
+			return errors.New("nested")
+		})
+
+		return nil
+	})
+
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, c.tx == nil, true)
+
+	func() {
+		defer func() {
+			test.ExpectBool(t, recover() != nil, true)
+		}()
+
+		c.WithTransaction(func(Client) error {
+			panic("failed")
+		})
+	}()
+
+	test.ExpectBool(t, c.tx == nil, true)
+
+	// Retryable errors
+	calls := 0
+
+	c.retry = transactionRetry{retryable: func(err error) bool { return err == errDeadlock }, max: 2, backoff: time.Millisecond}
+
+	err = c.WithTransaction(func(Client) error {
+		calls++
+		return errDeadlock
+	})
+
+	test.ExpectBool(t, err == errDeadlock, true)
+	test.ExpectInt(t, calls, 3)
+
+	calls = 0
+
+	err = c.WithTransaction(func(Client) error {
+		calls++
+
+		if calls == 1 {
+			return errDeadlock
+		}
+
+		return nil
+	})
+
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, calls, 2)
+
+	calls = 0
+
+	err = c.WithTransaction(func(Client) error {
+		calls++
+		return errors.New("not retryable")
+	})
+
+	test.ExpectNotNil(t, err)
+	test.ExpectInt(t, calls, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c.ctx = ctx
+	c.retry.backoff = time.Hour
+
+	err = c.WithTransaction(func(Client) error {
+		return errDeadlock
+	})
+
+	test.ExpectBool(t, err == context.Canceled, true)
+}
+
+func TestNestedCommitThenOuterError(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+	drv.prepared = nil
+
+	calls := 0
+	c.retry = transactionRetry{retryable: func(err error) bool { return err == errDeadlock }, max: 1}
+
+	err := c.WithTransaction(func(tc Client) error {
+
+		calls++
+
+		// Each attempt must start a new outermost transaction rather than a savepoint in a stale one
+		test.ExpectInt(t, len(tc.(*ManagedClient).levels), 1)
+
+		test.ExpectNil(t, tc.WithTransaction(func(Client) error {
+			return nil
+		}))
+
+		return errDeadlock
+	})
+
+	test.ExpectBool(t, err == errDeadlock, true)
+	test.ExpectInt(t, calls, 2)
+	test.ExpectBool(t, c.tx == nil, true)
+
+	// The same sequence using deferred rollbacks of transaction handles
+	func() {
+		outer, err := c.BeginTransaction()
+		test.ExpectNil(t, err)
+		defer outer.Rollback()
+
+		func() {
+			inner, err := c.BeginTransaction()
+			test.ExpectNil(t, err)
+			defer inner.Rollback()
+
+			test.ExpectNil(t, inner.Commit())
+		}()
+
+		test.ExpectBool(t, c.tx != nil, true)
+		test.ExpectInt(t, len(c.levels), 1)
+	}()
+
+	test.ExpectBool(t, c.tx == nil, true)
+}
+
+func TestRollbackAfterNestedCommit(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+
+	test.ExpectNil(t, c.StartTransaction())
+	test.ExpectNil(t, c.StartTransaction())
+	test.ExpectNil(t, c.CommitTransaction())
+
+	// Applies to the outer transaction, which is now the innermost open transaction
+	c.Rollback()
+
+	test.ExpectBool(t, c.tx == nil, true)
+	test.ExpectInt(t, len(c.levels), 0)
+}
+
+func TestTransactionHandles(t *testing.T) {
+
+	c := newRdbmsClient(db, qm, DefaultInsertWithReturnedID, logging.CreateAnonymousLogger("testLog", logging.Fatal))
+	drv.prepared = nil
+
+	outer, _ := c.BeginTransaction()
+	inner, _ := c.BeginTransaction()
+	innermost, _ := c.BeginTransaction()
+
+	// Transactions cannot be committed while a transaction nested in them is open
+	test.ExpectNotNil(t, inner.Commit())
+
+	// Rolling back a transaction rolls back the transactions nested in it
+	inner.Rollback()
+	test.ExpectInt(t, len(c.levels), 1)
+
+	test.ExpectNotNil(t, innermost.Commit())
+	innermost.Rollback()
+	inner.Rollback()
+
+	test.ExpectInt(t, len(drv.prepared), 3)
+	test.ExpectString(t, drv.prepared[2], "ROLLBACK TO SAVEPOINT grnc_sp_1")
+
+	test.ExpectNil(t, outer.Commit())
+	test.ExpectBool(t, c.tx == nil, true)
+
+	test.ExpectNotNil(t, outer.Commit())
+	outer.Rollback()
+}
+
+func TestTransactionDialect(t *testing.T) {
+
+	m := new(GraniticRdbmsClientManager)
+	m.QueryManager = qm
+	m.SharedLog = logging.CreateAnonymousLogger("testLog", logging.Fatal)
+	m.Configuration = &ClientManagerConfig{Provider: new(dialectProvider), TransactionRetries: 4, TransactionRetryBackoffMilliseconds: 10}
+
+	m.StartComponent()
+
+	cl, _ := m.Client()
+	c := cl.(*ManagedClient)
+
+	test.ExpectInt(t, c.retry.max, 4)
+	test.ExpectBool(t, c.retry.backoff == 10*time.Millisecond, true)
+	test.ExpectBool(t, c.retry.retryable(errDeadlock), true)
+}
+
+type dialectProvider struct{}
+
+func (dp *dialectProvider) Database() (*sql.DB, error) {
+	return db, nil
+}
+
+func (dp *dialectProvider) RetryableTransactionError(err error) bool {
+	return err == errDeadlock
+}
@@ -0,0 +1,120 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbms
+
+import (
+	"context"
+	"fmt"
+	"github.com/graniticio/granitic/v2/dsquery"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const defaultVerifyTimeout = 30 * time.Second
+
+// The value given to every variable when a query is built for verification. The value is not sent to the database, as
+// the query is only prepared.
+const verificationValue = "0"
+
+// Queries whose first word is not one of these are assumed to be fragments used to build other queries and are not verified
+var statementStart = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|WITH|REPLACE|MERGE|CALL)\b`)
+
+// queryVerification records the outcome of verifying the QueryManager's queries against the database
+type queryVerification struct {
+	complete bool
+	err      error
+}
+
+// verifyQueries prepares every statement held by the QueryManager against the primary database (without executing it)
+// so that syntax errors and references to unknown tables and columns are found before the application becomes accessible.
+// Queries are built with every variable set, so the content of [[unless]] blocks is not verified.
+func (cm *GraniticRdbmsClientManager) verifyQueries() error {
+
+	v := &cm.verification
+
+	if v.complete {
+		return v.err
+	}
+
+	qi, inspectable := cm.QueryManager.(dsquery.QueryInspector)
+	pqm, parameterised := cm.QueryManager.(dsquery.ParameterisedQueryManager)
+
+	if !inspectable || !parameterised {
+		cm.FrameworkLogger.LogWarnf("Queries cannot be verified as the QueryManager (%T) does not implement dsquery.QueryInspector and dsquery.ParameterisedQueryManager", cm.QueryManager)
+		v.complete = true
+
+		return nil
+	}
+
+	style := dsquery.QuestionMarkPlaceholders
+
+	if ps := cm.Configuration.PlaceholderStyle; ps != "" {
+
+		var err error
+
+		if style, err = dsquery.PlaceholderStyleFromName(ps); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultVerifyTimeout)
+	defer cancel()
+
+	db, err := providerDatabase(ctx, cm.Configuration.Provider)
+
+	if err != nil {
+		return fmt.Errorf("unable to verify queries: %s", err.Error())
+	}
+
+	failures := make([]string, 0)
+	verified := 0
+
+	for _, qid := range qi.QueryIDs() {
+
+		vars, err := qi.QueryVariables(qid)
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", qid, err.Error()))
+			continue
+		}
+
+		params := make(map[string]interface{})
+
+		for _, name := range vars {
+			params[name] = verificationValue
+		}
+
+		pq, err := pqm.BuildParameterisedQueryFromID(qid, params, style)
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", qid, err.Error()))
+			continue
+		}
+
+		if !statementStart.MatchString(pq.Query) {
+			continue
+		}
+
+		stmt, err := db.PrepareContext(ctx, pq.Query)
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", qid, err.Error()))
+			continue
+		}
+
+		stmt.Close()
+		verified++
+	}
+
+	v.complete = true
+
+	if len(failures) > 0 {
+		v.err = fmt.Errorf("%d queries were rejected by the database:\n%s", len(failures), strings.Join(failures, "\n"))
+	} else {
+		cm.FrameworkLogger.LogInfof("Verified %d queries against the database", verified)
+	}
+
+	return v.err
+}
@@ -0,0 +1,67 @@
+package rdbms
+
+import (
+	"github.com/graniticio/granitic/v2/dsquery"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"strings"
+	"testing"
+)
+
+func TestVerifyQueries(t *testing.T) {
+
+	tqm := templatedQueryManager(t)
+
+	m := new(GraniticRdbmsClientManager)
+	m.FrameworkLogger = logging.CreateAnonymousLogger("testLog", logging.Fatal)
+	m.QueryManager = tqm
+	m.Configuration = &ClientManagerConfig{Provider: new(testDBProvider), VerifyQueries: true, PlaceholderStyle: "NUMBERED"}
+
+	drv.prepared = nil
+	defer func() { drv.prepared = nil }()
+
+	block, err := m.BlockAccess()
+	test.ExpectBool(t, block, false)
+	test.ExpectNil(t, err)
+
+	// Fragments are not prepared
+	test.ExpectInt(t, len(drv.prepared), 2)
+	test.ExpectString(t, drv.prepared[0], "INSERT INTO artist (name, formed) VALUES ($1, $2)\n")
+	test.ExpectBool(t, strings.Contains(drv.prepared[1], "WHERE name = $1\n  AND id IN ($2)"), true)
+
+	drv.rejectPrepare = "formed"
+	defer func() { drv.rejectPrepare = "" }()
+
+	// The outcome of verification is retained
+	block, _ = m.BlockAccess()
+	test.ExpectBool(t, block, false)
+
+	m.verification = queryVerification{}
+
+	block, err = m.BlockAccess()
+	test.ExpectBool(t, block, true)
+	test.ExpectString(t, err.Error(), "1 queries were rejected by the database:\nARTIST_INSERT: unknown column formed")
+
+	block, _ = m.BlockAccess()
+	test.ExpectBool(t, block, true)
+
+	m.Configuration.VerifyQueries = false
+	block, _ = m.BlockAccess()
+	test.ExpectBool(t, block, false)
+}
+
+func templatedQueryManager(t *testing.T) *dsquery.TemplatedQueryManager {
+
+	tqm := dsquery.NewTemplatedQueryManager()
+	tqm.TemplateLocation = test.FilePath("verify")
+	tqm.QueryIDPrefix = "ID:"
+	tqm.TrimIDWhiteSpace = true
+	tqm.VarMatchRegEx = "\\$\\{([^\\}]*)\\}"
+	tqm.ElementSeparator = ", "
+	tqm.ValueProcessor = new(dsquery.SQLProcessor)
+	tqm.FrameworkLogger = logging.CreateAnonymousLogger("qm", logging.Fatal)
+
+	test.ExpectNil(t, tqm.StartComponent())
+
+	return tqm
+}
@@ -0,0 +1,123 @@
+// Copyright 2018-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Values for Task.CatchUp
+const (
+	// CatchUpSkip means scheduled runs missed while the application was not running are ignored
+	CatchUpSkip = "SKIP"
+	// CatchUpOnce means the task runs once at startup if any scheduled runs were missed
+	CatchUpOnce = "ONCE"
+	// CatchUpAll means the task runs once at startup for each scheduled run that was missed (up to the most recent maxCatchUpRuns)
+	CatchUpAll = "ALL"
+)
+
+// The maximum number of missed runs that will be caught up with the ALL policy
+const maxCatchUpRuns = 100
+
+func validCatchUp(policy string) (string, error) {
+
+	switch p := strings.ToUpper(policy); p {
+	case "":
+		return CatchUpSkip, nil
+	case CatchUpSkip, CatchUpOnce, CatchUpAll:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("The 'CatchUp' field must be set to %s, %s or %s", CatchUpSkip, CatchUpOnce, CatchUpAll)
+}
+
+// missedRuns returns the most recent times (up to limit, oldest first) the task should have run between the last
+// scheduled run recorded in the task's history and the supplied time, along with the first time the task should have run
+// and whether more than limit runs were missed. Missed runs are found by working backwards from the supplied time, so
+// the cost does not depend on how many runs were missed.
+func (im *invocationManager) missedRuns(before time.Time, limit int) (missed []time.Time, first time.Time, more bool, err error) {
+
+	last, err := im.history.LastScheduledRun(im.Task.ID)
+
+	if err != nil || last == nil || last.ScheduledFor.IsZero() {
+		return nil, first, false, err
+	}
+
+	first = im.Interval.nextAfter(last.ScheduledFor)
+
+	if first.IsZero() || !first.Before(before) {
+		return nil, first, false, nil
+	}
+
+	missed = make([]time.Time, 0)
+
+	for t := im.Interval.lastBefore(first, before); !t.IsZero() && !t.Before(first); t = im.Interval.previous(t) {
+
+		if len(missed) == limit {
+			more = true
+			break
+		}
+
+		missed = append(missed, t)
+	}
+
+	for i, j := 0, len(missed)-1; i < j; i, j = i+1, j-1 {
+		missed[i], missed[j] = missed[j], missed[i]
+	}
+
+	return missed, first, more, nil
+}
+
+// queueCatchUp adds invocations to replace scheduled runs missed while the application was not running, according to
+// the task's CatchUp policy.
+func (im *invocationManager) queueCatchUp(now time.Time) {
+
+	task := im.Task
+
+	if im.history == nil {
+		return
+	}
+
+	limit := 1
+
+	if task.CatchUp == CatchUpAll {
+		limit = maxCatchUpRuns
+	}
+
+	missed, first, more, err := im.missedRuns(now, limit)
+
+	if err != nil {
+		im.Log.LogErrorf("Unable to check history of task %s for missed runs: %s", task.FullName(), err.Error())
+		return
+	}
+
+	if len(missed) == 0 {
+		return
+	}
+
+	switch task.CatchUp {
+	case CatchUpOnce:
+		// Only the most recent missed run was found
+	case CatchUpAll:
+		if more {
+			im.Log.LogWarnf("Task %s missed more than %d scheduled runs (first at %s) - only the most recent %d will be run", task.FullName(), maxCatchUpRuns, first.Format(firstRunFormat), maxCatchUpRuns)
+		}
+	default:
+		im.Log.LogWarnf("Task %s missed scheduled run(s) between %s and %s while the application was not running", task.FullName(), first.Format(firstRunFormat), missed[len(missed)-1].Format(firstRunFormat))
+		return
+	}
+
+	im.Log.LogInfof("Task %s will run %d time(s) to catch up with runs missed while the application was not running", task.FullName(), len(missed))
+
+	for _, t := range missed {
+
+		i := newInvocation(im.nextCounter(), task.MaxRetries, CatchUp)
+		i.runAt = now
+		i.scheduledFor = t
+
+		im.scheduled.EnqueueAtTail(i)
+	}
+}
@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"context"
+	"github.com/graniticio/granitic/v2/instrument"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskTimeout(t *testing.T) {
+
+	ms := new(MemoryHistoryStore)
+	logic := &blockingLogic{block: true}
+
+	im := newInvocationManager(&Task{ID: "t", contextLogic: logic, timeout: 10 * time.Millisecond, MaxRetries: 1})
+	im.Log = logging.CreateAnonymousLogger("timeout", logging.Fatal)
+	im.history = ms
+
+	im.runTask(newInvocation(im.nextCounter(), 0, Manual))
+
+	runs, _ := ms.Runs("t", 1)
+	test.ExpectInt(t, len(runs), 1)
+	test.ExpectString(t, runs[0].Outcome, Cancelled)
+	test.ExpectBool(t, strings.HasPrefix(runs[0].StatusMessage, "timed out after 10ms"), true)
+
+	test.ExpectBool(t, logic.instrumented, true)
+	test.ExpectInt(t, len(im.cancels), 0)
+}
+
+func TestTaskCancel(t *testing.T) {
+
+	ms := new(MemoryHistoryStore)
+	logic := &blockingLogic{block: true, started: make(chan bool, 2)}
+
+	im := newInvocationManager(&Task{ID: "t", contextLogic: logic})
+	im.Log = logging.CreateAnonymousLogger("cancel", logging.Fatal)
+	im.history = ms
+
+	tc := NewTaskCommand(&TaskScheduler{managedTasks: []*invocationManager{im}})
+
+	_, errs := tc.ExecuteCommand([]string{"t", "cancel"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+
+	done := make(chan bool)
+
+	go func() {
+		im.runTask(newInvocation(im.nextCounter(), 0, Scheduled))
+		close(done)
+	}()
+
+	<-logic.started
+
+	_, errs = tc.ExecuteCommand([]string{"t", "cancel"}, nil)
+	test.ExpectInt(t, len(errs), 0)
+
+	<-done
+
+	runs, _ := ms.Runs("t", 1)
+	test.ExpectString(t, runs[0].Outcome, Cancelled)
+	test.ExpectBool(t, strings.HasPrefix(runs[0].StatusMessage, "cancelled"), true)
+
+	// Stopping the scheduler cancels running invocations
+	done = make(chan bool)
+
+	go func() {
+		im.runTask(newInvocation(im.nextCounter(), 0, Scheduled))
+		close(done)
+	}()
+
+	<-logic.started
+	im.PrepareToStop()
+	<-done
+
+	ready, _ := im.ReadyToStop()
+	test.ExpectBool(t, ready, true)
+
+	// Tasks that are not context aware cannot be cancelled
+	im = newInvocationManager(&Task{ID: "plain", logic: new(nullLogic)})
+
+	_, errs = NewTaskCommand(&TaskScheduler{managedTasks: []*invocationManager{im}}).ExecuteCommand([]string{"plain", "cancel"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+}
+
+func TestTaskInstrumentation(t *testing.T) {
+
+	ts := new(TaskScheduler)
+	ts.FrameworkLogger = new(logging.ConsoleErrorLogger)
+	ts.FrameworkLogManager = logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	logic := new(blockingLogic)
+	tim := new(recordingInstrumentationManager)
+
+	ts.componentContainer = &mockComponentsContainer{components: []*ioc.Component{
+		ioc.NewComponent("task", &Task{ID: "t", Component: "logic", Every: "1 hour", Timeout: "1 second"}),
+		ioc.NewComponent("logic", logic),
+		ioc.NewComponent("instrumentation", tim),
+	}}
+
+	test.ExpectNil(t, ts.StartComponent())
+
+	im := ts.manager("t")
+	im.runTask(newInvocation(im.nextCounter(), 0, Manual))
+
+	test.ExpectBool(t, logic.instrumented, true)
+	test.ExpectString(t, tim.taskID, "t")
+	test.ExpectBool(t, tim.ended, true)
+
+	// A timeout requires a context aware task
+	ts = new(TaskScheduler)
+	ts.FrameworkLogger = new(logging.ConsoleErrorLogger)
+	ts.FrameworkLogManager = logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	err := ts.validateAndPrepare(&mockLogicContainer{logic: new(nullLogic)}, &Task{ID: "plain", Component: "logic", Every: "1 hour", Timeout: "1 second"})
+	test.ExpectNotNil(t, err)
+}
+
+// blockingLogic runs until its context is cancelled or times out (if block is true)
+type blockingLogic struct {
+	block        bool
+	started      chan bool
+	instrumented bool
+}
+
+func (bl *blockingLogic) ExecuteTaskWithContext(ctx context.Context, c chan TaskStatusUpdate) error {
+
+	bl.instrumented = instrument.InstrumentorFromContext(ctx) != nil
+
+	if bl.started != nil {
+		bl.started <- true
+	}
+
+	if !bl.block {
+		return nil
+	}
+
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+type recordingInstrumentationManager struct {
+	taskID string
+	ended  bool
+}
+
+func (rm *recordingInstrumentationManager) Begin(ctx context.Context, taskID string, invocation uint64) (context.Context, instrument.Instrumentor, func()) {
+	rm.taskID = taskID
+
+	ti := new(noopTaskInstrumentor)
+
+	return instrument.AddInstrumentorToContext(ctx, ti), ti, func() { rm.ended = true }
+}
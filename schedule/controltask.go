@@ -1,25 +1,262 @@
 package schedule
 
 import (
+	"fmt"
+	"github.com/graniticio/granitic/v2/ctl"
 	"github.com/graniticio/granitic/v2/instance"
-	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/ws"
+	"strconv"
+	"strings"
 )
 
 const (
 	//LLComponentName is the name of the component that handles the management of scheduled tasks
 	LLComponentName = instance.FrameworkPrefix + "CommandScheduledTasks"
 	llCommandName   = "task"
-	llSummary       = "Shows information about all scheduled tasks or invokes/suspends a specified task"
-	llUsage         = "task [ID] [invoke|suspend|resume]"
+	llSummary       = "Shows information about all scheduled tasks or invokes/suspends/cancels a specified task"
+	llUsage         = "task [ID] [invoke|suspend|resume|cancel]"
 	llHelp          = "With no qualifier, this command shows a list of scheduled tasks defined for this service."
-	llHelpTwo       = "If a single qualifier is specified, that is assumed to be the ID of a task and more detailed information is shown for that task."
+	llHelpTwo       = "If a single qualifier is specified, that is assumed to be the ID of a task and more detailed information is shown for that task, including the times of its next scheduled runs, the tasks it runs before and after, which instance holds its lock (for Singleton tasks) and the outcome of its most recent runs."
 	llHelpThree     = "If a task ID is specified followed by invoke, that task is will be scheduled to run immediately (but task configuration is respected, so multiple conncurrent invocations might be forbidden."
 	llHelpFour      = "If a task ID is specified followed by suspend, that task will not be executed until resumed."
 	llHelpFive      = "If a task ID is specified followed by resumed, that task will be allowed to run again, if it is currently suspended"
+	llHelpSix       = "If a task ID is specified followed by cancel, the context of each running invocation of that task is cancelled (only for tasks implementing schedule.ContextAwareTaskLogic)."
+
+	invokeAction  = "invoke"
+	suspendAction = "suspend"
+	resumeAction  = "resume"
+	cancelAction  = "cancel"
+
+	upcomingRuns   = 5
+	recentRuns     = 5
+	upcomingFormat = "2006-01-02 15:04:05 MST"
 )
 
+// NewTaskCommand creates the RuntimeCtl command that shows and controls the tasks managed by the supplied scheduler
+func NewTaskCommand(ts *TaskScheduler) ctl.Command {
+	return &taskCommand{scheduler: ts}
+}
+
 type taskCommand struct {
-	FrameworkLogger    logging.Logger
-	FrameworkManager   *logging.ComponentLoggerManager
-	ApplicationManager *logging.ComponentLoggerManager
+	scheduler *TaskScheduler
+}
+
+func (c *taskCommand) ExecuteCommand(qualifiers []string, args map[string]string) (*ctl.CommandOutput, []*ws.CategorisedError) {
+
+	if len(qualifiers) == 0 {
+		return c.listTasks(), nil
+	}
+
+	im := c.scheduler.manager(qualifiers[0])
+
+	if im == nil {
+		return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("There is no task with the ID %s", qualifiers[0]))}
+	}
+
+	if len(qualifiers) == 1 {
+		return c.describeTask(im), nil
+	}
+
+	switch qualifiers[1] {
+	case invokeAction:
+
+		if im.isSuspended() {
+			return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("Task %s is suspended and must be resumed before it can be invoked", im.Task.ID))}
+		}
+
+		im.invoke()
+
+	case suspendAction:
+		im.setSuspended(true)
+
+	case resumeAction:
+		im.setSuspended(false)
+
+	case cancelAction:
+
+		if im.Task.contextLogic == nil {
+			return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("Task %s cannot be cancelled as its component does not implement schedule.ContextAwareTaskLogic", im.Task.ID))}
+		}
+
+		if im.cancel() == 0 {
+			return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("Task %s is not running", im.Task.ID))}
+		}
+
+	default:
+		return nil, []*ws.CategorisedError{ctl.NewCommandClientError(fmt.Sprintf("unknown qualifier %s. Usage: %s", qualifiers[1], llUsage))}
+	}
+
+	return new(ctl.CommandOutput), nil
+}
+
+func (c *taskCommand) listTasks() *ctl.CommandOutput {
+
+	co := new(ctl.CommandOutput)
+	co.RenderHint = ctl.Columns
+
+	for _, im := range c.scheduler.managedTasks {
+
+		next := ""
+
+		if upcoming := im.upcoming(1); len(upcoming) > 0 {
+			next = upcoming[0].Format(upcomingFormat)
+		}
+
+		co.OutputBody = append(co.OutputBody, []string{im.Task.ID, im.Task.Name, describeSchedule(im), next, taskStatus(im)})
+	}
+
+	return co
+}
+
+func (c *taskCommand) describeTask(im *invocationManager) *ctl.CommandOutput {
+
+	t := im.Task
+
+	co := new(ctl.CommandOutput)
+	co.RenderHint = ctl.Columns
+
+	co.OutputBody = [][]string{
+		{"ID", t.ID},
+		{"Name", t.Name},
+		{"Component", t.Component},
+		{"Schedule", describeSchedule(im)},
+		{"Status", taskStatus(im)},
+		{"Maximum retries", strconv.Itoa(t.MaxRetries)},
+	}
+
+	if t.timeout > 0 {
+		co.OutputBody = append(co.OutputBody, []string{"Timeout", t.timeout.String()})
+	}
+
+	if len(t.After) > 0 {
+		co.OutputBody = append(co.OutputBody, []string{"Current run", describeCurrentRun(im)})
+	}
+
+	if len(im.downstream) > 0 {
+		co.OutputBody = append(co.OutputBody, []string{"Runs before", strings.Join(downstreamIDs(im), ", ")})
+	}
+
+	if t.Singleton {
+		co.OutputBody = append(co.OutputBody, []string{"Lock holder", describeLock(im)})
+	}
+
+	for _, next := range im.upcoming(upcomingRuns) {
+		co.OutputBody = append(co.OutputBody, []string{"Next run", next.Format(upcomingFormat)})
+	}
+
+	if im.history == nil {
+		return co
+	}
+
+	runs, err := im.history.Runs(t.ID, recentRuns)
+
+	if err != nil {
+		co.OutputBody = append(co.OutputBody, []string{"Previous runs", "unavailable: " + err.Error()})
+	}
+
+	for _, run := range runs {
+		co.OutputBody = append(co.OutputBody, []string{"Previous run", describeRun(run)})
+	}
+
+	return co
+}
+
+func describeRun(run *TaskRun) string {
+
+	d := fmt.Sprintf("%s %s %s (%v", run.StartedAt.Format(upcomingFormat), run.Reason, run.Outcome, run.EndedAt.Sub(run.StartedAt))
+
+	if run.Retries > 0 {
+		d += fmt.Sprintf(", %d retries", run.Retries)
+	}
+
+	d += ")"
+
+	if run.StatusMessage != "" {
+		d += " " + run.StatusMessage
+	}
+
+	return d
+}
+
+func describeLock(im *invocationManager) string {
+
+	holder, expires, err := im.locks.Holder(im.Task.ID)
+
+	switch {
+	case err != nil:
+		return "unavailable: " + err.Error()
+	case holder == "":
+		return "none"
+	case holder == im.holder:
+		return fmt.Sprintf("%s (this instance, lease expires %s)", holder, expires.Format(upcomingFormat))
+	}
+
+	return fmt.Sprintf("%s (lease expires %s)", holder, expires.Format(upcomingFormat))
+}
+
+func describeCurrentRun(im *invocationManager) string {
+
+	if im.running.Size() > 0 {
+		return "running"
+	}
+
+	return "waiting for " + strings.Join(im.waitingFor(), ", ")
+}
+
+func downstreamIDs(im *invocationManager) []string {
+
+	ids := make([]string, len(im.downstream))
+
+	for i, dm := range im.downstream {
+		ids[i] = dm.Task.ID
+	}
+
+	return ids
+}
+
+func describeSchedule(im *invocationManager) string {
+
+	if len(im.Task.After) > 0 {
+		return "after " + strings.Join(im.Task.After, ", ")
+	}
+
+	if im.Interval == nil {
+		return ""
+	}
+
+	if im.Interval.Mode == CronExpression {
+		return im.Interval.String()
+	}
+
+	return "every " + im.Task.Every
+}
+
+func taskStatus(im *invocationManager) string {
+
+	switch {
+	case im.Task.Disabled:
+		return "disabled"
+	case im.isSuspended():
+		return "suspended"
+	case im.running.Size() > 0:
+		return fmt.Sprintf("running (%d)", im.running.Size())
+	}
+
+	return "waiting"
+}
+
+func (c *taskCommand) Name() string {
+	return llCommandName
+}
+
+func (c *taskCommand) Summmary() string {
+	return llSummary
+}
+
+func (c *taskCommand) Usage() string {
+	return llUsage
+}
+
+func (c *taskCommand) Help() []string {
+	return []string{llHelp, llHelpTwo, llHelpThree, llHelpFour, llHelpFive, llHelpSix}
 }
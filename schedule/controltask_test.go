@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"strings"
+	"testing"
+)
+
+func TestTaskCommand(t *testing.T) {
+
+	ts := new(TaskScheduler)
+	ts.FrameworkLogger = new(logging.ConsoleErrorLogger)
+	ts.FrameworkLogManager = logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	cn := &mockLogicContainer{logic: new(nullLogic)}
+
+	test.ExpectNil(t, ts.validateAndPrepare(cn, &Task{ID: "nightly", Name: "Nightly", Component: "logic", Cron: "0 2 * * *", TimeZone: "UTC"}))
+	test.ExpectNil(t, ts.validateAndPrepare(cn, &Task{ID: "hourly", Component: "logic", Every: "1 hour"}))
+
+	for _, im := range ts.managedTasks {
+		im.setFirstInvocation()
+	}
+
+	c := NewTaskCommand(ts)
+
+	co, errs := c.ExecuteCommand(nil, nil)
+	test.ExpectInt(t, len(errs), 0)
+	test.ExpectInt(t, len(co.OutputBody), 2)
+	test.ExpectString(t, co.OutputBody[0][2], "cron 0 2 * * * (UTC)")
+	test.ExpectBool(t, strings.HasSuffix(co.OutputBody[0][3], "02:00:00 UTC"), true)
+	test.ExpectString(t, co.OutputBody[1][2], "every 1 hour")
+	test.ExpectString(t, co.OutputBody[1][4], "waiting")
+
+	co, errs = c.ExecuteCommand([]string{"nightly"}, nil)
+	test.ExpectInt(t, len(errs), 0)
+
+	runs := 0
+
+	for _, row := range co.OutputBody {
+		if row[0] == "Next run" {
+			runs++
+		}
+	}
+
+	test.ExpectInt(t, runs, upcomingRuns)
+
+	_, errs = c.ExecuteCommand([]string{"nightly", "suspend"}, nil)
+	test.ExpectInt(t, len(errs), 0)
+
+	co, _ = c.ExecuteCommand(nil, nil)
+	test.ExpectString(t, co.OutputBody[0][4], "suspended")
+
+	_, errs = c.ExecuteCommand([]string{"nightly", "invoke"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+
+	_, errs = c.ExecuteCommand([]string{"nightly", "resume"}, nil)
+	test.ExpectInt(t, len(errs), 0)
+
+	_, errs = c.ExecuteCommand([]string{"nightly", "invoke"}, nil)
+	test.ExpectInt(t, len(errs), 0)
+	test.ExpectString(t, ts.manager("nightly").scheduled.PeekHead().reason, Manual)
+
+	_, errs = c.ExecuteCommand([]string{"nightly", "explode"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+
+	_, errs = c.ExecuteCommand([]string{"missing"}, nil)
+	test.ExpectInt(t, len(errs), 1)
+}
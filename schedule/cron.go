@@ -0,0 +1,503 @@
+// Copyright 2018-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How far ahead to search for a time matching a cron expression before deciding it will never match
+const cronSearchYears = 8
+
+var cronMacros = map[string]string{
+	"@YEARLY":   "0 0 1 1 *",
+	"@ANNUALLY": "0 0 1 1 *",
+	"@MONTHLY":  "0 0 1 * *",
+	"@WEEKLY":   "0 0 * * 0",
+	"@DAILY":    "0 0 * * *",
+	"@MIDNIGHT": "0 0 * * *",
+	"@HOURLY":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// cronField is the set of values that one field of a cron expression matches
+type cronField struct {
+	bits uint64
+	// True if the field was * or ?
+	any bool
+}
+
+func (cf cronField) matches(v int) bool {
+	return cf.bits&(1<<uint(v)) != 0
+}
+
+// dayMatcher is used for day of month and day of week values that depend on the month being checked (L, W and #)
+type dayMatcher func(t time.Time) bool
+
+// cronSchedule is a parsed cron expression
+type cronSchedule struct {
+	expression string
+	location   *time.Location
+
+	second, minute, hour, dayOfMonth, month, dayOfWeek cronField
+
+	domSpecial []dayMatcher
+	dowSpecial []dayMatcher
+}
+
+// parseCron parses a standard five field (minute hour day-of-month month day-of-week) or six field (with a leading
+// seconds field) cron expression. Times are calculated in the supplied location.
+func parseCron(expression string, loc *time.Location) (*cronSchedule, error) {
+
+	m := fmt.Sprintf("Cannot parse cron expression [%s] ", expression)
+
+	norm := strings.ToUpper(strings.TrimSpace(expression))
+
+	if macro, found := cronMacros[norm]; found {
+		norm = macro
+	}
+
+	fields := strings.Fields(norm)
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, errors.New(m + "expressions must have five or six fields")
+	}
+
+	cs := new(cronSchedule)
+	cs.expression = expression
+	cs.location = loc
+
+	var err error
+
+	if cs.second, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, errors.New(m + "(seconds) " + err.Error())
+	}
+
+	if cs.minute, err = parseCronField(fields[1], 0, 59, nil); err != nil {
+		return nil, errors.New(m + "(minutes) " + err.Error())
+	}
+
+	if cs.hour, err = parseCronField(fields[2], 0, 23, nil); err != nil {
+		return nil, errors.New(m + "(hours) " + err.Error())
+	}
+
+	if cs.dayOfMonth, cs.domSpecial, err = parseDayOfMonth(fields[3]); err != nil {
+		return nil, errors.New(m + "(day of month) " + err.Error())
+	}
+
+	if cs.month, err = parseCronField(fields[4], 1, 12, monthNames); err != nil {
+		return nil, errors.New(m + "(month) " + err.Error())
+	}
+
+	if cs.dayOfWeek, cs.dowSpecial, err = parseDayOfWeek(fields[5]); err != nil {
+		return nil, errors.New(m + "(day of week) " + err.Error())
+	}
+
+	if cs.next(time.Now()).IsZero() {
+		return nil, errors.New(m + "the expression never matches a date")
+	}
+
+	return cs, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges (a-b) and steps (*/n, a/n or a-b/n)
+func parseCronField(field string, min, max int, names map[string]int) (cronField, error) {
+
+	var cf cronField
+
+	for _, part := range strings.Split(field, ",") {
+
+		bits, any, err := parseCronRange(part, min, max, names)
+
+		if err != nil {
+			return cf, err
+		}
+
+		cf.bits |= bits
+		cf.any = cf.any || any
+	}
+
+	return cf, nil
+}
+
+func parseCronRange(part string, min, max int, names map[string]int) (uint64, bool, error) {
+
+	rangePart := part
+	step := 1
+	any := false
+
+	if i := strings.Index(part, "/"); i >= 0 {
+
+		var err error
+
+		if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+			return 0, false, fmt.Errorf("%s is not a valid step", part[i+1:])
+		}
+
+		rangePart = part[:i]
+	}
+
+	var from, to int
+
+	switch {
+	case rangePart == "*" || rangePart == "?":
+		from, to = min, max
+		any = step == 1
+
+	case strings.Contains(rangePart, "-"):
+
+		bounds := strings.SplitN(rangePart, "-", 2)
+
+		var err error
+
+		if from, err = cronValue(bounds[0], min, max, names); err != nil {
+			return 0, false, err
+		}
+
+		if to, err = cronValue(bounds[1], min, max, names); err != nil {
+			return 0, false, err
+		}
+
+		if to < from {
+			return 0, false, fmt.Errorf("%s is not a valid range", rangePart)
+		}
+
+	default:
+
+		var err error
+
+		if from, err = cronValue(rangePart, min, max, names); err != nil {
+			return 0, false, err
+		}
+
+		to = from
+
+		if step > 1 {
+			to = max
+		}
+	}
+
+	var bits uint64
+
+	for v := from; v <= to; v += step {
+		bits |= 1 << uint(v)
+	}
+
+	return bits, any, nil
+}
+
+func cronValue(s string, min, max int, names map[string]int) (int, error) {
+
+	if v, found := names[s]; found {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("%s is not a valid value (must be %d-%d)", s, min, max)
+	}
+
+	return v, nil
+}
+
+// parseDayOfMonth supports L (last day of the month), L-n (n days before the last day), nW (the weekday nearest day n)
+// and LW (the last weekday of the month) in addition to the standard syntax
+func parseDayOfMonth(field string) (cronField, []dayMatcher, error) {
+
+	var special []dayMatcher
+	standard := make([]string, 0)
+
+	for _, part := range strings.Split(field, ",") {
+
+		switch {
+		case part == "L":
+			special = append(special, func(t time.Time) bool { return t.Day() == lastDay(t) })
+
+		case part == "LW":
+			special = append(special, func(t time.Time) bool { return t.Day() == nearestWeekday(t, lastDay(t)) })
+
+		case strings.HasPrefix(part, "L-"):
+
+			offset, err := strconv.Atoi(part[2:])
+
+			if err != nil || offset < 1 || offset > 30 {
+				return cronField{}, nil, fmt.Errorf("%s is not a valid offset from the last day of the month", part)
+			}
+
+			special = append(special, func(t time.Time) bool { return t.Day() == lastDay(t)-offset })
+
+		case strings.HasSuffix(part, "W"):
+
+			day, err := cronValue(strings.TrimSuffix(part, "W"), 1, 31, nil)
+
+			if err != nil {
+				return cronField{}, nil, err
+			}
+
+			special = append(special, func(t time.Time) bool { return t.Day() == nearestWeekday(t, day) })
+
+		default:
+			standard = append(standard, part)
+		}
+	}
+
+	if len(standard) == 0 {
+		return cronField{}, special, nil
+	}
+
+	cf, err := parseCronField(strings.Join(standard, ","), 1, 31, nil)
+
+	return cf, special, err
+}
+
+// parseDayOfWeek supports nL (the last day n of the month) and n#i (the ith day n of the month) in addition to the
+// standard syntax. Both 0 and 7 mean Sunday.
+func parseDayOfWeek(field string) (cronField, []dayMatcher, error) {
+
+	var special []dayMatcher
+	standard := make([]string, 0)
+
+	for _, part := range strings.Split(field, ",") {
+
+		switch {
+		case len(part) > 1 && strings.HasSuffix(part, "L"):
+
+			day, err := weekday(strings.TrimSuffix(part, "L"))
+
+			if err != nil {
+				return cronField{}, nil, err
+			}
+
+			special = append(special, func(t time.Time) bool {
+				return int(t.Weekday()) == day && t.Day()+7 > lastDay(t)
+			})
+
+		case strings.Contains(part, "#"):
+
+			elements := strings.SplitN(part, "#", 2)
+
+			day, err := weekday(elements[0])
+
+			if err != nil {
+				return cronField{}, nil, err
+			}
+
+			nth, err := strconv.Atoi(elements[1])
+
+			if err != nil || nth < 1 || nth > 5 {
+				return cronField{}, nil, fmt.Errorf("%s is not a valid occurrence (must be 1-5)", elements[1])
+			}
+
+			special = append(special, func(t time.Time) bool {
+				return int(t.Weekday()) == day && (t.Day()-1)/7 == nth-1
+			})
+
+		default:
+			standard = append(standard, part)
+		}
+	}
+
+	if len(standard) == 0 {
+		return cronField{}, special, nil
+	}
+
+	cf, err := parseCronField(strings.Join(standard, ","), 0, 7, dayNames)
+
+	if cf.matches(7) {
+		cf.bits |= 1
+	}
+
+	return cf, special, err
+}
+
+func weekday(s string) (int, error) {
+
+	day, err := cronValue(s, 0, 7, dayNames)
+
+	return day % 7, err
+}
+
+// lastDay returns the number of days in the month containing t
+func lastDay(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// nearestWeekday returns the Monday-Friday day of t's month nearest to the supplied day, without leaving the month
+func nearestWeekday(t time.Time, day int) int {
+
+	last := lastDay(t)
+
+	if day > last {
+		day = last
+	}
+
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location()).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return 3
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+
+	return day
+}
+
+// dayMatches follows the convention of cron that, if both day of month and day of week are restricted, a day matches
+// if either field matches.
+func (cs *cronSchedule) dayMatches(t time.Time) bool {
+
+	dom := cs.dayOfMonth.matches(t.Day())
+
+	for _, m := range cs.domSpecial {
+		dom = dom || m(t)
+	}
+
+	dow := cs.dayOfWeek.matches(int(t.Weekday()))
+
+	for _, m := range cs.dowSpecial {
+		dow = dow || m(t)
+	}
+
+	domAny := cs.dayOfMonth.any && len(cs.domSpecial) == 0
+	dowAny := cs.dayOfWeek.any && len(cs.dowSpecial) == 0
+
+	switch {
+	case domAny && dowAny:
+		return true
+	case domAny:
+		return dow
+	case dowAny:
+		return dom
+	}
+
+	return dom || dow
+}
+
+// next returns the first time after the supplied time that matches the expression or the zero time if no time in the
+// next few years matches.
+func (cs *cronSchedule) next(after time.Time) time.Time {
+
+	t := after.In(cs.location).Truncate(time.Second).Add(time.Second)
+	limit := t.Year() + cronSearchYears
+
+	for t.Year() <= limit {
+
+		if !cs.month.matches(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, cs.location)
+			continue
+		}
+
+		if !cs.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, cs.location)
+			continue
+		}
+
+		// Hours, minutes and seconds are advanced by adding durations so that daylight saving changes can't move t backwards
+		if !cs.hour.matches(t.Hour()) {
+			t = t.Add(time.Duration(60-t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+
+		if !cs.minute.matches(t.Minute()) {
+			t = t.Add(time.Duration(60-t.Second()) * time.Second)
+			continue
+		}
+
+		if !cs.second.matches(t.Second()) {
+			t = t.Add(time.Second)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// previous returns the last time before the supplied time that matches the expression (or a zero time if there is no
+// match in the preceding cronSearchYears years). The search mirrors next, moving backwards to the last second of the
+// preceding month, day, hour or minute when a field does not match.
+func (cs *cronSchedule) previous(before time.Time) time.Time {
+
+	t := before.In(cs.location).Add(-time.Nanosecond).Truncate(time.Second)
+	limit := t.Year() - cronSearchYears
+
+	for t.Year() >= limit {
+
+		if !cs.month.matches(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, cs.location).Add(-time.Second)
+			continue
+		}
+
+		if !cs.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, cs.location).Add(-time.Second)
+			continue
+		}
+
+		if !cs.hour.matches(t.Hour()) {
+			t = t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second()+1)*time.Second)
+			continue
+		}
+
+		if !cs.minute.matches(t.Minute()) {
+			t = t.Add(-time.Duration(t.Second()+1) * time.Second)
+			continue
+		}
+
+		if !cs.second.matches(t.Second()) {
+			t = t.Add(-time.Second)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// upcoming returns the next n times after the supplied time that match the expression
+func (cs *cronSchedule) upcoming(after time.Time, n int) []time.Time {
+
+	times := make([]time.Time, 0, n)
+
+	for len(times) < n {
+
+		after = cs.next(after)
+
+		if after.IsZero() {
+			break
+		}
+
+		times = append(times, after)
+	}
+
+	return times
+}
+
+// String returns the expression and the time zone used to evaluate it
+func (cs *cronSchedule) String() string {
+	return fmt.Sprintf("%s (%s)", cs.expression, cs.location)
+}
@@ -0,0 +1,165 @@
+package schedule
+
+import (
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+	"time"
+)
+
+func TestCronNextRun(t *testing.T) {
+
+	utc := time.UTC
+
+	values := []struct {
+		expression string
+		after      time.Time
+		next       time.Time
+	}{
+		// Weekdays at 17:30, from a Friday evening
+		{"30 17 * * MON-FRI", date(2020, 1, 3, 18, 0, 0), date(2020, 1, 6, 17, 30, 0)},
+		// Last day of the month in a leap year
+		{"0 0 L * *", date(2020, 2, 1, 0, 0, 0), date(2020, 2, 29, 0, 0, 0)},
+		// Three days before the last day of the month
+		{"0 0 L-3 * *", date(2020, 2, 1, 0, 0, 0), date(2020, 2, 26, 0, 0, 0)},
+		// Last weekday of a month ending on a Saturday
+		{"0 0 LW * *", date(2020, 2, 1, 0, 0, 0), date(2020, 2, 28, 0, 0, 0)},
+		// Weekday nearest the 1st, which is a Saturday
+		{"0 0 1W * *", date(2020, 1, 31, 0, 0, 0), date(2020, 2, 3, 0, 0, 0)},
+		// Last Friday of the month
+		{"0 0 * * 5L", date(2020, 1, 1, 0, 0, 0), date(2020, 1, 31, 0, 0, 0)},
+		// Second Monday of the month
+		{"0 0 * * MON#2", date(2020, 1, 1, 0, 0, 0), date(2020, 1, 13, 0, 0, 0)},
+		// Day of month or day of week
+		{"0 0 13 * 5", date(2020, 1, 1, 0, 0, 0), date(2020, 1, 3, 0, 0, 0)},
+		// Seconds field and steps
+		{"*/15 * * * * *", date(2020, 1, 1, 10, 0, 7), date(2020, 1, 1, 10, 0, 15)},
+		{"0 10-20/5 * * *", date(2020, 1, 1, 10, 0, 0), date(2020, 1, 1, 15, 0, 0)},
+		// Month names and lists
+		{"0 12 1 JAN,JUL *", date(2020, 2, 1, 0, 0, 0), date(2020, 7, 1, 12, 0, 0)},
+		// Sunday as 7
+		{"0 0 * * 7", date(2020, 1, 1, 0, 0, 0), date(2020, 1, 5, 0, 0, 0)},
+		{"@hourly", date(2020, 1, 1, 10, 0, 0), date(2020, 1, 1, 11, 0, 0)},
+	}
+
+	for _, v := range values {
+
+		cs, err := parseCron(v.expression, utc)
+
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %s: %s", v.expression, err.Error())
+		}
+
+		if next := cs.next(v.after); !next.Equal(v.next) {
+			t.Errorf("%s: expected %v, got %v", v.expression, v.next, next)
+		}
+
+		// Searching backwards finds the same time, and the time before it is the previous match
+		if previous := cs.previous(v.next.Add(time.Second)); !previous.Equal(v.next) {
+			t.Errorf("%s: expected previous %v, got %v", v.expression, v.next, previous)
+		}
+
+		if previous := cs.previous(v.next); previous.IsZero() || !cs.next(previous).Equal(v.next) {
+			t.Errorf("%s: %v is not the match before %v", v.expression, previous, v.next)
+		}
+	}
+}
+
+func TestCronTimeZone(t *testing.T) {
+
+	est := time.FixedZone("EST", -5*60*60)
+
+	cs, err := parseCron("0 9 * * *", est)
+	test.ExpectNil(t, err)
+
+	next := cs.next(date(2020, 1, 1, 0, 0, 0))
+
+	test.ExpectBool(t, next.Equal(date(2020, 1, 1, 14, 0, 0)), true)
+	test.ExpectInt(t, next.Hour(), 9)
+	test.ExpectString(t, cs.String(), "0 9 * * * (EST)")
+
+	upcoming := cs.upcoming(date(2020, 1, 1, 0, 0, 0), 3)
+	test.ExpectInt(t, len(upcoming), 3)
+	test.ExpectBool(t, upcoming[2].Equal(date(2020, 1, 3, 14, 0, 0)), true)
+}
+
+func TestInvalidCron(t *testing.T) {
+
+	invalid := []string{
+		"* * *",
+		"61 * * * *",
+		"* * * * * * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"0 0 30 FEB *",
+		"0 0 * * FRI#6",
+		"0 0 32W * *",
+		"0 0 * BAD *",
+	}
+
+	for _, e := range invalid {
+		if _, err := parseCron(e, time.UTC); err == nil {
+			t.Errorf("Expected an error parsing %s", e)
+		}
+	}
+}
+
+func TestCronTaskValidation(t *testing.T) {
+
+	ts := new(TaskScheduler)
+	ts.FrameworkLogger = new(logging.ConsoleErrorLogger)
+	ts.FrameworkLogManager = logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+
+	cn := &mockLogicContainer{logic: new(nullLogic)}
+
+	task := &Task{ID: "cron", Component: "logic", Cron: "0 9 * * MON-FRI", TimeZone: "UTC"}
+	test.ExpectNil(t, ts.validateAndPrepare(cn, task))
+
+	im := ts.manager("cron")
+	test.ExpectBool(t, im.Interval.Mode == CronExpression, true)
+
+	im.Log = new(logging.ConsoleErrorLogger)
+	im.setFirstInvocation()
+
+	upcoming := im.upcoming(5)
+	test.ExpectInt(t, len(upcoming), 5)
+
+	for _, u := range upcoming {
+		test.ExpectInt(t, u.Hour(), 9)
+		test.ExpectBool(t, u.Weekday() != time.Saturday && u.Weekday() != time.Sunday, true)
+	}
+
+	first := im.scheduled.Dequeue()
+	im.addNextInvocation(first)
+	test.ExpectBool(t, im.scheduled.PeekHead().runAt.Equal(upcoming[1]), true)
+
+	invalid := []*Task{
+		{ID: "both", Component: "logic", Cron: "0 9 * * *", Every: "1 hour"},
+		{ID: "zone", Component: "logic", Every: "1 hour", TimeZone: "UTC"},
+		{ID: "unknown", Component: "logic", Cron: "0 9 * * *", TimeZone: "Not/AZone"},
+		{ID: "bad", Component: "logic", Cron: "0 9 * *"},
+	}
+
+	for _, task := range invalid {
+		if err := ts.validateAndPrepare(cn, task); err == nil {
+			t.Errorf("Expected an error for task %s", task.ID)
+		}
+	}
+}
+
+func date(year int, month time.Month, day, hour, minute, second int) time.Time {
+	return time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+}
+
+type mockLogicContainer struct {
+	logic TaskLogic
+}
+
+func (c *mockLogicContainer) ComponentByName(name string) *ioc.Component {
+	return ioc.NewComponent(name, c.logic)
+}
+
+func (c *mockLogicContainer) AllComponents() []*ioc.Component {
+	return []*ioc.Component{}
+}
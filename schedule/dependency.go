@@ -0,0 +1,195 @@
+// Copyright 2018-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// linkDependencies connects each task to the tasks it runs after and checks that no task (indirectly) runs after itself
+func (ts *TaskScheduler) linkDependencies() error {
+
+	for _, tm := range ts.managedTasks {
+
+		seen := make(map[string]bool)
+
+		for _, id := range tm.Task.After {
+
+			if seen[id] {
+				return fmt.Errorf("Task %s lists task %s more than once in its 'After' field", tm.Task.FullName(), id)
+			}
+
+			seen[id] = true
+
+			upstream := ts.manager(id)
+
+			if upstream == nil {
+				return fmt.Errorf("Task %s runs after task %s, which does not exist", tm.Task.FullName(), id)
+			}
+
+			upstream.downstream = append(upstream.downstream, tm)
+		}
+	}
+
+	if cycle := ts.findCycle(); cycle != nil {
+		return fmt.Errorf("Tasks cannot run after themselves but the 'After' fields of these tasks form a cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// findCycle returns the IDs of tasks that form a cycle of dependencies (with the first task repeated at the end) or
+// nil if there are no cycles
+func (ts *TaskScheduler) findCycle() []string {
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	path := make([]string, 0)
+
+	var visit func(im *invocationManager) []string
+
+	visit = func(im *invocationManager) []string {
+
+		id := im.Task.ID
+
+		switch state[id] {
+		case visited:
+			return nil
+		case visiting:
+			for i, p := range path {
+				if p == id {
+					return append(append([]string{}, path[i:]...), id)
+				}
+			}
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+
+		for _, dm := range im.downstream {
+			if cycle := visit(dm); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[id] = visited
+
+		return nil
+	}
+
+	for _, tm := range ts.managedTasks {
+		if cycle := visit(tm); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// notifyDownstream tells the tasks that run after this task the outcome of an invocation
+func (im *invocationManager) notifyDownstream(run *TaskRun) {
+
+	for _, dm := range im.downstream {
+		dm.upstreamCompleted(run)
+	}
+}
+
+// upstreamCompleted is called when a task this task runs after completes. Each cycle ends once all of the tasks this task
+// runs after have completed and the task is triggered if all of them succeeded. The first failure in a cycle causes this
+// task's run to be skipped immediately; the outcomes of the remaining tasks in that cycle are then ignored.
+func (im *invocationManager) upstreamCompleted(run *TaskRun) {
+
+	task := im.Task
+
+	im.depMux.Lock()
+
+	failed := run.Outcome != Succeeded
+	firstFailure := failed && !im.cycleFailed
+
+	if failed {
+		im.cycleFailed = true
+	}
+
+	im.reported[run.TaskID] = true
+
+	complete := len(im.reported) == len(task.After)
+	ready := complete && !im.cycleFailed
+
+	if complete {
+		im.reported = make(map[string]bool)
+		im.cycleFailed = false
+	}
+
+	im.depMux.Unlock()
+
+	if firstFailure {
+		im.skip(fmt.Sprintf("task %s did not succeed (%s)", run.TaskID, run.Outcome))
+		return
+	}
+
+	if !ready {
+		if !complete {
+			im.Log.LogDebugf("Task %s is waiting for %s", task.FullName(), strings.Join(im.waitingFor(), ", "))
+		}
+
+		return
+	}
+
+	if task.Disabled {
+		im.Log.LogWarnf("Task %s will not run after %s as it has been disabled", task.FullName(), strings.Join(task.After, ", "))
+		return
+	}
+
+	i := newInvocation(im.nextCounter(), task.MaxRetries, Upstream)
+	i.runAt = time.Now()
+
+	im.scheduled.EnqueueAtTail(i)
+}
+
+// skip records that the current run of the task will not take place and propagates the failure to the tasks that run
+// after this task
+func (im *invocationManager) skip(reason string) {
+
+	im.Log.LogWarnf("Task %s will not run as %s", im.Task.FullName(), reason)
+
+	now := time.Now()
+
+	run := &TaskRun{
+		TaskID:        im.Task.ID,
+		Invocation:    im.nextCounter(),
+		Reason:        Upstream,
+		StartedAt:     now,
+		EndedAt:       now,
+		Outcome:       Skipped,
+		StatusMessage: reason,
+	}
+
+	im.recordRun(run)
+	im.notifyDownstream(run)
+}
+
+// waitingFor returns the IDs of the tasks this task runs after that have not yet completed in the current cycle
+func (im *invocationManager) waitingFor() []string {
+
+	im.depMux.Lock()
+	defer im.depMux.Unlock()
+
+	waiting := make([]string, 0)
+
+	for _, id := range im.Task.After {
+		if !im.reported[id] {
+			waiting = append(waiting, id)
+		}
+	}
+
+	return waiting
+}
@@ -0,0 +1,199 @@
+package schedule
+
+import (
+	"errors"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"strings"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+
+	failing := &messageLogic{message: "loading", err: errors.New("no data")}
+
+	ts := dependentScheduler(
+		ioc.NewComponent("failing", failing),
+		ioc.NewComponent("extract", &Task{ID: "extract", Component: "logic", Every: "1 hour"}),
+		ioc.NewComponent("load", &Task{ID: "load", Component: "failing", Every: "1 hour"}),
+		ioc.NewComponent("transform", &Task{ID: "transform", Component: "logic", After: []string{"extract", "load"}}),
+		ioc.NewComponent("publish", &Task{ID: "publish", Component: "logic", After: []string{"transform"}}),
+	)
+
+	test.ExpectNil(t, ts.StartComponent())
+
+	extract, load, transform, publish := ts.manager("extract"), ts.manager("load"), ts.manager("transform"), ts.manager("publish")
+
+	for _, im := range ts.managedTasks {
+		im.Log = logging.CreateAnonymousLogger(im.Task.ID, logging.Fatal)
+	}
+
+	extract.runTask(newInvocation(extract.nextCounter(), 0, Manual))
+
+	test.ExpectInt(t, len(transform.scheduled.Contents()), 0)
+	test.ExpectString(t, strings.Join(transform.waitingFor(), ","), "load")
+
+	tc := NewTaskCommand(ts)
+
+	co, _ := tc.ExecuteCommand([]string{"transform"}, nil)
+	test.ExpectString(t, rowValue(co.OutputBody, "Schedule"), "after extract, load")
+	test.ExpectString(t, rowValue(co.OutputBody, "Current run"), "waiting for load")
+	test.ExpectString(t, rowValue(co.OutputBody, "Runs before"), "publish")
+
+	// A failure is propagated to every task that runs after the failing task
+	load.runTask(newInvocation(load.nextCounter(), 0, Manual))
+
+	test.ExpectInt(t, len(transform.scheduled.Contents()), 0)
+	test.ExpectString(t, strings.Join(transform.waitingFor(), ","), "extract,load")
+
+	for _, id := range []string{"transform", "publish"} {
+		runs, _ := ts.history.Runs(id, 1)
+		test.ExpectInt(t, len(runs), 1)
+		test.ExpectString(t, runs[0].Outcome, Skipped)
+	}
+
+	// Fan-in - the task runs once all of the tasks it runs after have succeeded
+	failing.err = nil
+
+	load.runTask(newInvocation(load.nextCounter(), 0, Manual))
+	test.ExpectInt(t, len(transform.scheduled.Contents()), 0)
+
+	extract.runTask(newInvocation(extract.nextCounter(), 0, Manual))
+
+	queued := transform.scheduled.Contents()
+	test.ExpectInt(t, len(queued), 1)
+	test.ExpectString(t, queued[0].reason, Upstream)
+
+	transform.runTask(transform.scheduled.Dequeue())
+
+	queued = publish.scheduled.Contents()
+	test.ExpectInt(t, len(queued), 1)
+
+	runs, _ := ts.history.Runs("transform", 1)
+	test.ExpectString(t, runs[0].Outcome, Succeeded)
+	test.ExpectString(t, runs[0].Reason, Upstream)
+}
+
+func TestDependencyCycles(t *testing.T) {
+
+	first := &messageLogic{message: "first", err: errors.New("no data")}
+	second := &messageLogic{message: "second", err: errors.New("no data")}
+
+	ts := dependentScheduler(
+		ioc.NewComponent("first", first),
+		ioc.NewComponent("second", second),
+		ioc.NewComponent("a", &Task{ID: "a", Component: "first", Every: "1 hour"}),
+		ioc.NewComponent("b", &Task{ID: "b", Component: "second", Every: "1 hour"}),
+		ioc.NewComponent("c", &Task{ID: "c", Component: "logic", After: []string{"a", "b"}}),
+		ioc.NewComponent("d", &Task{ID: "d", Component: "logic", After: []string{"c"}}),
+	)
+
+	test.ExpectNil(t, ts.StartComponent())
+
+	a, b, c := ts.manager("a"), ts.manager("b"), ts.manager("c")
+
+	for _, im := range ts.managedTasks {
+		im.Log = logging.CreateAnonymousLogger(im.Task.ID, logging.Fatal)
+	}
+
+	// Only the first failure in a cycle causes the run to be skipped
+	a.runTask(newInvocation(a.nextCounter(), 0, Manual))
+	b.runTask(newInvocation(b.nextCounter(), 0, Manual))
+
+	for _, id := range []string{"c", "d"} {
+		runs, _ := ts.history.Runs(id, 10)
+		test.ExpectInt(t, len(runs), 1)
+		test.ExpectString(t, runs[0].Outcome, Skipped)
+	}
+
+	test.ExpectString(t, strings.Join(c.waitingFor(), ","), "a,b")
+
+	// A success that completes a failed cycle does not count towards the next cycle
+	second.err = nil
+
+	a.runTask(newInvocation(a.nextCounter(), 0, Manual))
+	b.runTask(newInvocation(b.nextCounter(), 0, Manual))
+
+	first.err = nil
+
+	a.runTask(newInvocation(a.nextCounter(), 0, Manual))
+	test.ExpectInt(t, len(c.scheduled.Contents()), 0)
+	test.ExpectString(t, strings.Join(c.waitingFor(), ","), "b")
+
+	b.runTask(newInvocation(b.nextCounter(), 0, Manual))
+	test.ExpectInt(t, len(c.scheduled.Contents()), 1)
+
+	runs, _ := ts.history.Runs("c", 10)
+	test.ExpectInt(t, len(runs), 2)
+}
+
+func TestDependencyValidation(t *testing.T) {
+
+	invalid := [][]*Task{
+		{
+			{ID: "a", Component: "logic", After: []string{"a"}},
+		},
+		{
+			{ID: "a", Component: "logic", After: []string{"missing"}},
+		},
+		{
+			{ID: "a", Component: "logic", Every: "1 hour"},
+			{ID: "b", Component: "logic", After: []string{"a", "a"}},
+		},
+		{
+			{ID: "a", Component: "logic", Every: "1 hour"},
+			{ID: "b", Component: "logic", Every: "1 hour", After: []string{"a"}},
+		},
+		{
+			{ID: "a", Component: "logic", Every: "1 hour"},
+			{ID: "b", Component: "logic", CatchUp: CatchUpOnce, After: []string{"a"}},
+		},
+	}
+
+	for _, tasks := range invalid {
+
+		components := make([]*ioc.Component, len(tasks))
+
+		for i, task := range tasks {
+			components[i] = ioc.NewComponent(task.ID, task)
+		}
+
+		if err := dependentScheduler(components...).StartComponent(); err == nil {
+			t.Errorf("Expected an error for tasks starting with %s", tasks[0].ID)
+		}
+	}
+
+	err := dependentScheduler(invalidCycle()...).StartComponent()
+	test.ExpectNotNil(t, err)
+	test.ExpectBool(t, strings.HasSuffix(err.Error(), "a -> b -> c -> a"), true)
+}
+
+func invalidCycle() []*ioc.Component {
+	return []*ioc.Component{
+		ioc.NewComponent("a", &Task{ID: "a", Component: "logic", After: []string{"c"}}),
+		ioc.NewComponent("b", &Task{ID: "b", Component: "logic", After: []string{"a"}}),
+		ioc.NewComponent("c", &Task{ID: "c", Component: "logic", After: []string{"b"}}),
+	}
+}
+
+func dependentScheduler(components ...*ioc.Component) *TaskScheduler {
+
+	ts := new(TaskScheduler)
+	ts.FrameworkLogger = logging.CreateAnonymousLogger("scheduler", logging.Fatal)
+	ts.FrameworkLogManager = logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+	ts.componentContainer = &mockComponentsContainer{components: components}
+
+	return ts
+}
+
+func rowValue(rows [][]string, label string) string {
+
+	for _, row := range rows {
+		if row[0] == label {
+			return row[1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,487 @@
+// Copyright 2018-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package schedule
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The outcomes of an invocation of a task recorded in a TaskRun
+const (
+	// Succeeded indicates the task returned without an error
+	Succeeded = "SUCCEEDED"
+	// Failed indicates the task returned an error (after any retries) or panicked
+	Failed = "FAILED"
+	// Cancelled indicates the task returned an error after its context was cancelled or timed out
+	Cancelled = "CANCELLED"
+	// Skipped indicates the task did not run because a task it runs after did not succeed
+	Skipped = "SKIPPED"
+)
+
+const (
+	defaultMaxRunsPerTask = 100
+
+	defaultRecordQueryID        = "taskHistoryRecord"
+	defaultRunsQueryID          = "taskHistoryRuns"
+	defaultLastScheduledQueryID = "taskHistoryLastScheduled"
+)
+
+// TaskRun records the outcome of a single invocation of a task, including any retries.
+type TaskRun struct {
+	// The ID of the task
+	TaskID string
+	// The number of the invocation since the application started
+	Invocation uint64
+	// Why the task was run (Scheduled, Manual or CatchUp)
+	Reason string
+	// The time the invocation was scheduled to run
+	ScheduledFor time.Time
+	// When the first attempt started
+	StartedAt time.Time
+	// When the final attempt ended
+	EndedAt time.Time
+	// Succeeded, Failed, Cancelled or Skipped
+	Outcome string
+	// The number of times the invocation was retried
+	Retries int
+	// The error returned by the final attempt or, if the task succeeded, the last status message sent by the task
+	StatusMessage string
+}
+
+// scheduled returns true if the run was triggered by the task's schedule rather than manually
+func (tr *TaskRun) scheduled() bool {
+	return tr.Reason == Scheduled || tr.Reason == CatchUp
+}
+
+// TaskHistoryStore is implemented by components that can record the outcome of each invocation of a task. If your
+// application defines a component implementing this interface, the TaskScheduler will use it instead of an in-memory
+// store.
+type TaskHistoryStore interface {
+	// Record stores the outcome of an invocation
+	Record(run *TaskRun) error
+
+	// Runs returns up to limit of the most recent runs of the task, newest first
+	Runs(taskID string, limit int) ([]*TaskRun, error)
+
+	// LastScheduledRun returns the most recent run of the task that was triggered by its schedule, or nil if the task
+	// has never run on its schedule
+	LastScheduledRun(taskID string) (*TaskRun, error)
+}
+
+// MemoryHistoryStore is a TaskHistoryStore that keeps the most recent runs of each task in memory. It is used by the
+// TaskScheduler if no other store is defined. History is lost when the application stops.
+type MemoryHistoryStore struct {
+	// The number of runs kept for each task. Defaults to 100
+	MaxRunsPerTask int
+
+	runs map[string][]*TaskRun
+	mux  sync.RWMutex
+}
+
+// Record implements TaskHistoryStore.Record
+func (ms *MemoryHistoryStore) Record(run *TaskRun) error {
+
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+
+	ms.add(run)
+
+	return nil
+}
+
+func (ms *MemoryHistoryStore) add(run *TaskRun) {
+
+	if ms.runs == nil {
+		ms.runs = make(map[string][]*TaskRun)
+	}
+
+	max := ms.MaxRunsPerTask
+
+	if max <= 0 {
+		max = defaultMaxRunsPerTask
+	}
+
+	runs := append(ms.runs[run.TaskID], run)
+
+	if len(runs) > max {
+		runs = runs[len(runs)-max:]
+	}
+
+	ms.runs[run.TaskID] = runs
+}
+
+// Runs implements TaskHistoryStore.Runs
+func (ms *MemoryHistoryStore) Runs(taskID string, limit int) ([]*TaskRun, error) {
+
+	ms.mux.RLock()
+	defer ms.mux.RUnlock()
+
+	stored := ms.runs[taskID]
+	runs := make([]*TaskRun, 0, limit)
+
+	for i := len(stored) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, stored[i])
+	}
+
+	return runs, nil
+}
+
+// LastScheduledRun implements TaskHistoryStore.LastScheduledRun
+func (ms *MemoryHistoryStore) LastScheduledRun(taskID string) (*TaskRun, error) {
+
+	ms.mux.RLock()
+	defer ms.mux.RUnlock()
+
+	stored := ms.runs[taskID]
+
+	for i := len(stored) - 1; i >= 0; i-- {
+		if stored[i].scheduled() {
+			return stored[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// FileHistoryStore is a TaskHistoryStore that appends each run to a file (as a line of JSON) so that history is kept
+// when the application restarts. When the store starts, the file is read and rewritten to keep only the most recent
+// MaxRunsPerTask runs of each task.
+type FileHistoryStore struct {
+	// The path of the file in which runs are recorded. The file is created if it does not exist
+	Path string
+
+	// The number of runs kept for each task. Defaults to 100
+	MaxRunsPerTask int
+
+	memory *MemoryHistoryStore
+	mux    sync.Mutex
+	state  ioc.ComponentState
+}
+
+// Record implements TaskHistoryStore.Record
+func (fs *FileHistoryStore) Record(run *TaskRun) error {
+
+	b, err := json.Marshal(run)
+
+	if err != nil {
+		return err
+	}
+
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	f, err := os.OpenFile(fs.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return fs.memory.Record(run)
+}
+
+// Runs implements TaskHistoryStore.Runs
+func (fs *FileHistoryStore) Runs(taskID string, limit int) ([]*TaskRun, error) {
+	return fs.memory.Runs(taskID, limit)
+}
+
+// LastScheduledRun implements TaskHistoryStore.LastScheduledRun
+func (fs *FileHistoryStore) LastScheduledRun(taskID string) (*TaskRun, error) {
+	return fs.memory.LastScheduledRun(taskID)
+}
+
+// StartComponent is called by the IoC container. Loads and compacts the history file.
+func (fs *FileHistoryStore) StartComponent() error {
+
+	if fs.state != ioc.StoppedState {
+		return nil
+	}
+
+	fs.state = ioc.StartingState
+
+	if fs.Path == "" {
+		return errors.New("no Path set for task history file")
+	}
+
+	fs.memory = &MemoryHistoryStore{MaxRunsPerTask: fs.MaxRunsPerTask}
+
+	if err := fs.load(); err != nil {
+		return err
+	}
+
+	fs.state = ioc.RunningState
+
+	return nil
+}
+
+func (fs *FileHistoryStore) load() error {
+
+	f, err := os.Open(fs.Path)
+
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	order := make([]*TaskRun, 0)
+	scanner := bufio.NewScanner(f)
+	line := 0
+
+	for scanner.Scan() {
+
+		line++
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		run := new(TaskRun)
+
+		if err := json.Unmarshal(scanner.Bytes(), run); err != nil {
+			f.Close()
+			return fmt.Errorf("unable to parse line %d of task history file %s: %s", line, fs.Path, err.Error())
+		}
+
+		fs.memory.add(run)
+		order = append(order, run)
+	}
+
+	f.Close()
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return fs.compact(order)
+}
+
+// compact rewrites the history file so that it only contains the runs kept in memory
+func (fs *FileHistoryStore) compact(order []*TaskRun) error {
+
+	kept := make(map[*TaskRun]bool)
+
+	for _, runs := range fs.memory.runs {
+		for _, run := range runs {
+			kept[run] = true
+		}
+	}
+
+	if len(kept) == len(order) {
+		return nil
+	}
+
+	tmp, err := os.Create(filepath.Join(filepath.Dir(fs.Path), "."+filepath.Base(fs.Path)+".tmp"))
+
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+
+	for _, run := range order {
+
+		if !kept[run] {
+			continue
+		}
+
+		b, _ := json.Marshal(run)
+		w.Write(append(b, '\n'))
+	}
+
+	if err = w.Flush(); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fs.Path)
+}
+
+/*
+RDBMSHistoryStore is a TaskHistoryStore that records runs in a database via the RdbmsAccess and QueryManager facilities.
+
+The query identified by RecordQueryID (taskHistoryRecord by default) is passed the parameters TaskID, Invocation, Reason,
+ScheduledFor, StartedAt, EndedAt, Outcome, Retries and StatusMessage. Times are passed as Unix timestamps in milliseconds.
+For example:
+
+	INSERT INTO task_history (task_id, invocation, reason, scheduled_for, started_at, ended_at, outcome, retries, status_message)
+	VALUES (${TaskID}, ${Invocation}, ${Reason}, ${ScheduledFor}, ${StartedAt}, ${EndedAt}, ${Outcome}, ${Retries}, ${StatusMessage})
+
+The queries identified by RunsQueryID (taskHistoryRuns) and LastScheduledQueryID (taskHistoryLastScheduled) are passed
+TaskID (and Limit for RunsQueryID) and must return the columns task_id, invocation, reason, scheduled_for, started_at,
+ended_at, outcome, retries and status_message (which may be null), newest first:
+
+	SELECT * FROM task_history WHERE task_id = ${TaskID} ORDER BY started_at DESC LIMIT ${Limit}
+
+	SELECT * FROM task_history WHERE task_id = ${TaskID} AND reason IN ('Scheduled', 'CatchUp') ORDER BY scheduled_for DESC LIMIT 1
+*/
+type RDBMSHistoryStore struct {
+	// Injected by Granitic if the RdbmsAccess facility is enabled.
+	DBClientManager rdbms.ClientManager
+
+	// The ID of the query used to record a run. Defaults to taskHistoryRecord.
+	RecordQueryID string
+
+	// The ID of the query used to find the most recent runs of a task. Defaults to taskHistoryRuns.
+	RunsQueryID string
+
+	// The ID of the query used to find the most recent scheduled run of a task. Defaults to taskHistoryLastScheduled.
+	LastScheduledQueryID string
+}
+
+type historyRow struct {
+	TaskID        string `column:"task_id"`
+	Invocation    int64  `column:"invocation"`
+	Reason        string `column:"reason"`
+	ScheduledFor  int64  `column:"scheduled_for"`
+	StartedAt     int64  `column:"started_at"`
+	EndedAt       int64  `column:"ended_at"`
+	Outcome       string `column:"outcome"`
+	Retries       int64  `column:"retries"`
+	StatusMessage string `column:"status_message"`
+}
+
+func (hr *historyRow) run() *TaskRun {
+
+	run := &TaskRun{
+		TaskID:        hr.TaskID,
+		Invocation:    uint64(hr.Invocation),
+		Reason:        hr.Reason,
+		ScheduledFor:  fromMillis(hr.ScheduledFor),
+		StartedAt:     fromMillis(hr.StartedAt),
+		EndedAt:       fromMillis(hr.EndedAt),
+		Outcome:       hr.Outcome,
+		Retries:       int(hr.Retries),
+		StatusMessage: hr.StatusMessage,
+	}
+
+	return run
+}
+
+// Record implements TaskHistoryStore.Record
+func (rs *RDBMSHistoryStore) Record(run *TaskRun) error {
+
+	client, err := rs.DBClientManager.ClientFromContext(context.Background())
+
+	if err != nil {
+		return err
+	}
+
+	params := map[string]interface{}{
+		"TaskID":        run.TaskID,
+		"Invocation":    int64(run.Invocation),
+		"Reason":        run.Reason,
+		"ScheduledFor":  toMillis(run.ScheduledFor),
+		"StartedAt":     toMillis(run.StartedAt),
+		"EndedAt":       toMillis(run.EndedAt),
+		"Outcome":       run.Outcome,
+		"Retries":       run.Retries,
+		"StatusMessage": run.StatusMessage,
+	}
+
+	_, err = client.InsertQIDParams(rs.RecordQueryID, params)
+
+	return err
+}
+
+// Runs implements TaskHistoryStore.Runs
+func (rs *RDBMSHistoryStore) Runs(taskID string, limit int) ([]*TaskRun, error) {
+	return rs.find(rs.RunsQueryID, map[string]interface{}{"TaskID": taskID, "Limit": limit})
+}
+
+// LastScheduledRun implements TaskHistoryStore.LastScheduledRun
+func (rs *RDBMSHistoryStore) LastScheduledRun(taskID string) (*TaskRun, error) {
+
+	runs, err := rs.find(rs.LastScheduledQueryID, map[string]interface{}{"TaskID": taskID})
+
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+
+	return runs[0], nil
+}
+
+func (rs *RDBMSHistoryStore) find(qid string, params map[string]interface{}) ([]*TaskRun, error) {
+
+	client, err := rs.DBClientManager.ClientFromContext(context.Background())
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := client.SelectBindQIDParams(qid, new(historyRow), params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*TaskRun, len(rows))
+
+	for i, r := range rows {
+		runs[i] = r.(*historyRow).run()
+	}
+
+	return runs, nil
+}
+
+// StartComponent is called by the IoC container. Checks that a ClientManager is available.
+func (rs *RDBMSHistoryStore) StartComponent() error {
+
+	if rs.DBClientManager == nil {
+		return errors.New("no DBClientManager available. Is the RdbmsAccess facility enabled?")
+	}
+
+	if rs.RecordQueryID == "" {
+		rs.RecordQueryID = defaultRecordQueryID
+	}
+
+	if rs.RunsQueryID == "" {
+		rs.RunsQueryID = defaultRunsQueryID
+	}
+
+	if rs.LastScheduledQueryID == "" {
+		rs.LastScheduledQueryID = defaultLastScheduledQueryID
+	}
+
+	return nil
+}
+
+func toMillis(t time.Time) int64 {
+
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
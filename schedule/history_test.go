@@ -0,0 +1,221 @@
+package schedule
+
+import (
+	"errors"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/test/rdbmstest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryHistoryStore(t *testing.T) {
+
+	ms := &MemoryHistoryStore{MaxRunsPerTask: 3}
+
+	for i := 1; i <= 4; i++ {
+		ms.Record(&TaskRun{TaskID: "t", Invocation: uint64(i), Reason: Scheduled})
+	}
+
+	ms.Record(&TaskRun{TaskID: "t", Invocation: 5, Reason: Manual})
+
+	runs, _ := ms.Runs("t", 10)
+	test.ExpectInt(t, len(runs), 3)
+	test.ExpectBool(t, runs[0].Invocation == 5, true)
+	test.ExpectBool(t, runs[2].Invocation == 3, true)
+
+	last, _ := ms.LastScheduledRun("t")
+	test.ExpectBool(t, last.Invocation == 4, true)
+
+	last, _ = ms.LastScheduledRun("other")
+	test.ExpectBool(t, last == nil, true)
+}
+
+func TestFileHistoryStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "grnc-history")
+	test.ExpectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "history")
+
+	fs := &FileHistoryStore{Path: path, MaxRunsPerTask: 2}
+	test.ExpectNil(t, fs.StartComponent())
+
+	scheduled := time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		test.ExpectNil(t, fs.Record(&TaskRun{TaskID: "t", Invocation: uint64(i), Reason: Scheduled, ScheduledFor: scheduled, Outcome: Succeeded}))
+	}
+
+	// A new store (as after a restart) reads the file and keeps only the most recent runs
+	fs = &FileHistoryStore{Path: path, MaxRunsPerTask: 2}
+	test.ExpectNil(t, fs.StartComponent())
+
+	runs, _ := fs.Runs("t", 10)
+	test.ExpectInt(t, len(runs), 2)
+	test.ExpectBool(t, runs[0].Invocation == 3, true)
+	test.ExpectBool(t, runs[0].ScheduledFor.Equal(scheduled), true)
+
+	b, _ := ioutil.ReadFile(path)
+	test.ExpectInt(t, strings.Count(string(b), "\n"), 2)
+
+	ioutil.WriteFile(path, []byte("not json\n"), 0644)
+
+	fs = &FileHistoryStore{Path: path}
+	test.ExpectNotNil(t, fs.StartComponent())
+	test.ExpectNotNil(t, new(FileHistoryStore).StartComponent())
+}
+
+func TestRDBMSHistoryStore(t *testing.T) {
+
+	fc := rdbmstest.NewFakeClient()
+
+	rs := new(RDBMSHistoryStore)
+	test.ExpectNotNil(t, rs.StartComponent())
+
+	rs.DBClientManager = rdbmstest.NewFakeClientManager(fc)
+	test.ExpectNil(t, rs.StartComponent())
+
+	started := time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	test.ExpectNil(t, rs.Record(&TaskRun{TaskID: "t", Invocation: 2, Reason: Scheduled, StartedAt: started, Outcome: Failed, Retries: 1}))
+
+	fc.ExpectParam(t, defaultRecordQueryID, "TaskID", "t")
+	fc.ExpectParam(t, defaultRecordQueryID, "StartedAt", toMillis(started))
+	fc.ExpectParam(t, defaultRecordQueryID, "Retries", 1)
+
+	columns := []string{"task_id", "invocation", "reason", "scheduled_for", "started_at", "ended_at", "outcome", "retries", "status_message"}
+
+	fc.On(defaultRunsQueryID).WithRows(columns, []interface{}{"t", int64(2), Scheduled, int64(0), toMillis(started), int64(0), Failed, int64(1), "timeout"})
+
+	runs, err := rs.Runs("t", 5)
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(runs), 1)
+	test.ExpectBool(t, runs[0].StartedAt.Equal(started), true)
+	test.ExpectString(t, runs[0].StatusMessage, "timeout")
+	fc.ExpectParam(t, defaultRunsQueryID, "Limit", 5)
+
+	last, err := rs.LastScheduledRun("t")
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, last == nil, true)
+}
+
+func TestCatchUp(t *testing.T) {
+
+	now := time.Now()
+
+	missed := func(policy string) []*invocation {
+
+		ms := new(MemoryHistoryStore)
+		ms.Record(&TaskRun{TaskID: "t", Reason: Scheduled, ScheduledFor: now.Add(-210 * time.Minute)})
+		ms.Record(&TaskRun{TaskID: "t", Reason: Manual, ScheduledFor: now.Add(-10 * time.Minute)})
+
+		im := newInvocationManager(&Task{ID: "t", CatchUp: policy})
+		im.Log = logging.CreateAnonymousLogger("catchUp", logging.Fatal)
+		im.Interval = &interval{Mode: OffsetFromStart, Frequency: time.Hour}
+		im.history = ms
+
+		im.queueCatchUp(now)
+
+		return im.scheduled.Contents()
+	}
+
+	test.ExpectInt(t, len(missed(CatchUpSkip)), 0)
+
+	all := missed(CatchUpAll)
+	test.ExpectInt(t, len(all), 3)
+	test.ExpectString(t, all[0].reason, CatchUp)
+	test.ExpectBool(t, all[0].scheduledFor.Equal(now.Add(-150*time.Minute)), true)
+
+	once := missed(CatchUpOnce)
+	test.ExpectInt(t, len(once), 1)
+	test.ExpectBool(t, once[0].scheduledFor.Equal(now.Add(-30*time.Minute)), true)
+
+	// Only the most recent runs are found, however many were missed
+	ms := new(MemoryHistoryStore)
+	ms.Record(&TaskRun{TaskID: "t", Reason: Scheduled, ScheduledFor: now.Add(-21 * 24 * time.Hour)})
+
+	im := newInvocationManager(&Task{ID: "t", CatchUp: CatchUpAll})
+	im.Interval = &interval{Mode: OffsetFromStart, Frequency: time.Second}
+	im.history = ms
+
+	runs, first, more, err := im.missedRuns(now, maxCatchUpRuns)
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(runs), maxCatchUpRuns)
+	test.ExpectBool(t, more, true)
+	test.ExpectBool(t, first.Equal(now.Add(-21*24*time.Hour+time.Second)), true)
+	test.ExpectBool(t, runs[0].Equal(now.Add(-maxCatchUpRuns*time.Second)), true)
+	test.ExpectBool(t, runs[maxCatchUpRuns-1].Equal(now.Add(-time.Second)), true)
+
+	cron, _ := parseCron("0 0 * * * *", time.UTC)
+	im.Interval = &interval{Mode: CronExpression, Cron: cron}
+
+	at := time.Date(2020, 3, 1, 10, 30, 0, 0, time.UTC)
+	ms.Record(&TaskRun{TaskID: "t", Reason: Scheduled, ScheduledFor: at.Add(-21 * 24 * time.Hour)})
+
+	runs, _, more, _ = im.missedRuns(at, 2)
+	test.ExpectInt(t, len(runs), 2)
+	test.ExpectBool(t, more, true)
+	test.ExpectBool(t, runs[0].Equal(time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC)), true)
+	test.ExpectBool(t, runs[1].Equal(time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)), true)
+
+	_, err = validCatchUp("sometimes")
+	test.ExpectNotNil(t, err)
+
+	p, _ := validCatchUp("once")
+	test.ExpectString(t, p, CatchUpOnce)
+}
+
+func TestRunsRecorded(t *testing.T) {
+
+	ms := new(MemoryHistoryStore)
+
+	logic := &messageLogic{message: "processed 3 files"}
+
+	im := newInvocationManager(&Task{ID: "t", logic: logic})
+	im.Log = logging.CreateAnonymousLogger("recorded", logging.Fatal)
+	im.history = ms
+
+	runAt := time.Now()
+
+	i := newInvocation(im.nextCounter(), 0, Scheduled)
+	i.runAt = runAt
+	im.runTask(i)
+
+	logic.err = errors.New("disk full")
+
+	i = newInvocation(im.nextCounter(), 0, Manual)
+	im.runTask(i)
+
+	runs, _ := ms.Runs("t", 10)
+	test.ExpectInt(t, len(runs), 2)
+
+	test.ExpectString(t, runs[1].Outcome, Succeeded)
+	test.ExpectString(t, runs[1].StatusMessage, "processed 3 files")
+	test.ExpectBool(t, runs[1].ScheduledFor.Equal(runAt), true)
+
+	test.ExpectString(t, runs[0].Outcome, Failed)
+	test.ExpectString(t, runs[0].Reason, Manual)
+	test.ExpectString(t, runs[0].StatusMessage, "disk full")
+
+	co, _ := NewTaskCommand(&TaskScheduler{managedTasks: []*invocationManager{im}}).ExecuteCommand([]string{"t"}, nil)
+	last := co.OutputBody[len(co.OutputBody)-1]
+	test.ExpectString(t, last[0], "Previous run")
+	test.ExpectBool(t, strings.HasSuffix(last[1], "processed 3 files"), true)
+}
+
+type messageLogic struct {
+	message string
+	err     error
+}
+
+func (ml *messageLogic) ExecuteTask(c chan TaskStatusUpdate) error {
+	c <- StatusMessagef(ml.message)
+
+	return ml.err
+}
@@ -15,6 +15,10 @@ const (
 	Retry = "Retry"
 	//Manual indicates that this is the invocation was triggered outside of a schedule
 	Manual = "Manual"
+	//CatchUp indicates that this invocation replaces a scheduled invocation missed while the application was not running
+	CatchUp = "CatchUp"
+	//Upstream indicates that this invocation was triggered by the successful completion of the tasks it runs after
+	Upstream = "Upstream"
 )
 
 func newInvocation(counter uint64, retries int, reason string) *invocation {
@@ -34,6 +38,13 @@ type invocation struct {
 	attempt     int
 	maxAttempts int
 	reason      string
+	// The reason for the first attempt of an invocation that is being retried
+	originalReason string
+	scheduledFor   time.Time
+	firstStarted   time.Time
+	lastMessage    string
+	// Why the most recent attempt was stopped before it finished (if it was)
+	interrupted string
 }
 
 func (i *invocation) firstAttempt() bool {
@@ -0,0 +1,484 @@
+// Copyright 2018-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLockLease = time.Minute
+
+	// How long a FileLockProvider waits to enter its critical section and how old a guard file must be before it is
+	// assumed to have been left behind by a process that stopped
+	guardWait  = 5 * time.Second
+	guardStale = 10 * time.Second
+
+	defaultLockTakeQueryID    = "taskLockTake"
+	defaultLockCreateQueryID  = "taskLockCreate"
+	defaultLockRenewQueryID   = "taskLockRenew"
+	defaultLockReleaseQueryID = "taskLockRelease"
+	defaultLockHolderQueryID  = "taskLockHolder"
+)
+
+// TaskLockProvider is implemented by components that can hold a lock on a task that is shared between all instances of
+// an application, so that Singleton tasks only run on one instance at a time. Locks are held for a lease period and
+// expire unless they are renewed, so a lock held by an instance that stops unexpectedly is eventually released.
+type TaskLockProvider interface {
+	// Acquire takes the lock on the task for the holder for the duration of the lease. Returns false if the lock is
+	// held by another holder.
+	Acquire(taskID, holder string, lease time.Duration) (bool, error)
+
+	// Renew extends the lease on a lock held by the holder. Returns false if the lock is no longer held by the holder.
+	Renew(taskID, holder string, lease time.Duration) (bool, error)
+
+	// Release gives up a lock held by the holder.
+	Release(taskID, holder string) error
+
+	// Holder returns the current holder of the lock and when its lease expires, or an empty string if the lock is not held.
+	Holder(taskID string) (string, time.Time, error)
+}
+
+// fileLock is the content of a lock file
+type fileLock struct {
+	Holder  string
+	Expires time.Time
+}
+
+// FileLockProvider is a TaskLockProvider that holds locks in files, for use when all instances of an application run on
+// the same host (or share a file system that supports exclusive file creation).
+type FileLockProvider struct {
+	// The directory in which lock files are created. Defaults to the system's temporary directory
+	Directory string
+
+	mux sync.Mutex
+}
+
+// Acquire implements TaskLockProvider.Acquire
+func (fp *FileLockProvider) Acquire(taskID, holder string, lease time.Duration) (bool, error) {
+	return fp.update(taskID, holder, func(current *fileLock, now time.Time) *fileLock {
+
+		if current != nil && current.Holder != holder && now.Before(current.Expires) {
+			return nil
+		}
+
+		return &fileLock{Holder: holder, Expires: now.Add(lease)}
+	})
+}
+
+// Renew implements TaskLockProvider.Renew
+func (fp *FileLockProvider) Renew(taskID, holder string, lease time.Duration) (bool, error) {
+	return fp.update(taskID, holder, func(current *fileLock, now time.Time) *fileLock {
+
+		if current == nil || current.Holder != holder {
+			return nil
+		}
+
+		return &fileLock{Holder: holder, Expires: now.Add(lease)}
+	})
+}
+
+// Release implements TaskLockProvider.Release
+func (fp *FileLockProvider) Release(taskID, holder string) error {
+
+	_, err := fp.update(taskID, holder, func(current *fileLock, now time.Time) *fileLock {
+
+		if current == nil || current.Holder != holder {
+			return nil
+		}
+
+		return &fileLock{}
+	})
+
+	return err
+}
+
+// Holder implements TaskLockProvider.Holder
+func (fp *FileLockProvider) Holder(taskID string) (string, time.Time, error) {
+
+	current, err := fp.read(fp.path(taskID))
+
+	if err != nil || current == nil || !time.Now().Before(current.Expires) {
+		return "", time.Time{}, err
+	}
+
+	return current.Holder, current.Expires, nil
+}
+
+// update replaces the content of a task's lock file with the lock returned by change. If change returns nil, the lock
+// file is not changed and false is returned.
+func (fp *FileLockProvider) update(taskID, holder string, change func(current *fileLock, now time.Time) *fileLock) (bool, error) {
+
+	fp.mux.Lock()
+	defer fp.mux.Unlock()
+
+	path := fp.path(taskID)
+	guard := path + ".guard"
+
+	if err := fp.enter(guard); err != nil {
+		return false, err
+	}
+
+	defer os.Remove(guard)
+
+	current, err := fp.read(path)
+
+	if err != nil {
+		return false, err
+	}
+
+	updated := change(current, time.Now())
+
+	if updated == nil {
+		return false, nil
+	}
+
+	b, _ := json.Marshal(updated)
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return false, err
+	}
+
+	return true, os.Rename(tmp, path)
+}
+
+// enter creates the guard file that gives this process exclusive access to a lock file, waiting for other processes to
+// remove it.
+func (fp *FileLockProvider) enter(guard string) error {
+
+	deadline := time.Now().Add(guardWait)
+
+	for {
+
+		f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+
+		if err == nil {
+			return f.Close()
+		}
+
+		if !os.IsExist(err) {
+			return err
+		}
+
+		if fi, err := os.Stat(guard); err == nil && time.Since(fi.ModTime()) > guardStale {
+			os.Remove(guard)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for access to lock file guard %s", guard)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (fp *FileLockProvider) read(path string) (*fileLock, error) {
+
+	b, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	fl := new(fileLock)
+
+	if err := json.Unmarshal(b, fl); err != nil {
+		return nil, fmt.Errorf("unable to parse lock file %s: %s", path, err.Error())
+	}
+
+	return fl, nil
+}
+
+func (fp *FileLockProvider) path(taskID string) string {
+
+	dir := fp.Directory
+
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "grnc-task-"+taskID+".lock")
+}
+
+/*
+RDBMSLockProvider is a TaskLockProvider that holds locks in a database table via the RdbmsAccess and QueryManager
+facilities. Each query is passed the parameters TaskID, Holder, Now and Expires (times are Unix timestamps in
+milliseconds). The default query IDs and example queries are:
+
+	taskLockTake (takes an expired lock, or a lock already held by the holder)
+
+	UPDATE task_lock SET holder = ${Holder}, expires = ${Expires}
+	WHERE task_id = ${TaskID} AND (holder = ${Holder} OR expires < ${Now})
+
+	taskLockCreate (creates the row for a task the first time it is locked - task_id must be the primary key)
+
+	INSERT INTO task_lock (task_id, holder, expires) VALUES (${TaskID}, ${Holder}, ${Expires})
+
+	taskLockRenew
+
+	UPDATE task_lock SET expires = ${Expires} WHERE task_id = ${TaskID} AND holder = ${Holder}
+
+	taskLockRelease
+
+	UPDATE task_lock SET expires = 0 WHERE task_id = ${TaskID} AND holder = ${Holder}
+
+	taskLockHolder (must return the columns holder and expires)
+
+	SELECT holder, expires FROM task_lock WHERE task_id = ${TaskID}
+
+Because lease expiry is calculated from the clock of each instance, the clocks of all instances should be synchronised.
+*/
+type RDBMSLockProvider struct {
+	// Injected by Granitic if the RdbmsAccess facility is enabled.
+	DBClientManager rdbms.ClientManager
+
+	// The ID of the query used to take an existing lock. Defaults to taskLockTake.
+	TakeQueryID string
+
+	// The ID of the query used to create a lock. Defaults to taskLockCreate.
+	CreateQueryID string
+
+	// The ID of the query used to renew a lock. Defaults to taskLockRenew.
+	RenewQueryID string
+
+	// The ID of the query used to release a lock. Defaults to taskLockRelease.
+	ReleaseQueryID string
+
+	// The ID of the query used to find the holder of a lock. Defaults to taskLockHolder.
+	HolderQueryID string
+}
+
+type lockRow struct {
+	Holder  string `column:"holder"`
+	Expires int64  `column:"expires"`
+}
+
+// Acquire implements TaskLockProvider.Acquire
+func (rp *RDBMSLockProvider) Acquire(taskID, holder string, lease time.Duration) (bool, error) {
+
+	client, err := rp.DBClientManager.ClientFromContext(context.Background())
+
+	if err != nil {
+		return false, err
+	}
+
+	params := rp.params(taskID, holder, lease)
+
+	if taken, err := affected(client.UpdateQIDParams(rp.TakeQueryID, params)); err != nil || taken {
+		return taken, err
+	}
+
+	// The lock is held by another holder or has never been created
+	found, err := client.SelectBindSingleQIDParams(rp.HolderQueryID, new(lockRow), params)
+
+	if err != nil || found {
+		return false, err
+	}
+
+	if _, err = client.InsertQIDParams(rp.CreateQueryID, params); err == nil {
+		return true, nil
+	}
+
+	// Another instance may have created the lock first
+	if found, _ = client.SelectBindSingleQIDParams(rp.HolderQueryID, new(lockRow), params); found {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// Renew implements TaskLockProvider.Renew
+func (rp *RDBMSLockProvider) Renew(taskID, holder string, lease time.Duration) (bool, error) {
+
+	client, err := rp.DBClientManager.ClientFromContext(context.Background())
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected(client.UpdateQIDParams(rp.RenewQueryID, rp.params(taskID, holder, lease)))
+}
+
+// Release implements TaskLockProvider.Release
+func (rp *RDBMSLockProvider) Release(taskID, holder string) error {
+
+	client, err := rp.DBClientManager.ClientFromContext(context.Background())
+
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UpdateQIDParams(rp.ReleaseQueryID, rp.params(taskID, holder, 0))
+
+	return err
+}
+
+// Holder implements TaskLockProvider.Holder
+func (rp *RDBMSLockProvider) Holder(taskID string) (string, time.Time, error) {
+
+	client, err := rp.DBClientManager.ClientFromContext(context.Background())
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	lr := new(lockRow)
+
+	found, err := client.SelectBindSingleQIDParams(rp.HolderQueryID, lr, map[string]interface{}{"TaskID": taskID})
+
+	if err != nil || !found {
+		return "", time.Time{}, err
+	}
+
+	expires := fromMillis(lr.Expires)
+
+	if !time.Now().Before(expires) {
+		return "", time.Time{}, nil
+	}
+
+	return lr.Holder, expires, nil
+}
+
+func (rp *RDBMSLockProvider) params(taskID, holder string, lease time.Duration) map[string]interface{} {
+
+	now := time.Now()
+
+	return map[string]interface{}{
+		"TaskID":  taskID,
+		"Holder":  holder,
+		"Now":     toMillis(now),
+		"Expires": toMillis(now.Add(lease)),
+	}
+}
+
+// StartComponent is called by the IoC container. Checks that a ClientManager is available.
+func (rp *RDBMSLockProvider) StartComponent() error {
+
+	if rp.DBClientManager == nil {
+		return errors.New("no DBClientManager available. Is the RdbmsAccess facility enabled?")
+	}
+
+	if rp.TakeQueryID == "" {
+		rp.TakeQueryID = defaultLockTakeQueryID
+	}
+
+	if rp.CreateQueryID == "" {
+		rp.CreateQueryID = defaultLockCreateQueryID
+	}
+
+	if rp.RenewQueryID == "" {
+		rp.RenewQueryID = defaultLockRenewQueryID
+	}
+
+	if rp.ReleaseQueryID == "" {
+		rp.ReleaseQueryID = defaultLockReleaseQueryID
+	}
+
+	if rp.HolderQueryID == "" {
+		rp.HolderQueryID = defaultLockHolderQueryID
+	}
+
+	return nil
+}
+
+// affected returns true if the result of a query shows at least one row was changed
+func affected(r sql.Result, err error) (bool, error) {
+
+	if err != nil {
+		return false, err
+	}
+
+	n, err := r.RowsAffected()
+
+	return n > 0, err
+}
+
+// acquireLock takes the lock on a Singleton task before an invocation runs and starts renewing its lease. Returns false
+// if the invocation should not run.
+func (im *invocationManager) acquireLock(i *invocation) bool {
+
+	task := im.Task
+
+	acquired, err := im.locks.Acquire(task.ID, im.holder, task.lockLease)
+
+	if err != nil {
+		im.Log.LogErrorf("Unable to acquire lock on task %s - invocation %d will not run: %s", task.FullName(), i.counter, err.Error())
+		return false
+	}
+
+	if !acquired {
+		holder, _, _ := im.locks.Holder(task.ID)
+		im.Log.LogInfof("Task %s is locked by another instance (%s) - invocation %d will not run", task.FullName(), holder, i.counter)
+		return false
+	}
+
+	stop := make(chan struct{})
+
+	im.lockMux.Lock()
+	im.lockStop = stop
+	im.lockMux.Unlock()
+
+	go im.renewLock(stop)
+
+	return true
+}
+
+// renewLock extends the lease on a Singleton task's lock at intervals of a third of the lease until stop is closed
+func (im *invocationManager) renewLock(stop chan struct{}) {
+
+	task := im.Task
+
+	ticker := time.NewTicker(task.lockLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+
+			renewed, err := im.locks.Renew(task.ID, im.holder, task.lockLease)
+
+			if err != nil {
+				im.Log.LogErrorf("Unable to renew lock on task %s: %s", task.FullName(), err.Error())
+			} else if !renewed {
+				im.Log.LogErrorf("Lock on task %s was lost while the task was running - another instance may run the task at the same time", task.FullName())
+			}
+		}
+	}
+}
+
+// releaseLock stops the lease on a Singleton task's lock being renewed and gives up the lock, if this instance holds it
+func (im *invocationManager) releaseLock() {
+
+	im.lockMux.Lock()
+	stop := im.lockStop
+	im.lockStop = nil
+	im.lockMux.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+
+	if err := im.locks.Release(im.Task.ID, im.holder); err != nil {
+		im.Log.LogErrorf("Unable to release lock on task %s: %s", im.Task.FullName(), err.Error())
+	}
+}
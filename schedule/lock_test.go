@@ -0,0 +1,224 @@
+package schedule
+
+import (
+	"errors"
+	"github.com/graniticio/granitic/v2/instance"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/test/rdbmstest"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLockProvider(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "grnc-lock")
+	test.ExpectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	fp := &FileLockProvider{Directory: dir}
+
+	acquired, err := fp.Acquire("t", "a", time.Minute)
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, acquired, true)
+
+	// A second provider simulates another process using the same directory
+	other := &FileLockProvider{Directory: dir}
+
+	acquired, _ = other.Acquire("t", "b", time.Minute)
+	test.ExpectBool(t, acquired, false)
+
+	renewed, _ := other.Renew("t", "b", time.Minute)
+	test.ExpectBool(t, renewed, false)
+
+	holder, expires, _ := other.Holder("t")
+	test.ExpectString(t, holder, "a")
+	test.ExpectBool(t, expires.After(time.Now()), true)
+
+	renewed, _ = fp.Renew("t", "a", time.Minute)
+	test.ExpectBool(t, renewed, true)
+
+	// Releasing a lock held by another holder has no effect
+	test.ExpectNil(t, other.Release("t", "b"))
+	holder, _, _ = fp.Holder("t")
+	test.ExpectString(t, holder, "a")
+
+	test.ExpectNil(t, fp.Release("t", "a"))
+	holder, _, _ = fp.Holder("t")
+	test.ExpectString(t, holder, "")
+
+	acquired, _ = other.Acquire("t", "b", time.Millisecond)
+	test.ExpectBool(t, acquired, true)
+
+	// An expired lease can be taken by another holder
+	time.Sleep(5 * time.Millisecond)
+
+	acquired, _ = fp.Acquire("t", "a", time.Minute)
+	test.ExpectBool(t, acquired, true)
+}
+
+func TestRDBMSLockProvider(t *testing.T) {
+
+	fc := rdbmstest.NewFakeClient()
+
+	rp := new(RDBMSLockProvider)
+	test.ExpectNotNil(t, rp.StartComponent())
+
+	rp.DBClientManager = rdbmstest.NewFakeClientManager(fc)
+	test.ExpectNil(t, rp.StartComponent())
+
+	// The lock has never been created
+	fc.On(defaultLockTakeQueryID).WithResult(0, 0)
+
+	acquired, err := rp.Acquire("t", "a", time.Minute)
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, acquired, true)
+	fc.ExpectCalls(t, defaultLockTakeQueryID, defaultLockHolderQueryID, defaultLockCreateQueryID)
+	fc.ExpectParam(t, defaultLockCreateQueryID, "Holder", "a")
+
+	// The lock is held by another holder
+	fc.Reset()
+	expires := time.Now().Add(time.Minute)
+	fc.On(defaultLockTakeQueryID).WithResult(0, 0)
+	fc.On(defaultLockHolderQueryID).WithRows([]string{"holder", "expires"}, []interface{}{"b", toMillis(expires)})
+
+	acquired, err = rp.Acquire("t", "a", time.Minute)
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, acquired, false)
+	fc.ExpectNotCalled(t, defaultLockCreateQueryID)
+
+	holder, _, err := rp.Holder("t")
+	test.ExpectNil(t, err)
+	test.ExpectString(t, holder, "b")
+
+	fc.Reset()
+	fc.On(defaultLockTakeQueryID).WithResult(1, 0)
+
+	acquired, _ = rp.Acquire("t", "a", time.Minute)
+	test.ExpectBool(t, acquired, true)
+	fc.ExpectNotCalled(t, defaultLockHolderQueryID)
+
+	fc.On(defaultLockRenewQueryID).WithResult(0, 0)
+	renewed, _ := rp.Renew("t", "a", time.Minute)
+	test.ExpectBool(t, renewed, false)
+
+	fc.On(defaultLockReleaseQueryID).WithError(errors.New("connection lost"))
+	test.ExpectNotNil(t, rp.Release("t", "a"))
+}
+
+func TestSingletonTask(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "grnc-lock")
+	test.ExpectNil(t, err)
+	defer os.RemoveAll(dir)
+
+	fp := &FileLockProvider{Directory: dir}
+	logic := new(lockCheckingLogic)
+
+	task := &Task{ID: "t", Singleton: true, lockLease: time.Minute, logic: logic}
+	logic.provider = fp
+	logic.task = task
+
+	im := newInvocationManager(task)
+	im.Log = logging.CreateAnonymousLogger("singleton", logging.Fatal)
+	im.locks = fp
+	im.holder = "a"
+
+	im.runTask(newInvocation(im.nextCounter(), 0, Manual))
+	test.ExpectString(t, logic.holder, "a")
+	test.ExpectInt(t, logic.runs, 1)
+
+	// The lock is released when the task finishes
+	holder, _, _ := fp.Holder("t")
+	test.ExpectString(t, holder, "")
+
+	// Another instance holds the lock, so the task does not run
+	fp.Acquire("t", "b", time.Minute)
+
+	im.runTask(newInvocation(im.nextCounter(), 0, Manual))
+	test.ExpectInt(t, logic.runs, 1)
+	test.ExpectBool(t, im.running.Size() == 0, true)
+
+	co, _ := NewTaskCommand(&TaskScheduler{managedTasks: []*invocationManager{im}}).ExecuteCommand([]string{"t"}, nil)
+
+	found := false
+
+	for _, row := range co.OutputBody {
+		if row[0] == "Lock holder" {
+			found = strings.HasPrefix(row[1], "b (lease expires")
+		}
+	}
+
+	test.ExpectBool(t, found, true)
+}
+
+func TestSingletonValidation(t *testing.T) {
+
+	start := func(task *Task, components ...*ioc.Component) error {
+
+		task.logic = new(nullLogic)
+		task.Every = "1 hour"
+
+		ts := new(TaskScheduler)
+		ts.FrameworkLogger = new(logging.ConsoleErrorLogger)
+		ts.FrameworkLogManager = logging.CreateComponentLoggerManager(logging.Fatal, make(map[string]interface{}), []logging.LogWriter{}, logging.NewFrameworkLogMessageFormatter(), false)
+		ts.RegisterInstanceID(&instance.Identifier{ID: "node-1"})
+		ts.componentContainer = &mockComponentsContainer{components: append(components, ioc.NewComponent("task", task))}
+
+		err := ts.StartComponent()
+
+		if err == nil && task.Singleton {
+			test.ExpectString(t, ts.managedTasks[0].holder, "node-1")
+		}
+
+		return err
+	}
+
+	test.ExpectNotNil(t, start(&Task{Component: "logic", Singleton: true}))
+	test.ExpectNil(t, start(&Task{Component: "logic", Singleton: true}, ioc.NewComponent("locks", new(FileLockProvider))))
+	test.ExpectNil(t, start(&Task{Component: "logic"}))
+
+	test.ExpectNotNil(t, start(&Task{Component: "logic", LockLease: "1 minute"}))
+	test.ExpectNotNil(t, start(&Task{Component: "logic", Singleton: true, MaxOverlapping: 1}, ioc.NewComponent("locks", new(FileLockProvider))))
+	test.ExpectNotNil(t, start(&Task{Component: "logic", Singleton: true, LockLease: "soon"}, ioc.NewComponent("locks", new(FileLockProvider))))
+	test.ExpectNotNil(t, start(&Task{Component: "logic", Singleton: true}, ioc.NewComponent("l1", new(FileLockProvider)), ioc.NewComponent("l2", new(FileLockProvider))))
+}
+
+// mockComponentsContainer returns the supplied components and resolves any other name to a TaskLogic
+type mockComponentsContainer struct {
+	components []*ioc.Component
+}
+
+func (c *mockComponentsContainer) ComponentByName(name string) *ioc.Component {
+
+	for _, component := range c.components {
+		if component.Name == name {
+			return component
+		}
+	}
+
+	return ioc.NewComponent(name, new(nullLogic))
+}
+
+func (c *mockComponentsContainer) AllComponents() []*ioc.Component {
+	return c.components
+}
+
+// lockCheckingLogic records which holder has the lock on the task while it runs
+type lockCheckingLogic struct {
+	provider TaskLockProvider
+	task     *Task
+	holder   string
+	runs     int
+}
+
+func (ll *lockCheckingLogic) ExecuteTask(c chan TaskStatusUpdate) error {
+	ll.runs++
+	ll.holder, _, _ = ll.provider.Holder(ll.task.ID)
+
+	return nil
+}
@@ -1,10 +1,14 @@
 package schedule
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/graniticio/granitic/v2/instrument"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,17 +21,44 @@ func newInvocationManager(t *Task) *invocationManager {
 	im.scheduled = new(invocationQueue)
 	im.running = new(invocationQueue)
 	im.State = ioc.StoppedState
+	im.instrumentation = new(noopTaskInstrumentationManager)
+	im.ctx, im.cancelAll = context.WithCancel(context.Background())
+	im.cancels = make(map[uint64]context.CancelFunc)
+	im.reported = make(map[string]bool)
 	return im
 
 }
 
 type invocationManager struct {
+	// Accessed atomically, so must be the first field to guarantee alignment on 32-bit platforms
+	counter uint64
+
 	Task      *Task
 	Interval  *interval
 	scheduled *invocationQueue
 	running   *invocationQueue
 	State     ioc.ComponentState
 	Log       logging.Logger
+	suspended int32
+	history   TaskHistoryStore
+	locks     TaskLockProvider
+	holder    string
+	lockMux   sync.Mutex
+	lockStop  chan struct{}
+
+	instrumentation instrument.TaskInstrumentationManager
+	// Parent of the context passed to each invocation - cancelled when the scheduler stops
+	ctx       context.Context
+	cancelAll context.CancelFunc
+	cancelMux sync.Mutex
+	cancels   map[uint64]context.CancelFunc
+
+	// The managers of tasks that run after this task
+	downstream []*invocationManager
+	depMux     sync.Mutex
+	// The IDs of the tasks this task runs after that have completed in the current cycle and whether any of them failed
+	reported    map[string]bool
+	cycleFailed bool
 }
 
 func (im *invocationManager) Start() {
@@ -35,7 +66,9 @@ func (im *invocationManager) Start() {
 	task := im.Task
 	im.State = ioc.StartingState
 
-	if !task.Disabled {
+	// Tasks that run after other tasks have no schedule of their own
+	if !task.Disabled && im.Interval != nil {
+		im.queueCatchUp(time.Now())
 		im.setFirstInvocation()
 	}
 
@@ -51,7 +84,10 @@ func (im *invocationManager) Start() {
 
 			now := time.Now()
 
-			if runAt == now || runAt.Before(now) {
+			// Catch-up invocations wait for earlier invocations to finish rather than being abandoned
+			waiting := next.reason == CatchUp && im.running.Size() > uint64(task.MaxOverlapping)
+
+			if (runAt == now || runAt.Before(now)) && !waiting {
 				// The time at which this invocation was scheduled to run has arrived (or passed)
 				im.scheduled.Dequeue()
 
@@ -59,8 +95,10 @@ func (im *invocationManager) Start() {
 					im.addNextInvocation(next)
 				}
 
-				// Only run this invocation if this task manager is running
-				if im.State == ioc.RunningState {
+				if im.isSuspended() {
+					im.Log.LogDebugf("Task %s is suspended - invocation %d will not run", im.Task.FullName(), next.counter)
+				} else if im.State == ioc.RunningState {
+					// Only run this invocation if this task manager is running
 
 					if im.running.Size() != 0 && !task.NoWarnOnOverlap {
 						im.Log.LogWarnf("%d instance(s) of task %s are already running.", im.running.Size(), im.Task.FullName())
@@ -88,39 +126,92 @@ func (im *invocationManager) Start() {
 
 func (im *invocationManager) runTask(i *invocation) {
 
+	if im.Task.Singleton && !im.acquireLock(i) {
+		im.running.Remove(i.counter)
+		return
+	}
+
 	i.startedAt = time.Now()
 
+	if i.firstAttempt() {
+		i.firstStarted = i.startedAt
+
+		if i.scheduledFor.IsZero() {
+			i.scheduledFor = i.runAt
+		}
+	}
+
 	if im.Log.IsLevelEnabled(logging.Trace) {
 		im.Log.LogTracef("Accuracy: %v", i.startedAt.Sub(i.runAt))
 	}
 
 	updates := make(chan TaskStatusUpdate, 20)
+	listened := make(chan bool)
 
 	im.Log.LogDebugf("Executing %s", im.Task.FullName())
 
-	if im.Task.LogStatusMessages || im.Task.receiver != nil {
-		go im.listenForStatusUpdates(i, updates)
-	}
+	go im.listenForStatusUpdates(i, updates, listened)
+
+	ctx, endInvocation := im.invocationContext(i)
+	defer endInvocation()
+
+	ctx, _, endInstrumentation := im.instrumentation.Begin(ctx, im.Task.ID, i.counter)
+	defer endInstrumentation()
+
+	var err error
+	retrying := false
 
 	defer func() {
 		if r := recover(); r != nil {
 			im.Log.LogErrorfWithTrace("Panic recovered while executing task %s (invocation %d started at %v)\n %v", im.Task.FullName(), i.counter, i.startedAt, r)
+			err = fmt.Errorf("panic: %v", r)
 		}
 
 		close(updates)
+		<-listened
+
+		if !retrying {
+			run := im.completedRun(i, err)
+			im.recordRun(run)
+			im.notifyDownstream(run)
+		}
+
+		if im.Task.Singleton {
+			im.releaseLock()
+		}
+
 		im.running.Remove(i.counter)
 
 	}()
 
-	err := im.Task.logic.ExecuteTask(updates)
+	if cl := im.Task.contextLogic; cl != nil {
+		err = cl.ExecuteTaskWithContext(ctx, updates)
+	} else {
+		err = im.Task.logic.ExecuteTask(updates)
+	}
+
+	i.interrupted = ""
 
 	if err != nil {
 
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			i.interrupted = fmt.Sprintf("timed out after %v", im.Task.timeout)
+		case context.Canceled:
+			i.interrupted = "cancelled"
+		}
+
 		m := fmt.Sprintf("Problem executing task %s (invocation %d, attempt %d started at %v): %s", im.Task.FullName(), i.counter, i.attempt, i.startedAt, err.Error())
 
-		if _, ok := err.(*AllowRetryError); ok {
+		if i.interrupted != "" {
+			m = fmt.Sprintf("Task %s (invocation %d, attempt %d started at %v) %s: %s", im.Task.FullName(), i.counter, i.attempt, i.startedAt, i.interrupted, err.Error())
+		}
+
+		// Invocations cancelled manually or because the scheduler is stopping are not retried
+		if _, ok := err.(*AllowRetryError); ok && ctx.Err() != context.Canceled {
 
 			if okay, when := im.attemptRetry(i); okay {
+				retrying = true
 				im.Log.LogWarnf(m)
 				im.Log.LogWarnf("Will retry at %v", when)
 			} else {
@@ -144,14 +235,18 @@ func (im *invocationManager) attemptRetry(i *invocation) (bool, time.Time) {
 
 	retryTime := time.Now().Add(im.Task.retryWait)
 
-	nextScheduled := im.scheduled.PeekHead().runAt
+	next := im.scheduled.PeekHead()
 
-	if nextScheduled.Before(retryTime) {
+	if next != nil && next.runAt.Before(retryTime) {
 		//No point retrying as next scheduled run will happen before that
 		im.Log.LogWarnf("Retry attempt attempt abandoned as next scheduled invocation will arrive first")
 		return false, time.Now()
 	}
 
+	if i.reason != Retry {
+		i.originalReason = i.reason
+	}
+
 	i.attempt++
 	i.runAt = retryTime
 	i.reason = Retry
@@ -162,7 +257,53 @@ func (im *invocationManager) attemptRetry(i *invocation) (bool, time.Time) {
 
 }
 
-func (im *invocationManager) listenForStatusUpdates(i *invocation, ch chan TaskStatusUpdate) {
+// completedRun summarises the outcome of an invocation that will not be retried
+func (im *invocationManager) completedRun(i *invocation, err error) *TaskRun {
+
+	run := &TaskRun{
+		TaskID:        im.Task.ID,
+		Invocation:    i.counter,
+		Reason:        i.reason,
+		ScheduledFor:  i.scheduledFor,
+		StartedAt:     i.firstStarted,
+		EndedAt:       time.Now(),
+		Outcome:       Succeeded,
+		Retries:       i.attempt - 1,
+		StatusMessage: i.lastMessage,
+	}
+
+	if i.reason == Retry {
+		run.Reason = i.originalReason
+	}
+
+	if err != nil {
+		run.Outcome = Failed
+		run.StatusMessage = err.Error()
+	}
+
+	if err != nil && i.interrupted != "" {
+		run.Outcome = Cancelled
+		run.StatusMessage = i.interrupted + ": " + err.Error()
+	}
+
+	return run
+}
+
+// recordRun adds the outcome of an invocation to the task's history
+func (im *invocationManager) recordRun(run *TaskRun) {
+
+	if im.history == nil {
+		return
+	}
+
+	if err := im.history.Record(run); err != nil {
+		im.Log.LogErrorf("Unable to record the outcome of task %s (invocation %d): %s", im.Task.FullName(), run.Invocation, err.Error())
+	}
+}
+
+func (im *invocationManager) listenForStatusUpdates(i *invocation, ch chan TaskStatusUpdate, done chan bool) {
+
+	defer close(done)
 
 	task := im.Task
 
@@ -180,6 +321,10 @@ func (im *invocationManager) listenForStatusUpdates(i *invocation, ch chan TaskS
 			break
 		}
 
+		if len(su.Message) > 0 {
+			i.lastMessage = su.Message
+		}
+
 		if task.LogStatusMessages && len(su.Message) > 0 {
 			im.Log.LogInfof("Task: %s Invocation: %d: %s", task.FullName(), i.counter, su.Message)
 		}
@@ -192,6 +337,46 @@ func (im *invocationManager) listenForStatusUpdates(i *invocation, ch chan TaskS
 
 }
 
+// invocationContext creates the context for an attempt to run an invocation, which is cancelled if the scheduler stops,
+// if the task's timeout expires or if running invocations are cancelled. The returned function must be called when the
+// attempt ends.
+func (im *invocationManager) invocationContext(i *invocation) (context.Context, func()) {
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if im.Task.timeout > 0 {
+		ctx, cancel = context.WithTimeout(im.ctx, im.Task.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(im.ctx)
+	}
+
+	im.cancelMux.Lock()
+	im.cancels[i.counter] = cancel
+	im.cancelMux.Unlock()
+
+	return ctx, func() {
+		im.cancelMux.Lock()
+		delete(im.cancels, i.counter)
+		im.cancelMux.Unlock()
+
+		cancel()
+	}
+}
+
+// cancel cancels the context of every running invocation of the task and returns the number of invocations cancelled
+func (im *invocationManager) cancel() int {
+
+	im.cancelMux.Lock()
+	defer im.cancelMux.Unlock()
+
+	for _, cancel := range im.cancels {
+		cancel()
+	}
+
+	return len(im.cancels)
+}
+
 func (im *invocationManager) determineWait() time.Duration {
 
 	next := im.scheduled.PeekHead()
@@ -213,15 +398,22 @@ func (im *invocationManager) setFirstInvocation() {
 
 	interval := im.Interval
 
-	i := newInvocation(1, im.Task.MaxRetries, Scheduled)
+	i := newInvocation(im.nextCounter(), im.Task.MaxRetries, Scheduled)
 
-	if interval.Mode == OffsetFromStart {
+	switch interval.Mode {
+	case OffsetFromStart:
 		i.runAt = time.Now().Add(interval.OffsetFromStart)
-	} else {
+	case CronExpression:
+		i.runAt = interval.Cron.next(time.Now())
+	default:
 		i.runAt = interval.ActualStart
 	}
 
-	im.Log.LogInfof("Task '%s' will first run at %s and intervals of %v thereafter", im.Task.FullName(), i.runAt.Format(firstRunFormat), interval.Frequency)
+	if interval.Mode == CronExpression {
+		im.Log.LogInfof("Task '%s' will first run at %s and then on the schedule %s", im.Task.FullName(), i.runAt.Format(firstRunFormat), interval.Cron)
+	} else {
+		im.Log.LogInfof("Task '%s' will first run at %s and intervals of %v thereafter", im.Task.FullName(), i.runAt.Format(firstRunFormat), interval.Frequency)
+	}
 
 	t := im.Task
 
@@ -237,8 +429,21 @@ func (im *invocationManager) addNextInvocation(previous *invocation) time.Time {
 
 	interval := im.Interval
 
-	i := newInvocation(previous.counter+1, im.Task.MaxRetries, Scheduled)
-	i.runAt = previous.runAt.Add(interval.Frequency)
+	i := newInvocation(im.nextCounter(), im.Task.MaxRetries, Scheduled)
+
+	if interval.Mode == CronExpression {
+		// Calculate from now so that times missed while the previous invocation was waiting are skipped
+		from := previous.runAt
+
+		if now := time.Now(); now.After(from) {
+			from = now
+		}
+
+		i.runAt = interval.nextAfter(from)
+
+	} else {
+		i.runAt = interval.nextAfter(previous.runAt)
+	}
 
 	im.scheduled.EnqueueAtTail(i)
 
@@ -246,8 +451,68 @@ func (im *invocationManager) addNextInvocation(previous *invocation) time.Time {
 
 }
 
+// nextCounter returns the number to identify a new invocation of the task
+func (im *invocationManager) nextCounter() uint64 {
+	return atomic.AddUint64(&im.counter, 1)
+}
+
+// upcoming returns the times of the next n scheduled invocations of the task
+func (im *invocationManager) upcoming(n int) []time.Time {
+
+	times := make([]time.Time, 0, n)
+
+	if im.Task.Disabled || im.Interval == nil {
+		return times
+	}
+
+	var next time.Time
+
+	for _, i := range im.scheduled.Contents() {
+		if i.reason == Scheduled {
+			next = i.runAt
+			break
+		}
+	}
+
+	if next.IsZero() {
+		return times
+	}
+
+	for len(times) < n && !next.IsZero() {
+		times = append(times, next)
+		next = im.Interval.nextAfter(next)
+	}
+
+	return times
+}
+
+// invoke schedules an invocation of the task to run immediately
+func (im *invocationManager) invoke() {
+
+	i := newInvocation(im.nextCounter(), im.Task.MaxRetries, Manual)
+	i.runAt = time.Now()
+
+	im.scheduled.EnqueueAtHead(i)
+}
+
+func (im *invocationManager) setSuspended(suspended bool) {
+
+	var v int32
+
+	if suspended {
+		v = 1
+	}
+
+	atomic.StoreInt32(&im.suspended, v)
+}
+
+func (im *invocationManager) isSuspended() bool {
+	return atomic.LoadInt32(&im.suspended) == 1
+}
+
 func (im *invocationManager) PrepareToStop() {
 	im.State = ioc.StoppingState
+	im.cancelAll()
 }
 
 func (im *invocationManager) ReadyToStop() (bool, error) {
@@ -261,6 +526,11 @@ func (im *invocationManager) ReadyToStop() (bool, error) {
 }
 
 func (im *invocationManager) Stop() error {
+
+	if im.Task.Singleton {
+		im.releaseLock()
+	}
+
 	if im.running.Size() > 0 {
 		m := fmt.Sprintf("%d instance(s) of task %s are still running", im.running.size, im.Task.FullName())
 		return errors.New(m)
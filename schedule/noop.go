@@ -0,0 +1,39 @@
+// Copyright 2018-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package schedule
+
+import (
+	"context"
+	"github.com/graniticio/granitic/v2/instrument"
+)
+
+// A default implementation of instrument.TaskInstrumentationManager that does nothing
+type noopTaskInstrumentationManager struct{}
+
+func (nm *noopTaskInstrumentationManager) Begin(ctx context.Context, taskID string, invocation uint64) (context.Context, instrument.Instrumentor, func()) {
+	ti := new(noopTaskInstrumentor)
+	nc := instrument.AddInstrumentorToContext(ctx, ti)
+
+	return nc, ti, func() {}
+}
+
+// A default implementation of instrument.Instrumentor that does nothing
+type noopTaskInstrumentor struct {
+}
+
+func (ni *noopTaskInstrumentor) StartEvent(id string, metadata ...interface{}) instrument.EndEvent {
+	return func() {}
+}
+
+func (ni *noopTaskInstrumentor) Fork(ctx context.Context) (context.Context, instrument.Instrumentor) {
+	return ctx, ni
+}
+
+func (ni *noopTaskInstrumentor) Integrate(instrumentor instrument.Instrumentor) {
+	return
+}
+
+func (ni *noopTaskInstrumentor) Amend(additional instrument.Additional, value interface{}) {
+	return
+}
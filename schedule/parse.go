@@ -293,6 +293,48 @@ type interval struct {
 	ActualStart     time.Time
 	Frequency       time.Duration
 	CalculatedAt    time.Time
+	Cron            *cronSchedule
+}
+
+// nextAfter returns the first time after the supplied time that the task should run
+func (i *interval) nextAfter(t time.Time) time.Time {
+
+	if i.Mode == CronExpression {
+		return i.Cron.next(t)
+	}
+
+	return t.Add(i.Frequency)
+}
+
+// lastBefore returns the last time before the supplied time that the task should run, given that it was due to run at
+// first (which must be before the supplied time)
+func (i *interval) lastBefore(first time.Time, before time.Time) time.Time {
+
+	if i.Mode == CronExpression {
+		return i.Cron.previous(before)
+	}
+
+	return first.Add(before.Sub(first) - 1 - (before.Sub(first)-1)%i.Frequency)
+}
+
+// previous returns the run of the task before a time that the task was due to run at
+func (i *interval) previous(t time.Time) time.Time {
+
+	if i.Mode == CronExpression {
+		return i.Cron.previous(t)
+	}
+
+	return t.Add(-i.Frequency)
+}
+
+// String describes how often the task runs
+func (i *interval) String() string {
+
+	if i.Mode == CronExpression {
+		return "cron " + i.Cron.String()
+	}
+
+	return "every " + i.Frequency.String()
 }
 
 type intervalMode int
@@ -303,6 +345,9 @@ const (
 
 	//ActualStartTime indicates that the invocation will run at a specified time
 	ActualStartTime
+
+	//CronExpression indicates that invocations will run at the times matched by a cron expression
+	CronExpression
 )
 
 type intervalToken struct {
@@ -1,4 +1,115 @@
 // Copyright 2018-2020 Granitic. All rights reserved.
 // Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
 
+/*
+Package schedule provides a mechanism for running tasks at regular intervals or at fixed times.
+
+A task is defined by adding a component of type Task to your component definition file. The Component field of the
+task names a component implementing TaskLogic, whose ExecuteTask method is called each time the task runs. When the task
+runs is defined with either the Every or the Cron field.
+
+Every
+
+Every is a human-readable (English) description of how often the task runs, optionally with the time of the first run:
+
+	"Every": "5 minutes"
+	"Every": "1 hour at 00:15"
+	"Every": "day at 0130"
+
+Cron
+
+Cron accepts a standard five field cron expression (minute, hour, day of month, month, day of week) or a six field
+expression with a leading seconds field. Each field can be a *, a value, a range (1-5), a list (0,30) or a step
+(0-59/15 or 10-20/5). Months and days of the week can be given as names (JAN-DEC, SUN-SAT). The following modifiers are also
+supported:
+
+	L     in day of month - the last day of the month (L-2 is two days before the last day)
+	W     in day of month - the weekday nearest to the day (15W) or the last weekday of the month (LW)
+	L     in day of week  - the last occurrence of that day in the month (5L or FRIL is the last Friday)
+	#     in day of week  - the nth occurrence of that day in the month (MON#1 is the first Monday)
+
+The macros @yearly, @monthly, @weekly, @daily and @hourly can be used instead of an expression. As with other
+implementations of cron, if both the day of month and the day of week are restricted, the task runs when either matches.
+For example:
+
+	"Cron": "0 9 * * MON-FRI"     (weekdays at 09:00)
+	"Cron": "0 0 L * *"           (midnight on the last day of each month)
+
+Cron expressions are evaluated in the time zone named by the task's TimeZone field (e.g. Europe/London), or in the local
+time zone of the server if TimeZone is not set. Runs scheduled for times skipped when clocks go forward do not take place.
+
+Running tasks after other tasks
+
+Instead of Every or Cron, a task can set the After field to the IDs of one or more other tasks. The task runs once every
+task it runs after has completed successfully since the task last ran:
+
+	"transform": {
+	  "type": "schedule.Task",
+	  "Component": "transformLogic",
+	  "After": ["extract", "load"]
+	}
+
+If a task it runs after fails (or is cancelled or skipped), the task's current run is recorded as Skipped (once, however
+many of the tasks it runs after fail) and the failure is passed on to any tasks that run after it. The outcomes of the
+remaining tasks in that run are ignored; the task then waits for all of the tasks it runs after to succeed again. The
+scheduler will not start if the After fields of the tasks form a cycle.
+
+Cancellation and timeouts
+
+A component implementing ContextAwareTaskLogic instead of TaskLogic is passed a context.Context that is cancelled when
+the application stops, when the invocation has run for longer than the task's Timeout field (e.g. "10 minutes") or when
+the invocation is cancelled with the task command (see below). Invocations that return an error after being cancelled
+are recorded as Cancelled and are not retried (invocations that time out may be retried).
+
+The context contains an instrument.Instrumentor. If a component implementing instrument.TaskInstrumentationManager is
+declared, it is used to instrument each invocation. If more than one is declared, set
+TaskScheduler.InstrumentationManagerComponent to the name of the one the scheduler should use.
+
+Run history
+
+The outcome of each invocation of a task (when it was scheduled for, when it started and ended, whether it succeeded,
+how many times it was retried and its final status message or error) is recorded in a TaskHistoryStore. By default,
+history is kept in memory. To keep history when your application restarts, declare a FileHistoryStore or an
+RDBMSHistoryStore component (or your own implementation of TaskHistoryStore):
+
+	"taskHistory": {
+	  "type": "schedule.FileHistoryStore",
+	  "Path": "/var/lib/myapp/task-history"
+	}
+
+If you declare more than one store, set TaskScheduler.HistoryStoreComponent to the name of the one the scheduler should use.
+
+Catching up with missed runs
+
+A task's CatchUp field controls what happens to scheduled runs that were missed while the application was not running.
+When the scheduler starts, it finds the last scheduled run of the task in the history store and works out which runs
+should have happened since then:
+
+	SKIP  missed runs are logged but not run (the default)
+	ONCE  the task runs once, straight away, if any runs were missed
+	ALL   the task runs once for each missed run (up to the 100 most recent), one after another
+
+Catch-up relies on a history store that is kept between restarts.
+
+Running tasks on a cluster
+
+If several instances of an application run the same tasks, set a task's Singleton field to true so that each
+invocation only runs on the instance that acquires a lock on the task. Locks are provided by a component implementing
+TaskLockProvider - Granitic provides FileLockProvider (for instances running on the same host) and RDBMSLockProvider
+(which keeps locks in a database table):
+
+	"taskLocks": {
+	  "type": "schedule.RDBMSLockProvider"
+	}
+
+A lock is held for a lease (one minute unless the task's LockLease field is set) and renewed while the task runs, then
+released when the task finishes or the scheduler stops. If an instance stops without releasing a lock, other instances
+can run the task once the lease expires. Instances identify themselves as the holder of a lock with their instance ID
+(see the -i command line argument), or with their host name and process ID if no instance ID is set.
+
+Runtime control
+
+If the RuntimeCtl facility is enabled, the task command lists each task with the time of its next run. task ID shows
+the next and most recent runs of a single task (and the holder of its lock, if it is a Singleton), and task ID invoke, suspend, resume and cancel control it.
+*/
 package schedule
@@ -7,8 +7,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"github.com/graniticio/granitic/v2/instance"
+	"github.com/graniticio/granitic/v2/instrument"
 	"github.com/graniticio/granitic/v2/ioc"
 	"github.com/graniticio/granitic/v2/logging"
+	"os"
 	"time"
 )
 
@@ -20,6 +23,25 @@ type TaskScheduler struct {
 	// Logger used by Granitic framework components. Automatically injected.
 	FrameworkLogger     logging.Logger
 	FrameworkLogManager *logging.ComponentLoggerManager
+
+	// The name of the component implementing TaskHistoryStore to use if more than one is defined
+	HistoryStoreComponent string
+
+	// The name of the component implementing TaskLockProvider to use if more than one is defined
+	LockProviderComponent string
+
+	// The name of the component implementing instrument.TaskInstrumentationManager to use if more than one is defined
+	InstrumentationManagerComponent string
+
+	history    TaskHistoryStore
+	locks      TaskLockProvider
+	instanceID string
+}
+
+// RegisterInstanceID implements instance.Receiver. The instance ID identifies this instance as the holder of locks on
+// Singleton tasks.
+func (ts *TaskScheduler) RegisterInstanceID(i *instance.Identifier) {
+	ts.instanceID = i.ID
 }
 
 // Container implements ioc.ContainerAccessor.Container
@@ -38,10 +60,26 @@ func (ts *TaskScheduler) StartComponent() error {
 
 	ts.FrameworkLogger.LogDebugf("Searching for schedule.Task components")
 
+	stores := make(map[string]TaskHistoryStore)
+	providers := make(map[string]TaskLockProvider)
+	instrumentation := make(map[string]instrument.TaskInstrumentationManager)
+
 	for _, component := range ts.componentContainer.AllComponents() {
 
 		ts.FrameworkLogger.LogTracef("Considering %s", component.Name)
 
+		if store, found := component.Instance.(TaskHistoryStore); found {
+			stores[component.Name] = store
+		}
+
+		if provider, found := component.Instance.(TaskLockProvider); found {
+			providers[component.Name] = provider
+		}
+
+		if manager, found := component.Instance.(instrument.TaskInstrumentationManager); found {
+			instrumentation[component.Name] = manager
+		}
+
 		if task, found := component.Instance.(*Task); found {
 			if task.ID == "" {
 				//Use the name of the component to be run as ID for the task if it isn't explicitly set
@@ -61,9 +99,147 @@ func (ts *TaskScheduler) StartComponent() error {
 		}
 	}
 
+	if err := ts.linkDependencies(); err != nil {
+		return err
+	}
+
+	if err := ts.useHistoryStore(stores); err != nil {
+		return err
+	}
+
+	if err := ts.useLockProvider(providers); err != nil {
+		return err
+	}
+
+	return ts.useInstrumentationManager(instrumentation)
+}
+
+// useHistoryStore chooses the store in which the outcome of each task invocation will be recorded. If the application
+// has not defined a TaskHistoryStore, history is kept in memory.
+func (ts *TaskScheduler) useHistoryStore(stores map[string]TaskHistoryStore) error {
+
+	switch {
+	case ts.HistoryStoreComponent != "":
+
+		if ts.history = stores[ts.HistoryStoreComponent]; ts.history == nil {
+			return fmt.Errorf("HistoryStoreComponent %s does not exist or does not implement schedule.TaskHistoryStore", ts.HistoryStoreComponent)
+		}
+
+	case len(stores) > 1:
+		return errors.New("More than one component implements schedule.TaskHistoryStore. Set TaskScheduler.HistoryStoreComponent to choose one")
+
+	case len(stores) == 1:
+		for _, store := range stores {
+			ts.history = store
+		}
+
+	default:
+		ts.history = new(MemoryHistoryStore)
+	}
+
+	_, inMemory := ts.history.(*MemoryHistoryStore)
+
+	for _, tm := range ts.managedTasks {
+
+		tm.history = ts.history
+
+		if inMemory && tm.Task.CatchUp != CatchUpSkip {
+			ts.FrameworkLogger.LogWarnf("Task %s has a CatchUp policy but task history is only held in memory, so runs missed while the application was not running cannot be found", tm.Task.FullName())
+		}
+	}
+
 	return nil
 }
 
+// useLockProvider chooses the provider of locks on Singleton tasks. A provider is only required if at least one task is a
+// Singleton.
+func (ts *TaskScheduler) useLockProvider(providers map[string]TaskLockProvider) error {
+
+	switch {
+	case ts.LockProviderComponent != "":
+
+		if ts.locks = providers[ts.LockProviderComponent]; ts.locks == nil {
+			return fmt.Errorf("LockProviderComponent %s does not exist or does not implement schedule.TaskLockProvider", ts.LockProviderComponent)
+		}
+
+	case len(providers) > 1:
+		return errors.New("More than one component implements schedule.TaskLockProvider. Set TaskScheduler.LockProviderComponent to choose one")
+
+	case len(providers) == 1:
+		for _, provider := range providers {
+			ts.locks = provider
+		}
+	}
+
+	holder := ts.lockHolder()
+
+	for _, tm := range ts.managedTasks {
+
+		if !tm.Task.Singleton {
+			continue
+		}
+
+		if ts.locks == nil {
+			return fmt.Errorf("Task %s is a Singleton but no component implements schedule.TaskLockProvider", tm.Task.FullName())
+		}
+
+		tm.locks = ts.locks
+		tm.holder = holder
+	}
+
+	return nil
+}
+
+// useInstrumentationManager chooses the component used to instrument each invocation of a task. If the application has
+// not defined a TaskInstrumentationManager, invocations are not instrumented.
+func (ts *TaskScheduler) useInstrumentationManager(managers map[string]instrument.TaskInstrumentationManager) error {
+
+	var manager instrument.TaskInstrumentationManager
+
+	switch {
+	case ts.InstrumentationManagerComponent != "":
+
+		if manager = managers[ts.InstrumentationManagerComponent]; manager == nil {
+			return fmt.Errorf("InstrumentationManagerComponent %s does not exist or does not implement instrument.TaskInstrumentationManager", ts.InstrumentationManagerComponent)
+		}
+
+	case len(managers) > 1:
+		return errors.New("More than one component implements instrument.TaskInstrumentationManager. Set TaskScheduler.InstrumentationManagerComponent to choose one")
+
+	case len(managers) == 1:
+		for _, m := range managers {
+			manager = m
+		}
+
+	default:
+		ts.FrameworkLogger.LogDebugf("No TaskInstrumentationManager found. Using noop implementation")
+		return nil
+	}
+
+	for _, tm := range ts.managedTasks {
+		tm.instrumentation = manager
+	}
+
+	return nil
+}
+
+// lockHolder returns the name that identifies this instance of the application as the holder of a lock. The instance ID
+// is used if one has been set, otherwise the host name and process ID.
+func (ts *TaskScheduler) lockHolder() string {
+
+	if ts.instanceID != "" {
+		return ts.instanceID
+	}
+
+	host, err := os.Hostname()
+
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 // AllowAccess spawns a goroutinue managing each scheduled task
 func (ts *TaskScheduler) AllowAccess() error {
 
@@ -84,8 +260,23 @@ func (ts *TaskScheduler) validateAndPrepare(cn ioc.ComponentLookup, task *Task)
 		return err
 	}
 
-	if task.Every == "" {
-		m := fmt.Sprintf("You must set the 'Every' field to set an execution interval")
+	if task.Every == "" && task.Cron == "" && len(task.After) == 0 {
+		m := fmt.Sprintf("You must set the 'Every', 'Cron' or 'After' field to set when the task runs")
+		return errors.New(m)
+	}
+
+	if len(task.After) > 0 && (task.Every != "" || task.Cron != "") {
+		m := fmt.Sprintf("The 'After' field cannot be used with the 'Every' or 'Cron' fields")
+		return errors.New(m)
+	}
+
+	if task.Every != "" && task.Cron != "" {
+		m := fmt.Sprintf("Only one of the 'Every' and 'Cron' fields can be set")
+		return errors.New(m)
+	}
+
+	if task.TimeZone != "" && task.Cron == "" {
+		m := fmt.Sprintf("The 'TimeZone' field can only be used with the 'Cron' field")
 		return errors.New(m)
 	}
 
@@ -94,6 +285,37 @@ func (ts *TaskScheduler) validateAndPrepare(cn ioc.ComponentLookup, task *Task)
 		return errors.New(m)
 	}
 
+	if task.Timeout != "" {
+
+		if task.contextLogic == nil {
+			m := fmt.Sprintf("The 'Timeout' field can only be used if component %s implements schedule.ContextAwareTaskLogic", task.Component)
+			return errors.New(m)
+		}
+
+		timeout, err := parseNaturalToDuration(task.Timeout)
+
+		if err != nil {
+			return err
+		}
+
+		task.timeout = timeout
+	}
+
+	if err := validateSingleton(task); err != nil {
+		return err
+	}
+
+	if policy, err := validCatchUp(task.CatchUp); err == nil {
+		task.CatchUp = policy
+	} else {
+		return err
+	}
+
+	if len(task.After) > 0 && task.CatchUp != CatchUpSkip {
+		m := fmt.Sprintf("The 'CatchUp' field cannot be used with the 'After' field")
+		return errors.New(m)
+	}
+
 	if task.MaxRetries > 0 {
 		if task.RetryInterval == "" {
 			m := fmt.Sprintf("The 'RetryInterval' must be set if 'MaxRetries' > 0")
@@ -116,6 +338,14 @@ func (ts *TaskScheduler) validateAndPrepare(cn ioc.ComponentLookup, task *Task)
 	ts.managedTasks = append(ts.managedTasks, tm)
 	tm.Log = ts.FrameworkLogManager.CreateLogger(task.Component + "TaskManager")
 
+	if task.Cron != "" {
+		return ts.prepareCron(tm, task)
+	}
+
+	if len(task.After) > 0 {
+		return nil
+	}
+
 	if interval, err := parseEvery(task.Every); err == nil {
 		tm.Interval = interval
 	} else {
@@ -126,6 +356,62 @@ func (ts *TaskScheduler) validateAndPrepare(cn ioc.ComponentLookup, task *Task)
 	return nil
 }
 
+func validateSingleton(task *Task) error {
+
+	if !task.Singleton {
+
+		if task.LockLease != "" {
+			return errors.New("The 'LockLease' field can only be used when 'Singleton' is true")
+		}
+
+		return nil
+	}
+
+	if task.MaxOverlapping > 0 {
+		return errors.New("The 'MaxOverlapping' field cannot be set for a Singleton task")
+	}
+
+	task.lockLease = defaultLockLease
+
+	if task.LockLease == "" {
+		return nil
+	}
+
+	lease, err := parseNaturalToDuration(task.LockLease)
+
+	if err != nil {
+		return err
+	}
+
+	task.lockLease = lease
+
+	return nil
+}
+
+func (ts *TaskScheduler) prepareCron(tm *invocationManager, task *Task) error {
+
+	loc := time.Local
+
+	if task.TimeZone != "" {
+
+		var err error
+
+		if loc, err = time.LoadLocation(task.TimeZone); err != nil {
+			return fmt.Errorf("Unable to load time zone %s: %s", task.TimeZone, err.Error())
+		}
+	}
+
+	cs, err := parseCron(task.Cron, loc)
+
+	if err != nil {
+		return err
+	}
+
+	tm.Interval = &interval{Mode: CronExpression, Cron: cs, CalculatedAt: time.Now()}
+
+	return nil
+}
+
 func (ts *TaskScheduler) findLogic(cn ioc.ComponentLookup, task *Task) error {
 	if task.Component == "" {
 		return errors.New("Missing Component (you must provide the name of the component that will execute your task")
@@ -138,15 +424,15 @@ func (ts *TaskScheduler) findLogic(cn ioc.ComponentLookup, task *Task) error {
 		return errors.New(m)
 	}
 
-	tl, okay := tc.Instance.(TaskLogic)
-
-	if !okay {
-		m := fmt.Sprintf("Component %s does not implement schedule.TaskLogic", task.Component)
+	if cl, okay := tc.Instance.(ContextAwareTaskLogic); okay {
+		task.contextLogic = cl
+	} else if tl, okay := tc.Instance.(TaskLogic); okay {
+		task.logic = tl
+	} else {
+		m := fmt.Sprintf("Component %s does not implement schedule.TaskLogic or schedule.ContextAwareTaskLogic", task.Component)
 		return errors.New(m)
 	}
 
-	task.logic = tl
-
 	if task.StatusUpdateReceiver == "" {
 		return nil
 	}
@@ -170,6 +456,18 @@ func (ts *TaskScheduler) findLogic(cn ioc.ComponentLookup, task *Task) error {
 	return nil
 }
 
+// manager returns the invocation manager for the task with the supplied ID, or nil if there is no such task
+func (ts *TaskScheduler) manager(id string) *invocationManager {
+
+	for _, tm := range ts.managedTasks {
+		if tm.Task.ID == id {
+			return tm
+		}
+	}
+
+	return nil
+}
+
 // PrepareToStop calls the same method of each of the managed Tasks
 func (ts *TaskScheduler) PrepareToStop() {
 
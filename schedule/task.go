@@ -4,6 +4,7 @@
 package schedule
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -16,7 +17,7 @@ type Task struct {
 	Name string
 	// An optional unique ID for the task (the IoC component name for this task will be used if not specified)
 	ID string
-	// The name of the IoC component implementing TaskLogic that actually performs this task
+	// The name of the IoC component implementing TaskLogic or ContextAwareTaskLogic that actually performs this task
 	Component string
 	// The maximum number of overlapping instances of the task that are allowed to run. Zero means only one instance of this task can run at a time
 	MaxOverlapping int
@@ -25,6 +26,22 @@ type Task struct {
 	// A human-readable expression (in English) of how frequently the task should be run - see package docs
 	Every string
 
+	// A five field (minute hour day-of-month month day-of-week) or six field (with a leading seconds field) cron
+	// expression defining when the task should be run. Cannot be used with Every - see package docs
+	Cron string
+
+	// The IDs of tasks that must all complete successfully before this task runs. Cannot be used with Every or Cron -
+	// see package docs
+	After []string
+
+	// The name of the time zone (e.g. Europe/London) in which the Cron expression is evaluated. Defaults to the local
+	// time zone of the server
+	TimeZone string
+
+	// What to do about scheduled runs missed while the application was not running: SKIP (the default), ONCE or ALL -
+	// see package docs
+	CatchUp string
+
 	// If set to true, any status updates messages sent from the task to the scheduler will be logged
 	LogStatusMessages bool
 
@@ -41,11 +58,29 @@ type Task struct {
 	// Must be set if MaxRetries > 0
 	RetryInterval string
 
+	// If set to true, an invocation of the task will only run if a lock on the task can be acquired from the application's
+	// TaskLockProvider, so that only one instance of a clustered application runs the task at a time - see package docs
+	Singleton bool
+
+	// A human-readable expression (in English) of how long a lock on a Singleton task is held before it expires unless
+	// renewed (e.g. 1 minute, 30 seconds). Defaults to 1 minute
+	LockLease string
+
+	// A human-readable expression (in English) of how long an invocation of the task is allowed to run before its context
+	// is cancelled (e.g. 10 minutes). Can only be used if the task's component implements ContextAwareTaskLogic
+	Timeout string
+
 	receiver TaskStatusUpdateReceiver
 
 	logic TaskLogic
 
+	contextLogic ContextAwareTaskLogic
+
 	retryWait time.Duration
+
+	lockLease time.Duration
+
+	timeout time.Duration
 }
 
 // FullName returns either task name + ID, just task name or just ID depending on which fields are set
@@ -82,6 +117,13 @@ type TaskLogic interface {
 	ExecuteTask(c chan TaskStatusUpdate) error
 }
 
+// ContextAwareTaskLogic is implemented by any component that can be invoked via a scheduled task and that should stop
+// work when its context is cancelled. The context is cancelled when the scheduler stops, when the task's Timeout
+// expires or when the task is cancelled with the RuntimeCtl task command. The context contains an instrument.Instrumentor.
+type ContextAwareTaskLogic interface {
+	ExecuteTaskWithContext(ctx context.Context, c chan TaskStatusUpdate) error
+}
+
 // TaskStatusUpdateReceiver is implemented by a component that wants to receive status updates about an invocation of a task
 type TaskStatusUpdateReceiver interface {
 	Receive(summary TaskInvocationSummary, update TaskStatusUpdate)
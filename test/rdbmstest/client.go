@@ -0,0 +1,799 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+/*
+Package rdbmstest provides an in-memory test double for rdbms.Client and rdbms.ClientManager, allowing components that
+access a database to be unit tested without a database or a hand-written fake.
+
+A FakeClient records every call made to it and can be scripted with canned rows, errors and results for each query ID:
+
+	fc := rdbmstest.NewFakeClient()
+
+	fc.On("ARTIST_DETAIL").WithRows([]string{"Name", "Year"}, []interface{}{"Nico", 1967})
+	fc.On("ARTIST_DELETE").WithError(errors.New("locked"))
+
+	component.DBManager = rdbmstest.NewFakeClientManager(fc)
+
+	// Exercise the component
+
+	fc.ExpectCalls(t, "ARTIST_DETAIL", "ARTIST_DELETE")
+	fc.ExpectParam(t, "ARTIST_DETAIL", "ID", 5)
+	fc.ExpectTransactions(t, rdbmstest.Begin, rdbmstest.Rollback)
+
+Canned rows are bound to your structs by rdbms.RowBinder, so the same column-to-field mapping rules apply as when using a
+real database. Queries with no canned response return no rows and a Result with no affected rows.
+
+This package is separate from Granitic's test package so that it can depend on the rdbms package.
+*/
+package rdbmstest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// The transaction events recorded by a FakeClient
+const (
+	Begin    = "BEGIN"
+	Commit   = "COMMIT"
+	Rollback = "ROLLBACK"
+)
+
+// Call is a record of a single call to one of the query methods on a FakeClient.
+type Call struct {
+	// The name of the Client method that was called
+	Method string
+
+	// The query ID, or the query itself for calls to Exec, Query and QueryRow
+	QID string
+
+	// The parameters supplied to a QID method, merged as they would be by rdbms.ManagedClient
+	Params map[string]interface{}
+
+	// The arguments passed to Exec, Query or QueryRow, or the elements of the rows passed to a batch insert
+	Args []interface{}
+}
+
+// Response is the canned behaviour of a FakeClient for a query ID.
+type Response struct {
+	// The names of the columns in Rows
+	Columns []string
+
+	// Row data returned by Select methods and Query
+	Rows [][]interface{}
+
+	// If set, returned by any method called with the query ID instead of a result
+	Err error
+
+	// The sql.Result returned by Insert, Update, Delete and Exec methods
+	Result sql.Result
+
+	// IDs returned by InsertCaptureQIDParams (the first ID) and batch inserts that capture IDs
+	IDs []int64
+
+	// The text returned by FindFragment and BuildQueryFromQIDParams
+	Text string
+}
+
+// WithRows sets the rows returned for the query ID. Each row must have a value for each column.
+func (r *Response) WithRows(columns []string, rows ...[]interface{}) *Response {
+	r.Columns = columns
+	r.Rows = rows
+
+	return r
+}
+
+// WithError causes calls with the query ID to fail with the supplied error.
+func (r *Response) WithError(err error) *Response {
+	r.Err = err
+
+	return r
+}
+
+// WithResult sets the number of rows affected and the last inserted ID reported by the sql.Result returned for the query ID.
+func (r *Response) WithResult(rowsAffected, lastInsertID int64) *Response {
+	r.Result = Result{Affected: rowsAffected, LastID: lastInsertID}
+
+	return r
+}
+
+// WithIDs sets the generated IDs returned when the query ID is used to insert rows.
+func (r *Response) WithIDs(ids ...int64) *Response {
+	r.IDs = ids
+
+	return r
+}
+
+// WithText sets the query text returned by FindFragment and BuildQueryFromQIDParams for the query ID.
+func (r *Response) WithText(text string) *Response {
+	r.Text = text
+
+	return r
+}
+
+// Result is a canned implementation of sql.Result.
+type Result struct {
+	Affected int64
+	LastID   int64
+}
+
+// LastInsertId returns LastID
+func (r Result) LastInsertId() (int64, error) {
+	return r.LastID, nil
+}
+
+// RowsAffected returns Affected
+func (r Result) RowsAffected() (int64, error) {
+	return r.Affected, nil
+}
+
+// NewFakeClient creates a FakeClient with no canned responses.
+func NewFakeClient() *FakeClient {
+	fc := new(FakeClient)
+	fc.responses = make(map[string]*Response)
+	fc.ctx = context.Background()
+
+	return fc
+}
+
+/*
+FakeClient is an implementation of rdbms.Client that records calls and returns canned responses instead of accessing a
+database. Transactions are tracked (including nested transactions and transaction handles) but have no effect
+on the responses. Unlike rdbms.ManagedClient, a FakeClient is safe to share between goroutines.
+*/
+type FakeClient struct {
+	// If set, returned by CommitTransaction instead of committing the transaction
+	CommitError error
+
+	responses    map[string]*Response
+	calls        []Call
+	transactions []string
+	ctx          context.Context
+	mux          sync.Mutex
+
+	// One entry per open transaction level, innermost last
+	levels []*fakeTransaction
+}
+
+// fakeTransaction is the rdbms.Transaction returned by FakeClient.BeginTransaction
+type fakeTransaction struct {
+	fc *FakeClient
+}
+
+// Commit records a Commit event, or returns CommitError if it is set. Returns an error if the transaction is not the
+// innermost open transaction.
+func (ft *fakeTransaction) Commit() error {
+
+	fc := ft.fc
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	switch fc.levelIndex(ft) {
+	case -1:
+		return errors.New("transaction already committed or rolled back")
+	case len(fc.levels) - 1:
+		return fc.commitLevel()
+	default:
+		return errors.New("a nested transaction is still open")
+	}
+}
+
+// Rollback records a Rollback event if the transaction is still open.
+func (ft *fakeTransaction) Rollback() {
+
+	fc := ft.fc
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	if i := fc.levelIndex(ft); i >= 0 {
+		fc.rollbackFrom(i)
+	}
+}
+
+// On returns the canned Response for the supplied query ID (or query, for Exec, Query and QueryRow), creating it if necessary.
+func (fc *FakeClient) On(qid string) *Response {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	r := fc.responses[qid]
+
+	if r == nil {
+		r = new(Response)
+		fc.responses[qid] = r
+	}
+
+	return r
+}
+
+// Calls returns a copy of every query call recorded since the FakeClient was created or Reset.
+func (fc *FakeClient) Calls() []Call {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	return append([]Call(nil), fc.calls...)
+}
+
+// Transactions returns the sequence of Begin, Commit and Rollback events recorded since the FakeClient was created or Reset.
+func (fc *FakeClient) Transactions() []string {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	return append([]string(nil), fc.transactions...)
+}
+
+// Reset discards recorded calls and transaction events. Canned responses are retained.
+func (fc *FakeClient) Reset() {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	fc.calls = nil
+	fc.transactions = nil
+	fc.levels = nil
+}
+
+// ExpectCalls stops the test if the query IDs called (in order) do not exactly match the expected IDs.
+func (fc *FakeClient) ExpectCalls(t *testing.T, qids ...string) {
+	t.Helper()
+
+	calls := fc.Calls()
+	actual := make([]string, len(calls))
+
+	for i, c := range calls {
+		actual[i] = c.QID
+	}
+
+	if !reflect.DeepEqual(actual, qids) && (len(actual) > 0 || len(qids) > 0) {
+		t.Fatalf("Expected calls %v, actual %v", qids, actual)
+	}
+}
+
+// ExpectCalled stops the test if the query ID has not been called, otherwise returns the most recent call with that ID.
+func (fc *FakeClient) ExpectCalled(t *testing.T, qid string) Call {
+	t.Helper()
+
+	c, found := fc.lastCall(qid)
+
+	if !found {
+		t.Fatalf("Expected a call to %s", qid)
+	}
+
+	return c
+}
+
+// ExpectNotCalled stops the test if the query ID has been called.
+func (fc *FakeClient) ExpectNotCalled(t *testing.T, qid string) {
+	t.Helper()
+
+	if _, found := fc.lastCall(qid); found {
+		t.Fatalf("Unexpected call to %s", qid)
+	}
+}
+
+// ExpectParam stops the test if the most recent call with the query ID did not have a parameter with the expected name and value.
+func (fc *FakeClient) ExpectParam(t *testing.T, qid string, name string, expected interface{}) {
+	t.Helper()
+
+	c := fc.ExpectCalled(t, qid)
+
+	actual, found := c.Params[name]
+
+	if !found {
+		t.Fatalf("Expected parameter %s to be supplied to %s", name, qid)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expected parameter %s supplied to %s to be %v (%T), actual %v (%T)", name, qid, expected, expected, actual, actual)
+	}
+}
+
+// ExpectTransactions stops the test if the sequence of Begin, Commit and Rollback events does not exactly match the
+// expected sequence. Rollbacks that have no effect (for example a deferred Rollback after a Commit) are not recorded.
+func (fc *FakeClient) ExpectTransactions(t *testing.T, events ...string) {
+	t.Helper()
+
+	actual := fc.Transactions()
+
+	if !reflect.DeepEqual(actual, events) && (len(actual) > 0 || len(events) > 0) {
+		t.Fatalf("Expected transaction events %v, actual %v", events, actual)
+	}
+}
+
+func (fc *FakeClient) lastCall(qid string) (Call, bool) {
+
+	calls := fc.Calls()
+
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].QID == qid {
+			return calls[i], true
+		}
+	}
+
+	return Call{}, false
+}
+
+// record stores a call and returns the canned response for the query ID (an empty response if none has been set).
+func (fc *FakeClient) record(c Call) *Response {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	fc.calls = append(fc.calls, c)
+
+	if r := fc.responses[c.QID]; r != nil {
+		return r
+	}
+
+	return new(Response)
+}
+
+func (fc *FakeClient) recordQID(method, qid string, params []interface{}) (*Response, error) {
+
+	pm, err := rdbms.ParamsFromFieldsOrTags(params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := fc.record(Call{Method: method, QID: qid, Params: pm})
+
+	return r, r.Err
+}
+
+func (fc *FakeClient) exec(method, qid string, params []interface{}) (sql.Result, error) {
+
+	r, err := fc.recordQID(method, qid, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Result == nil {
+		return Result{}, nil
+	}
+
+	return r.Result, nil
+}
+
+func (fc *FakeClient) rows(method, qid string, params []interface{}) (*sql.Rows, error) {
+
+	r, err := fc.recordQID(method, qid, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cannedRows(r)
+}
+
+func param(name string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{name: value}
+}
+
+// FindFragment records the call and returns the canned text for the query ID (or the query ID itself if no text has been set).
+func (fc *FakeClient) FindFragment(qid string) (string, error) {
+	return fc.text("FindFragment", qid, nil)
+}
+
+// BuildQueryFromQIDParams records the call and returns the canned text for the query ID (or the query ID itself if no text has been set).
+func (fc *FakeClient) BuildQueryFromQIDParams(qid string, p ...interface{}) (string, error) {
+	return fc.text("BuildQueryFromQIDParams", qid, p)
+}
+
+func (fc *FakeClient) text(method, qid string, params []interface{}) (string, error) {
+
+	r, err := fc.recordQID(method, qid, params)
+
+	if err != nil {
+		return "", err
+	}
+
+	if r.Text == "" {
+		return qid, nil
+	}
+
+	return r.Text, nil
+}
+
+// DeleteQIDParams records the call and returns the canned result for the query ID.
+func (fc *FakeClient) DeleteQIDParams(qid string, params ...interface{}) (sql.Result, error) {
+	return fc.exec("DeleteQIDParams", qid, params)
+}
+
+// DeleteQIDParam records the call and returns the canned result for the query ID.
+func (fc *FakeClient) DeleteQIDParam(qid string, name string, value interface{}) (sql.Result, error) {
+	return fc.exec("DeleteQIDParam", qid, []interface{}{param(name, value)})
+}
+
+// RegisterTempQuery has no effect.
+func (fc *FakeClient) RegisterTempQuery(qid string, query string) {
+}
+
+// ExistingIDOrInsertParams calls SelectBindSingleQIDParams with the check query and, if no row is returned, InsertCaptureQIDParams
+// with the insert query.
+func (fc *FakeClient) ExistingIDOrInsertParams(checkQueryID, insertQueryID string, idTarget *int64, p ...interface{}) error {
+
+	found, err := fc.SelectBindSingleQIDParams(checkQueryID, idTarget, p...)
+
+	if err != nil || found {
+		return err
+	}
+
+	return fc.InsertCaptureQIDParams(insertQueryID, idTarget, p...)
+}
+
+// InsertQIDParams records the call and returns the canned result for the query ID.
+func (fc *FakeClient) InsertQIDParams(qid string, params ...interface{}) (sql.Result, error) {
+	return fc.exec("InsertQIDParams", qid, params)
+}
+
+// InsertCaptureQIDParams records the call and sets target to the first canned ID (or the LastInsertId of the canned result) for the query ID.
+func (fc *FakeClient) InsertCaptureQIDParams(qid string, target *int64, params ...interface{}) error {
+
+	r, err := fc.recordQID("InsertCaptureQIDParams", qid, params)
+
+	if err != nil {
+		return err
+	}
+
+	if len(r.IDs) > 0 {
+		*target = r.IDs[0]
+	} else if r.Result != nil {
+		*target, err = r.Result.LastInsertId()
+	}
+
+	return err
+}
+
+// InsertBatchQID records the call with each of the rows as an argument. See InsertBatchQIDOptions.
+func (fc *FakeClient) InsertBatchQID(qid string, rows interface{}) (*rdbms.BatchResult, error) {
+	return fc.insertBatch("InsertBatchQID", qid, rdbms.BatchOptions{}, rows)
+}
+
+// InsertBatchQIDOptions records the call with each of the rows as an argument. The returned BatchResult reports every row
+// as affected and, if opts.IDColumn is set, contains the canned IDs for the query ID.
+func (fc *FakeClient) InsertBatchQIDOptions(qid string, opts rdbms.BatchOptions, rows interface{}) (*rdbms.BatchResult, error) {
+	return fc.insertBatch("InsertBatchQIDOptions", qid, opts, rows)
+}
+
+func (fc *FakeClient) insertBatch(method, qid string, opts rdbms.BatchOptions, rows interface{}) (*rdbms.BatchResult, error) {
+
+	v := reflect.ValueOf(rows)
+
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("rows must be a slice or array (is %T)", rows)
+	}
+
+	args := make([]interface{}, v.Len())
+
+	for i := range args {
+		args[i] = v.Index(i).Interface()
+	}
+
+	r := fc.record(Call{Method: method, QID: qid, Args: args})
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	br := &rdbms.BatchResult{RowsAffected: int64(len(args))}
+
+	if opts.IDColumn != "" {
+		br.IDs = append(br.IDs, r.IDs...)
+	}
+
+	return br, nil
+}
+
+// SelectBindSingleQID records the call and binds the first canned row for the query ID into target.
+func (fc *FakeClient) SelectBindSingleQID(qid string, target interface{}) (bool, error) {
+	return fc.selectBindSingle("SelectBindSingleQID", qid, target, nil)
+}
+
+// SelectBindSingleQIDParam records the call and binds the first canned row for the query ID into target.
+func (fc *FakeClient) SelectBindSingleQIDParam(qid string, name string, value interface{}, target interface{}) (bool, error) {
+	return fc.selectBindSingle("SelectBindSingleQIDParam", qid, target, []interface{}{param(name, value)})
+}
+
+// SelectBindSingleQIDParams records the call and binds the first canned row for the query ID into target.
+func (fc *FakeClient) SelectBindSingleQIDParams(qid string, target interface{}, params ...interface{}) (bool, error) {
+	return fc.selectBindSingle("SelectBindSingleQIDParams", qid, target, params)
+}
+
+func (fc *FakeClient) selectBindSingle(method, qid string, target interface{}, params []interface{}) (bool, error) {
+
+	r, err := fc.rows(method, qid, params)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer r.Close()
+
+	return new(rdbms.RowBinder).BindRow(r, target)
+}
+
+// SelectBindQID records the call and binds the canned rows for the query ID into instances of the template's type.
+func (fc *FakeClient) SelectBindQID(qid string, template interface{}) ([]interface{}, error) {
+	return fc.selectBind("SelectBindQID", qid, template, nil)
+}
+
+// SelectBindQIDParam records the call and binds the canned rows for the query ID into instances of the template's type.
+func (fc *FakeClient) SelectBindQIDParam(qid string, name string, value interface{}, template interface{}) ([]interface{}, error) {
+	return fc.selectBind("SelectBindQIDParam", qid, template, []interface{}{param(name, value)})
+}
+
+// SelectBindQIDParams records the call and binds the canned rows for the query ID into instances of the template's type.
+func (fc *FakeClient) SelectBindQIDParams(qid string, template interface{}, params ...interface{}) ([]interface{}, error) {
+	return fc.selectBind("SelectBindQIDParams", qid, template, params)
+}
+
+func (fc *FakeClient) selectBind(method, qid string, template interface{}, params []interface{}) ([]interface{}, error) {
+
+	r, err := fc.rows(method, qid, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	return new(rdbms.RowBinder).BindRows(r, template)
+}
+
+// SelectBindSliceQID records the call and appends the canned rows for the query ID to the slice pointed to by target.
+func (fc *FakeClient) SelectBindSliceQID(qid string, target interface{}) error {
+	return fc.selectBindSlice("SelectBindSliceQID", qid, target, nil)
+}
+
+// SelectBindSliceQIDParam records the call and appends the canned rows for the query ID to the slice pointed to by target.
+func (fc *FakeClient) SelectBindSliceQIDParam(qid string, name string, value interface{}, target interface{}) error {
+	return fc.selectBindSlice("SelectBindSliceQIDParam", qid, target, []interface{}{param(name, value)})
+}
+
+// SelectBindSliceQIDParams records the call and appends the canned rows for the query ID to the slice pointed to by target.
+func (fc *FakeClient) SelectBindSliceQIDParams(qid string, target interface{}, params ...interface{}) error {
+	return fc.selectBindSlice("SelectBindSliceQIDParams", qid, target, params)
+}
+
+func (fc *FakeClient) selectBindSlice(method, qid string, target interface{}, params []interface{}) error {
+
+	r, err := fc.rows(method, qid, params)
+
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	return new(rdbms.RowBinder).BindSlice(r, target)
+}
+
+// SelectCursorQID records the call and returns a RowCursor over the canned rows for the query ID.
+func (fc *FakeClient) SelectCursorQID(qid string, target interface{}) (*rdbms.RowCursor, error) {
+	return fc.selectCursor("SelectCursorQID", qid, target, nil)
+}
+
+// SelectCursorQIDParam records the call and returns a RowCursor over the canned rows for the query ID.
+func (fc *FakeClient) SelectCursorQIDParam(qid string, name string, value interface{}, target interface{}) (*rdbms.RowCursor, error) {
+	return fc.selectCursor("SelectCursorQIDParam", qid, target, []interface{}{param(name, value)})
+}
+
+// SelectCursorQIDParams records the call and returns a RowCursor over the canned rows for the query ID.
+func (fc *FakeClient) SelectCursorQIDParams(qid string, target interface{}, params ...interface{}) (*rdbms.RowCursor, error) {
+	return fc.selectCursor("SelectCursorQIDParams", qid, target, params)
+}
+
+func (fc *FakeClient) selectCursor(method, qid string, target interface{}, params []interface{}) (*rdbms.RowCursor, error) {
+
+	r, err := fc.rows(method, qid, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fc.mux.Lock()
+	ctx := fc.ctx
+	fc.mux.Unlock()
+
+	return rdbms.NewRowCursor(ctx, r, target)
+}
+
+// SelectQID records the call and returns the canned rows for the query ID.
+func (fc *FakeClient) SelectQID(qid string) (*sql.Rows, error) {
+	return fc.rows("SelectQID", qid, nil)
+}
+
+// SelectQIDParam records the call and returns the canned rows for the query ID.
+func (fc *FakeClient) SelectQIDParam(qid string, name string, value interface{}) (*sql.Rows, error) {
+	return fc.rows("SelectQIDParam", qid, []interface{}{param(name, value)})
+}
+
+// SelectQIDParams records the call and returns the canned rows for the query ID.
+func (fc *FakeClient) SelectQIDParams(qid string, params ...interface{}) (*sql.Rows, error) {
+	return fc.rows("SelectQIDParams", qid, params)
+}
+
+// UpdateQIDParams records the call and returns the canned result for the query ID.
+func (fc *FakeClient) UpdateQIDParams(qid string, params ...interface{}) (sql.Result, error) {
+	return fc.exec("UpdateQIDParams", qid, params)
+}
+
+// UpdateQIDParam records the call and returns the canned result for the query ID.
+func (fc *FakeClient) UpdateQIDParam(qid string, name string, value interface{}) (sql.Result, error) {
+	return fc.exec("UpdateQIDParam", qid, []interface{}{param(name, value)})
+}
+
+// StartTransaction records a Begin event.
+func (fc *FakeClient) StartTransaction() error {
+	fc.begin()
+
+	return nil
+}
+
+// StartTransactionWithOptions records a Begin event.
+func (fc *FakeClient) StartTransactionWithOptions(opts *sql.TxOptions) error {
+	return fc.StartTransaction()
+}
+
+// BeginTransaction records a Begin event and returns a handle on the new transaction.
+func (fc *FakeClient) BeginTransaction() (rdbms.Transaction, error) {
+	return fc.begin(), nil
+}
+
+// WithTransaction calls the supplied function between Begin and Commit events, or Begin and Rollback events if the
+// function returns an error or panics. Transactions are never retried.
+func (fc *FakeClient) WithTransaction(fn func(rdbms.Client) error) (err error) {
+
+	tx := fc.begin()
+
+	// Does nothing if the transaction is committed
+	defer tx.Rollback()
+
+	if err = fn(fc); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Rollback records a Rollback event if a transaction is open. Like rdbms.ManagedClient.Rollback, it applies to the
+// innermost open transaction.
+func (fc *FakeClient) Rollback() {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	if n := len(fc.levels); n > 0 {
+		fc.rollbackFrom(n - 1)
+	}
+}
+
+// CommitTransaction records a Commit event, or returns CommitError if it is set.
+func (fc *FakeClient) CommitTransaction() error {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	if len(fc.levels) == 0 {
+		return errors.New("no transaction open")
+	}
+
+	return fc.commitLevel()
+}
+
+func (fc *FakeClient) begin() *fakeTransaction {
+
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	ft := &fakeTransaction{fc: fc}
+
+	fc.levels = append(fc.levels, ft)
+	fc.transactions = append(fc.transactions, Begin)
+
+	return ft
+}
+
+func (fc *FakeClient) levelIndex(ft *fakeTransaction) int {
+
+	for i, l := range fc.levels {
+		if l == ft {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (fc *FakeClient) commitLevel() error {
+
+	if fc.CommitError != nil {
+		return fc.CommitError
+	}
+
+	fc.levels = fc.levels[:len(fc.levels)-1]
+	fc.transactions = append(fc.transactions, Commit)
+
+	return nil
+}
+
+func (fc *FakeClient) rollbackFrom(i int) {
+	fc.levels = fc.levels[:i]
+	fc.transactions = append(fc.transactions, Rollback)
+}
+
+// Exec records the call and returns the canned result for the query.
+func (fc *FakeClient) Exec(query string, args ...interface{}) (sql.Result, error) {
+
+	r := fc.record(Call{Method: "Exec", QID: query, Args: args})
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	if r.Result == nil {
+		return Result{}, nil
+	}
+
+	return r.Result, nil
+}
+
+// Query records the call and returns the canned rows for the query.
+func (fc *FakeClient) Query(query string, args ...interface{}) (*sql.Rows, error) {
+
+	r := fc.record(Call{Method: "Query", QID: query, Args: args})
+
+	return cannedRows(r)
+}
+
+// QueryRow records the call and returns the first canned row for the query.
+func (fc *FakeClient) QueryRow(query string, args ...interface{}) *sql.Row {
+
+	r := fc.record(Call{Method: "QueryRow", QID: query, Args: args})
+
+	return cannedRow(r)
+}
+
+// NewFakeClientManager creates a ClientManager that always returns the supplied FakeClient.
+func NewFakeClientManager(fc *FakeClient) *FakeClientManager {
+	return &FakeClientManager{FakeClient: fc}
+}
+
+// FakeClientManager is an implementation of rdbms.ClientManager that returns the same FakeClient for every request.
+type FakeClientManager struct {
+	FakeClient *FakeClient
+
+	// If set, returned by Client and ClientFromContext instead of the FakeClient
+	Err error
+}
+
+// Client returns the FakeClient
+func (fcm *FakeClientManager) Client() (rdbms.Client, error) {
+	return fcm.ClientFromContext(context.Background())
+}
+
+// ClientFromContext returns the FakeClient. Cursors created by the FakeClient stop iterating if the context is cancelled.
+func (fcm *FakeClientManager) ClientFromContext(ctx context.Context) (rdbms.Client, error) {
+
+	if fcm.Err != nil {
+		return nil, fcm.Err
+	}
+
+	fcm.FakeClient.mux.Lock()
+	fcm.FakeClient.ctx = ctx
+	fcm.FakeClient.mux.Unlock()
+
+	return fcm.FakeClient, nil
+}
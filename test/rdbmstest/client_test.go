@@ -0,0 +1,201 @@
+package rdbmstest
+
+import (
+	"context"
+	"errors"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"github.com/graniticio/granitic/v2/test"
+	"testing"
+)
+
+var _ rdbms.Client = new(FakeClient)
+var _ rdbms.ClientManager = new(FakeClientManager)
+
+type artist struct {
+	Name string
+	Year int
+	ID   int64 `column:"artist_id"`
+}
+
+func TestCannedRows(t *testing.T) {
+
+	fc := NewFakeClient()
+	fc.On("ARTISTS").WithRows([]string{"Name", "Year", "artist_id"},
+		[]interface{}{"Nico", 1967, 1}, []interface{}{"Can", 1968, 2})
+
+	a := new(artist)
+
+	_, err := fc.SelectBindSingleQIDParam("ARTISTS", "ID", 5, a)
+	test.ExpectNotNil(t, err)
+
+	fc.On("ARTIST").WithRows([]string{"Name", "Year", "artist_id"}, []interface{}{"Nico", 1967, 1})
+
+	found, err := fc.SelectBindSingleQIDParam("ARTIST", "ID", 5, a)
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, found, true)
+	test.ExpectString(t, a.Name, "Nico")
+	test.ExpectInt(t, int(a.ID), 1)
+
+	results, err := fc.SelectBindQID("ARTISTS", new(artist))
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, len(results), 2)
+	test.ExpectInt(t, results[1].(*artist).Year, 1968)
+
+	var slice []artist
+
+	test.ExpectNil(t, fc.SelectBindSliceQIDParams("ARTISTS", &slice, &artist{Name: "N"}))
+	test.ExpectInt(t, len(slice), 2)
+
+	c, err := fc.SelectCursorQID("ARTISTS", a)
+	test.ExpectNil(t, err)
+
+	n := 0
+
+	for c.Next() {
+		n++
+	}
+
+	test.ExpectNil(t, c.Err())
+	test.ExpectInt(t, n, 2)
+
+	r, err := fc.SelectQID("UNSCRIPTED")
+	test.ExpectNil(t, err)
+	test.ExpectBool(t, r.Next(), false)
+	r.Close()
+
+	var name string
+
+	fc.On("SELECT name FROM artist").WithRows([]string{"name"}, []interface{}{"Nico"})
+	test.ExpectNil(t, fc.QueryRow("SELECT name FROM artist").Scan(&name))
+	test.ExpectString(t, name, "Nico")
+
+	fc.ExpectCalls(t, "ARTISTS", "ARTIST", "ARTISTS", "ARTISTS", "ARTISTS", "UNSCRIPTED", "SELECT name FROM artist")
+	fc.ExpectParam(t, "ARTIST", "ID", 5)
+	test.ExpectString(t, fc.Calls()[3].Params["Name"].(string), "N")
+	fc.ExpectNotCalled(t, "OTHER")
+}
+
+func TestCannedResultsAndErrors(t *testing.T) {
+
+	fc := NewFakeClient()
+	fc.On("DELETE").WithError(errors.New("locked"))
+	fc.On("UPDATE").WithResult(3, 0)
+	fc.On("INSERT").WithIDs(10, 11)
+
+	_, err := fc.DeleteQIDParam("DELETE", "ID", 1)
+	test.ExpectNotNil(t, err)
+
+	_, err = fc.SelectQID("DELETE")
+	test.ExpectNotNil(t, err)
+
+	r, err := fc.UpdateQIDParams("UPDATE", map[string]interface{}{"ID": 1})
+	test.ExpectNil(t, err)
+
+	ra, _ := r.RowsAffected()
+	test.ExpectInt(t, int(ra), 3)
+
+	var id int64
+
+	test.ExpectNil(t, fc.ExistingIDOrInsertParams("CHECK", "INSERT", &id, map[string]interface{}{"Name": "Nico"}))
+	test.ExpectInt(t, int(id), 10)
+
+	br, err := fc.InsertBatchQIDOptions("INSERT", rdbms.BatchOptions{IDColumn: "id"}, []artist{{Name: "A"}, {Name: "B"}})
+	test.ExpectNil(t, err)
+	test.ExpectInt(t, int(br.RowsAffected), 2)
+	test.ExpectInt(t, len(br.IDs), 2)
+
+	c := fc.ExpectCalled(t, "INSERT")
+	test.ExpectString(t, c.Method, "InsertBatchQIDOptions")
+	test.ExpectInt(t, len(c.Args), 2)
+
+	fc.ExpectParam(t, "CHECK", "Name", "Nico")
+
+	fc.Reset()
+	fc.ExpectCalls(t)
+}
+
+func TestTransactionEvents(t *testing.T) {
+
+	fc := NewFakeClient()
+
+	fc.StartTransaction()
+	fc.StartTransaction()
+	test.ExpectNil(t, fc.CommitTransaction())
+
+	// Rolls back the outer transaction
+	fc.Rollback()
+	fc.Rollback()
+
+	fc.ExpectTransactions(t, Begin, Begin, Commit, Rollback)
+	test.ExpectNotNil(t, fc.CommitTransaction())
+
+	// Deferred rollbacks of transaction handles
+	fc.Reset()
+
+	outer, _ := fc.BeginTransaction()
+	inner, _ := fc.BeginTransaction()
+
+	test.ExpectNotNil(t, outer.Commit())
+	test.ExpectNil(t, inner.Commit())
+	inner.Rollback()
+	outer.Rollback()
+
+	fc.ExpectTransactions(t, Begin, Begin, Commit, Rollback)
+	test.ExpectNotNil(t, inner.Commit())
+
+	fc.Reset()
+
+	err := fc.WithTransaction(func(c rdbms.Client) error {
+		return c.WithTransaction(func(rdbms.Client) error {
+			return errors.New("failed")
+		})
+	})
+
+	test.ExpectNotNil(t, err)
+	fc.ExpectTransactions(t, Begin, Begin, Rollback, Rollback)
+
+	// A nested transaction that commits followed by a failure of the enclosing transaction
+	fc.Reset()
+
+	err = fc.WithTransaction(func(c rdbms.Client) error {
+
+		test.ExpectNil(t, c.WithTransaction(func(rdbms.Client) error {
+			return nil
+		}))
+
+		return errors.New("outer")
+	})
+
+	test.ExpectNotNil(t, err)
+	fc.ExpectTransactions(t, Begin, Begin, Commit, Rollback)
+	test.ExpectNotNil(t, fc.CommitTransaction())
+
+	fc.Reset()
+	fc.CommitError = errors.New("serialisation failure")
+
+	test.ExpectNotNil(t, fc.WithTransaction(func(rdbms.Client) error { return nil }))
+	fc.ExpectTransactions(t, Begin, Rollback)
+}
+
+func TestFakeClientManager(t *testing.T) {
+
+	fc := NewFakeClient()
+	fc.On("ARTISTS").WithRows([]string{"Name"}, []interface{}{"Nico"})
+
+	m := NewFakeClientManager(fc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c, err := m.ClientFromContext(ctx)
+	test.ExpectNil(t, err)
+
+	cursor, _ := c.SelectCursorQID("ARTISTS", new(artist))
+	test.ExpectBool(t, cursor.Next(), false)
+	test.ExpectBool(t, cursor.Err() == context.Canceled, true)
+
+	m.Err = errors.New("unavailable")
+
+	_, err = m.Client()
+	test.ExpectNotNil(t, err)
+}
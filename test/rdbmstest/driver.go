@@ -0,0 +1,198 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package rdbmstest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+const driverName = "grnc-rdbmstest"
+
+// The canned responses currently being served by the driver, keyed by the 'query' passed to the driver.
+var served = struct {
+	responses map[string]*Response
+	seq       int
+	mux       sync.Mutex
+}{responses: make(map[string]*Response)}
+
+var (
+	cannedDB   *sql.DB
+	openCanned sync.Once
+)
+
+func init() {
+	sql.Register(driverName, new(cannedDriver))
+}
+
+// cannedRows converts the rows of a canned response into a *sql.Rows, so that they can be bound with rdbms.RowBinder.
+func cannedRows(resp *Response) (*sql.Rows, error) {
+
+	key := serve(resp)
+	defer withdraw(key)
+
+	return database().Query(key)
+}
+
+// cannedRow converts the first row of a canned response into a *sql.Row.
+func cannedRow(resp *Response) *sql.Row {
+
+	key := serve(resp)
+	defer withdraw(key)
+
+	return database().QueryRow(key)
+}
+
+func database() *sql.DB {
+
+	openCanned.Do(func() {
+		cannedDB, _ = sql.Open(driverName, "")
+	})
+
+	return cannedDB
+}
+
+func serve(resp *Response) string {
+
+	served.mux.Lock()
+	defer served.mux.Unlock()
+
+	served.seq++
+	key := strconv.Itoa(served.seq)
+
+	served.responses[key] = resp
+
+	return key
+}
+
+func withdraw(key string) {
+
+	served.mux.Lock()
+	defer served.mux.Unlock()
+
+	delete(served.responses, key)
+}
+
+func lookup(key string) *Response {
+
+	served.mux.Lock()
+	defer served.mux.Unlock()
+
+	return served.responses[key]
+}
+
+type cannedDriver struct{}
+
+func (d *cannedDriver) Open(name string) (driver.Conn, error) {
+	return new(cannedConn), nil
+}
+
+type cannedConn struct{}
+
+func (c *cannedConn) Prepare(query string) (driver.Stmt, error) {
+	return &cannedStmt{key: query}, nil
+}
+
+func (c *cannedConn) Close() error {
+	return nil
+}
+
+func (c *cannedConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported by the canned response driver")
+}
+
+type cannedStmt struct {
+	key string
+}
+
+func (s *cannedStmt) Close() error {
+	return nil
+}
+
+func (s *cannedStmt) NumInput() int {
+	return -1
+}
+
+func (s *cannedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("Exec is not supported by the canned response driver")
+}
+
+func (s *cannedStmt) Query(args []driver.Value) (driver.Rows, error) {
+
+	resp := lookup(s.key)
+
+	if resp == nil {
+		return nil, errors.New("no canned response found")
+	}
+
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	rows := make([][]driver.Value, len(resp.Rows))
+
+	for i, row := range resp.Rows {
+
+		rows[i] = make([]driver.Value, len(row))
+
+		for j, v := range row {
+
+			dv, err := driverValue(v)
+
+			if err != nil {
+				return nil, err
+			}
+
+			rows[i][j] = dv
+		}
+	}
+
+	return &cannedDriverRows{columns: resp.Columns, rows: rows}, nil
+}
+
+// driverValue converts a canned value to a value a driver could return. Numbers are returned as text, as they are by
+// many drivers, so that rdbms.RowBinder can convert them to the type of the target field.
+func driverValue(v interface{}) (driver.Value, error) {
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return []byte(fmt.Sprint(v)), nil
+	}
+
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+type cannedDriverRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *cannedDriverRows) Columns() []string {
+	return r.columns
+}
+
+func (r *cannedDriverRows) Close() error {
+	return nil
+}
+
+func (r *cannedDriverRows) Next(dest []driver.Value) error {
+
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[r.next])
+	r.next++
+
+	return nil
+}
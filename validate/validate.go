@@ -75,6 +75,13 @@ levels of granularity - on an operation, on a rule or on a RuleValidator. The mo
 
 Using the validation framework requires the ServiceErrorManager facility to be enabled (see https://granitic.io/ref/service-error-management )
 
+Warnings
+
+If the error code used by a check is defined with the W (Warning) category (see ws.ServiceErrorCategory), a failure
+of that check does not stop the request from being processed. Instead, the failure is recorded as a warning in the
+ws.Response.Warnings passed to your handler's Logic and is rendered alongside the response body. Checks that fail with
+a warning still count as failures for the purposes of BREAK, STOPALL and cross-field dependencies.
+
 Sharing rules
 
 Sometimes it is useful for a rule to be defined once and re-used by multiple RuleValidators. This is also required
@@ -0,0 +1,209 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+/*
+Package access provides a configurable implementation of ws.AccessChecker that allows or denies requests based on the
+roles, scopes and other values stored in the caller's iam.ClientIdentity.
+
+Per-handler policies
+
+The simplest way to use a Checker is to declare one as a nested component on a handler:
+
+	{
+	  "deleteArtistHandler": {
+		"type": "handler.WsHandler",
+		"RequireAuthentication": true,
+		"UserIdentifier": "ref:jwtIdentifier",
+		"AccessChecker": {
+		  "type": "access.Checker",
+		  "Roles": ["admin"],
+		  "Scopes": ["write:artist"],
+		  "Claims": ["tenant in acme,globex"]
+		}
+	  }
+	}
+
+By default, all of the listed requirements must be met. Set Match to "any" to allow callers that meet at least one of them.
+
+Shared rules
+
+A single Checker can also be shared between handlers, with a list of Rules loaded from configuration:
+
+	{
+	  "accessChecker": {
+		"type": "access.Checker",
+		"Rules": "$AccessRules"
+	  }
+	}
+
+with configuration like:
+
+	{
+	  "AccessRules": [
+		{"Handler": "deleteArtistHandler", "Roles": ["admin"]},
+		{"Method": "GET", "Path": "^/artist", "Match": "any", "Scopes": ["read:artist"], "Roles": ["admin"]},
+		{"Method": "POST", "Policies": [
+		  {"Roles": ["editor"]},
+		  {"Match": "any", "Scopes": ["write:artist"], "Claims": ["email_verified == true"]}
+		]}
+	  ]
+	}
+
+Each rule may restrict the requests it applies to by Handler (the component name of the handler), Method and Path (a regular
+expression matched against the request path). The first matching rule is used. Nested Policies count as a single requirement of
+the enclosing rule or policy, allowing arbitrary combinations of AND and OR.
+
+If no rule matches, the policy defined directly on the Checker (its Roles, Scopes, Claims and Policies fields) is applied.
+If that is empty, the request is denied unless AllowUnmatched is set to true.
+
+Identity values
+
+Roles and scopes are read using iam.ClientIdentity's Roles and Scopes methods. Claim predicates take the form
+
+	claim operator [value]
+
+where claim is the key of a value in the iam.ClientIdentity. If no such key exists and the claim contains dots, it is treated as
+a path through nested maps (e.g. JWTClaims.email). The supported operators are:
+
+	exists              The claim is present
+	== value            The claim is equal to the value
+	!= value            The claim is missing or not equal to the value
+	in a,b,c            The claim is equal to one of the comma separated values
+	contains value      The claim is a list (or space separated string) containing the value
+
+Reviewing policies
+
+If the RuntimeCtl facility is enabled, the 'policies' command lists the policy that applies to each handler in a running
+application.
+*/
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/iam"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/ws"
+)
+
+// Checker is a ws.AccessChecker that compares the caller's identity against a configured set of policies.
+type Checker struct {
+	// The policy applied if none of the Rules match the request.
+	Policy
+
+	// Rules associating policies with particular handlers, HTTP methods and paths. The first matching rule is used.
+	Rules []*Rule
+
+	// Allow requests that match none of the Rules if the Checker's own policy is empty.
+	AllowUnmatched bool
+
+	// Injected by Granitic.
+	Log logging.Logger
+
+	state ioc.ComponentState
+}
+
+// Allowed implements ws.AccessChecker.Allowed
+func (c *Checker) Allowed(ctx context.Context, r *ws.Request) bool {
+
+	ci := r.UserIdentity
+
+	if ci == nil {
+		ci = iam.NewAnonymousIdentity()
+	}
+
+	p := c.policyFor(r)
+
+	if p == nil {
+		c.Log.LogDebugfCtx(ctx, "No access rule matched %s %s (%s)", r.HTTPMethod, r.Path, r.ServingHandler)
+		return c.AllowUnmatched
+	}
+
+	if allowed := p.allows(ci); !allowed {
+		c.Log.LogDebugfCtx(ctx, "%s denied access to %s %s by policy: %s", ci.LoggableUserID(), r.HTTPMethod, r.Path, p.describe())
+		return false
+	}
+
+	return true
+}
+
+// policyFor returns the policy that should be applied to the request or nil if there isn't one
+func (c *Checker) policyFor(r *ws.Request) *Policy {
+
+	for _, rule := range c.Rules {
+		if rule.matches(r.ServingHandler, r.HTTPMethod, r.Path) {
+			return &rule.Policy
+		}
+	}
+
+	if c.Policy.empty() {
+		return nil
+	}
+
+	return &c.Policy
+}
+
+// DescribePolicy implements ws.AccessPolicyDescriber.DescribePolicy
+func (c *Checker) DescribePolicy(handlerName string, method string) []string {
+
+	lines := make([]string, 0)
+
+	for _, rule := range c.Rules {
+
+		if !rule.couldMatch(handlerName, method) {
+			continue
+		}
+
+		if rule.pathRegex == nil {
+			return append(lines, rule.Policy.describe())
+		}
+
+		lines = append(lines, fmt.Sprintf("path %s: %s", rule.Path, rule.Policy.describe()))
+	}
+
+	switch {
+	case !c.Policy.empty():
+		lines = append(lines, c.Policy.describe())
+	case c.AllowUnmatched:
+		lines = append(lines, "allow all")
+	default:
+		lines = append(lines, "deny all")
+	}
+
+	if len(lines) > 1 {
+		lines[len(lines)-1] = "otherwise: " + lines[len(lines)-1]
+	}
+
+	return lines
+}
+
+// StartComponent is called by the IoC container. Validates the configured claim predicates and path patterns.
+func (c *Checker) StartComponent() error {
+
+	if c.state != ioc.StoppedState {
+		return nil
+	}
+
+	c.state = ioc.StartingState
+
+	if err := c.Policy.compile(); err != nil {
+		return err
+	}
+
+	for i, r := range c.Rules {
+
+		if r == nil {
+			return errors.New("access rules must not be null")
+		}
+
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("problem with access rule %d: %s", i, err.Error())
+		}
+	}
+
+	c.state = ioc.RunningState
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package access
+
+import (
+	"context"
+	"github.com/graniticio/granitic/v2/iam"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/ws"
+	"testing"
+)
+
+func newChecker() *Checker {
+	c := new(Checker)
+	c.Log = logging.NewStdoutLogger(logging.Fatal)
+
+	return c
+}
+
+func request(handler, method, path string, ci iam.ClientIdentity) *ws.Request {
+	r := new(ws.Request)
+	r.ServingHandler = handler
+	r.HTTPMethod = method
+	r.Path = path
+	r.UserIdentity = ci
+
+	return r
+}
+
+func identity(roles []string, scopes string) iam.ClientIdentity {
+	ci := iam.NewAuthenticatedIdentity("user")
+	ci.SetRoles(roles)
+	ci["Scopes"] = scopes
+
+	return ci
+}
+
+func TestHandlerPolicy(t *testing.T) {
+
+	c := newChecker()
+	c.Roles = []string{"admin"}
+	c.Scopes = []string{"write:artist"}
+	c.Claims = []string{"tenant in acme, globex"}
+
+	if err := c.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	ci := identity([]string{"admin"}, "read:artist write:artist")
+	ci["tenant"] = "globex"
+
+	test.ExpectBool(t, c.Allowed(ctx, request("h", "POST", "/artist", ci)), true)
+
+	ci["tenant"] = "other"
+	test.ExpectBool(t, c.Allowed(ctx, request("h", "POST", "/artist", ci)), false)
+
+	test.ExpectBool(t, c.Allowed(ctx, request("h", "POST", "/artist", nil)), false)
+
+	c = newChecker()
+	c.Match = "any"
+	c.Roles = []string{"admin"}
+	c.Scopes = []string{"write:artist"}
+
+	if err := c.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	test.ExpectBool(t, c.Allowed(ctx, request("h", "POST", "/artist", identity(nil, "write:artist"))), true)
+	test.ExpectBool(t, c.Allowed(ctx, request("h", "POST", "/artist", identity([]string{"viewer"}, ""))), false)
+}
+
+func TestRules(t *testing.T) {
+
+	c := newChecker()
+	c.Rules = []*Rule{
+		{Handler: "deleteHandler", Policy: Policy{Roles: []string{"admin"}}},
+		{Method: "get", Path: "^/artist", Policy: Policy{Match: "any", Roles: []string{"admin"}, Scopes: []string{"read:artist"}}},
+		{Method: "POST", Policy: Policy{Policies: []*Policy{
+			{Roles: []string{"editor"}},
+			{Match: "any", Scopes: []string{"write:artist"}, Claims: []string{"email_verified == true"}},
+		}}},
+	}
+
+	if err := c.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	admin := identity([]string{"admin"}, "")
+	reader := identity(nil, "read:artist")
+
+	test.ExpectBool(t, c.Allowed(ctx, request("deleteHandler", "DELETE", "/artist/1", admin)), true)
+	test.ExpectBool(t, c.Allowed(ctx, request("deleteHandler", "DELETE", "/artist/1", reader)), false)
+	test.ExpectBool(t, c.Allowed(ctx, request("getHandler", "GET", "/artist/1", reader)), true)
+	test.ExpectBool(t, c.Allowed(ctx, request("getHandler", "GET", "/album/1", reader)), false)
+
+	editor := identity([]string{"editor"}, "")
+	editor["JWTClaims"] = map[string]interface{}{"email_verified": true}
+
+	// Nested claim paths are only used if the identity doesn't contain the full name as a key
+	test.ExpectBool(t, c.Allowed(ctx, request("postHandler", "POST", "/artist", editor)), false)
+
+	c.Rules[2].Policies[1].Claims = []string{"JWTClaims.email_verified == true"}
+	c.state = 0
+
+	if err := c.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	test.ExpectBool(t, c.Allowed(ctx, request("postHandler", "POST", "/artist", editor)), true)
+
+	c.AllowUnmatched = true
+	test.ExpectBool(t, c.Allowed(ctx, request("putHandler", "PUT", "/artist", reader)), true)
+}
+
+func TestPredicates(t *testing.T) {
+
+	ci := iam.NewAuthenticatedIdentity("user")
+	ci["groups"] = []interface{}{"a", "b"}
+	ci["level"] = float64(3)
+
+	expected := map[string]bool{
+		"groups contains b": true,
+		"groups contains c": false,
+		"level == 3":        true,
+		"level != 3":        false,
+		"missing != 3":      true,
+		"missing exists":    false,
+		"level exists":      true,
+		"level in 1,2, 3":   true,
+	}
+
+	for expr, result := range expected {
+
+		p, err := parsePredicate(expr)
+
+		if err != nil {
+			t.Fatalf("%s: %s", expr, err.Error())
+		}
+
+		if p.satisfiedBy(ci) != result {
+			t.Errorf("Expected %s to be %v", expr, result)
+		}
+	}
+
+	for _, invalid := range []string{"level", "level > 3", "level ==", "level exists 4"} {
+		if _, err := parsePredicate(invalid); err == nil {
+			t.Errorf("Expected %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestDescribePolicy(t *testing.T) {
+
+	c := newChecker()
+	c.Rules = []*Rule{
+		{Handler: "deleteHandler", Policy: Policy{Roles: []string{"admin"}, Scopes: []string{"delete"}}},
+		{Method: "GET", Path: "^/artist", Policy: Policy{Match: "any", Roles: []string{"admin", "viewer"}}},
+	}
+
+	if err := c.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := c.DescribePolicy("deleteHandler", "DELETE")
+	test.ExpectInt(t, len(d), 1)
+	test.ExpectString(t, d[0], "role admin AND scope delete")
+
+	d = c.DescribePolicy("getHandler", "GET")
+	test.ExpectInt(t, len(d), 2)
+	test.ExpectString(t, d[0], "path ^/artist: role admin OR role viewer")
+	test.ExpectString(t, d[1], "otherwise: deny all")
+}
+
+func TestInvalidConfiguration(t *testing.T) {
+
+	c := newChecker()
+	c.Match = "some"
+
+	if c.StartComponent() == nil {
+		t.Errorf("Expected invalid Match value to be rejected")
+	}
+
+	c = newChecker()
+	c.Rules = []*Rule{{Path: "(", Policy: Policy{Roles: []string{"admin"}}}}
+
+	if c.StartComponent() == nil {
+		t.Errorf("Expected invalid path pattern to be rejected")
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package access
+
+import (
+	"fmt"
+	"github.com/graniticio/granitic/v2/iam"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	matchAll = "all"
+	matchAny = "any"
+)
+
+const (
+	opExists   = "exists"
+	opEquals   = "=="
+	opNotEqual = "!="
+	opIn       = "in"
+	opContains = "contains"
+)
+
+// Policy describes the roles, scopes and claims a caller must have to be allowed access to an endpoint.
+type Policy struct {
+	// Whether 'all' (the default) or 'any' of the requirements in this policy must be met.
+	Match string
+
+	// Roles that the caller must have (see iam.ClientIdentity.Roles).
+	Roles []string
+
+	// Scopes that the caller must have (see iam.ClientIdentity.Scopes).
+	Scopes []string
+
+	// Predicates on values stored in the caller's iam.ClientIdentity in the form 'claim operator value'. See the package
+	// documentation for the supported operators.
+	Claims []string
+
+	// Nested policies, each of which counts as a single requirement of this policy.
+	Policies []*Policy
+
+	predicates []*predicate
+}
+
+// Rule associates a Policy with the requests it applies to. Empty Handler, Method and Path fields match any request.
+type Rule struct {
+	// The component name of the handler serving the request.
+	Handler string
+
+	// The HTTP method of the request.
+	Method string
+
+	// A regular expression that must match the path of the request.
+	Path string
+
+	Policy
+
+	pathRegex *regexp.Regexp
+}
+
+func (r *Rule) compile() error {
+
+	if r.Path != "" {
+		re, err := regexp.Compile(r.Path)
+
+		if err != nil {
+			return fmt.Errorf("unable to compile path pattern %s: %s", r.Path, err.Error())
+		}
+
+		r.pathRegex = re
+	}
+
+	return r.Policy.compile()
+}
+
+func (r *Rule) matches(handler, method, path string) bool {
+
+	if r.Handler != "" && r.Handler != handler {
+		return false
+	}
+
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+
+	return r.pathRegex == nil || r.pathRegex.MatchString(path)
+}
+
+// couldMatch returns true if the rule might apply to requests served by the named handler with the supplied method,
+// depending on the path of the request.
+func (r *Rule) couldMatch(handler, method string) bool {
+	return (r.Handler == "" || r.Handler == handler) && (r.Method == "" || strings.EqualFold(r.Method, method))
+}
+
+func (p *Policy) compile() error {
+
+	switch strings.ToLower(p.Match) {
+	case "", matchAll:
+		p.Match = matchAll
+	case matchAny:
+		p.Match = matchAny
+	default:
+		return fmt.Errorf("%s is not a valid Match value (all, any)", p.Match)
+	}
+
+	p.predicates = make([]*predicate, 0, len(p.Claims))
+
+	for _, c := range p.Claims {
+
+		pr, err := parsePredicate(c)
+
+		if err != nil {
+			return err
+		}
+
+		p.predicates = append(p.predicates, pr)
+	}
+
+	for _, np := range p.Policies {
+		if err := np.compile(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) empty() bool {
+	return len(p.Roles) == 0 && len(p.Scopes) == 0 && len(p.Claims) == 0 && len(p.Policies) == 0
+}
+
+// allows returns true if the supplied identity meets the requirements of this policy. An empty policy allows everyone.
+func (p *Policy) allows(ci iam.ClientIdentity) bool {
+
+	if p.empty() {
+		return true
+	}
+
+	or := p.Match == matchAny
+
+	results := make([]bool, 0)
+
+	held := ci.Roles()
+
+	for _, r := range p.Roles {
+		results = append(results, contains(held, r))
+	}
+
+	held = ci.Scopes()
+
+	for _, s := range p.Scopes {
+		results = append(results, contains(held, s))
+	}
+
+	for _, pr := range p.predicates {
+		results = append(results, pr.satisfiedBy(ci))
+	}
+
+	for _, np := range p.Policies {
+		results = append(results, np.allows(ci))
+	}
+
+	for _, r := range results {
+		if r == or {
+			return or
+		}
+	}
+
+	return !or
+}
+
+// describe returns a human-readable version of the policy.
+func (p *Policy) describe() string {
+
+	if p.empty() {
+		return "allow all"
+	}
+
+	terms := make([]string, 0)
+
+	for _, r := range p.Roles {
+		terms = append(terms, "role "+r)
+	}
+
+	for _, s := range p.Scopes {
+		terms = append(terms, "scope "+s)
+	}
+
+	for _, c := range p.Claims {
+		terms = append(terms, "claim "+c)
+	}
+
+	for _, np := range p.Policies {
+		terms = append(terms, "("+np.describe()+")")
+	}
+
+	sep := " AND "
+
+	if p.Match == matchAny {
+		sep = " OR "
+	}
+
+	return strings.Join(terms, sep)
+}
+
+type predicate struct {
+	claim  string
+	op     string
+	values []string
+}
+
+func parsePredicate(expr string) (*predicate, error) {
+
+	f := strings.Fields(expr)
+
+	if len(f) < 2 {
+		return nil, fmt.Errorf("claim predicate '%s' must be in the form 'claim operator [value]'", expr)
+	}
+
+	p := &predicate{claim: f[0], op: strings.ToLower(f[1])}
+	value := strings.TrimSpace(strings.Join(f[2:], " "))
+
+	switch p.op {
+	case opExists:
+		if value != "" {
+			return nil, fmt.Errorf("claim predicate '%s' must not have a value", expr)
+		}
+
+		return p, nil
+
+	case opIn:
+		for _, v := range strings.Split(value, ",") {
+			p.values = append(p.values, strings.TrimSpace(v))
+		}
+
+	case opEquals, opNotEqual, opContains:
+		p.values = []string{value}
+
+	default:
+		return nil, fmt.Errorf("claim predicate '%s' uses unsupported operator %s", expr, f[1])
+	}
+
+	if value == "" {
+		return nil, fmt.Errorf("claim predicate '%s' must have a value", expr)
+	}
+
+	return p, nil
+}
+
+func (p *predicate) satisfiedBy(ci iam.ClientIdentity) bool {
+
+	v, found := claimValue(ci, p.claim)
+
+	switch p.op {
+	case opExists:
+		return found
+	case opNotEqual:
+		return !found || asString(v) != p.values[0]
+	}
+
+	if !found {
+		return false
+	}
+
+	switch p.op {
+	case opEquals, opIn:
+		return contains(p.values, asString(v))
+	case opContains:
+		return contains(asStrings(v), p.values[0])
+	}
+
+	return false
+}
+
+// claimValue finds the value in the identity with the supplied name. If the name is not found and contains dots,
+// it is treated as a path through nested maps (e.g. JWTClaims.email).
+func claimValue(ci iam.ClientIdentity, name string) (interface{}, bool) {
+
+	if v, found := ci[name]; found {
+		return v, true
+	}
+
+	var current interface{} = map[string]interface{}(ci)
+
+	for _, p := range strings.Split(name, ".") {
+
+		m, found := current.(map[string]interface{})
+
+		if !found {
+			return nil, false
+		}
+
+		if current, found = m[p]; !found {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func asString(v interface{}) string {
+
+	if f, found := v.(float64); found {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+func asStrings(v interface{}) []string {
+
+	switch l := v.(type) {
+	case []string:
+		return l
+	case string:
+		return strings.Fields(l)
+	case []interface{}:
+
+		s := make([]string, len(l))
+
+		for i, e := range l {
+			s[i] = asString(e)
+		}
+
+		return s
+	}
+
+	return []string{asString(v)}
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,271 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+/*
+Package credential provides implementations of ws.Identifier that authenticate callers using API keys or HTTP Basic
+authentication, checking the supplied secrets against salted hashes held in a pluggable Store.
+
+Declaring identifiers
+
+	{
+	  "credentialStore": {
+		"type": "credential.FileStore",
+		"Path": "$Credentials.File"
+	  },
+
+	  "apiKeyIdentifier": {
+		"type": "credential.APIKeyIdentifier",
+		"Store": "ref:credentialStore",
+		"Header": "X-API-Key"
+	  },
+
+	  "basicIdentifier": {
+		"type": "credential.BasicAuthIdentifier",
+		"Store": "ref:credentialStore",
+		"MaxFailedAttempts": 5,
+		"LockoutSeconds": 300
+	  }
+	}
+
+Either identifier can then be referenced from the UserIdentifier field of a handler.WsHandler.
+
+API keys
+
+API keys take the form <credential ID>.<secret> and are supplied in the header named by APIKeyIdentifier.Header (X-API-Key by
+default). The GenerateAPIKey function creates a new random key and the hash that should be stored for it.
+
+HTTP Basic authentication
+
+The user name in the Authorization header is used as the credential ID and the password as the secret.
+
+Credentials, expiry and rotation
+
+Each Credential has one or more Secrets, each stored as a hash created by HashSecret, with an optional expiry time. A secret
+is accepted if it matches any unexpired hash, so a key can be rotated by adding a new secret to a credential and setting an
+expiry time on the old one. Successfully authenticated callers receive an iam.ClientIdentity with the Credential's UserID (or ID) as
+its loggable user ID and the Credential's Roles and Scopes (see iam.ClientIdentity.Roles and iam.ClientIdentity.Scopes).
+
+Stores
+
+FileStore loads credentials from a JSON file, which is reloaded when it changes:
+
+	{
+	  "Credentials": [
+		{
+		  "ID": "billing",
+		  "UserID": "billing-service",
+		  "Roles": ["invoicer"],
+		  "Secrets": [
+			{"Hash": "pbkdf2-sha256$10000$...", "Expires": "2020-12-31T00:00:00Z"},
+			{"Hash": "pbkdf2-sha256$10000$..."}
+		  ]
+		}
+	  ]
+	}
+
+RDBMSStore loads credentials from a database using a query managed by the QueryManager facility. See RDBMSStore for details.
+Applications can provide their own store by implementing the Store interface.
+
+Lockout
+
+Failed attempts to authenticate with a known credential ID are counted. After MaxFailedAttempts consecutive failures the
+credential is locked for LockoutSeconds, during which all attempts to use it are rejected. Failure counts are held in memory
+and are not shared between instances of an application.
+*/
+package credential
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IDIdentityKey is the key in an iam.ClientIdentity under which the ID of the credential used to authenticate is stored.
+const IDIdentityKey = "CredentialID"
+
+const (
+	hashScheme       = "pbkdf2-sha256"
+	hashIterations   = 10000
+	saltLength       = 16
+	secretLength     = 32
+	derivedKeyLength = sha256.Size
+	apiKeySeparator  = "."
+)
+
+// Store is implemented by components able to find credentials by ID.
+type Store interface {
+	// Find returns the credential with the supplied ID or nil if no such credential exists.
+	Find(ctx context.Context, id string) (*Credential, error)
+}
+
+// Credential is the stored record of a caller that is able to authenticate with an API key or user name and password.
+type Credential struct {
+	// The API key ID or user name of the caller.
+	ID string
+
+	// A string to use as the caller's loggable user ID. If not set, ID is used.
+	UserID string
+
+	// The roles granted to the caller.
+	Roles []string
+
+	// The scopes granted to the caller.
+	Scopes []string
+
+	// Additional values to copy into the caller's iam.ClientIdentity.
+	Attributes map[string]interface{}
+
+	// The hashes of the secrets that may be used with this credential.
+	Secrets []*Secret
+}
+
+// Secret is the hashed form of an API key secret or password.
+type Secret struct {
+	// The hash of the secret, as created by HashSecret.
+	Hash string
+
+	// The time after which the secret may no longer be used. The zero value means the secret never expires.
+	Expires time.Time
+}
+
+// Expired returns true if the secret may not be used at the supplied time.
+func (s *Secret) Expired(at time.Time) bool {
+	return !s.Expires.IsZero() && !at.Before(s.Expires)
+}
+
+// matches returns true if the supplied secret matches one of the credential's unexpired hashes.
+func (c *Credential) matches(secret string, at time.Time) bool {
+
+	for _, s := range c.Secrets {
+
+		if s == nil || s.Expired(at) {
+			continue
+		}
+
+		if VerifySecret(secret, s.Hash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HashSecret creates a salted hash of the supplied secret, suitable for storing in a Secret.
+func HashSecret(secret string) (string, error) {
+
+	salt := make([]byte, saltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := pbkdf2([]byte(secret), salt, hashIterations)
+
+	enc := base64.RawStdEncoding
+
+	return fmt.Sprintf("%s$%d$%s$%s", hashScheme, hashIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+// VerifySecret returns true if the supplied secret matches the supplied hash (as created by HashSecret).
+func VerifySecret(secret, hash string) bool {
+
+	parts := strings.Split(hash, "$")
+
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+
+	if err != nil || iterations < 1 {
+		return false
+	}
+
+	enc := base64.RawStdEncoding
+
+	salt, err := enc.DecodeString(parts[2])
+
+	if err != nil {
+		return false
+	}
+
+	expected, err := enc.DecodeString(parts[3])
+
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(pbkdf2([]byte(secret), salt, iterations), expected) == 1
+}
+
+// GenerateAPIKey creates a new random API key for the credential with the supplied ID. The key should be given to the
+// client and the hash stored as a Secret of the credential.
+func GenerateAPIKey(id string) (key string, hash string, err error) {
+
+	if id == "" || strings.Contains(id, apiKeySeparator) {
+		return "", "", fmt.Errorf("credential IDs used for API keys must not be empty or contain '%s'", apiKeySeparator)
+	}
+
+	b := make([]byte, secretLength)
+
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	if hash, err = HashSecret(secret); err != nil {
+		return "", "", err
+	}
+
+	return id + apiKeySeparator + secret, hash, nil
+}
+
+// splitAPIKey separates an API key into the credential ID and secret.
+func splitAPIKey(key string) (string, string, error) {
+
+	i := strings.Index(key, apiKeySeparator)
+
+	if i < 1 || i == len(key)-1 {
+		return "", "", errors.New("API key is not in the form <id>.<secret>")
+	}
+
+	return key[:i], key[i+1:], nil
+}
+
+// pbkdf2 derives a key from the password and salt using PBKDF2 with HMAC-SHA256 (RFC 8018), producing a single block of output.
+func pbkdf2(password, salt []byte, iterations int) []byte {
+
+	prf := hmac.New(sha256.New, password)
+
+	block := make([]byte, 4)
+	binary.BigEndian.PutUint32(block, 1)
+
+	prf.Write(salt)
+	prf.Write(block)
+
+	u := prf.Sum(nil)
+	result := make([]byte, derivedKeyLength)
+	copy(result, u)
+
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+
+	return result
+}
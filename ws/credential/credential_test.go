@@ -0,0 +1,258 @@
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/graniticio/granitic/v2/iam"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"github.com/graniticio/granitic/v2/test"
+	"github.com/graniticio/granitic/v2/types"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type mapStore map[string]*Credential
+
+func (ms mapStore) Find(ctx context.Context, id string) (*Credential, error) {
+	return ms[id], nil
+}
+
+func TestHashing(t *testing.T) {
+
+	h, err := HashSecret("secret")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h2, _ := HashSecret("secret")
+
+	if h == h2 {
+		t.Errorf("Expected hashes of the same secret to be salted differently")
+	}
+
+	test.ExpectBool(t, VerifySecret("secret", h), true)
+	test.ExpectBool(t, VerifySecret("Secret", h), false)
+	test.ExpectBool(t, VerifySecret("secret", "sha1$abc"), false)
+}
+
+func TestAPIKeyIdentifier(t *testing.T) {
+
+	key, hash, err := GenerateAPIKey("billing")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, oldHash, _ := GenerateAPIKey("billing")
+
+	store := mapStore{
+		"billing": {
+			ID:      "billing",
+			UserID:  "billing-service",
+			Roles:   []string{"invoicer"},
+			Scopes:  []string{"read:invoice"},
+			Secrets: []*Secret{{Hash: oldHash, Expires: time.Now().Add(-time.Minute)}, {Hash: hash}},
+		},
+	}
+
+	ai := new(APIKeyIdentifier)
+	ai.Store = store
+	ai.Log = logging.NewStdoutLogger(logging.Fatal)
+
+	if err := ai.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	ci := identifyWithKey(ai, key)
+
+	test.ExpectBool(t, ci.Authenticated(), true)
+	test.ExpectString(t, ci.LoggableUserID(), "billing-service")
+	test.ExpectString(t, ci.Roles()[0], "invoicer")
+	test.ExpectString(t, ci.Scopes()[0], "read:invoice")
+	test.ExpectString(t, ci[IDIdentityKey].(string), "billing")
+
+	test.ExpectBool(t, identifyWithKey(ai, oldKey).Authenticated(), false)
+	test.ExpectBool(t, identifyWithKey(ai, "malformed").Authenticated(), false)
+	test.ExpectBool(t, identifyWithKey(ai, "unknown.secret").Authenticated(), false)
+
+	if _, _, err := GenerateAPIKey("bad.id"); err == nil {
+		t.Errorf("Expected ID containing the separator to be rejected")
+	}
+}
+
+func TestLockout(t *testing.T) {
+
+	hash, _ := HashSecret("password")
+
+	bi := new(BasicAuthIdentifier)
+	bi.Store = mapStore{"alice": {ID: "alice", Secrets: []*Secret{{Hash: hash}}}}
+	bi.Log = logging.NewStdoutLogger(logging.Fatal)
+	bi.MaxFailedAttempts = 2
+	bi.LockoutSeconds = 60
+
+	if err := bi.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	bi.authenticator.now = func() time.Time { return now }
+
+	test.ExpectBool(t, identifyBasic(bi, "alice", "password").Authenticated(), true)
+	test.ExpectBool(t, identifyBasic(bi, "alice", "wrong").Authenticated(), false)
+
+	// A successful attempt resets the count
+	test.ExpectBool(t, identifyBasic(bi, "alice", "password").Authenticated(), true)
+	test.ExpectBool(t, identifyBasic(bi, "alice", "wrong").Authenticated(), false)
+	test.ExpectBool(t, identifyBasic(bi, "alice", "wrong").Authenticated(), false)
+
+	test.ExpectBool(t, identifyBasic(bi, "alice", "password").Authenticated(), false)
+
+	now = now.Add(61 * time.Second)
+
+	test.ExpectBool(t, identifyBasic(bi, "alice", "password").Authenticated(), true)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	ci, _ := bi.Identify(context.Background(), req)
+
+	test.ExpectBool(t, ci.Authenticated(), false)
+}
+
+func TestFileStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "credentials")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "credentials.json")
+
+	hash, _ := HashSecret("password")
+
+	writeCredentials(t, path, &Credential{ID: "alice", Roles: []string{"admin"}, Secrets: []*Secret{{Hash: hash}}})
+
+	fs := new(FileStore)
+	fs.Path = path
+	fs.Log = logging.NewStdoutLogger(logging.Fatal)
+
+	now := time.Now()
+	fs.now = func() time.Time { return now }
+
+	if err := fs.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := fs.Find(context.Background(), "alice")
+
+	if c == nil || c.Roles[0] != "admin" || !c.matches("password", now) {
+		t.Fatalf("Unexpected credential %v", c)
+	}
+
+	writeCredentials(t, path, &Credential{ID: "bob", Secrets: []*Secret{{Hash: hash}}})
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	if c, _ = fs.Find(context.Background(), "bob"); c != nil {
+		t.Errorf("Did not expect file to be reloaded before the check interval")
+	}
+
+	now = now.Add(time.Minute)
+
+	if c, _ = fs.Find(context.Background(), "bob"); c == nil {
+		t.Errorf("Expected modified file to be reloaded")
+	}
+}
+
+type fakeClientManager struct {
+	rows []interface{}
+}
+
+func (cm *fakeClientManager) Client() (rdbms.Client, error) {
+	return cm.ClientFromContext(context.Background())
+}
+
+func (cm *fakeClientManager) ClientFromContext(ctx context.Context) (rdbms.Client, error) {
+	return &fakeClient{rows: cm.rows}, nil
+}
+
+type fakeClient struct {
+	rdbms.Client
+	rows []interface{}
+}
+
+func (fc *fakeClient) SelectBindQIDParam(qid string, name string, value interface{}, template interface{}) ([]interface{}, error) {
+	return fc.rows, nil
+}
+
+func TestRDBMSStore(t *testing.T) {
+
+	expires := time.Now().Add(time.Hour).Unix()
+
+	row := func(hash string, exp *types.NilableInt64) *secretRow {
+		return &secretRow{ID: "svc", UserID: types.NewNilableString("service"), Hash: hash, Expires: exp,
+			Roles: types.NewNilableString("reader writer")}
+	}
+
+	cm := new(fakeClientManager)
+	cm.rows = []interface{}{row("a", types.NewNilableInt64(expires)), row("b", new(types.NilableInt64))}
+
+	rs := new(RDBMSStore)
+	rs.DBClientManager = cm
+
+	if err := rs.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := rs.Find(context.Background(), "svc")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test.ExpectString(t, c.UserID, "service")
+	test.ExpectInt(t, len(c.Roles), 2)
+	test.ExpectInt(t, len(c.Secrets), 2)
+	test.ExpectBool(t, c.Secrets[0].Expires.Unix() == expires, true)
+	test.ExpectBool(t, c.Secrets[1].Expires.IsZero(), true)
+
+	cm.rows = nil
+
+	if c, _ = rs.Find(context.Background(), "svc"); c != nil {
+		t.Errorf("Expected no credential when no rows are returned")
+	}
+}
+
+func identifyWithKey(ai *APIKeyIdentifier, key string) iam.ClientIdentity {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(ai.Header, key)
+
+	ci, _ := ai.Identify(context.Background(), req)
+
+	return ci
+}
+
+func identifyBasic(bi *BasicAuthIdentifier, user, password string) iam.ClientIdentity {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth(user, password)
+
+	ci, _ := bi.Identify(context.Background(), req)
+
+	return ci
+}
+
+func writeCredentials(t *testing.T, path string, c ...*Credential) {
+
+	b, _ := json.Marshal(map[string]interface{}{"Credentials": c})
+
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
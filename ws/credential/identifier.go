@@ -0,0 +1,275 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package credential
+
+import (
+	"context"
+	"errors"
+	"github.com/graniticio/granitic/v2/iam"
+	"github.com/graniticio/granitic/v2/logging"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAPIKeyHeader      = "X-API-Key"
+	defaultMaxFailedAttempts = 5
+	defaultLockoutSeconds    = 300
+)
+
+// APIKeyIdentifier is a ws.Identifier that authenticates callers using an API key supplied in an HTTP header.
+type APIKeyIdentifier struct {
+	// The header containing the API key. Defaults to X-API-Key.
+	Header string
+
+	// The source of credentials.
+	Store Store
+
+	// The number of consecutive failed attempts after which a credential is locked. Defaults to 5. Set to -1 to disable lockout.
+	MaxFailedAttempts int
+
+	// How long a credential remains locked. Defaults to 300 seconds.
+	LockoutSeconds int
+
+	// Injected by Granitic.
+	Log logging.Logger
+
+	authenticator *authenticator
+}
+
+// Identify implements ws.Identifier.Identify. Callers without a valid API key are returned an anonymous identity.
+func (ai *APIKeyIdentifier) Identify(ctx context.Context, req *http.Request) (iam.ClientIdentity, context.Context) {
+
+	key := req.Header.Get(ai.Header)
+
+	if key == "" {
+		return iam.NewAnonymousIdentity(), ctx
+	}
+
+	id, secret, err := splitAPIKey(key)
+
+	if err != nil {
+		ai.Log.LogDebugfCtx(ctx, "Rejected API key: %s", err.Error())
+		return iam.NewAnonymousIdentity(), ctx
+	}
+
+	return ai.authenticator.authenticate(ctx, id, secret), ctx
+}
+
+// StartComponent is called by the IoC container. Checks that a Store has been set and applies defaults.
+func (ai *APIKeyIdentifier) StartComponent() error {
+
+	if ai.authenticator != nil {
+		return nil
+	}
+
+	if ai.Header == "" {
+		ai.Header = defaultAPIKeyHeader
+	}
+
+	a, err := newAuthenticator(ai.Store, ai.MaxFailedAttempts, ai.LockoutSeconds, ai.Log)
+
+	if err != nil {
+		return err
+	}
+
+	ai.authenticator = a
+
+	return nil
+}
+
+// BasicAuthIdentifier is a ws.Identifier that authenticates callers using HTTP Basic authentication.
+type BasicAuthIdentifier struct {
+	// The source of credentials.
+	Store Store
+
+	// The number of consecutive failed attempts after which a credential is locked. Defaults to 5. Set to -1 to disable lockout.
+	MaxFailedAttempts int
+
+	// How long a credential remains locked. Defaults to 300 seconds.
+	LockoutSeconds int
+
+	// Injected by Granitic.
+	Log logging.Logger
+
+	authenticator *authenticator
+}
+
+// Identify implements ws.Identifier.Identify. Callers without valid Basic authentication credentials are returned an
+// anonymous identity.
+func (bi *BasicAuthIdentifier) Identify(ctx context.Context, req *http.Request) (iam.ClientIdentity, context.Context) {
+
+	user, password, found := req.BasicAuth()
+
+	if !found || user == "" {
+		return iam.NewAnonymousIdentity(), ctx
+	}
+
+	return bi.authenticator.authenticate(ctx, user, password), ctx
+}
+
+// StartComponent is called by the IoC container. Checks that a Store has been set and applies defaults.
+func (bi *BasicAuthIdentifier) StartComponent() error {
+
+	if bi.authenticator != nil {
+		return nil
+	}
+
+	a, err := newAuthenticator(bi.Store, bi.MaxFailedAttempts, bi.LockoutSeconds, bi.Log)
+
+	if err != nil {
+		return err
+	}
+
+	bi.authenticator = a
+
+	return nil
+}
+
+// authenticator checks secrets against stored credentials and tracks failed attempts.
+type authenticator struct {
+	store       Store
+	maxFailures int
+	lockout     time.Duration
+	log         logging.Logger
+	now         func() time.Time
+	failures    map[string]*failures
+	mux         sync.Mutex
+}
+
+type failures struct {
+	count       int
+	lockedUntil time.Time
+}
+
+func newAuthenticator(s Store, maxFailures, lockoutSeconds int, log logging.Logger) (*authenticator, error) {
+
+	if s == nil {
+		return nil, errors.New("no credential Store set")
+	}
+
+	if maxFailures == 0 {
+		maxFailures = defaultMaxFailedAttempts
+	}
+
+	if lockoutSeconds == 0 {
+		lockoutSeconds = defaultLockoutSeconds
+	}
+
+	a := new(authenticator)
+	a.store = s
+	a.maxFailures = maxFailures
+	a.lockout = time.Duration(lockoutSeconds) * time.Second
+	a.log = log
+	a.now = time.Now
+	a.failures = make(map[string]*failures)
+
+	return a, nil
+}
+
+// authenticate returns an authenticated identity if the secret is valid for the identified credential, otherwise an
+// anonymous identity.
+func (a *authenticator) authenticate(ctx context.Context, id, secret string) iam.ClientIdentity {
+
+	now := a.now()
+
+	if a.locked(id, now) {
+		a.log.LogWarnfCtx(ctx, "Rejected attempt to use locked credential %s", id)
+		return iam.NewAnonymousIdentity()
+	}
+
+	c, err := a.store.Find(ctx, id)
+
+	if err != nil {
+		a.log.LogErrorfCtx(ctx, "Unable to load credential %s: %s", id, err.Error())
+		return iam.NewAnonymousIdentity()
+	}
+
+	if c == nil {
+		a.log.LogDebugfCtx(ctx, "No credential with ID %s", id)
+		return iam.NewAnonymousIdentity()
+	}
+
+	if !c.matches(secret, now) {
+
+		if a.recordFailure(id, now) {
+			a.log.LogWarnfCtx(ctx, "Credential %s locked after %d failed attempts", id, a.maxFailures)
+		}
+
+		return iam.NewAnonymousIdentity()
+	}
+
+	a.resetFailures(id)
+
+	return identityFor(c)
+}
+
+func (a *authenticator) locked(id string, now time.Time) bool {
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	f := a.failures[id]
+
+	return f != nil && now.Before(f.lockedUntil)
+}
+
+// recordFailure counts a failed attempt and returns true if the credential has become locked as a result.
+func (a *authenticator) recordFailure(id string, now time.Time) bool {
+
+	if a.maxFailures < 0 {
+		return false
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	f := a.failures[id]
+
+	if f == nil {
+		f = new(failures)
+		a.failures[id] = f
+	}
+
+	f.count++
+
+	if f.count >= a.maxFailures {
+		f.count = 0
+		f.lockedUntil = now.Add(a.lockout)
+
+		return true
+	}
+
+	return false
+}
+
+func (a *authenticator) resetFailures(id string) {
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	delete(a.failures, id)
+}
+
+func identityFor(c *Credential) iam.ClientIdentity {
+
+	user := c.UserID
+
+	if user == "" {
+		user = c.ID
+	}
+
+	ci := iam.NewAuthenticatedIdentity(user)
+
+	for k, v := range c.Attributes {
+		ci[k] = v
+	}
+
+	ci.SetRoles(c.Roles)
+	ci.SetScopes(c.Scopes)
+	ci[IDIdentityKey] = c.ID
+
+	return ci
+}
@@ -0,0 +1,281 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"github.com/graniticio/granitic/v2/rdbms"
+	"github.com/graniticio/granitic/v2/types"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReloadCheckSeconds = 30
+	defaultFindQueryID        = "credentialFind"
+)
+
+// FileStore is a Store that loads credentials from a JSON file (see the package documentation for the format). The file is
+// checked for changes at most every ReloadCheckSeconds and reloaded if it has been modified.
+type FileStore struct {
+	// The path to the JSON file containing credentials.
+	Path string
+
+	// The minimum number of seconds between checks for changes to the file. Defaults to 30. Set to -1 to disable reloading.
+	ReloadCheckSeconds int
+
+	// Injected by Granitic.
+	Log logging.Logger
+
+	credentials map[string]*Credential
+	modified    time.Time
+	checked     time.Time
+	interval    time.Duration
+	now         func() time.Time
+	mux         sync.RWMutex
+	state       ioc.ComponentState
+}
+
+// Find implements Store.Find
+func (fs *FileStore) Find(ctx context.Context, id string) (*Credential, error) {
+
+	if fs.interval >= 0 {
+		fs.reloadIfModified(ctx)
+	}
+
+	fs.mux.RLock()
+	defer fs.mux.RUnlock()
+
+	return fs.credentials[id], nil
+}
+
+func (fs *FileStore) reloadIfModified(ctx context.Context) {
+
+	now := fs.now()
+
+	fs.mux.Lock()
+
+	if now.Sub(fs.checked) < fs.interval {
+		fs.mux.Unlock()
+		return
+	}
+
+	fs.checked = now
+	fs.mux.Unlock()
+
+	fi, err := os.Stat(fs.Path)
+
+	if err != nil {
+		fs.Log.LogErrorfCtx(ctx, "Unable to check credential file %s for changes: %s", fs.Path, err.Error())
+		return
+	}
+
+	fs.mux.RLock()
+	unchanged := fi.ModTime().Equal(fs.modified)
+	fs.mux.RUnlock()
+
+	if unchanged {
+		return
+	}
+
+	if err := fs.load(); err != nil {
+		fs.Log.LogErrorfCtx(ctx, "Unable to reload credential file, continuing to use previously loaded credentials: %s", err.Error())
+	}
+}
+
+func (fs *FileStore) load() error {
+
+	fi, err := os.Stat(fs.Path)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(fs.Path)
+
+	if err != nil {
+		return err
+	}
+
+	var f struct {
+		Credentials []*Credential
+	}
+
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("unable to parse credential file %s: %s", fs.Path, err.Error())
+	}
+
+	creds := make(map[string]*Credential)
+
+	for _, c := range f.Credentials {
+
+		if c == nil || c.ID == "" {
+			return fmt.Errorf("credential file %s contains a credential without an ID", fs.Path)
+		}
+
+		if _, found := creds[c.ID]; found {
+			return fmt.Errorf("credential file %s contains more than one credential with ID %s", fs.Path, c.ID)
+		}
+
+		creds[c.ID] = c
+	}
+
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	fs.credentials = creds
+	fs.modified = fi.ModTime()
+
+	return nil
+}
+
+// StartComponent is called by the IoC container. Loads the credential file.
+func (fs *FileStore) StartComponent() error {
+
+	if fs.state != ioc.StoppedState {
+		return nil
+	}
+
+	fs.state = ioc.StartingState
+
+	if fs.Path == "" {
+		return errors.New("no Path set for credential file")
+	}
+
+	if fs.now == nil {
+		fs.now = time.Now
+	}
+
+	if fs.ReloadCheckSeconds == 0 {
+		fs.ReloadCheckSeconds = defaultReloadCheckSeconds
+	}
+
+	if fs.ReloadCheckSeconds < 0 {
+		fs.interval = -1
+	} else {
+		fs.interval = time.Duration(fs.ReloadCheckSeconds) * time.Second
+	}
+
+	if err := fs.load(); err != nil {
+		return err
+	}
+
+	fs.checked = fs.now()
+	fs.state = ioc.RunningState
+
+	return nil
+}
+
+/*
+RDBMSStore is a Store that loads credentials from a database via the RdbmsAccess and QueryManager facilities.
+
+The query identified by FindQueryID (credentialFind by default) is passed the credential ID as a parameter named ID
+and must return one row per secret with the columns:
+
+	id        The credential ID
+	user_id   The loggable user ID (may be null)
+	hash      The hash of the secret, as created by HashSecret
+	expires   The expiry time of the secret as a Unix timestamp in seconds (null if the secret does not expire)
+	roles     Space separated roles (may be null)
+	scopes    Space separated scopes (may be null)
+
+For example:
+
+	SELECT c.id, c.user_id, s.hash, s.expires, c.roles, c.scopes
+	FROM credential c JOIN credential_secret s ON s.credential_id = c.id
+	WHERE c.id = ${ID}
+
+Roles, scopes and user ID are taken from the first row returned.
+*/
+type RDBMSStore struct {
+	// Injected by Granitic if the RdbmsAccess facility is enabled.
+	DBClientManager rdbms.ClientManager
+
+	// The ID of the query used to find credentials. Defaults to credentialFind.
+	FindQueryID string
+}
+
+type secretRow struct {
+	ID      string               `column:"id"`
+	UserID  *types.NilableString `column:"user_id"`
+	Hash    string               `column:"hash"`
+	Expires *types.NilableInt64  `column:"expires"`
+	Roles   *types.NilableString `column:"roles"`
+	Scopes  *types.NilableString `column:"scopes"`
+}
+
+// Find implements Store.Find
+func (rs *RDBMSStore) Find(ctx context.Context, id string) (*Credential, error) {
+
+	client, err := rs.DBClientManager.ClientFromContext(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := client.SelectBindQIDParam(rs.FindQueryID, "ID", id, new(secretRow))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	c := new(Credential)
+	c.ID = id
+
+	for i, r := range rows {
+
+		sr := r.(*secretRow)
+
+		if i == 0 {
+			c.UserID = nilableString(sr.UserID)
+			c.Roles = strings.Fields(nilableString(sr.Roles))
+			c.Scopes = strings.Fields(nilableString(sr.Scopes))
+		}
+
+		s := &Secret{Hash: sr.Hash}
+
+		if sr.Expires != nil && sr.Expires.IsSet() {
+			s.Expires = time.Unix(sr.Expires.Int64(), 0)
+		}
+
+		c.Secrets = append(c.Secrets, s)
+	}
+
+	return c, nil
+}
+
+// StartComponent is called by the IoC container. Checks that a ClientManager is available.
+func (rs *RDBMSStore) StartComponent() error {
+
+	if rs.DBClientManager == nil {
+		return errors.New("no DBClientManager available. Is the RdbmsAccess facility enabled?")
+	}
+
+	if rs.FindQueryID == "" {
+		rs.FindQueryID = defaultFindQueryID
+	}
+
+	return nil
+}
+
+func nilableString(s *types.NilableString) string {
+
+	if s == nil || !s.IsSet() {
+		return ""
+	}
+
+	return s.String()
+}
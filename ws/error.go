@@ -26,6 +26,10 @@ const (
 
 	// HTTP is an error that forces a specific HTTP status code.
 	HTTP
+
+	// Warning is a non-blocking problem that should be reported to the caller without affecting the HTTP status code
+	// or preventing the request from being processed. See ServiceWarnings.
+	Warning
 )
 
 // CategorisedError is a service error with a concept of the general 'type' of error it is.
@@ -61,6 +65,15 @@ type ServiceErrorFinder interface {
 	Find(code string) *CategorisedError
 }
 
+// LocalisedServiceErrorFinder is implemented by a ServiceErrorFinder that holds messages in more than one language.
+type LocalisedServiceErrorFinder interface {
+	ServiceErrorFinder
+
+	// FindLocalised behaves like Find, but the message on the returned error is taken from the first of the supplied
+	// locales that has a translation for the code. If none of the locales has a translation, the default message is used.
+	FindLocalised(code string, locales []string) *CategorisedError
+}
+
 // ServiceErrorConsumer is implemented by components that require a ServiceErrorFinder to be injected into them
 type ServiceErrorConsumer interface {
 	// ProvideErrorFinder receives a ServiceErrorFinder
@@ -77,6 +90,10 @@ type ServiceErrors struct {
 
 	// A component able to find additional information about error from that error's unique code.
 	ErrorFinder ServiceErrorFinder
+
+	// The caller's preferred locales, most preferred first. Used to choose a message if ErrorFinder
+	// implements LocalisedServiceErrorFinder.
+	Locales []string
 }
 
 // AddNewError creates a new CategorisedError from the supplied information and captures it.
@@ -103,10 +120,21 @@ func (se *ServiceErrors) AddPredefinedError(code string, field ...string) error
 		panic("No source of errors defined")
 	}
 
-	e := se.ErrorFinder.Find(code)
+	se.Errors = append(se.Errors, findPredefined(se.ErrorFinder, se.Locales, code, field...))
 
-	if len(field) > 0 {
-		e.Field = field[0]
+	return nil
+}
+
+// findPredefined looks up the definition of the supplied code (localised if possible) and returns a copy of it, associated
+// with the optional field name.
+func findPredefined(finder ServiceErrorFinder, locales []string, code string, field ...string) CategorisedError {
+
+	var e *CategorisedError
+
+	if lf, found := finder.(LocalisedServiceErrorFinder); found && len(locales) > 0 {
+		e = lf.FindLocalised(code, locales)
+	} else {
+		e = finder.Find(code)
 	}
 
 	if e == nil {
@@ -115,9 +143,13 @@ func (se *ServiceErrors) AddPredefinedError(code string, field ...string) error
 
 	}
 
-	se.Errors = append(se.Errors, *e)
+	ce := *e
 
-	return nil
+	if len(field) > 0 {
+		ce.Field = field[0]
+	}
+
+	return ce
 }
 
 // HasErrors returns true if one or more errors have been encountered and recorded.
@@ -138,6 +170,8 @@ func CodeToCategory(c string) (ServiceErrorCategory, error) {
 		return Logic, nil
 	case "S":
 		return Security, nil
+	case "W":
+		return Warning, nil
 	default:
 		message := fmt.Sprintf("Unknown error category %s", c)
 		return -1, errors.New(message)
@@ -161,6 +195,8 @@ func CategoryToCode(c ServiceErrorCategory) string {
 		return "C"
 	case HTTP:
 		return "H"
+	case Warning:
+		return "W"
 	}
 }
 
@@ -180,5 +216,7 @@ func CategoryToName(c ServiceErrorCategory) string {
 		return "Client"
 	case HTTP:
 		return "HTTP"
+	case Warning:
+		return "Warning"
 	}
 }
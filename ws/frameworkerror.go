@@ -118,16 +118,37 @@ type FrameworkErrorGenerator struct {
 	Messages        map[FrameworkErrorEvent][]string
 	HTTPMessages    map[string]string
 	FrameworkLogger logging.Logger
+
+	// Translations of the templates in Messages, keyed by locale (e.g. fr or fr-ca).
+	LocalisedMessages map[string]map[FrameworkErrorEvent]string
+
+	// Translations of the messages in HTTPMessages, keyed by locale (e.g. fr or fr-ca).
+	LocalisedHTTPMessages map[string]map[string]string
+
+	// Additional locales to try when no translation exists for a caller's preferred locale.
+	LocaleFallbacks LocaleFallbacks
 }
 
 // HTTPError generates a message to be displayed to a caller when a generic HTTP status (404 etc) is encountered. If
 // an error message is not defined for the supplied status, the message "HTTP (code)" is returned, e.g. "HTTP 101"
 func (feg *FrameworkErrorGenerator) HTTPError(status int, a ...interface{}) *CategorisedError {
+	return feg.LocalisedHTTPError(status, nil, a...)
+}
 
-	s := strconv.Itoa(status)
+// LocalisedHTTPError behaves like HTTPError, but uses the message from the first of the supplied locales (or their fallbacks)
+// that has a translation for the status.
+func (feg *FrameworkErrorGenerator) LocalisedHTTPError(status int, locales []string, a ...interface{}) *CategorisedError {
 
+	s := strconv.Itoa(status)
 	m := feg.HTTPMessages[s]
 
+	for _, l := range feg.LocaleFallbacks.Chain(locales) {
+		if lm := feg.LocalisedHTTPMessages[l][s]; lm != "" {
+			m = lm
+			break
+		}
+	}
+
 	if m == "" {
 		m = "HTTP " + s
 	} else {
@@ -135,48 +156,47 @@ func (feg *FrameworkErrorGenerator) HTTPError(status int, a ...interface{}) *Cat
 	}
 
 	ce := new(CategorisedError)
-
 	ce.Category = HTTP
 	ce.Code = s
 	ce.Message = m
 
 	return ce
-
 }
 
 // Error creates a service error given a framework error.
 func (feg *FrameworkErrorGenerator) Error(e FrameworkErrorEvent, c ServiceErrorCategory, a ...interface{}) *CategorisedError {
 
-	l := feg.FrameworkLogger
-	mc := feg.Messages[e]
-
-	if mc == nil || len(mc) < 2 {
-		l.LogWarnf("No framework error message defined for '%s'. Returning a default message.")
-		return NewCategorisedError(c, "UNKNOWN", "No error message defined for this error")
-	}
-
-	cd := mc[0]
-	t := mc[1]
-
-	fm := fmt.Sprintf(t, a...)
-
-	return NewCategorisedError(c, cd, fm)
+	m, cd := feg.LocalisedMessageCode(e, nil, a...)
 
+	return NewCategorisedError(c, cd, m)
 }
 
 // MessageCode returns a message and code for a Framework error event (leaving the caller to create a CategorisedError)
 func (feg *FrameworkErrorGenerator) MessageCode(e FrameworkErrorEvent, a ...interface{}) (message string, code string) {
+	return feg.LocalisedMessageCode(e, nil, a...)
+}
+
+// LocalisedMessageCode behaves like MessageCode, but uses the message template from the first of the supplied locales
+// (or their fallbacks) that has a translation for the event. The code is never translated.
+func (feg *FrameworkErrorGenerator) LocalisedMessageCode(e FrameworkErrorEvent, locales []string, a ...interface{}) (message string, code string) {
 
 	l := feg.FrameworkLogger
+
 	mc := feg.Messages[e]
 
 	if mc == nil || len(mc) < 2 {
-		l.LogWarnf("No framework error message defined for '%s'. Returning a default message.")
+		l.LogWarnf("No framework error message defined for '%s'. Returning a default message.", e)
 		return "No error message defined for this error", "UNKNOWN"
 	}
 
 	t := mc[1]
 
-	return fmt.Sprintf(t, a...), mc[0]
+	for _, lc := range feg.LocaleFallbacks.Chain(locales) {
+		if lt := feg.LocalisedMessages[lc][e]; lt != "" {
+			t = lt
+			break
+		}
+	}
 
+	return fmt.Sprintf(t, a...), mc[0]
 }
@@ -240,6 +240,7 @@ func (wh *WsHandler) ServeHTTP(ctx context.Context, w *httpendpoint.HTTPResponse
 
 	wsReq := new(ws.Request)
 	wsReq.HTTPMethod = req.Method
+	wsReq.Path = req.URL.Path
 	wsReq.ServingHandler = wh.ComponentName()
 
 	wsReq.ID = ws.RecoverIDFunction(ctx)
@@ -258,6 +259,8 @@ func (wh *WsHandler) ServeHTTP(ctx context.Context, w *httpendpoint.HTTPResponse
 		wsReq.UnderlyingHTTP = da
 	}
 
+	wsReq.Locales = wh.preferredLocales(nil, req)
+
 	//Try to identify and/or authenticate the caller
 	var okay bool
 
@@ -289,22 +292,26 @@ func (wh *WsHandler) ServeHTTP(ctx context.Context, w *httpendpoint.HTTPResponse
 	//Validate request
 	var errors ws.ServiceErrors
 	errors.ErrorFinder = wh.ErrorFinder
+	errors.Locales = wsReq.Locales
+
+	warnings := ws.NewServiceWarnings(wh.ErrorFinder)
+	warnings.Locales = wsReq.Locales
 
-	wh.validateRequest(ctx, wsReq, &errors)
+	wh.validateRequest(ctx, wsReq, &errors, warnings)
 
 	if errors.HasErrors() {
-		wh.writeErrorResponse(ctx, &errors, w, wsReq)
+		wh.writeErrorResponse(ctx, &errors, warnings, w, wsReq)
 
 		return ctx
 	}
 
 	//Execute logic
-	wh.process(ctx, wsReq, w)
+	wh.process(ctx, wsReq, w, warnings)
 
 	return ctx
 }
 
-func (wh *WsHandler) validateRequest(ctx context.Context, wsReq *ws.Request, errors *ws.ServiceErrors) {
+func (wh *WsHandler) validateRequest(ctx context.Context, wsReq *ws.Request, errors *ws.ServiceErrors, warnings *ws.ServiceWarnings) {
 	if wh.validationEnabled {
 		proceed := true
 
@@ -331,22 +338,22 @@ func (wh *WsHandler) validateRequest(ctx context.Context, wsReq *ws.Request, err
 
 				wh.Log.LogErrorfCtx(ctx, "Problem encountered during automatic body validation %v", err)
 
-				ce := wh.FrameworkErrors.HTTPError(http.StatusInternalServerError)
+				ce := wh.FrameworkErrors.LocalisedHTTPError(http.StatusInternalServerError, wsReq.Locales)
 				errors.AddError(ce)
 				return
 			}
 
 			if fe != nil && len(fe) > 0 {
 
-				ef := wh.ErrorFinder
-
 				for _, e := range fe {
 
 					for _, code := range e.ErrorCodes {
 
-						ce := ef.Find(code)
-						ce.Field = e.Field
-						errors.AddError(ce)
+						if wh.isWarning(code) {
+							warnings.AddPredefinedWarning(code, e.Field)
+						} else {
+							errors.AddPredefinedError(code, e.Field)
+						}
 
 					}
 
@@ -366,6 +373,14 @@ func (wh *WsHandler) validateRequest(ctx context.Context, wsReq *ws.Request, err
 
 }
 
+// isWarning returns true if the supplied code is defined as being in the ws.Warning category, meaning that a failed validation
+// check using that code should not prevent the request from being processed.
+func (wh *WsHandler) isWarning(code string) bool {
+	d := wh.ErrorFinder.Find(code)
+
+	return d != nil && d.Category == ws.Warning
+}
+
 func (wh *WsHandler) unmarshall(ctx context.Context, req *http.Request, wsReq *ws.Request) {
 
 	var uf func() interface{}
@@ -394,7 +409,7 @@ func (wh *WsHandler) unmarshall(ctx context.Context, req *http.Request, wsReq *w
 
 		wh.Log.LogDebugfCtx(ctx, "Error unmarshalling request body for %s %s %s", req.URL.Path, req.Method, err)
 
-		m, c := wh.FrameworkErrors.MessageCode(ws.UnableToParseRequest)
+		m, c := wh.FrameworkErrors.LocalisedMessageCode(ws.UnableToParseRequest, wsReq.Locales)
 
 		f := ws.NewUnmarshallFrameworkError(m, c)
 		wsReq.AddFrameworkError(f)
@@ -475,6 +490,7 @@ func (wh *WsHandler) identifyAndAuthenticate(ctx context.Context, w *httpendpoin
 
 		i, ctx = wh.UserIdentifier.Identify(ctx, req)
 		wsReq.UserIdentity = i
+		wsReq.Locales = wh.preferredLocales(i, req)
 
 		if wh.RequireAuthentication && !i.Authenticated() {
 
@@ -496,6 +512,23 @@ func (wh *WsHandler) identifyAndAuthenticate(ctx context.Context, w *httpendpoin
 
 }
 
+// preferredLocales builds the list of locales that messages shown to the caller should use. A locale stored on
+// the caller's identity takes precedence over those requested in the Accept-Language header.
+func (wh *WsHandler) preferredLocales(i iam.ClientIdentity, req *http.Request) []string {
+
+	locales := make([]string, 0)
+
+	if i != nil && i.Locale() != "" {
+		locales = append(locales, ws.NormaliseLocale(i.Locale()))
+	}
+
+	if al := req.Header.Get(ws.AcceptLanguageHeader); al != "" {
+		locales = append(locales, ws.ParseAcceptLanguage(al)...)
+	}
+
+	return locales
+}
+
 // SupportedHTTPMethods returns the HTTP method that this handler supports. Returns an array in order to
 // implement Provider, but will always be a single element array.
 func (wh *WsHandler) SupportedHTTPMethods() []string {
@@ -533,16 +566,17 @@ func (wh *WsHandler) handleFrameworkErrors(ctx context.Context, w *httpendpoint.
 
 	var se ws.ServiceErrors
 	se.HTTPStatus = http.StatusBadRequest
+	se.Locales = wsReq.Locales
 
 	for _, fe := range wsReq.FrameworkErrors {
 		se.AddNewError(ws.Client, fe.Code, fe.Message)
 	}
 
-	wh.writeErrorResponse(ctx, &se, w, wsReq)
+	wh.writeErrorResponse(ctx, &se, nil, w, wsReq)
 
 }
 
-func (wh *WsHandler) process(ctx context.Context, request *ws.Request, w *httpendpoint.HTTPResponseWriter) {
+func (wh *WsHandler) process(ctx context.Context, request *ws.Request, w *httpendpoint.HTTPResponseWriter, warnings *ws.ServiceWarnings) {
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -552,6 +586,8 @@ func (wh *WsHandler) process(ctx context.Context, request *ws.Request, w *httpen
 	}()
 
 	wsRes := ws.NewResponse(wh.ErrorFinder)
+	wsRes.Errors.Locales = request.Locales
+	wsRes.Warnings = warnings
 
 	if wh.genericProcessor != nil {
 		//Logic component implements WsRequestProcessor
@@ -596,7 +632,7 @@ func (wh *WsHandler) process(ctx context.Context, request *ws.Request, w *httpen
 
 }
 
-func (wh *WsHandler) writeErrorResponse(ctx context.Context, errors *ws.ServiceErrors, w *httpendpoint.HTTPResponseWriter, wsReq *ws.Request) {
+func (wh *WsHandler) writeErrorResponse(ctx context.Context, errors *ws.ServiceErrors, warnings *ws.ServiceWarnings, w *httpendpoint.HTTPResponseWriter, wsReq *ws.Request) {
 
 	l := wh.Log
 
@@ -608,6 +644,7 @@ func (wh *WsHandler) writeErrorResponse(ctx context.Context, errors *ws.ServiceE
 
 	state := new(ws.ProcessState)
 	state.ServiceErrors = errors
+	state.ServiceWarnings = warnings
 	state.WsRequest = wsReq
 	state.HTTPResponseWriter = w
 
@@ -21,3 +21,12 @@ type AccessChecker interface {
 	// Allowed returns true if the caller is allowed to have this request processed, false otherwise.
 	Allowed(ctx context.Context, r *Request) bool
 }
+
+// AccessPolicyDescriber is implemented by AccessCheckers that are able to describe, in human-readable form, the rules
+// they will apply to requests served by a particular handler. Used by runtime control to support security reviews of a
+// running application.
+type AccessPolicyDescriber interface {
+	// DescribePolicy returns one line of text for each rule that might be applied to requests with the supplied HTTP method
+	// that are served by the named handler component.
+	DescribePolicy(handlerName string, method string) []string
+}
@@ -107,8 +107,9 @@ func (rw *BodyOrErrorWrapper) WrapResponse(body interface{}, errors interface{})
 
 // GraniticJSONResponseWrapper is a component for wrapping response data before it is serialised. The wrapping structure is a map[string]string
 type GraniticJSONResponseWrapper struct {
-	ErrorsFieldName string
-	BodyFieldName   string
+	ErrorsFieldName   string
+	BodyFieldName     string
+	WarningsFieldName string
 }
 
 // WrapResponse creates a map[string]string to wrap the supplied response body and errors.
@@ -126,6 +127,18 @@ func (rw *GraniticJSONResponseWrapper) WrapResponse(body interface{}, errors int
 	return f
 }
 
+// WrapResponseWithWarnings behaves like WrapResponse, but also includes the supplied warnings (if not nil) in a
+// field named according to WarningsFieldName.
+func (rw *GraniticJSONResponseWrapper) WrapResponseWithWarnings(body interface{}, errors interface{}, warnings interface{}) interface{} {
+	f := rw.WrapResponse(body, errors).(map[string]interface{})
+
+	if warnings != nil && rw.WarningsFieldName != "" {
+		f[rw.WarningsFieldName] = warnings
+	}
+
+	return f
+}
+
 // GraniticJSONErrorFormatter converts service errors into a data structure for consistent serialisation to JSON.
 type GraniticJSONErrorFormatter struct{}
 
@@ -136,12 +149,28 @@ func (ef *GraniticJSONErrorFormatter) FormatErrors(errors *ws.ServiceErrors) int
 		return nil
 	}
 
+	return ef.format(errors.Errors)
+}
+
+// FormatWarnings converts all of the warnings present in the supplied object into a structure suitable for serialisation. The
+// structure is the same as that used for errors.
+func (ef *GraniticJSONErrorFormatter) FormatWarnings(warnings *ws.ServiceWarnings) interface{} {
+
+	if !warnings.HasWarnings() {
+		return nil
+	}
+
+	return ef.format(warnings.Warnings)
+}
+
+func (ef *GraniticJSONErrorFormatter) format(errors []ws.CategorisedError) interface{} {
+
 	f := make(map[string]interface{})
 
 	generalErrors := make([]errorWrapper, 0)
 	fieldErrors := make(map[string][]errorWrapper, 0)
 
-	for _, error := range errors.Errors {
+	for _, error := range errors {
 
 		c := ws.CategoryToCode(error.Category)
 		displayCode := c + "-" + error.Code
@@ -26,6 +26,35 @@ func TestErrorMarshalling(t *testing.T) {
 
 }
 
+func TestWarningWrapping(t *testing.T) {
+
+	w := ws.NewServiceWarnings(nil)
+	w.AddNewWarning("LEGACY", "Field is deprecated", "Legacy")
+
+	gef := new(GraniticJSONErrorFormatter)
+
+	fw := gef.FormatWarnings(w).(map[string]interface{})
+
+	if fw["ByField"] == nil {
+		t.Fatalf("Expected warning to be associated with a field")
+	}
+
+	if gef.FormatWarnings(ws.NewServiceWarnings(nil)) != nil {
+		t.Fatalf("Expected nil when no warnings present")
+	}
+
+	rw := new(GraniticJSONResponseWrapper)
+	rw.BodyFieldName = "Response"
+	rw.ErrorsFieldName = "Errors"
+	rw.WarningsFieldName = "Warnings"
+
+	wrapped := rw.WrapResponseWithWarnings("body", nil, fw).(map[string]interface{})
+
+	if wrapped["Warnings"] == nil || wrapped["Response"] == nil || wrapped["Errors"] != nil {
+		t.Fatalf("Unexpected wrapped structure %v", wrapped)
+	}
+}
+
 func TestUnmarshalling(t *testing.T) {
 
 	r := new(http.Request)
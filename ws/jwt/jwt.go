@@ -0,0 +1,389 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+/*
+Package jwt provides a configurable implementation of ws.Identifier that identifies callers using JSON Web Tokens (JWTs)
+supplied in the Authorization header of an HTTP request.
+
+Declaring an identifier
+
+An Identifier is declared in your component definition file and referenced from the UserIdentifier field of
+your handlers:
+
+	{
+	  "jwtIdentifier": {
+		"type": "jwt.Identifier",
+		"Algorithms": ["RS256"],
+		"JWKSURL": "https://auth.example.com/.well-known/jwks.json",
+		"Issuer": "https://auth.example.com/",
+		"Audience": ["inventory-api"],
+		"ClaimMappings": {"email": "Email"}
+	  },
+
+	  "artistHandler": {
+		"type": "handler.WsHandler",
+		"UserIdentifier": "ref:jwtIdentifier",
+		"RequireAuthentication": true
+	  }
+	}
+
+Tokens must be supplied in the form
+
+	Authorization: Bearer <token>
+
+Signatures
+
+Tokens signed with HS256, RS256 and ES256 are supported. HS256 tokens are verified against the shared secret in HMACSecret.
+RS256 and ES256 tokens are verified against PEM encoded public keys in PublicKeys (keyed by the key ID (kid) used in
+the token's header) and/or the keys in a JWKS document loaded from JWKSFile or JWKSURL. JWKS documents are cached and reloaded
+every JWKSRefreshInterval (one hour by default) or when a token refers to a key ID that has not been seen before.
+
+Tokens using the 'none' algorithm or an algorithm not listed in Algorithms are always rejected.
+
+Claims
+
+The exp, nbf and iss claims and the aud claim (if Audience is set) are checked, allowing for ClockSkewSeconds of difference
+between the clocks of the token issuer and this application. The claim named by UserIDClaim (sub by default) is used as
+the identity's loggable user ID. Other claims can be copied into the resulting iam.ClientIdentity using ClaimMappings
+and all verified claims are available via the Claims function.
+
+Failures
+
+If a token is missing or fails verification, an unauthenticated, anonymous iam.ClientIdentity is returned. Handlers with
+RequireAuthentication set to true will then respond with the standard HTTP 401 framework error.
+*/
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/iam"
+	"github.com/graniticio/granitic/v2/ioc"
+	"github.com/graniticio/granitic/v2/logging"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ClaimsIdentityKey is the key in an iam.ClientIdentity under which the verified claims of a token are stored.
+const ClaimsIdentityKey = "JWTClaims"
+
+const bearerPrefix = "bearer "
+const authorizationHeader = "Authorization"
+const defaultUserIDClaim = "sub"
+const defaultRefreshInterval = time.Hour
+
+// Identifier is a ws.Identifier that verifies JWT bearer tokens and converts their claims into an iam.ClientIdentity.
+type Identifier struct {
+	// The signing algorithms (HS256, RS256, ES256) that tokens may use. If empty, all supported algorithms are allowed.
+	Algorithms []string
+
+	// The shared secret used to verify HS256 signatures.
+	HMACSecret string
+
+	// PEM encoded RSA or ECDSA public keys, keyed by the key ID (kid) that will appear in the headers of tokens signed with the
+	// corresponding private key.
+	PublicKeys map[string]string
+
+	// The path to a file containing a JWKS document.
+	JWKSFile string
+
+	// The URL of a JWKS document.
+	JWKSURL string
+
+	// How often the JWKS document should be reloaded, in Go's duration format (e.g. 30m). Defaults to 1h.
+	JWKSRefreshInterval string
+
+	// If set, the iss claim of tokens must match this value.
+	Issuer string
+
+	// If set, the aud claim of tokens must contain at least one of these values.
+	Audience []string
+
+	// The number of seconds of difference allowed between the clock of the token issuer and this application when checking
+	// the exp and nbf claims.
+	ClockSkewSeconds int
+
+	// If true, tokens without an exp claim are rejected.
+	RequireExpiry bool
+
+	// The claim to use as the identity's loggable user ID. Defaults to sub.
+	UserIDClaim string
+
+	// A map of claim names to the keys under which their values should be stored in the resulting iam.ClientIdentity.
+	ClaimMappings map[string]string
+
+	// Injected by Granitic.
+	Log logging.Logger
+
+	allowed  map[string]bool
+	keys     *keySource
+	hmacKey  []byte
+	skew     time.Duration
+	now      func() time.Time
+	state    ioc.ComponentState
+	userFrom string
+}
+
+// Identify implements ws.Identifier.Identify. Callers without a valid bearer token are returned an anonymous identity.
+func (id *Identifier) Identify(ctx context.Context, req *http.Request) (iam.ClientIdentity, context.Context) {
+
+	token, found := bearerToken(req)
+
+	if !found {
+		return iam.NewAnonymousIdentity(), ctx
+	}
+
+	claims, err := id.Verify(token)
+
+	if err != nil {
+		id.Log.LogDebugfCtx(ctx, "Rejected bearer token: %s", err.Error())
+		return iam.NewAnonymousIdentity(), ctx
+	}
+
+	return id.identityFromClaims(claims), ctx
+}
+
+// Verify checks the signature and standard claims of the supplied token and returns the token's claims if it is valid.
+func (id *Identifier) Verify(token string) (map[string]interface{}, error) {
+
+	t, err := parseToken(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !id.allowed[t.header.Alg] {
+		return nil, fmt.Errorf("algorithm %s is not allowed", t.header.Alg)
+	}
+
+	if err := id.verifySignature(t); err != nil {
+		return nil, err
+	}
+
+	if err := id.checkClaims(t.claims); err != nil {
+		return nil, err
+	}
+
+	return t.claims, nil
+}
+
+func (id *Identifier) verifySignature(t *token) error {
+
+	if t.header.Alg == hs256 {
+		if len(id.hmacKey) == 0 {
+			return errors.New("no HMACSecret configured for HS256 tokens")
+		}
+
+		return verifyHMAC(t, id.hmacKey)
+	}
+
+	candidates := id.keys.find(t.header.Kid, t.header.Alg)
+
+	if len(candidates) == 0 {
+		return fmt.Errorf("no %s key available with ID '%s'", t.header.Alg, t.header.Kid)
+	}
+
+	for _, k := range candidates {
+		if verifyWithKey(t, k) == nil {
+			return nil
+		}
+	}
+
+	return errors.New("signature could not be verified")
+}
+
+func (id *Identifier) checkClaims(c map[string]interface{}) error {
+
+	now := id.now()
+
+	if exp, found, err := numericClaim(c, "exp"); err != nil {
+		return err
+	} else if found && !now.Before(exp.Add(id.skew)) {
+		return errors.New("token has expired")
+	} else if !found && id.RequireExpiry {
+		return errors.New("token has no exp claim")
+	}
+
+	if nbf, found, err := numericClaim(c, "nbf"); err != nil {
+		return err
+	} else if found && now.Add(id.skew).Before(nbf) {
+		return errors.New("token is not yet valid")
+	}
+
+	if id.Issuer != "" {
+		if iss, _ := c["iss"].(string); iss != id.Issuer {
+			return fmt.Errorf("unexpected issuer '%s'", iss)
+		}
+	}
+
+	if len(id.Audience) > 0 && !audienceMatches(c["aud"], id.Audience) {
+		return errors.New("token was not issued for this audience")
+	}
+
+	return nil
+}
+
+func (id *Identifier) identityFromClaims(c map[string]interface{}) iam.ClientIdentity {
+
+	user := fmt.Sprintf("%v", c[id.userFrom])
+
+	if c[id.userFrom] == nil {
+		user = "-"
+	}
+
+	ci := iam.NewAuthenticatedIdentity(user)
+
+	for claim, key := range id.ClaimMappings {
+		if v, found := c[claim]; found {
+			ci[key] = v
+		}
+	}
+
+	ci[ClaimsIdentityKey] = c
+
+	return ci
+}
+
+// Claims returns the verified claims stored in the supplied identity by an Identifier, or nil if the identity was not created by
+// an Identifier.
+func Claims(ci iam.ClientIdentity) map[string]interface{} {
+	c, _ := ci[ClaimsIdentityKey].(map[string]interface{})
+
+	return c
+}
+
+// StartComponent is called by the IoC container. Parses the configured keys and loads any JWKS document.
+func (id *Identifier) StartComponent() error {
+
+	if id.state != ioc.StoppedState {
+		return nil
+	}
+
+	id.state = ioc.StartingState
+
+	if id.now == nil {
+		id.now = time.Now
+	}
+
+	id.skew = time.Duration(id.ClockSkewSeconds) * time.Second
+	id.hmacKey = []byte(id.HMACSecret)
+
+	if id.userFrom = id.UserIDClaim; id.userFrom == "" {
+		id.userFrom = defaultUserIDClaim
+	}
+
+	if err := id.buildAllowedAlgorithms(); err != nil {
+		return err
+	}
+
+	refresh := defaultRefreshInterval
+
+	if id.JWKSRefreshInterval != "" {
+		d, err := time.ParseDuration(id.JWKSRefreshInterval)
+
+		if err != nil {
+			return fmt.Errorf("%s cannot be parsed as a JWKSRefreshInterval: %s", id.JWKSRefreshInterval, err.Error())
+		}
+
+		refresh = d
+	}
+
+	ks, err := newKeySource(id.PublicKeys, id.JWKSFile, id.JWKSURL, refresh, id.now)
+
+	if err != nil {
+		return err
+	}
+
+	ks.log = id.Log
+	id.keys = ks
+
+	if len(id.hmacKey) == 0 && ks.empty() {
+		return errors.New("no keys configured. Set at least one of HMACSecret, PublicKeys, JWKSFile or JWKSURL")
+	}
+
+	id.state = ioc.RunningState
+
+	return nil
+}
+
+func (id *Identifier) buildAllowedAlgorithms() error {
+
+	id.allowed = make(map[string]bool)
+
+	if len(id.Algorithms) == 0 {
+		id.Algorithms = []string{hs256, rs256, es256}
+	}
+
+	for _, a := range id.Algorithms {
+
+		a = strings.ToUpper(a)
+
+		switch a {
+		case hs256, rs256, es256:
+			id.allowed[a] = true
+		default:
+			return fmt.Errorf("unsupported JWT algorithm %s", a)
+		}
+	}
+
+	return nil
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+
+	h := req.Header.Get(authorizationHeader)
+
+	if len(h) <= len(bearerPrefix) || strings.ToLower(h[:len(bearerPrefix)]) != bearerPrefix {
+		return "", false
+	}
+
+	return strings.TrimSpace(h[len(bearerPrefix):]), true
+}
+
+func audienceMatches(aud interface{}, expected []string) bool {
+
+	var supplied []string
+
+	switch a := aud.(type) {
+	case string:
+		supplied = []string{a}
+	case []interface{}:
+		for _, v := range a {
+			if s, found := v.(string); found {
+				supplied = append(supplied, s)
+			}
+		}
+	}
+
+	for _, s := range supplied {
+		for _, e := range expected {
+			if s == e {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func numericClaim(c map[string]interface{}, name string) (time.Time, bool, error) {
+
+	v, found := c[name]
+
+	if !found {
+		return time.Time{}, false, nil
+	}
+
+	f, okay := v.(float64)
+
+	if !okay {
+		return time.Time{}, true, fmt.Errorf("claim %s is not a number", name)
+	}
+
+	sec := int64(f)
+	nsec := int64((f - float64(sec)) * float64(time.Second))
+
+	return time.Unix(sec, nsec), true, nil
+}
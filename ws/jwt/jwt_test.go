@@ -0,0 +1,331 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/graniticio/granitic/v2/logging"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var fixedNow = time.Unix(1600000000, 0)
+
+func encodeSegment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func sign(t *testing.T, alg, kid string, claims map[string]interface{}, key interface{}) string {
+
+	h := map[string]string{"alg": alg, "typ": "JWT"}
+
+	if kid != "" {
+		h["kid"] = kid
+	}
+
+	signed := encodeSegment(h) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newIdentifier() *Identifier {
+	id := new(Identifier)
+	id.Log = logging.NewStdoutLogger(logging.Fatal)
+	id.now = func() time.Time { return fixedNow }
+
+	return id
+}
+
+func claims(extra map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub": "user-1",
+		"exp": fixedNow.Add(time.Minute).Unix(),
+		"iss": "issuer",
+		"aud": "api",
+	}
+
+	for k, v := range extra {
+		c[k] = v
+	}
+
+	return c
+}
+
+func identify(id *Identifier, token string) (bool, string) {
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	ci, _ := id.Identify(context.Background(), req)
+
+	return ci.Authenticated(), ci.LoggableUserID()
+}
+
+func TestHMACTokens(t *testing.T) {
+
+	secret := []byte("secret")
+
+	id := newIdentifier()
+	id.HMACSecret = string(secret)
+	id.Issuer = "issuer"
+	id.Audience = []string{"other", "api"}
+	id.ClaimMappings = map[string]string{"email": "Email"}
+
+	if err := id.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	token := sign(t, hs256, "", claims(map[string]interface{}{"email": "a@example.com"}), secret)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	ci, _ := id.Identify(context.Background(), req)
+
+	if !ci.Authenticated() || ci.LoggableUserID() != "user-1" || ci["Email"] != "a@example.com" {
+		t.Errorf("Unexpected identity %v", ci)
+	}
+
+	if Claims(ci)["iss"] != "issuer" {
+		t.Errorf("Expected claims to be available from identity")
+	}
+
+	if auth, _ := identify(id, sign(t, hs256, "", claims(nil), []byte("wrong"))); auth {
+		t.Errorf("Expected token with bad signature to be rejected")
+	}
+
+	if auth, _ := identify(id, sign(t, hs256, "", claims(map[string]interface{}{"iss": "other"}), secret)); auth {
+		t.Errorf("Expected token with wrong issuer to be rejected")
+	}
+
+	if auth, _ := identify(id, sign(t, hs256, "", claims(map[string]interface{}{"aud": []string{"x", "y"}}), secret)); auth {
+		t.Errorf("Expected token with wrong audience to be rejected")
+	}
+
+	if auth, _ := identify(id, sign(t, "none", "", claims(nil), secret)); auth {
+		t.Errorf("Expected token with none algorithm to be rejected")
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+
+	if ci, _ := id.Identify(context.Background(), req); ci.Authenticated() {
+		t.Errorf("Expected request without token to be anonymous")
+	}
+}
+
+func TestExpiryAndSkew(t *testing.T) {
+
+	secret := []byte("secret")
+
+	id := newIdentifier()
+	id.HMACSecret = string(secret)
+	id.ClockSkewSeconds = 30
+	id.RequireExpiry = true
+
+	if err := id.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := claims(map[string]interface{}{"exp": fixedNow.Add(-10 * time.Second).Unix()})
+
+	if auth, _ := identify(id, sign(t, hs256, "", expired, secret)); !auth {
+		t.Errorf("Expected recently expired token to be allowed within clock skew")
+	}
+
+	expired["exp"] = fixedNow.Add(-time.Minute).Unix()
+
+	if auth, _ := identify(id, sign(t, hs256, "", expired, secret)); auth {
+		t.Errorf("Expected expired token to be rejected")
+	}
+
+	early := claims(map[string]interface{}{"nbf": fixedNow.Add(time.Minute).Unix()})
+
+	if auth, _ := identify(id, sign(t, hs256, "", early, secret)); auth {
+		t.Errorf("Expected token that is not yet valid to be rejected")
+	}
+
+	noExpiry := claims(nil)
+	delete(noExpiry, "exp")
+
+	if auth, _ := identify(id, sign(t, hs256, "", noExpiry, secret)); auth {
+		t.Errorf("Expected token without exp to be rejected")
+	}
+}
+
+func TestStaticPublicKeys(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	id := newIdentifier()
+	id.Algorithms = []string{"RS256", "ES256"}
+	id.PublicKeys = map[string]string{
+		"rsa": encodePEM(t, &rsaKey.PublicKey),
+		"ec":  encodePEM(t, &ecKey.PublicKey),
+	}
+
+	if err := id.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth, user := identify(id, sign(t, rs256, "rsa", claims(nil), rsaKey)); !auth || user != "user-1" {
+		t.Errorf("Expected RS256 token to be accepted")
+	}
+
+	if auth, _ := identify(id, sign(t, es256, "ec", claims(nil), ecKey)); !auth {
+		t.Errorf("Expected ES256 token to be accepted")
+	}
+
+	if auth, _ := identify(id, sign(t, es256, "", claims(nil), ecKey)); !auth {
+		t.Errorf("Expected ES256 token without a key ID to be accepted")
+	}
+
+	if auth, _ := identify(id, sign(t, rs256, "ec", claims(nil), rsaKey)); auth {
+		t.Errorf("Expected token signed with the wrong key to be rejected")
+	}
+
+	if auth, _ := identify(id, sign(t, hs256, "", claims(nil), []byte("secret"))); auth {
+		t.Errorf("Expected token with disallowed algorithm to be rejected")
+	}
+}
+
+func TestJWKSFile(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	dir, err := ioutil.TempDir("", "jwks")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "jwks.json")
+
+	writeJWKS(t, path, map[string]interface{}{
+		"kty": "RSA",
+		"kid": "r1",
+		"n":   encodeInt(rsaKey.N),
+		"e":   encodeInt(big.NewInt(int64(rsaKey.E))),
+	})
+
+	id := newIdentifier()
+	id.JWKSFile = path
+
+	if err := id.StartComponent(); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth, _ := identify(id, sign(t, rs256, "r1", claims(nil), rsaKey)); !auth {
+		t.Errorf("Expected token signed with JWKS key to be accepted")
+	}
+
+	writeJWKS(t, path, map[string]interface{}{
+		"kty": "EC",
+		"kid": "e1",
+		"crv": "P-256",
+		"x":   encodeInt(ecKey.X),
+		"y":   encodeInt(ecKey.Y),
+	})
+
+	token := sign(t, es256, "e1", claims(nil), ecKey)
+
+	if auth, _ := identify(id, token); auth {
+		t.Errorf("Did not expect unknown key to be loaded before the minimum reload interval")
+	}
+
+	fixedNow = fixedNow.Add(2 * minimumUnknownKeyReload)
+	defer func() { fixedNow = fixedNow.Add(-2 * minimumUnknownKeyReload) }()
+
+	token = sign(t, es256, "e1", claims(nil), ecKey)
+
+	if auth, _ := identify(id, token); !auth {
+		t.Errorf("Expected unknown key ID to trigger a reload of the JWKS document")
+	}
+}
+
+func TestNoKeysConfigured(t *testing.T) {
+
+	id := newIdentifier()
+
+	if err := id.StartComponent(); err == nil {
+		t.Errorf("Expected an error when no keys are configured")
+	}
+
+	id = newIdentifier()
+	id.HMACSecret = "secret"
+	id.Algorithms = []string{"PS512"}
+
+	if err := id.StartComponent(); err == nil {
+		t.Errorf("Expected an error for an unsupported algorithm")
+	}
+}
+
+func encodePEM(t *testing.T, key interface{}) string {
+
+	b, err := x509.MarshalPKIXPublicKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func writeJWKS(t *testing.T, path string, key map[string]interface{}) {
+
+	b, _ := json.Marshal(map[string]interface{}{"keys": []interface{}{key}})
+
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatalf("Unable to write JWKS file: %s", err.Error())
+	}
+}
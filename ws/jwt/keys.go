@@ -0,0 +1,312 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/graniticio/granitic/v2/logging"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minimumUnknownKeyReload stops tokens with unknown key IDs from causing the JWKS document to be reloaded on every request
+const minimumUnknownKeyReload = time.Minute
+
+const jwksFetchTimeout = 10 * time.Second
+
+// keySource holds the public keys available to verify tokens. Static keys never change, but keys loaded from a JWKS
+// document are periodically refreshed.
+type keySource struct {
+	static   map[string][]crypto.PublicKey
+	jwks     map[string][]crypto.PublicKey
+	file     string
+	url      string
+	refresh  time.Duration
+	loaded   time.Time
+	now      func() time.Time
+	log      logging.Logger
+	client   *http.Client
+	mux      sync.RWMutex
+	fetching sync.Mutex
+}
+
+func newKeySource(pems map[string]string, file, url string, refresh time.Duration, now func() time.Time) (*keySource, error) {
+
+	ks := new(keySource)
+	ks.static = make(map[string][]crypto.PublicKey)
+	ks.jwks = make(map[string][]crypto.PublicKey)
+	ks.file = file
+	ks.url = url
+	ks.refresh = refresh
+	ks.now = now
+	ks.client = &http.Client{Timeout: jwksFetchTimeout}
+
+	for kid, p := range pems {
+
+		k, err := parsePEM(p)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse public key %s: %s", kid, err.Error())
+		}
+
+		ks.static[kid] = append(ks.static[kid], k)
+	}
+
+	if ks.usesJWKS() {
+		if err := ks.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, nil
+}
+
+func (ks *keySource) usesJWKS() bool {
+	return ks.file != "" || ks.url != ""
+}
+
+func (ks *keySource) empty() bool {
+	ks.mux.RLock()
+	defer ks.mux.RUnlock()
+
+	return len(ks.static) == 0 && len(ks.jwks) == 0
+}
+
+// find returns all keys that could be used to verify a token signed with the supplied algorithm and key ID. If no key ID
+// is supplied, every key compatible with the algorithm is returned.
+func (ks *keySource) find(kid, alg string) []crypto.PublicKey {
+
+	if ks.usesJWKS() {
+		ks.refreshIfNeeded(kid)
+	}
+
+	ks.mux.RLock()
+	defer ks.mux.RUnlock()
+
+	matches := make([]crypto.PublicKey, 0)
+
+	for _, set := range []map[string][]crypto.PublicKey{ks.static, ks.jwks} {
+		for id, keys := range set {
+
+			if kid != "" && id != kid {
+				continue
+			}
+
+			for _, k := range keys {
+				if keyMatchesAlgorithm(k, alg) {
+					matches = append(matches, k)
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+func (ks *keySource) refreshIfNeeded(kid string) {
+
+	ks.mux.RLock()
+	age := ks.now().Sub(ks.loaded)
+	_, known := ks.jwks[kid]
+	_, static := ks.static[kid]
+	ks.mux.RUnlock()
+
+	unknown := kid != "" && !known && !static
+
+	if age < ks.refresh && !(unknown && age >= minimumUnknownKeyReload) {
+		return
+	}
+
+	if err := ks.reload(); err != nil && ks.log != nil {
+		ks.log.LogErrorf("Unable to reload JWKS document, continuing to use previously loaded keys: %s", err.Error())
+	}
+}
+
+// reload fetches and parses the JWKS document, replacing the previously loaded keys if successful.
+func (ks *keySource) reload() error {
+
+	ks.fetching.Lock()
+	defer ks.fetching.Unlock()
+
+	var data []byte
+	var err error
+
+	if ks.url != "" {
+		data, err = ks.fetch()
+	} else {
+		data, err = ioutil.ReadFile(ks.file)
+	}
+
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+
+	// Record the attempt even if it failed so a broken JWKS source isn't retried on every request
+	ks.loaded = ks.now()
+
+	if err != nil {
+		return fmt.Errorf("unable to load JWKS document: %s", err.Error())
+	}
+
+	keys, err := parseJWKS(data)
+
+	if err != nil {
+		return err
+	}
+
+	ks.jwks = keys
+
+	return nil
+}
+
+func (ks *keySource) fetch() ([]byte, error) {
+
+	resp, err := ks.client.Get(ks.url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, ks.url)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(data []byte) (map[string][]crypto.PublicKey, error) {
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse JWKS document: %s", err.Error())
+	}
+
+	keys := make(map[string][]crypto.PublicKey)
+
+	for _, j := range doc.Keys {
+
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+
+		k, err := j.publicKey()
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse JWKS key %s: %s", j.Kid, err.Error())
+		}
+
+		if k != nil {
+			keys[j.Kid] = append(keys[j.Kid], k)
+		}
+	}
+
+	return keys, nil
+}
+
+// publicKey converts the JWK to a public key. Returns nil if the key is of a type that is not supported.
+func (j *jwk) publicKey() (crypto.PublicKey, error) {
+
+	switch j.Kty {
+	case "RSA":
+
+		n, err := decodeBigInt(j.N)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(j.E)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+
+		if j.Crv != "P-256" {
+			return nil, nil
+		}
+
+		x, err := decodeBigInt(j.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(j.Y)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func parsePEM(p string) (crypto.PublicKey, error) {
+
+	block, _ := pem.Decode([]byte(p))
+
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if k, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+
+	c, err := x509.ParseCertificate(block.Bytes)
+
+	if err != nil {
+		return nil, errors.New("PEM data is not a public key or certificate")
+	}
+
+	return c.PublicKey, nil
+}
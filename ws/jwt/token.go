@@ -0,0 +1,131 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const hs256 = "HS256"
+const rs256 = "RS256"
+const es256 = "ES256"
+
+// es256SignatureLength is the length of the concatenated R and S values in an ES256 signature
+const es256SignatureLength = 64
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+type token struct {
+	header    header
+	claims    map[string]interface{}
+	signed    string
+	signature []byte
+}
+
+func parseToken(raw string) (*token, error) {
+
+	parts := strings.Split(raw, ".")
+
+	if len(parts) != 3 {
+		return nil, errors.New("token does not have three parts")
+	}
+
+	t := new(token)
+	t.signed = parts[0] + "." + parts[1]
+
+	if err := decodeSegment(parts[0], &t.header); err != nil {
+		return nil, fmt.Errorf("unable to parse token header: %s", err.Error())
+	}
+
+	if err := decodeSegment(parts[1], &t.claims); err != nil {
+		return nil, fmt.Errorf("unable to parse token claims: %s", err.Error())
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode token signature: %s", err.Error())
+	}
+
+	t.signature = sig
+
+	return t, nil
+}
+
+func decodeSegment(seg string, target interface{}) error {
+
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
+
+func verifyHMAC(t *token, secret []byte) error {
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(t.signed))
+
+	if !hmac.Equal(mac.Sum(nil), t.signature) {
+		return errors.New("signature could not be verified")
+	}
+
+	return nil
+}
+
+func verifyWithKey(t *token, key crypto.PublicKey) error {
+
+	digest := sha256.Sum256([]byte(t.signed))
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], t.signature)
+
+	case *ecdsa.PublicKey:
+
+		if len(t.signature) != es256SignatureLength {
+			return errors.New("ES256 signature has the wrong length")
+		}
+
+		r := new(big.Int).SetBytes(t.signature[:32])
+		s := new(big.Int).SetBytes(t.signature[32:])
+
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return errors.New("signature could not be verified")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported key type %T", key)
+}
+
+// keyMatchesAlgorithm returns true if the supplied key can be used to verify a token with the supplied algorithm
+func keyMatchesAlgorithm(key crypto.PublicKey, alg string) bool {
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return alg == rs256
+	case *ecdsa.PublicKey:
+		return alg == es256 && k.Curve.Params().Name == "P-256"
+	}
+
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package ws
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptLanguageHeader is the name of the HTTP header used by callers to express their preferred languages.
+const AcceptLanguageHeader = "Accept-Language"
+
+// ParseAcceptLanguage converts the value of an HTTP Accept-Language header into a list of locales (e.g. en-GB, fr)
+// ordered from the most to the least preferred. Wildcards and entries with a quality of zero are discarded and
+// all locales are normalised to lower case.
+func ParseAcceptLanguage(header string) []string {
+
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+
+	candidates := make([]weighted, 0)
+
+	for _, entry := range strings.Split(header, ",") {
+
+		parts := strings.Split(entry, ";")
+		l := NormaliseLocale(parts[0])
+
+		if l == "" || l == "*" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+
+			if strings.HasPrefix(p, "q=") {
+				if pq, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = pq
+				}
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		candidates = append(candidates, weighted{l, q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	locales := make([]string, len(candidates))
+
+	for i, c := range candidates {
+		locales[i] = c.locale
+	}
+
+	return locales
+}
+
+// NormaliseLocale trims whitespace from the supplied locale, converts it to lower case and replaces underscores
+// with hyphens so that en_GB, EN-gb and en-GB are all treated as en-gb.
+func NormaliseLocale(locale string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(locale)), "_", "-", -1)
+}
+
+// LocaleFallbacks maps a locale to an ordered list of other locales that should be tried if no message is available
+// in that locale. For example:
+//
+//	{"fr-ca": ["fr-fr", "en"]}
+type LocaleFallbacks map[string][]string
+
+// Chain expands the supplied list of preferred locales into the full list of locales that should be tried, in order,
+// when looking up a message. Each preferred locale is followed by its configured fallbacks and then by its base
+// language (e.g. fr for fr-ca). Duplicates are removed.
+func (lf LocaleFallbacks) Chain(preferred []string) []string {
+
+	chain := make([]string, 0)
+	seen := make(map[string]bool)
+
+	add := func(l string) {
+		l = NormaliseLocale(l)
+
+		if l != "" && !seen[l] {
+			seen[l] = true
+			chain = append(chain, l)
+		}
+	}
+
+	for _, p := range preferred {
+
+		p = NormaliseLocale(p)
+		add(p)
+
+		for k, fallbacks := range lf {
+			if NormaliseLocale(k) == p {
+				for _, f := range fallbacks {
+					add(f)
+				}
+			}
+		}
+
+		if i := strings.Index(p, "-"); i > 0 {
+			add(p[:i])
+		}
+	}
+
+	return chain
+}
@@ -0,0 +1,67 @@
+package ws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+
+	l := ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5")
+
+	if !reflect.DeepEqual(l, []string{"fr-ch", "fr", "en", "de"}) {
+		t.Errorf("Unexpected order %v", l)
+	}
+
+	l = ParseAcceptLanguage("en;q=0.1, es_MX, it;q=0")
+
+	if !reflect.DeepEqual(l, []string{"es-mx", "en"}) {
+		t.Errorf("Unexpected order %v", l)
+	}
+
+	if len(ParseAcceptLanguage("")) != 0 {
+		t.Errorf("Expected no locales")
+	}
+}
+
+func TestLocaleFallbackChain(t *testing.T) {
+
+	lf := LocaleFallbacks{"fr-CA": {"fr-FR", "en"}}
+
+	c := lf.Chain([]string{"fr-ca", "de-AT", "fr"})
+
+	if !reflect.DeepEqual(c, []string{"fr-ca", "fr-fr", "en", "fr", "de-at", "de"}) {
+		t.Errorf("Unexpected chain %v", c)
+	}
+
+	var empty LocaleFallbacks
+
+	if len(empty.Chain(nil)) != 0 {
+		t.Errorf("Expected empty chain")
+	}
+}
+
+func TestLocalisedFrameworkMessages(t *testing.T) {
+
+	feg := new(FrameworkErrorGenerator)
+	feg.Messages = map[FrameworkErrorEvent][]string{QueryWrongType: {"QUERYBIND", "Bad value %s"}}
+	feg.HTTPMessages = map[string]string{"404": "No such resource."}
+	feg.LocalisedMessages = map[string]map[FrameworkErrorEvent]string{"fr": {QueryWrongType: "Valeur incorrecte %s"}}
+	feg.LocalisedHTTPMessages = map[string]map[string]string{"fr": {"404": "Ressource introuvable."}}
+
+	if m, c := feg.LocalisedMessageCode(QueryWrongType, []string{"fr-be"}, "x"); m != "Valeur incorrecte x" || c != "QUERYBIND" {
+		t.Errorf("Unexpected message %s %s", m, c)
+	}
+
+	if m, _ := feg.MessageCode(QueryWrongType, "x"); m != "Bad value x" {
+		t.Errorf("Unexpected message %s", m)
+	}
+
+	if e := feg.LocalisedHTTPError(404, []string{"de", "fr"}); e.Message != "Ressource introuvable." {
+		t.Errorf("Unexpected message %s", e.Message)
+	}
+
+	if e := feg.LocalisedHTTPError(404, []string{"de"}); e.Message != "No such resource." {
+		t.Errorf("Unexpected message %s", e.Message)
+	}
+}
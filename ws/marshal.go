@@ -79,9 +79,9 @@ func (rw *MarshallingResponseWriter) Write(ctx context.Context, state *ProcessSt
 	case Normal:
 		return rw.write(ctx, state.WsResponse, state.HTTPResponseWriter, ch)
 	case Error:
-		return rw.writeErrors(ctx, state.ServiceErrors, state.HTTPResponseWriter, ch)
+		return rw.writeErrors(ctx, state.ServiceErrors, state.ServiceWarnings, state.HTTPResponseWriter, ch)
 	case Abnormal:
-		return rw.writeAbnormalStatus(ctx, state.Status, state.HTTPResponseWriter, ch)
+		return rw.writeAbnormalStatus(ctx, state.Status, state.Locales(), state.HTTPResponseWriter, ch)
 	}
 
 	return errors.New("Unsuported Outcome value")
@@ -105,8 +105,9 @@ func (rw *MarshallingResponseWriter) write(ctx context.Context, res *Response, w
 	w.WriteHeader(s)
 
 	e := res.Errors
+	wn := res.Warnings
 
-	if res.Body == nil && !e.HasErrors() {
+	if res.Body == nil && !e.HasErrors() && !wn.HasWarnings() {
 		return nil
 	}
 
@@ -114,7 +115,17 @@ func (rw *MarshallingResponseWriter) write(ctx context.Context, res *Response, w
 	wrap := rw.ResponseWrapper
 
 	fe := ef.FormatErrors(e)
-	wrapper := wrap.WrapResponse(res.Body, fe)
+
+	var wrapper interface{}
+
+	wf, canFormat := ef.(WarningFormatter)
+	ww, canWrap := wrap.(WarningResponseWrapper)
+
+	if wn.HasWarnings() && canFormat && canWrap {
+		wrapper = ww.WrapResponseWithWarnings(res.Body, fe, wf.FormatWarnings(wn))
+	} else {
+		wrapper = wrap.WrapResponse(res.Body, fe)
+	}
 
 	return rw.MarshalingWriter.MarshalAndWrite(wrapper, w)
 }
@@ -124,13 +135,13 @@ func (rw *MarshallingResponseWriter) WriteAbnormalStatus(ctx context.Context, st
 	return rw.Write(ctx, state, Abnormal)
 }
 
-func (rw *MarshallingResponseWriter) writeAbnormalStatus(ctx context.Context, status int, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
+func (rw *MarshallingResponseWriter) writeAbnormalStatus(ctx context.Context, status int, locales []string, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
 
 	res := new(Response)
 	res.HTTPStatus = status
 	var errors ServiceErrors
 
-	e := rw.FrameworkErrors.HTTPError(status)
+	e := rw.FrameworkErrors.LocalisedHTTPError(status, locales)
 	errors.AddError(e)
 
 	res.Errors = &errors
@@ -139,10 +150,11 @@ func (rw *MarshallingResponseWriter) writeAbnormalStatus(ctx context.Context, st
 
 }
 
-func (rw *MarshallingResponseWriter) writeErrors(ctx context.Context, errors *ServiceErrors, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
+func (rw *MarshallingResponseWriter) writeErrors(ctx context.Context, errors *ServiceErrors, warnings *ServiceWarnings, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
 
 	res := new(Response)
 	res.Errors = errors
+	res.Warnings = warnings
 
 	return rw.write(ctx, res, w, ch)
 }
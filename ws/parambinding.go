@@ -31,7 +31,7 @@ func (pb *ParamBinder) BindPathParameters(wsReq *Request, p *types.Params) {
 	for i, fieldName := range p.ParamNames() {
 
 		if rt.HasFieldOfName(t, fieldName) {
-			err := pb.bindValueToField(strconv.Itoa(i), fieldName, p, t, pb.pathParamError)
+			err := pb.bindValueToField(strconv.Itoa(i), fieldName, p, t, wsReq.Locales, pb.pathParamError(wsReq.Locales))
 
 			if err != nil {
 
@@ -69,7 +69,7 @@ func (pb *ParamBinder) BindQueryParameters(wsReq *Request, targets map[string]st
 			if p.Exists(param) {
 				l.LogTracef("Binding parameter %s to field %s", param, field)
 
-				err := pb.bindValueToField(param, field, p, t, pb.queryParamError)
+				err := pb.bindValueToField(param, field, p, t, wsReq.Locales, pb.queryParamError(wsReq.Locales))
 
 				if err != nil {
 					if fe, okay := err.(*FrameworkError); okay {
@@ -84,7 +84,7 @@ func (pb *ParamBinder) BindQueryParameters(wsReq *Request, targets map[string]st
 
 		} else {
 			l.LogErrorf("No field named %s exists to bind a query parameter into", field)
-			m, c := pb.FrameworkErrors.MessageCode(QueryNoTargetField, field, param)
+			m, c := pb.FrameworkErrors.LocalisedMessageCode(QueryNoTargetField, wsReq.Locales, field, param)
 			wsReq.AddFrameworkError(NewQueryBindFrameworkError(m, c, param, field))
 		}
 	}
@@ -104,7 +104,7 @@ func (pb *ParamBinder) AutoBindQueryParameters(wsReq *Request) {
 
 		if rt.HasFieldOfName(t, paramName) {
 
-			err := pb.bindValueToField(paramName, paramName, p, t, pb.queryParamError)
+			err := pb.bindValueToField(paramName, paramName, p, t, wsReq.Locales, pb.queryParamError(wsReq.Locales))
 
 			if err != nil {
 
@@ -125,10 +125,10 @@ func (pb *ParamBinder) AutoBindQueryParameters(wsReq *Request) {
 	pb.initialiseUnsetNilables(t)
 }
 
-func (pb *ParamBinder) bindValueToField(paramName string, fieldName string, p *types.Params, t interface{}, errorFn types.GenerateMappingError) error {
+func (pb *ParamBinder) bindValueToField(paramName string, fieldName string, p *types.Params, t interface{}, locales []string, errorFn types.GenerateMappingError) error {
 
 	if !rt.TargetFieldIsArray(t, fieldName) && p.MultipleValues(paramName) {
-		m, c := pb.FrameworkErrors.MessageCode(QueryTargetNotArray, fieldName)
+		m, c := pb.FrameworkErrors.LocalisedMessageCode(QueryTargetNotArray, locales, fieldName)
 		return NewQueryBindFrameworkError(m, c, paramName, fieldName)
 	}
 
@@ -176,28 +176,32 @@ FieldLoop:
 
 }
 
-func (pb *ParamBinder) queryParamError(paramName string, fieldName string, typeName string, p *types.Params) error {
+func (pb *ParamBinder) queryParamError(locales []string) types.GenerateMappingError {
 
-	var v = ""
+	return func(paramName string, fieldName string, typeName string, p *types.Params) error {
 
-	if p.Exists(paramName) {
-		v, _ = p.StringValue(paramName)
-	}
+		var v = ""
 
-	m, c := pb.FrameworkErrors.MessageCode(QueryWrongType, paramName, typeName, v)
-	return NewQueryBindFrameworkError(m, c, paramName, fieldName)
+		if p.Exists(paramName) {
+			v, _ = p.StringValue(paramName)
+		}
 
+		m, c := pb.FrameworkErrors.LocalisedMessageCode(QueryWrongType, locales, paramName, typeName, v)
+		return NewQueryBindFrameworkError(m, c, paramName, fieldName)
+	}
 }
 
-func (pb *ParamBinder) pathParamError(paramName string, fieldName string, typeName string, p *types.Params) error {
+func (pb *ParamBinder) pathParamError(locales []string) types.GenerateMappingError {
 
-	var v = ""
+	return func(paramName string, fieldName string, typeName string, p *types.Params) error {
 
-	if p.Exists(paramName) {
-		v, _ = p.StringValue(paramName)
-	}
+		var v = ""
 
-	m, c := pb.FrameworkErrors.MessageCode(PathWrongType, paramName, typeName, v)
-	return NewPathBindFrameworkError(m, c, fieldName)
+		if p.Exists(paramName) {
+			v, _ = p.StringValue(paramName)
+		}
 
+		m, c := pb.FrameworkErrors.LocalisedMessageCode(PathWrongType, locales, paramName, typeName, v)
+		return NewPathBindFrameworkError(m, c, fieldName)
+	}
 }
@@ -15,6 +15,9 @@ type Request struct {
 	// The HTTP method (GET, POST etc) of the underlying HTTP request.
 	HTTPMethod string
 
+	// The path component of the underlying HTTP request's URL.
+	Path string
+
 	// If the HTTP request had a body and if the handler that generated this Request implements WsUnmarshallTarget,
 	// then RequestBody will contain a struct representation of the request body.
 	RequestBody interface{}
@@ -41,6 +44,10 @@ type Request struct {
 
 	// The unique ID assigned to this request and stored in the context
 	ID func(ctx context.Context) string
+
+	// The caller's preferred locales (most preferred first), taken from the caller's identity and/or
+	// the Accept-Language header.
+	Locales []string
 }
 
 // HasFrameworkErrors returns true if one or more framework errors have been recorded.
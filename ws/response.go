@@ -114,6 +114,9 @@ type ProcessState struct {
 	// Errors detected while processing the web service request. If set, supersedes the errors present in Response field.
 	ServiceErrors *ServiceErrors
 
+	// Warnings detected while processing the web service request. If set, supersedes the warnings present in Response field.
+	ServiceWarnings *ServiceWarnings
+
 	// Information about the caller or user of the web service.
 	Identity iam.ClientIdentity
 
@@ -130,6 +133,15 @@ func NewAbnormalState(status int, w *httpendpoint.HTTPResponseWriter) *ProcessSt
 	return state
 }
 
+// Locales returns the caller's preferred locales if they are known, or nil otherwise.
+func (ps *ProcessState) Locales() []string {
+	if ps.WsRequest == nil {
+		return nil
+	}
+
+	return ps.WsRequest.Locales
+}
+
 // Response contains data that is relevant to the rendering of the result of a web service request to an HTTP response. This
 // type is agnostic of the format (JSON, XML etc) that is to be used to render the response.
 type Response struct {
@@ -144,6 +156,10 @@ type Response struct {
 	// All of the errors encountered while processing this request.
 	Errors *ServiceErrors
 
+	// Non-blocking problems encountered while processing this request. Warnings are rendered alongside the body
+	// and do not affect the HTTP status code.
+	Warnings *ServiceWarnings
+
 	// Headers that should be set on the HTTP response.
 	Headers map[string]string
 
@@ -157,6 +173,7 @@ func NewResponse(errorFinder ServiceErrorFinder) *Response {
 	r := new(Response)
 	r.Errors = new(ServiceErrors)
 	r.Errors.ErrorFinder = errorFinder
+	r.Warnings = NewServiceWarnings(errorFinder)
 
 	r.Headers = make(map[string]string)
 
@@ -0,0 +1,75 @@
+// Copyright 2016-2020 Granitic. All rights reserved.
+// Use of this source code is governed by an Apache 2.0 license that can be found in the LICENSE file at the root of this project.
+
+package ws
+
+// ServiceWarnings records non-blocking problems found during the processing of a request. Unlike ServiceErrors, the
+// presence of warnings does not stop a request from being processed or affect the HTTP status code of the response.
+type ServiceWarnings struct {
+	// All warnings found, in the order in which they occurred.
+	Warnings []CategorisedError
+
+	// A component able to find additional information about a warning from that warning's unique code.
+	ErrorFinder ServiceErrorFinder
+
+	// The caller's preferred locales, most preferred first. Used to choose a message if ErrorFinder
+	// implements LocalisedServiceErrorFinder.
+	Locales []string
+}
+
+// NewServiceWarnings creates an empty ServiceWarnings that will use the supplied ServiceErrorFinder to look up the
+// definitions of predefined warnings.
+func NewServiceWarnings(finder ServiceErrorFinder) *ServiceWarnings {
+	sw := new(ServiceWarnings)
+	sw.ErrorFinder = finder
+	sw.Warnings = make([]CategorisedError, 0)
+
+	return sw
+}
+
+// AddNewWarning creates a new CategorisedError in the Warning category from the supplied information and captures it.
+func (sw *ServiceWarnings) AddNewWarning(code string, message string, field ...string) {
+
+	w := NewCategorisedError(Warning, code, message)
+
+	if len(field) > 0 {
+		w.Field = field[0]
+	}
+
+	sw.Warnings = append(sw.Warnings, *w)
+}
+
+// AddPredefinedWarning creates a CategorisedError by looking up the supplied code and records it as a warning. If the variadic field
+// parameter is supplied, the created warning will be associated with that field name. The warning is recorded in the
+// Warning category, whatever category the code was originally defined with.
+func (sw *ServiceWarnings) AddPredefinedWarning(code string, field ...string) {
+
+	if sw.ErrorFinder == nil {
+		panic("No source of errors defined")
+	}
+
+	w := findPredefined(sw.ErrorFinder, sw.Locales, code, field...)
+	w.Category = Warning
+
+	sw.Warnings = append(sw.Warnings, w)
+}
+
+// HasWarnings returns true if one or more warnings have been recorded.
+func (sw *ServiceWarnings) HasWarnings() bool {
+	return sw != nil && len(sw.Warnings) != 0
+}
+
+// WarningFormatter is implemented by ErrorFormatters that are also able to convert a set of warnings into a structure
+// suitable for serialisation.
+type WarningFormatter interface {
+	// FormatWarnings converts the supplied warnings into a structure that a response writer will use to write the warnings to
+	// the current HTTP response.
+	FormatWarnings(warnings *ServiceWarnings) interface{}
+}
+
+// WarningResponseWrapper is implemented by ResponseWrappers that are able to include warnings in the common structure
+// they wrap responses in.
+type WarningResponseWrapper interface {
+	// WrapResponseWithWarnings takes the supplied body, errors and warnings and wraps them in a standardised data structure.
+	WrapResponseWithWarnings(body interface{}, errors interface{}, warnings interface{}) interface{}
+}
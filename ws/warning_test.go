@@ -0,0 +1,52 @@
+package ws
+
+import "testing"
+
+func TestPredefinedWarnings(t *testing.T) {
+
+	sw := NewServiceWarnings(new(mockFinder))
+
+	if sw.HasWarnings() {
+		t.Fatalf("Expected no warnings")
+	}
+
+	sw.AddPredefinedWarning("TRUNCATED", "Name")
+
+	w := sw.Warnings[0]
+
+	if w.Category != Warning || w.Field != "Name" || w.Message != "Value was truncated" {
+		t.Errorf("Unexpected warning %v", w)
+	}
+
+	r := NewResponse(new(mockFinder))
+	r.Warnings = sw
+
+	if NewGraniticHTTPStatusCodeDeterminer().DetermineCode(r) != 200 {
+		t.Errorf("Warnings should not affect the HTTP status")
+	}
+
+	var nilWarnings *ServiceWarnings
+
+	if nilWarnings.HasWarnings() {
+		t.Errorf("Expected nil warnings to have no warnings")
+	}
+}
+
+func TestWarningCategoryCodes(t *testing.T) {
+
+	c, err := CodeToCategory("W")
+
+	if err != nil || c != Warning {
+		t.Fatalf("Expected W to map to the Warning category")
+	}
+
+	if CategoryToCode(Warning) != "W" || CategoryToName(Warning) != "Warning" {
+		t.Errorf("Unexpected code or name for Warning category")
+	}
+}
+
+type mockFinder struct{}
+
+func (mf *mockFinder) Find(code string) *CategorisedError {
+	return NewCategorisedError(Warning, code, "Value was truncated")
+}
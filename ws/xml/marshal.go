@@ -108,11 +108,21 @@ func (rw *GraniticXMLResponseWrapper) WrapResponse(body interface{}, errors inte
 
 }
 
+// WrapResponseWithWarnings wraps the supplied data, errors and warnings with an XMLWrapper
+func (rw *GraniticXMLResponseWrapper) WrapResponseWithWarnings(body interface{}, errors interface{}, warnings interface{}) interface{} {
+
+	w := rw.WrapResponse(body, errors).(*GraniticXMLWrapper)
+	w.Warnings = warnings
+
+	return w
+}
+
 // GraniticXMLWrapper is a wrapper for web service data and errors giving a consistent structure across all XML endpoints.
 type GraniticXMLWrapper struct {
-	XMLName xml.Name
-	Errors  interface{}
-	Body    interface{} `xml:"body"`
+	XMLName  xml.Name
+	Errors   interface{}
+	Warnings interface{}
+	Body     interface{} `xml:"body"`
 }
 
 // GraniticXMLErrorFormatter converts service errors into a data structure for consistent serialisation to XML.
@@ -125,15 +135,30 @@ func (ef *GraniticXMLErrorFormatter) FormatErrors(errors *ws.ServiceErrors) inte
 		return nil
 	}
 
+	return ef.format(errors.Errors, "errors", "error")
+}
+
+// FormatWarnings converts all of the warnings present in the supplied object into a structure suitable for serialisation.
+func (ef *GraniticXMLErrorFormatter) FormatWarnings(warnings *ws.ServiceWarnings) interface{} {
+
+	if !warnings.HasWarnings() {
+		return nil
+	}
+
+	return ef.format(warnings.Warnings, "warnings", "warning")
+}
+
+func (ef *GraniticXMLErrorFormatter) format(errors []ws.CategorisedError, container, element string) interface{} {
+
 	es := new(Errors)
-	es.XMLName = xml.Name{Space: "", Local: "errors"}
+	es.XMLName = xml.Name{Space: "", Local: container}
 
-	fe := make([]*GraniticError, len(errors.Errors))
+	fe := make([]*GraniticError, len(errors))
 
-	for i, se := range errors.Errors {
+	for i, se := range errors {
 
 		e := new(GraniticError)
-		e.XMLName = xml.Name{Space: "", Local: "error"}
+		e.XMLName = xml.Name{Space: "", Local: element}
 
 		fe[i] = e
 		e.Error = se.Message
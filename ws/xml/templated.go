@@ -62,9 +62,9 @@ func (rw *TemplatedXMLResponseWriter) Write(ctx context.Context, state *ws.Proce
 	case ws.Normal:
 		return rw.writeNormal(ctx, state.WsResponse, state.HTTPResponseWriter, ch)
 	case ws.Error:
-		return rw.writeErrors(ctx, state.WsResponse, state.ServiceErrors, state.HTTPResponseWriter, ch)
+		return rw.writeErrors(ctx, state.WsResponse, state.ServiceErrors, state.ServiceWarnings, state.HTTPResponseWriter, ch)
 	case ws.Abnormal:
-		return rw.writeAbnormalStatus(ctx, state.Status, state.HTTPResponseWriter, ch)
+		return rw.writeAbnormalStatus(ctx, state.Status, state.Locales(), state.HTTPResponseWriter, ch)
 	}
 
 	return errors.New("Unsuported ws.Outcome value")
@@ -105,7 +105,7 @@ func (rw *TemplatedXMLResponseWriter) write(ctx context.Context, res *ws.Respons
 
 	e := res.Errors
 
-	if res.Body == nil && !e.HasErrors() {
+	if res.Body == nil && !e.HasErrors() && !res.Warnings.HasWarnings() {
 		return nil
 	}
 
@@ -113,13 +113,17 @@ func (rw *TemplatedXMLResponseWriter) write(ctx context.Context, res *ws.Respons
 
 }
 
-func (rw *TemplatedXMLResponseWriter) writeErrors(ctx context.Context, res *ws.Response, se *ws.ServiceErrors, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
+func (rw *TemplatedXMLResponseWriter) writeErrors(ctx context.Context, res *ws.Response, se *ws.ServiceErrors, sw *ws.ServiceWarnings, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
 
 	var t *template.Template
 	var tn string
 
 	res.Errors = se
 
+	if sw != nil {
+		res.Warnings = sw
+	}
+
 	if res.Template != "" {
 		tn = res.Template
 	} else if rw.ErrorTemplate != "" {
@@ -141,7 +145,7 @@ func (rw *TemplatedXMLResponseWriter) WriteAbnormalStatus(ctx context.Context, s
 	return rw.Write(ctx, state, ws.Abnormal)
 }
 
-func (rw *TemplatedXMLResponseWriter) writeAbnormalStatus(ctx context.Context, status int, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
+func (rw *TemplatedXMLResponseWriter) writeAbnormalStatus(ctx context.Context, status int, locales []string, w *httpendpoint.HTTPResponseWriter, ch map[string]string) error {
 
 	var t *template.Template
 	var tn string
@@ -158,7 +162,7 @@ func (rw *TemplatedXMLResponseWriter) writeAbnormalStatus(ctx context.Context, s
 	res.HTTPStatus = status
 	var errors ws.ServiceErrors
 
-	e := rw.FrameworkErrors.HTTPError(status)
+	e := rw.FrameworkErrors.LocalisedHTTPError(status, locales)
 	errors.AddError(e)
 
 	res.Errors = &errors